@@ -0,0 +1,330 @@
+// Package audit provides an append-only audit trail of the questions asked by the
+// users and the actions of the administrators, for the compliance and the abuse
+// investigations.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// Kind is the kind of an audited event.
+type Kind string
+
+const (
+	KindQuestion Kind = "question" // KindQuestion is a question asked by a user.
+	KindAdmin    Kind = "admin"    // KindAdmin is a command of an administrator.
+)
+
+// Entry is a record of the audit trail.
+type Entry struct {
+	Time  time.Time // Time is the time of the event.
+	Kind  Kind      // Kind is the kind of the event.
+	User  int64     // User is the ID of the user who caused the event.
+	Chat  int64     // Chat is the ID of the chat where the event happened.
+	Model string    `json:",omitempty"` // Model is the model which answered the question.
+	Cost  chat.Cost `json:",omitempty"` // Cost is the cost of the answer.
+	Text  string    `json:",omitempty"` // Text is the question or the command with its arguments.
+}
+
+// Filter selects the entries of the audit trail.
+type Filter struct {
+	User  int64     // User selects the entries of the user; zero selects all users.
+	Kind  Kind      // Kind selects the entries of the kind; empty selects all kinds.
+	Since time.Time // Since selects the entries since the time; zero selects all entries.
+	Limit int       // Limit is the maximum number of the latest entries; zero means no limit.
+}
+
+// matches reports whether the entry is selected by the filter.
+func (f Filter) matches(e Entry) bool {
+	return (f.User == 0 || e.User == f.User) &&
+		(f.Kind == "" || e.Kind == f.Kind) &&
+		!e.Time.Before(f.Since)
+}
+
+// filePrefix and fileExt make the names of the files of the audit trail, one per month,
+// e.g. "audit-2024-05.jsonl".
+const (
+	filePrefix = "audit-"
+	fileExt    = ".jsonl"
+)
+
+// File is an audit trail stored in a dedicated directory as JSON lines, in a file per
+// month. The entries are appended to the files and removed only by Erase and Prune.
+type File struct {
+	Dir string // Dir is the directory of the files.
+
+	// Key encrypts every entry with AES-GCM, e.g. with the key of the storage encryption;
+	// nil records the entries in plaintext. The plaintext entries recorded before the
+	// encryption was enabled are still read.
+	Key []byte
+
+	mu   sync.Mutex
+	aead cipher.AEAD // aead is the cipher of the Key, created on the first use.
+}
+
+// Append records the entry at the end of the file of its month.
+//
+// ctx: The context of the operation.
+// entry: The entry to record.
+//
+// Returns an error if the entry could not be written.
+func (f *File) Append(ctx context.Context, entry Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	line, err := f.encode(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return fmt.Errorf("error creating the audit directory: %w", err)
+	}
+
+	name := filePrefix + entry.Time.UTC().Format("2006-01") + fileExt
+	file, err := os.OpenFile(filepath.Join(f.Dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening the audit file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing the audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns the entries selected by the filter in the order they were recorded.
+// The lines which cannot be decoded, like a line cut short by a crash, are skipped.
+//
+// ctx: The context of the operation.
+// filter: The filter selecting the entries.
+//
+// Returns an error if the files could not be read.
+func (f *File) Query(ctx context.Context, filter Filter) ([]Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names, err := f.files()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	for _, name := range names {
+		// Skip the files of the months before the filter.
+		month := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileExt)
+		if !filter.Since.IsZero() && month < filter.Since.UTC().Format("2006-01") {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if entries, err = f.readFile(filepath.Join(f.Dir, name), filter, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+
+	return entries, nil
+}
+
+// Erase removes the entries of the user, e.g. on the erasure of the user's data.
+// The entries of the chats with the user, such as the private chat, are removed as
+// well.
+//
+// ctx: The context of the operation.
+// user: The ID of the user, or the pseudonym the user's entries are recorded with.
+//
+// Returns an error if the files could not be rewritten.
+func (f *File) Erase(ctx context.Context, user int64) error {
+	return f.rewrite(ctx, func(e Entry) bool {
+		return e.User != user && e.Chat != user
+	})
+}
+
+// Prune removes the entries recorded before the time, for the data minimization.
+//
+// ctx: The context of the operation.
+// before: The time of the oldest entry kept.
+//
+// Returns an error if the files could not be rewritten.
+func (f *File) Prune(ctx context.Context, before time.Time) error {
+	return f.rewrite(ctx, func(e Entry) bool {
+		return !e.Time.Before(before)
+	})
+}
+
+// rewrite replaces every file of the audit trail with the entries which are kept. The
+// files are replaced atomically, and the files left with no entries are removed. The
+// lines which cannot be decoded are dropped.
+//
+// ctx: The context of the operation.
+// keep: Reports whether the entry is kept.
+//
+// Returns an error if a file could not be read or replaced.
+func (f *File) rewrite(ctx context.Context, keep func(Entry) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names, err := f.files()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		path := filepath.Join(f.Dir, name)
+		entries, err := f.readFile(path, Filter{}, nil)
+		if err != nil {
+			return err
+		}
+
+		kept := entries[:0]
+		for _, entry := range entries {
+			if keep(entry) {
+				kept = append(kept, entry)
+			}
+		}
+
+		if len(kept) == len(entries) {
+			continue
+		}
+
+		if err := f.replaceFile(path, kept); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// files returns the names of the files of the audit trail in the chronological order.
+// The caller must hold the mutex.
+func (f *File) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(f.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the audit directory: %w", err)
+	}
+
+	var names []string
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileExt) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// replaceFile replaces the file atomically with the entries, or removes it if there
+// are none. The caller must hold the mutex.
+func (f *File) replaceFile(path string, entries []Entry) (err error) {
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing the audit file: %w", err)
+		}
+		return nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating the audit file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(file)
+	for _, entry := range entries {
+		line, err := f.encode(entry)
+		if err != nil {
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing the audit file: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error writing the audit file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing the audit file: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("error replacing the audit file: %w", err)
+	}
+
+	return nil
+}
+
+// readFile appends the entries of the file selected by the filter to the slice. The
+// caller must hold the mutex.
+func (f *File) readFile(path string, filter Filter, entries []Entry) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the audit file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		entry, err := f.decode(scanner.Bytes())
+		if errors.Is(err, errNoKey) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the audit file: %w", err)
+	}
+
+	return entries, nil
+}
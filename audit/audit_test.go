@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	log := &File{Dir: t.TempDir()}
+
+	april := time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC)
+	may := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: april, Kind: KindQuestion, User: 1, Chat: 1, Model: "gpt-4", Cost: 0.5, Text: "Hello"},
+		{Time: may, Kind: KindAdmin, User: 2, Chat: 2, Text: "/ban 1"},
+		{Time: may.Add(time.Hour), Kind: KindQuestion, User: 1, Chat: 1, Model: "gpt-4", Text: "Bye"},
+	}
+	for _, entry := range entries {
+		if err := log.Append(ctx, entry); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	// A line cut short by a crash is skipped.
+	file, err := os.OpenFile(filepath.Join(log.Dir, "audit-2024-05.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	file.WriteString(`{"Time": "2024-05-01`)
+	file.Close()
+
+	tests := []struct {
+		filter Filter
+		want   []string
+	}{
+		{Filter{}, []string{"Hello", "/ban 1", "Bye"}},
+		{Filter{User: 1}, []string{"Hello", "Bye"}},
+		{Filter{Kind: KindAdmin}, []string{"/ban 1"}},
+		{Filter{Since: may}, []string{"/ban 1", "Bye"}},
+		{Filter{User: 1, Limit: 1}, []string{"Bye"}},
+	}
+	for _, test := range tests {
+		got, err := log.Query(ctx, test.filter)
+		if err != nil {
+			t.Fatalf("Query(%+v): %v", test.filter, err)
+		}
+
+		texts := make([]string, len(got))
+		for i, entry := range got {
+			texts[i] = entry.Text
+		}
+		if len(texts) != len(test.want) {
+			t.Errorf("Query(%+v) = %q, want %q", test.filter, texts, test.want)
+			continue
+		}
+		for i := range texts {
+			if texts[i] != test.want[i] {
+				t.Errorf("Query(%+v) = %q, want %q", test.filter, texts, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFileErase(t *testing.T) {
+	ctx := context.Background()
+	log := &File{Dir: t.TempDir()}
+
+	april := time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC)
+	may := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	for _, entry := range []Entry{
+		{Time: april, Kind: KindQuestion, User: 1, Chat: 1, Text: "Hello"},
+		{Time: may, Kind: KindAdmin, User: 2, Chat: 2, Text: "/ban 1"},
+		{Time: may, Kind: KindQuestion, User: 1, Chat: -5, Text: "Bye"},
+	} {
+		if err := log.Append(ctx, entry); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	if err := log.Erase(ctx, 1); err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+
+	got, err := log.Query(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 || got[0].User != 2 {
+		t.Errorf("Query() = %+v after Erase, want the entry of the user 2", got)
+	}
+
+	// The file left with no entries is removed.
+	if _, err := os.Stat(filepath.Join(log.Dir, "audit-2024-04.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("The emptied file was kept: %v", err)
+	}
+}
+
+func TestFileEncryptionAndPrune(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// An entry recorded before the encryption was enabled.
+	april := time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC)
+	if err := (&File{Dir: dir}).Append(ctx, Entry{Time: april, Kind: KindQuestion, User: 1, Text: "Plain"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	log := &File{Dir: dir, Key: make([]byte, 32)}
+	may := time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC)
+	if err := log.Append(ctx, Entry{Time: may, Kind: KindQuestion, User: 1, Text: "My secret"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "audit-2024-05.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("The entry is recorded in plaintext: %s", data)
+	}
+
+	got, err := log.Query(ctx, Filter{})
+	if err != nil || len(got) != 2 || got[1].Text != "My secret" {
+		t.Errorf("Query() = %+v, %v, want both entries", got, err)
+	}
+
+	// The encrypted entries are not read without the key.
+	if _, err := (&File{Dir: dir}).Query(ctx, Filter{}); err == nil {
+		t.Error("Query() without the key succeeded")
+	}
+
+	if err := log.Prune(ctx, may); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if got, err := log.Query(ctx, Filter{}); err != nil || len(got) != 1 || got[0].Text != "My secret" {
+		t.Errorf("Query() = %+v, %v after Prune, want the entry of May", got, err)
+	}
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errNoKey is returned when an encrypted entry is read without the key.
+var errNoKey = errors.New("audit trail is encrypted, but no encryption key is configured")
+
+// keyCipher returns the cipher of the Key, or nil if the entries are not encrypted. The
+// caller must hold the mutex.
+func (f *File) keyCipher() (cipher.AEAD, error) {
+	if f.aead != nil || f.Key == nil {
+		return f.aead, nil
+	}
+
+	block, err := aes.NewCipher(f.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit encryption key: %w", err)
+	}
+
+	if f.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("could not create the audit cipher: %w", err)
+	}
+
+	return f.aead, nil
+}
+
+// encode returns the line of the entry: the entry in JSON, or the random nonce and the
+// entry sealed with the Key, encoded in base64, if the entries are encrypted. The
+// caller must hold the mutex.
+//
+// entry: The entry to encode.
+func (f *File) encode(entry Entry) ([]byte, error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the audit entry: %w", err)
+	}
+
+	aead, err := f.keyCipher()
+	if err != nil || aead == nil {
+		return line, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate the nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, line, nil)
+	return []byte(base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// decode parses the line written by encode. The plaintext lines are read regardless
+// of the Key. The caller must hold the mutex.
+//
+// line: The line of the file.
+//
+// Returns the entry, errNoKey if the line is encrypted and there is no Key, or another
+// error if the line is broken or encrypted with another key.
+func (f *File) decode(line []byte) (Entry, error) {
+	var entry Entry
+	if bytes.HasPrefix(line, []byte("{")) {
+		err := json.Unmarshal(line, &entry)
+		return entry, err
+	}
+
+	aead, err := f.keyCipher()
+	if err != nil {
+		return entry, err
+	}
+	if aead == nil {
+		return entry, errNoKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(line))
+	if err != nil {
+		return entry, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return entry, errors.New("audit entry is truncated")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return entry, fmt.Errorf("could not decrypt the audit entry: %w", err)
+	}
+
+	err = json.Unmarshal(plain, &entry)
+	return entry, err
+}
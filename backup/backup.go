@@ -0,0 +1,363 @@
+// Package backup snapshots all the content of a chat.Storage into a single archive and
+// restores it, independently of the storage backend. The archive is a gzip-compressed
+// tar file with a JSON file per stored object, grouped in directories by their kind,
+// optionally encrypted (see Encrypt).
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// The kinds of the stored objects, which name the directories of the archive.
+const (
+	kindHistory    = "history"
+	kindStatistics = "statistics"
+	kindProfile    = "profile"
+	kindBudget     = "budget"
+	kindLedger     = "ledger"
+	kindInvite     = "invite"
+	kindRollup     = "rollup"
+	kindUpdates    = "updates"
+	kindSettings   = "settings"
+)
+
+// Summary counts the objects written to or read from the archive by their kind.
+type Summary map[string]int
+
+// String formats the summary for the logs, e.g. "history: 3, profile: 2".
+func (s Summary) String() string {
+	var buf bytes.Buffer
+	for _, kind := range []string{
+		kindHistory, kindStatistics, kindProfile, kindBudget, kindLedger, kindInvite, kindRollup, kindUpdates,
+		kindSettings,
+	} {
+		if s[kind] == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%s: %d", kind, s[kind])
+	}
+
+	return buf.String()
+}
+
+// object is a stored object which can be written to the archive.
+type object interface {
+	Write(w io.Writer) error
+}
+
+// archive writes the objects to the tar archive.
+type archive struct {
+	tw      *tar.Writer
+	summary Summary
+	now     time.Time
+}
+
+// add writes the object to the archive.
+//
+// kind: The kind of the object.
+// obj: The object.
+func (a *archive) add(kind string, obj object) error {
+	var buf bytes.Buffer
+	if err := obj.Write(&buf); err != nil {
+		return fmt.Errorf("error encoding %s: %w", kind, err)
+	}
+
+	a.summary[kind]++
+	err := a.tw.WriteHeader(&tar.Header{
+		Name:    fmt.Sprintf("%s/%06d.json", kind, a.summary[kind]),
+		Mode:    0644,
+		Size:    int64(buf.Len()),
+		ModTime: a.now,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+
+	if _, err := a.tw.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+
+	return nil
+}
+
+// Create writes all the content of the storage to the archive.
+//
+// ctx: The context for the storage operations.
+// storage: The storage to back up.
+// w: The writer of the archive.
+//
+// Returns the number of the objects written by their kind, and an error if the
+// storage could not be read or the archive could not be written.
+func Create(ctx context.Context, storage chat.Storage, w io.Writer) (Summary, error) {
+	zw := gzip.NewWriter(w)
+	a := &archive{tw: tar.NewWriter(zw), summary: Summary{}, now: time.Now()}
+
+	if err := a.addAll(ctx, storage); err != nil {
+		return nil, err
+	}
+
+	if err := a.tw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing archive: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing archive: %w", err)
+	}
+
+	return a.summary, nil
+}
+
+// addAll writes every object of the storage to the archive, including the objects
+// of the optional interfaces the storage implements.
+func (a *archive) addAll(ctx context.Context, storage chat.Storage) error {
+	ids, err := storage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing sessions: %w", err)
+	}
+
+	for _, id := range ids {
+		history, err := storage.LoadHistory(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error loading history: %w", err)
+		}
+		if err := a.add(kindHistory, history); err != nil {
+			return err
+		}
+
+		statistics, err := storage.LoadStatistics(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error loading statistics: %w", err)
+		}
+		if err := a.add(kindStatistics, statistics); err != nil {
+			return err
+		}
+	}
+
+	if profiles, ok := storage.(chat.Profiles); ok {
+		users, err := profiles.ListProfiles(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing profiles: %w", err)
+		}
+
+		for _, user := range users {
+			profile, err := profiles.LoadProfile(ctx, user)
+			if err != nil {
+				return fmt.Errorf("error loading profile: %w", err)
+			}
+			if err := a.add(kindProfile, profile); err != nil {
+				return err
+			}
+		}
+	}
+
+	if budgets, ok := storage.(chat.Budgets); ok {
+		owners, err := budgets.ListBudgets(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing budgets: %w", err)
+		}
+
+		for _, owner := range owners {
+			budget, err := budgets.LoadBudget(ctx, owner)
+			if err != nil {
+				return fmt.Errorf("error loading budget: %w", err)
+			}
+			if err := a.add(kindBudget, budget); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The ledgers follow the statistics, so they replace the totals the restored
+	// statistics add to them.
+	if ledgers, ok := storage.(chat.Ledgers); ok {
+		owners, err := ledgers.ListLedgers(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing ledgers: %w", err)
+		}
+
+		for _, owner := range owners {
+			ledger, err := ledgers.LoadLedger(ctx, owner)
+			if err != nil {
+				return fmt.Errorf("error loading ledger: %w", err)
+			}
+			if err := a.add(kindLedger, ledger); err != nil {
+				return err
+			}
+		}
+	}
+
+	if invites, ok := storage.(chat.Invites); ok {
+		codes, err := invites.ListInvites(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing invites: %w", err)
+		}
+
+		for _, code := range codes {
+			invite, err := invites.LoadInvite(ctx, code)
+			if err != nil {
+				return fmt.Errorf("error loading invite: %w", err)
+			}
+			if err := a.add(kindInvite, invite); err != nil {
+				return err
+			}
+		}
+	}
+
+	if rollups, ok := storage.(chat.Rollups); ok {
+		rollup, err := rollups.LoadRollup(ctx)
+		if err != nil {
+			return fmt.Errorf("error loading rollup: %w", err)
+		}
+		if err := a.add(kindRollup, rollup); err != nil {
+			return err
+		}
+	}
+
+	if progress, ok := storage.(chat.Progress); ok {
+		updates, err := progress.LoadUpdates(ctx)
+		if err != nil {
+			return fmt.Errorf("error loading updates: %w", err)
+		}
+		if err := a.add(kindUpdates, updates); err != nil {
+			return err
+		}
+	}
+
+	if configuration, ok := storage.(chat.Configuration); ok {
+		settings, err := configuration.LoadSettings(ctx)
+		if err != nil {
+			return fmt.Errorf("error loading settings: %w", err)
+		}
+		if err := a.add(kindSettings, settings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restore saves all the objects of the archive to the storage, replacing the stored
+// objects with the same identifiers. The other stored objects are kept.
+//
+// ctx: The context for the storage operations.
+// storage: The storage to restore.
+// r: The reader of the archive created by Create.
+//
+// Returns the number of the objects restored by their kind, and an error if the
+// archive could not be read or an object could not be saved.
+func Restore(ctx context.Context, storage chat.Storage, r io.Reader) (Summary, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	defer zr.Close()
+
+	summary := Summary{}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return summary, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		kind := path.Dir(header.Name)
+		if err := restore(ctx, storage, kind, tr); err != nil {
+			return nil, fmt.Errorf("error restoring %s: %w", header.Name, err)
+		}
+		summary[kind]++
+	}
+}
+
+// restore decodes the object of the kind and saves it to the storage. The objects of
+// an optional interface the storage does not implement fail with chat.ErrNotSupported.
+func restore(ctx context.Context, backend chat.Storage, kind string, r io.Reader) error {
+	storage := chat.Wrapper{Storage: backend}
+
+	switch kind {
+	case kindHistory:
+		history := new(chat.History)
+		if err := history.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveHistory(ctx, history)
+
+	case kindStatistics:
+		statistics := new(chat.Statistics)
+		if err := statistics.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveStatistics(ctx, statistics)
+
+	case kindProfile:
+		profile := new(chat.Profile)
+		if err := profile.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveProfile(ctx, profile)
+
+	case kindBudget:
+		budget := new(chat.Budget)
+		if err := budget.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveBudget(ctx, budget)
+
+	case kindLedger:
+		ledger := new(chat.Ledger)
+		if err := ledger.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveLedger(ctx, ledger)
+
+	case kindInvite:
+		invite := new(chat.Invite)
+		if err := invite.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveInvite(ctx, invite)
+
+	case kindRollup:
+		rollup := new(chat.Rollup)
+		if err := rollup.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveRollup(ctx, rollup)
+
+	case kindUpdates:
+		updates := new(chat.Updates)
+		if err := updates.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveUpdates(ctx, updates)
+
+	case kindSettings:
+		settings := new(chat.Settings)
+		if err := settings.Read(r); err != nil {
+			return err
+		}
+		return storage.SaveSettings(ctx, settings)
+
+	default:
+		return fmt.Errorf("unknown kind: %q", kind)
+	}
+}
@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/storage"
+)
+
+func TestCreateAndRestore(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	source := &storage.FS{BaseDir: t.TempDir()}
+
+	id := chat.ID{User: 1, Chat: -100123, Model: "gpt-4"}
+	history := &chat.History{ID: id, Version: chat.HistoryVersion, Prompt: "Be brief", Log: []chat.Message{{User: "Hi", Assistant: "Hello"}}}
+	statistics := &chat.Statistics{
+		Version:    chat.StatisticsVersion,
+		ID:         id,
+		Days:       map[string]chat.Cost{"2024-05-01": 1},
+		Months:     map[string]chat.Cost{"2024-05": 1},
+		Total:      1,
+		LastUpdate: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	profile := &chat.Profile{User: 2, Timezone: "Europe/Berlin", Banned: true}
+	budget := &chat.Budget{Owner: -100123, Monthly: 10}
+	invite := &chat.Invite{Code: "abc", MaxUses: 3, Created: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+
+	for _, err := range []error{
+		source.SaveHistory(ctx, history),
+		source.SaveStatistics(ctx, statistics),
+		source.SaveProfile(ctx, profile),
+		source.SaveBudget(ctx, budget),
+		source.SaveInvite(ctx, invite),
+		source.SaveUpdates(ctx, &chat.Updates{LastID: 42}),
+	} {
+		if err != nil {
+			t.Fatalf("Save failed: %s", err)
+		}
+	}
+
+	// Execute Create.
+	var archive bytes.Buffer
+	created, err := Create(ctx, source, &archive)
+	if err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+
+	// Execute Restore into another backend configuration.
+	target := &storage.FS{BaseDir: t.TempDir(), Compress: true}
+	restored, err := Restore(ctx, target, &archive)
+	if err != nil {
+		t.Fatalf("Restore failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(created, restored) {
+		t.Errorf("Restored %v, want %v", restored, created)
+	}
+
+	if loaded, err := target.LoadHistory(ctx, id); err != nil || !reflect.DeepEqual(loaded, history) {
+		t.Errorf("LoadHistory returned %+v, %v, want %+v", loaded, err, history)
+	}
+	if loaded, err := target.LoadStatistics(ctx, id); err != nil || !reflect.DeepEqual(loaded, statistics) {
+		t.Errorf("LoadStatistics returned %+v, %v, want %+v", loaded, err, statistics)
+	}
+	if loaded, err := target.LoadProfile(ctx, 2); err != nil || !reflect.DeepEqual(loaded, profile) {
+		t.Errorf("LoadProfile returned %+v, %v, want %+v", loaded, err, profile)
+	}
+	if loaded, err := target.LoadBudget(ctx, -100123); err != nil || !reflect.DeepEqual(loaded, budget) {
+		t.Errorf("LoadBudget returned %+v, %v, want %+v", loaded, err, budget)
+	}
+	if loaded, err := target.LoadInvite(ctx, "abc"); err != nil || !reflect.DeepEqual(loaded, invite) {
+		t.Errorf("LoadInvite returned %+v, %v, want %+v", loaded, err, invite)
+	}
+	if loaded, err := target.LoadUpdates(ctx); err != nil || loaded.LastID != 42 {
+		t.Errorf("LoadUpdates returned %+v, %v, want the last update 42", loaded, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	from := &storage.FS{BaseDir: t.TempDir()}
+	to := &storage.FS{BaseDir: t.TempDir(), Compress: true}
+
+	for i := int64(1); i <= 3; i++ {
+		id := chat.ID{User: i, Chat: i, Model: "gpt-4"}
+		if err := from.SaveHistory(ctx, &chat.History{ID: id, Log: []chat.Message{{User: "Hi"}}}); err != nil {
+			t.Fatalf("SaveHistory failed: %s", err)
+		}
+		if err := from.SaveStatistics(ctx, &chat.Statistics{ID: id, Total: chat.Cost(i)}); err != nil {
+			t.Fatalf("SaveStatistics failed: %s", err)
+		}
+	}
+
+	// Execute Migrate.
+	summary, err := Migrate(ctx, from, to)
+	if err != nil {
+		t.Fatalf("Migrate failed: %s", err)
+	}
+
+	// Assert.
+	if summary[kindHistory] != 3 || summary[kindStatistics] != 3 {
+		t.Errorf("Migrated %v, want 3 histories and 3 statistics", summary)
+	}
+
+	ids, err := to.List(ctx)
+	if err != nil || len(ids) != 3 {
+		t.Errorf("Listed %+v, %v in the target, want 3 sessions", ids, err)
+	}
+}
+
+// coreStorage is a storage implementing only the chat.Storage interface, without the
+// optional ones.
+type coreStorage struct {
+	chat.Storage
+}
+
+func TestCoreStorage(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	full := &storage.FS{BaseDir: t.TempDir()}
+	core := coreStorage{&storage.FS{BaseDir: t.TempDir()}}
+
+	id := chat.ID{User: 1, Chat: 1, Model: "gpt-4"}
+	if err := core.SaveHistory(ctx, &chat.History{ID: id, Log: []chat.Message{{User: "Hi"}}}); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	if err := full.SaveProfile(ctx, &chat.Profile{User: 1}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	// Execute & Assert: only the sessions of the core storage are archived.
+	var archive bytes.Buffer
+	summary, err := Create(ctx, core, &archive)
+	if err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	if len(summary) != 2 || summary[kindHistory] != 1 || summary[kindStatistics] != 1 {
+		t.Errorf("Archived %v, want the history and the statistics only", summary)
+	}
+
+	// The profiles cannot be restored to the core storage.
+	archive.Reset()
+	if _, err := Create(ctx, full, &archive); err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	if _, err := Restore(ctx, core, &archive); !errors.Is(err, chat.ErrNotSupported) {
+		t.Errorf("Restore returned %v, want %v", err, chat.ErrNotSupported)
+	}
+}
+
+func TestEncryptedArchive(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{1}, 32)
+	keyDir := t.TempDir()
+	encryption, err := storage.NewEncryption(key, keyDir)
+	if err != nil {
+		t.Fatalf("NewEncryption failed: %s", err)
+	}
+	source := &storage.FS{BaseDir: t.TempDir(), Encryption: encryption}
+
+	// The random answers make the archive span several chunks.
+	for i := int64(1); i <= 100; i++ {
+		answer := make([]byte, 1000)
+		rand.Read(answer)
+		history := &chat.History{
+			ID:  chat.ID{User: i, Chat: i, Model: "gpt-4"},
+			Log: []chat.Message{{User: "My secret", Assistant: hex.EncodeToString(answer)}},
+		}
+		if err := source.SaveHistory(ctx, history); err != nil {
+			t.Fatalf("SaveHistory failed: %s", err)
+		}
+	}
+
+	// The files of a user whose key has been destroyed are left behind.
+	if err := os.RemoveAll(filepath.Join(keyDir, "1", "1")); err != nil {
+		t.Fatalf("RemoveAll failed: %s", err)
+	}
+	restarted, _ := storage.NewEncryption(key, keyDir)
+	source = &storage.FS{BaseDir: source.BaseDir, Encryption: restarted}
+
+	// Execute Create.
+	var archive bytes.Buffer
+	w, err := Encrypt(&archive, key)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %s", err)
+	}
+	if _, err := Create(ctx, source, w); err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+
+	// Assert: the archive is not plaintext and is read with the key only, in full.
+	if bytes.Contains(archive.Bytes(), []byte("gpt-4")) || !bytes.HasPrefix(archive.Bytes(), encryptedMagic) {
+		t.Error("The archive is not encrypted")
+	}
+	if _, err := Decrypt(bytes.NewReader(archive.Bytes()), nil); err == nil {
+		t.Error("Decrypted the archive without the key")
+	}
+	truncated, err := Decrypt(bytes.NewReader(archive.Bytes()[:archive.Len()-100]), key)
+	if err == nil {
+		_, err = io.ReadAll(truncated)
+	}
+	if err == nil {
+		t.Error("Read the truncated archive")
+	}
+
+	r, err := Decrypt(&archive, key)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %s", err)
+	}
+	target := &storage.FS{BaseDir: t.TempDir()}
+	if _, err := Restore(ctx, target, r); err != nil {
+		t.Fatalf("Restore failed: %s", err)
+	}
+
+	id := chat.ID{User: 100, Chat: 100, Model: "gpt-4"}
+	if loaded, err := target.LoadHistory(ctx, id); err != nil || len(loaded.Log) != 1 {
+		t.Errorf("LoadHistory returned %+v, %v, want the restored history", loaded, err)
+	}
+	if loaded, err := target.LoadHistory(ctx, chat.ID{User: 1, Chat: 1, Model: "gpt-4"}); err != nil || len(loaded.Log) != 0 {
+		t.Errorf("LoadHistory of the shredded user returned %+v, %v, want an empty history", loaded, err)
+	}
+}
@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedMagic starts the encrypted archives, which tells them from the plaintext ones.
+var encryptedMagic = []byte("TGPTBAK1")
+
+// chunkSize is the size of the plain content of a chunk of the encrypted archive.
+const chunkSize = 64 << 10
+
+// The flags of the chunks of the encrypted archive. The flag is authenticated along with
+// the chunk, so a truncated archive is not taken for a complete one.
+const (
+	chunkMore  byte = 0 // chunkMore marks the chunks followed by another chunk.
+	chunkFinal byte = 1 // chunkFinal marks the last chunk.
+)
+
+// newArchiveAEAD creates the AES-GCM cipher of the archive.
+func newArchiveAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+// chunkNonce returns the nonce of the chunk: the random nonce of the archive with the
+// number of the chunk added to its last bytes, so no nonce is used twice.
+func chunkNonce(base []byte, counter uint64) []byte {
+	nonce := bytes.Clone(base)
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^counter)
+	return nonce
+}
+
+// encryptWriter encrypts the archive in chunks with AES-GCM.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint64
+	buf     []byte
+}
+
+// Encrypt returns the writer encrypting the archive with the key, which must be closed
+// to write the last chunk. The archive is sealed in chunks, so it is never held in
+// memory as a whole.
+//
+// w: The writer of the encrypted archive.
+// key: The AES key of 16, 24 or 32 bytes.
+//
+// Returns:
+// io.WriteCloser: The writer of the plain archive.
+// error: An error if the key is invalid or the header could not be written.
+func Encrypt(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newArchiveAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate the nonce: %w", err)
+	}
+
+	if _, err := w.Write(append(bytes.Clone(encryptedMagic), nonce...)); err != nil {
+		return nil, fmt.Errorf("error writing archive: %w", err)
+	}
+
+	return &encryptWriter{w: w, aead: aead, nonce: nonce}, nil
+}
+
+// Write buffers the content and writes the full chunks.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	for len(e.buf) > chunkSize {
+		if err := e.seal(e.buf[:chunkSize], chunkMore); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[chunkSize:]
+	}
+
+	return len(p), nil
+}
+
+// Close writes the last chunk. It does not close the underlying writer.
+func (e *encryptWriter) Close() error {
+	return e.seal(e.buf, chunkFinal)
+}
+
+// seal writes the chunk: its flag, the size of the sealed content and the sealed content.
+func (e *encryptWriter) seal(plain []byte, flag byte) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.nonce, e.counter), plain, []byte{flag})
+	e.counter++
+
+	header := make([]byte, 5)
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+
+	if _, err := e.w.Write(append(header, sealed...)); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+
+	return nil
+}
+
+// decryptReader decrypts the archive written by encryptWriter.
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint64
+	plain   []byte
+	final   bool
+}
+
+// Decrypt returns the reader of the plain archive. The plaintext archives are read as
+// they are.
+//
+// r: The reader of the archive.
+// key: The AES key the archive was encrypted with; nil if the encryption is not configured.
+//
+// Returns:
+// io.Reader: The reader of the plain archive.
+// error: An error if the archive is encrypted and the key is missing or invalid.
+func Decrypt(r io.Reader, key []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	head, _ := br.Peek(len(encryptedMagic))
+	if !bytes.Equal(head, encryptedMagic) {
+		return br, nil
+	}
+
+	if key == nil {
+		return nil, errors.New("archive is encrypted, but no encryption key is configured")
+	}
+
+	aead, err := newArchiveAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(encryptedMagic)+aead.NonceSize())
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+
+	return &decryptReader{r: br, aead: aead, nonce: header[len(encryptedMagic):]}, nil
+}
+
+// Read returns the plain content, opening the chunks as they are needed.
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk.
+func (d *decryptReader) open() error {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return fmt.Errorf("archive is truncated: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > chunkSize+uint32(d.aead.Overhead()) {
+		return errors.New("archive is damaged: the chunk is too large")
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("archive is truncated: %w", err)
+	}
+
+	plain, err := d.aead.Open(nil, chunkNonce(d.nonce, d.counter), sealed, header[:1])
+	if err != nil {
+		return fmt.Errorf("could not decrypt the archive: %w", err)
+	}
+	d.counter++
+
+	d.plain, d.final = plain, header[0] == chunkFinal
+	return nil
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// Migrate copies all the content of one storage to another, e.g. to switch the
+// storage backend, and validates the copy: the number of the copied objects must
+// match, and every history and statistics must have the same checksum in both storages.
+//
+// ctx: The context for the storage operations.
+// from: The storage to copy.
+// to: The storage to copy to.
+//
+// Returns the number of the copied objects by their kind, and an error if the
+// content could not be copied or the copy does not match the original.
+func Migrate(ctx context.Context, from, to chat.Storage) (Summary, error) {
+	var buf bytes.Buffer
+	created, err := Create(ctx, from, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := Restore(ctx, to, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for kind, count := range created {
+		if restored[kind] != count {
+			return nil, fmt.Errorf("copied %d of %d %s objects", restored[kind], count, kind)
+		}
+	}
+
+	if err := verify(ctx, from, to); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// verify compares the checksums of every history and statistics in both storages.
+func verify(ctx context.Context, from, to chat.Storage) error {
+	ids, err := from.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing sessions: %w", err)
+	}
+
+	copied, err := to.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing copied sessions: %w", err)
+	}
+
+	listed := make(map[chat.ID]bool, len(copied))
+	for _, id := range copied {
+		listed[id] = true
+	}
+
+	for _, id := range ids {
+		if !listed[id] {
+			return fmt.Errorf("session %+v is missing in the copy", id)
+		}
+
+		history, err := checksums(
+			func(s chat.Storage) (object, error) { return s.LoadHistory(ctx, id) }, from, to,
+		)
+		if err != nil {
+			return fmt.Errorf("error verifying history %+v: %w", id, err)
+		}
+
+		statistics, err := checksums(
+			func(s chat.Storage) (object, error) { return s.LoadStatistics(ctx, id) }, from, to,
+		)
+		if err != nil {
+			return fmt.Errorf("error verifying statistics %+v: %w", id, err)
+		}
+
+		if !history || !statistics {
+			return fmt.Errorf("session %+v differs in the copy", id)
+		}
+	}
+
+	return nil
+}
+
+// checksums loads the object from both storages and reports whether the checksums of
+// their encodings match.
+func checksums(load func(chat.Storage) (object, error), from, to chat.Storage) (bool, error) {
+	var sums [2][sha256.Size]byte
+	for i, storage := range []chat.Storage{from, to} {
+		obj, err := load(storage)
+		if err != nil {
+			return false, err
+		}
+
+		var buf bytes.Buffer
+		if err := obj.Write(&buf); err != nil {
+			return false, err
+		}
+		sums[i] = sha256.Sum256(buf.Bytes())
+	}
+
+	return sums[0] == sums[1], nil
+}
@@ -0,0 +1,125 @@
+// Package chart renders simple charts as PNG images. It depends only on the
+// standard image packages and a built-in bitmap font, so it works on low-end
+// hardware without any external tools.
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Default chart dimensions and colors.
+var (
+	Width      = 800                                            // Width is the width of the rendered image in pixels.
+	Height     = 400                                            // Height is the height of the rendered image in pixels.
+	Background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff} // Background is the fill color of the image.
+	Foreground = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff} // Foreground is the color of the axes and texts.
+	BarColor   = color.RGBA{R: 0x2a, G: 0x9d, B: 0xf4, A: 0xff} // BarColor is the fill color of the bars.
+)
+
+// padding is the space around the plot area in pixels.
+const padding = 40
+
+// Bar renders a bar chart of the given values as a PNG image.
+//
+// title: The title drawn above the plot.
+// labels: The labels drawn below the bars; must be empty or match the number of values.
+// Labels are thinned out automatically if they do not fit.
+// values: The heights of the bars. Negative values are drawn as zero.
+// format: The format of the maximum value drawn on the vertical axis (e.g., "$%.2f").
+//
+// Returns the encoded PNG image and an error if the arguments are invalid or
+// the encoding fails.
+func Bar(title string, labels []string, values []float64, format string) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values to render")
+	}
+
+	if len(labels) != 0 && len(labels) != len(values) {
+		return nil, fmt.Errorf("got %d labels for %d values", len(labels), len(values))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(Background), image.Point{}, draw.Src)
+
+	plot := image.Rect(padding*2, padding, Width-padding, Height-padding)
+
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+
+	// Bars.
+	slot := float64(plot.Dx()) / float64(len(values))
+	gap := max(1, int(slot/5))
+	for i, v := range values {
+		if v <= 0 || maxValue == 0 {
+			continue
+		}
+
+		h := int(v / maxValue * float64(plot.Dy()))
+		x0 := plot.Min.X + int(float64(i)*slot) + gap/2
+		x1 := plot.Min.X + int(float64(i+1)*slot) - gap/2
+		bar := image.Rect(x0, plot.Max.Y-h, max(x0+1, x1), plot.Max.Y)
+		draw.Draw(img, bar, image.NewUniform(BarColor), image.Point{}, draw.Src)
+	}
+
+	// Axes.
+	hLine(img, plot.Min.X, plot.Max.X, plot.Max.Y, Foreground)
+	vLine(img, plot.Min.X, plot.Min.Y, plot.Max.Y, Foreground)
+
+	// Texts.
+	face := basicfont.Face7x13
+	drawText(img, face, title, padding*2, padding/2+5)
+	drawText(img, face, fmt.Sprintf(format, maxValue), 5, plot.Min.Y+5)
+	drawText(img, face, fmt.Sprintf(format, 0.0), 5, plot.Max.Y)
+
+	if len(labels) != 0 {
+		labelWidth := font.MeasureString(face, labels[0]).Ceil() + 10
+		step := max(1, int(float64(labelWidth)/slot)+1)
+		for i := 0; i < len(labels); i += step {
+			x := plot.Min.X + int(float64(i)*slot)
+			drawText(img, face, labels[i], x, plot.Max.Y+18)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, fmt.Errorf("error encoding the chart: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// hLine draws a horizontal line.
+func hLine(img draw.Image, x0, x1, y int, c color.Color) {
+	for x := x0; x <= x1; x++ {
+		img.Set(x, y, c)
+	}
+}
+
+// vLine draws a vertical line.
+func vLine(img draw.Image, x, y0, y1 int, c color.Color) {
+	for y := y0; y <= y1; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// drawText draws the text with its baseline starting at the given point.
+func drawText(img draw.Image, face font.Face, text string, x, y int) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(Foreground),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
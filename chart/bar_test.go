@@ -0,0 +1,35 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestBar(t *testing.T) {
+	// Execute Bar.
+	data, err := Bar("Daily spend", []string{"01-01", "01-02", "01-03"}, []float64{1, 0, 2.5}, "$%.2f")
+	if err != nil {
+		t.Fatalf("Bar failed: %s", err)
+	}
+
+	// Assert.
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Rendered chart is not a valid PNG: %s", err)
+	}
+
+	if img.Bounds().Dx() != Width || img.Bounds().Dy() != Height {
+		t.Errorf("Rendered chart size %v does not match %dx%d", img.Bounds().Size(), Width, Height)
+	}
+}
+
+func TestBarInvalidArguments(t *testing.T) {
+	if _, err := Bar("", nil, nil, "%.2f"); err == nil {
+		t.Error("Bar succeeded without values")
+	}
+
+	if _, err := Bar("", []string{"a"}, []float64{1, 2}, "%.2f"); err == nil {
+		t.Error("Bar succeeded with mismatched labels")
+	}
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Budget holds the monthly spending allowance of a user or of a group chat. The
+// budget of a group chat is pooled: the spending of all members in the chat counts
+// towards it. Users and group chats share the ID space, group chat IDs are negative.
+// A user may also pay from a prepaid balance, which is topped up by the administrators
+// and reduced by the cost of every answer.
+type Budget struct {
+	Owner   int64 // Owner is the ID of the user or the group chat the budget applies to.
+	Monthly Cost  // Monthly is the maximum cost per month; zero means no limit.
+	Prepaid bool  // Prepaid reports whether the owner pays from the prepaid balance.
+	Balance Cost  // Balance is the remaining prepaid balance, which may be negative.
+}
+
+// Write serializes the Budget instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized budget should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (b *Budget) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(b)
+}
+
+// Read deserializes the Budget instance from the provided io.Reader which should contain
+// the budget in JSON format.
+//
+// r: The reader from which the serialized budget should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (b *Budget) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(b)
+}
+
+// Clone creates a copy of the Budget object.
+//
+// Returns:
+// *Budget: A new instance of Budget which is a copy of the original.
+func (b *Budget) Clone() *Budget {
+	clone := *b
+	return &clone
+}
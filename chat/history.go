@@ -6,6 +6,7 @@ package chat
 import (
 	"encoding/json"
 	"io"
+	"time"
 )
 
 // ID uniquely identifies a chat session. It consists of a user ID, chat session ID,
@@ -26,9 +27,11 @@ type Message struct {
 // History captures the details of a chat session, including its unique ID,
 // initial prompt, conversation log, and total token usage.
 type History struct {
-	ID               // ID is the unique identifier for this chat session.
-	Prompt string    // Prompt is the initial statement or question that started the chat.
-	Log    []Message // Log maintains a sequential record of the chat interactions.
+	ID                // ID is the unique identifier for this chat session.
+	Version int       // Version is the version of the schema of the stored history (see HistoryVersion).
+	Prompt  string    // Prompt is the initial statement or question that started the chat.
+	Log     []Message // Log maintains a sequential record of the chat interactions.
+	Updated time.Time // Updated is the time the history was last changed; zero if unknown.
 }
 
 // Add includes a new chat interaction to the history and updates the total token usage.
@@ -46,6 +49,8 @@ func (h *History) Clear() {
 }
 
 // Write serializes the chat history and writes it to the provided io.Writer in JSON format.
+// The history is written with the current version of the schema; the instance is not
+// modified, so it can be written while being read by other goroutines.
 //
 // w: The writer to which the serialized history should be written.
 //
@@ -54,19 +59,27 @@ func (h *History) Clear() {
 func (h *History) Write(w io.Writer) error {
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "\t")
-	return enc.Encode(h)
+
+	stamped := *h
+	stamped.Version = HistoryVersion
+	return enc.Encode(&stamped)
 }
 
 // Read deserializes the chat history from the provided io.Reader which should contain
-// the chat history in JSON format.
+// the chat history in JSON format. The histories stored in an older version of the
+// schema are upgraded to the current one.
 //
 // r: The reader from which the serialized history should be read.
 //
 // Returns:
 // error: An error if encountered during the deserialization process.
 func (h *History) Read(r io.Reader) error {
-	dec := json.NewDecoder(r)
-	return dec.Decode(h)
+	if err := historyMigrations.Decode(r, h); err != nil {
+		return err
+	}
+
+	h.Version = HistoryVersion
+	return nil
 }
 
 // Clone creates a deep copy of the History object. This method ensures that the
@@ -79,8 +92,10 @@ func (h *History) Read(r io.Reader) error {
 func (h *History) Clone() *History {
 	// Create a new History object with the ID and Prompt copied from the original.
 	clone := &History{
-		ID:     h.ID,     // ID can be copied directly as it is composed of primitive types.
-		Prompt: h.Prompt, // String is immutable in Go, safe to directly assign.
+		ID:      h.ID,      // ID can be copied directly as it is composed of primitive types.
+		Version: h.Version, // Version is a primitive type.
+		Prompt:  h.Prompt,  // String is immutable in Go, safe to directly assign.
+		Updated: h.Updated, // Time is a value type.
 	}
 
 	// Make a deep copy of the Log slice to ensure independent manipulation.
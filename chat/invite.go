@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Invite is a code that grants access to the bot to the users who redeem it.
+type Invite struct {
+	Code      string    // Code is the secret redeemed by the users.
+	MaxUses   int       // MaxUses is the number of times the code can be redeemed.
+	Used      int       // Used is the number of times the code has been redeemed.
+	Revoked   bool      // Revoked marks a code that can no longer be redeemed.
+	CreatedBy int64     // CreatedBy is the ID of the administrator who created the code.
+	Created   time.Time // Created records the time the code was created; zero if the code does not exist.
+}
+
+// Valid reports whether the invite exists, is not revoked and has uses left.
+func (i *Invite) Valid() bool {
+	return !i.Created.IsZero() && !i.Revoked && i.Used < i.MaxUses
+}
+
+// Write serializes the Invite instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized invite should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (i *Invite) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(i)
+}
+
+// Read deserializes the Invite instance from the provided io.Reader which should contain
+// the invite in JSON format.
+//
+// r: The reader from which the serialized invite should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (i *Invite) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(i)
+}
+
+// Clone creates a copy of the Invite object.
+//
+// Returns:
+// *Invite: A new instance of Invite which is a copy of the original.
+func (i *Invite) Clone() *Invite {
+	clone := *i
+	return &clone
+}
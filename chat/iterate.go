@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// ErrStopIteration can be returned by the callbacks of the iteration helpers to
+// stop the iteration early. It is not reported as an error by the helpers.
+var ErrStopIteration = errors.New("stop iteration")
+
+// EachHistory loads every chat history persisted in the storage and passes it to fn.
+// Histories are visited in the order returned by Storage.List.
+//
+// ctx: The context for the storage operations. The iteration stops when it is cancelled.
+// storage: The storage to iterate over.
+// fn: The callback invoked for every history. Returning ErrStopIteration stops the
+// iteration without an error; any other error stops it and is returned.
+//
+// Returns an error if the storage could not be listed or read, or the callback failed.
+func EachHistory(ctx context.Context, storage Storage, fn func(*History) error) error {
+	return each(ctx, storage, func(id ID) error {
+		history, err := storage.LoadHistory(ctx, id)
+		if err != nil {
+			return err
+		}
+		return fn(history)
+	})
+}
+
+// EachStatistics loads every chat statistics persisted in the storage and passes it to fn.
+// Statistics are visited in the order returned by Storage.List.
+//
+// ctx: The context for the storage operations. The iteration stops when it is cancelled.
+// storage: The storage to iterate over.
+// fn: The callback invoked for every statistics. Returning ErrStopIteration stops the
+// iteration without an error; any other error stops it and is returned.
+//
+// Returns an error if the storage could not be listed or read, or the callback failed.
+func EachStatistics(ctx context.Context, storage Storage, fn func(*Statistics) error) error {
+	return each(ctx, storage, func(id ID) error {
+		statistics, err := storage.LoadStatistics(ctx, id)
+		if err != nil {
+			return err
+		}
+		return fn(statistics)
+	})
+}
+
+// ListUsers returns the sorted, deduplicated IDs of all users who have any chat
+// data persisted in the storage.
+//
+// ctx: The context for the storage operation.
+// storage: The storage to list.
+//
+// Returns the user IDs and an error if the storage could not be listed.
+func ListUsers(ctx context.Context, storage Storage) ([]int64, error) {
+	ids, err := storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]struct{}, len(ids))
+	users := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, exists := seen[id.User]; exists {
+			continue
+		}
+		seen[id.User] = struct{}{}
+		users = append(users, id.User)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return users, nil
+}
+
+// each lists the storage and invokes fn for every chat ID, honoring ctx cancellation
+// and ErrStopIteration.
+func each(ctx context.Context, storage Storage, fn func(ID) error) error {
+	ids, err := storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(id); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
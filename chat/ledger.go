@@ -0,0 +1,136 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Ledger holds the running spending totals of a user or of a group chat over all of
+// their chat sessions, so the limits based on the spending, such as the trial quota,
+// the budgets and the spending alerts, are checked without reading every session.
+// The storage adds the growth of the statistics to the ledgers whenever the statistics
+// are saved. The ledger is never reduced: deleting or resetting the statistics, e.g.
+// on the erasure of the user's data, does not reset the limits.
+type Ledger struct {
+	Owner   int64           // Owner is the ID of the user or the group chat.
+	Days    map[string]Cost // Days is the cost per day (see DayLayout) for the last RetentionDays days.
+	Months  map[string]Cost // Months is the cost per month (see MonthLayout).
+	Total   Cost            // Total is the cumulative cost.
+	Usage   Usage           // Usage is the cumulative number of the chat interactions and their tokens.
+	Updated time.Time       // Updated is the time the ledger was last changed.
+}
+
+// LedgerOwners returns the owners of the ledgers the spending of the chat session
+// counts towards: the user and, in a group chat, the chat.
+//
+// id: The ID of the chat session.
+func LedgerOwners(id ID) []int64 {
+	if id.Chat == 0 || id.Chat == id.User {
+		return []int64{id.User}
+	}
+
+	return []int64{id.User, id.Chat}
+}
+
+// Add records the spending of a chat session between two versions of its statistics.
+// Only the growth is recorded, so the statistics which were deleted or reset before
+// they were saved again do not reduce the totals.
+//
+// before: The statistics as they were stored, nil if there were none.
+// after: The statistics being saved.
+func (l *Ledger) Add(before, after *Statistics) {
+	if before == nil {
+		before = &Statistics{}
+	}
+
+	if l.Days == nil {
+		l.Days = make(map[string]Cost)
+	}
+
+	for day, cost := range after.Days {
+		if grown := cost - before.Days[day]; grown > 0 {
+			l.Days[day] += grown
+		}
+	}
+
+	if l.Months == nil {
+		l.Months = make(map[string]Cost)
+	}
+
+	for month, cost := range after.Months {
+		if grown := cost - before.Months[month]; grown > 0 {
+			l.Months[month] += grown
+		}
+	}
+
+	if grown := after.Total - before.Total; grown > 0 {
+		l.Total += grown
+	}
+
+	was, is := before.TotalUsage(), after.TotalUsage()
+	l.Usage.Messages += max(is.Messages-was.Messages, 0)
+	l.Usage.Tokens.Input += max(is.Tokens.Input-was.Tokens.Input, 0)
+	l.Usage.Tokens.Output += max(is.Tokens.Output-was.Tokens.Output, 0)
+
+	l.Updated = Now()
+	pruneDays(l.Days, l.Updated)
+}
+
+// Today returns the cost for the day of the given time.
+//
+// now: The current time, in the time zone defining the day boundaries.
+func (l *Ledger) Today(now time.Time) Cost {
+	return l.Days[now.Format(DayLayout)]
+}
+
+// ThisMonth returns the cost for the month of the given time.
+//
+// now: The current time, in the time zone defining the month boundaries.
+func (l *Ledger) ThisMonth(now time.Time) Cost {
+	return l.Months[now.Format(MonthLayout)]
+}
+
+// Write serializes the Ledger instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized ledger should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (l *Ledger) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(l)
+}
+
+// Read deserializes the Ledger instance from the provided io.Reader which should contain
+// the ledger in JSON format.
+//
+// r: The reader from which the serialized ledger should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (l *Ledger) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(l)
+}
+
+// Clone creates a deep copy of the Ledger object.
+//
+// Returns:
+// *Ledger: A new instance of Ledger which is a deep copy of the original.
+func (l *Ledger) Clone() *Ledger {
+	clone := *l
+
+	clone.Days = make(map[string]Cost, len(l.Days))
+	for k, v := range l.Days {
+		clone.Days[k] = v
+	}
+
+	clone.Months = make(map[string]Cost, len(l.Months))
+	for k, v := range l.Months {
+		clone.Months[k] = v
+	}
+
+	return &clone
+}
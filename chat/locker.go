@@ -0,0 +1,17 @@
+package chat
+
+import "context"
+
+// Locker provides exclusive access to a chat session across several instances of the
+// bot sharing the same storage, so their concurrent writes do not corrupt the history
+// and the statistics of the session.
+type Locker interface {
+	// Lock waits until no other instance holds the session and locks it.
+	//
+	// ctx: A context.Context to stop waiting for the lock.
+	// id: The unique identifier of the session.
+	//
+	// Returns the function releasing the lock, and an error if the context is done
+	// before the lock is acquired or the lock could not be created.
+	Lock(ctx context.Context, id ID) (unlock func(), err error)
+}
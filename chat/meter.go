@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"context"
+	"sync"
+)
+
+// meterKey is the key of the context holding the CostMeter of a request.
+type meterKey struct{}
+
+// CostMeter adds up the costs of the calls to the chat service made for a request, e.g.
+// the transcription, the answer and the spoken reply. The statistics of a session are
+// shared by the requests running at the same time, so the cost of a request cannot be
+// told from them.
+type CostMeter struct {
+	mu    sync.Mutex
+	total Cost
+}
+
+// WithCostMeter returns the context metering the costs of the calls made with it, and
+// the meter.
+//
+// ctx: The context of the request.
+func WithCostMeter(ctx context.Context) (context.Context, *CostMeter) {
+	meter := new(CostMeter)
+	return context.WithValue(ctx, meterKey{}, meter), meter
+}
+
+// MeterCost adds the cost of a call to the meter of the context, if any. The sessions
+// call it along with adding the cost to their statistics.
+//
+// ctx: The context of the call.
+// cost: The cost of the call.
+func MeterCost(ctx context.Context, cost Cost) {
+	if meter, ok := ctx.Value(meterKey{}).(*CostMeter); ok {
+		meter.mu.Lock()
+		meter.total += cost
+		meter.mu.Unlock()
+	}
+}
+
+// Total returns the sum of the metered costs.
+func (m *CostMeter) Total() Cost {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.total
+}
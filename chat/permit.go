@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"context"
+	"slices"
+)
+
+// permitKey is the key of the context holding the models permitted for a request.
+type permitKey struct{}
+
+// WithPermittedModels returns the context restricting the models the sessions may
+// switch to while answering the request, such as the fallback model, to the models
+// permitted to the user, e.g. by the user's role.
+//
+// ctx: The context of the request.
+// models: The permitted models; empty means any model.
+func WithPermittedModels(ctx context.Context, models []string) context.Context {
+	if len(models) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, permitKey{}, models)
+}
+
+// ModelPermitted reports whether the model is permitted for the request, which is so
+// for any model if the context carries no restriction.
+//
+// ctx: The context of the request.
+// model: The name of the model.
+func ModelPermitted(ctx context.Context, model string) bool {
+	models, ok := ctx.Value(permitKey{}).([]string)
+	return !ok || slices.Contains(models, model)
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"time"
+)
+
+// DefaultLocation is the time zone used for the day and month boundaries of the
+// statistics of users who have not chosen their own time zone. It defaults to UTC
+// and can be overridden with the bot-wide configuration.
+var DefaultLocation = time.UTC
+
+// Profile holds the persisted preferences and settings of a single user which
+// apply to all of the user's chat sessions.
+type Profile struct {
+	User     int64    // User is the unique identifier for the user.
+	Timezone string   // Timezone is the IANA time zone name chosen by the user; empty means DefaultLocation.
+	Allowed  bool     // Allowed grants the user access in addition to the configured allowlist (e.g., by an invite).
+	Role     Role     // Role is the role assigned by an administrator; empty means the role follows from the access.
+	Banned   bool     // Banned denies the user access regardless of the allowlist and the role.
+	Persona  string   // Persona is the name of the persona selected with a /start deep link; empty means the configured prompt.
+	Model    string   // Model is the model pinned by an administrator for the user's sessions; empty means the bot's model.
+	Models   []string // Models restricts the user's sessions to these models, overriding the models of the role; empty means no restriction.
+
+	AccessExpires time.Time // AccessExpires is the time the granted access expires; zero means it never expires.
+	ExpiryWarned  bool      // ExpiryWarned records that the user was warned about the upcoming expiry.
+
+	TermsAccepted time.Time // TermsAccepted is the time the user accepted the terms of use; zero means never.
+	TermsVersion  string    // TermsVersion identifies the text of the terms of use the user accepted.
+
+	Currency string  // Currency is the display currency assigned to the user; empty means the bot's currency.
+	Rate     float64 // Rate multiplies the bot's rate for the user, e.g. a reseller markup; zero means the bot's rate.
+
+	Challenges map[int64]Challenge // Challenges holds the join challenges the user has not passed, by the ID of the group chat.
+}
+
+// Challenge is the question a new member of a group chat has to answer before the bot
+// responds to them in the chat.
+type Challenge struct {
+	Answer  int       // Answer is the expected answer.
+	Expires time.Time // Expires is the time after which the challenge can no longer be passed.
+	Failed  bool      // Failed records that the member gave a wrong answer.
+}
+
+// Passable reports whether the challenge can still be passed at the given time, i.e.
+// it has not been failed and has not expired.
+//
+// now: The current time.
+func (c Challenge) Passable(now time.Time) bool {
+	return !c.Failed && now.Before(c.Expires)
+}
+
+// HasAccess reports whether the access granted to the user is in effect at the given time.
+// Users assigned the user role or a higher one always have access.
+//
+// now: The current time.
+func (p *Profile) HasAccess(now time.Time) bool {
+	if p.Role.AtLeast(RoleUser) {
+		return true
+	}
+
+	return p.Allowed && (p.AccessExpires.IsZero() || now.Before(p.AccessExpires))
+}
+
+// Location returns the time zone of the user. If the user has not chosen a time
+// zone or it cannot be loaded, DefaultLocation is returned.
+func (p *Profile) Location() *time.Location {
+	if p.Timezone == "" {
+		return DefaultLocation
+	}
+
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return DefaultLocation
+	}
+
+	return loc
+}
+
+// Write serializes the Profile instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized profile should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (p *Profile) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(p)
+}
+
+// Read deserializes the Profile instance from the provided io.Reader which should contain
+// the profile in JSON format.
+//
+// r: The reader from which the serialized profile should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (p *Profile) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(p)
+}
+
+// Clone creates a copy of the Profile object.
+//
+// Returns:
+// *Profile: A new instance of Profile which is a copy of the original.
+func (p *Profile) Clone() *Profile {
+	clone := *p
+	clone.Models = slices.Clone(p.Models)
+
+	if p.Challenges != nil {
+		clone.Challenges = make(map[int64]Challenge, len(p.Challenges))
+		for k, v := range p.Challenges {
+			clone.Challenges[k] = v
+		}
+	}
+
+	return &clone
+}
@@ -0,0 +1,51 @@
+package chat
+
+import "fmt"
+
+// Role defines the privileges of a user. Roles are ordered, every role has all
+// the privileges of the roles below it.
+type Role string
+
+// Roles in ascending order of privileges.
+const (
+	RoleGuest     Role = "guest"      // RoleGuest is a user without access, served only in the trial or public mode.
+	RoleUser      Role = "user"       // RoleUser is a regular user with access to the bot.
+	RolePowerUser Role = "power-user" // RolePowerUser is a user with access to extended models and budgets.
+	RoleAdmin     Role = "admin"      // RoleAdmin is an administrator of the bot.
+)
+
+// Roles lists all roles in ascending order of privileges.
+var Roles = []Role{RoleGuest, RoleUser, RolePowerUser, RoleAdmin}
+
+// Rank returns the position of the role in the order of privileges, or -1 for
+// an unknown role.
+func (r Role) Rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AtLeast reports whether the role has all the privileges of the given role.
+//
+// role: The role to compare with.
+func (r Role) AtLeast(role Role) bool {
+	return r.Rank() >= role.Rank()
+}
+
+// ParseRole converts a role name into a Role.
+//
+// name: The name of the role (e.g., "power-user").
+//
+// Returns the role and an error if the name is not a known role.
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if role.Rank() < 0 {
+		return "", fmt.Errorf("unknown role: %q", name)
+	}
+
+	return role, nil
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Totals holds the aggregated costs of a group of chat sessions as of a point in time.
+type Totals struct {
+	Today     Cost // Today is the cost for the day the totals were computed.
+	LastWeek  Cost // LastWeek is the cost for the 7 full days before the day the totals were computed.
+	ThisMonth Cost // ThisMonth is the cost for the month the totals were computed.
+	Total     Cost // Total is the all-time cost.
+}
+
+// Add folds the given statistics into the totals.
+//
+// statistics: The statistics of a single chat session.
+// now: The time the totals are computed for.
+func (t *Totals) Add(statistics *Statistics, now time.Time) {
+	t.Today += statistics.Today(now)
+	for _, cost := range statistics.DailySeries(now.AddDate(0, 0, -1), 7) {
+		t.LastWeek += cost
+	}
+	t.ThisMonth += statistics.ThisMonth(now)
+	t.Total += statistics.Total
+}
+
+// Rollup contains the statistics of all chat sessions folded into global,
+// per-user and per-model totals. It allows reporting on the whole fleet of
+// sessions without re-reading the statistics of every session.
+type Rollup struct {
+	Global   Totals            // Global holds the totals across all chat sessions.
+	PerUser  map[int64]Totals  // PerUser holds the totals of every user.
+	PerModel map[string]Totals // PerModel holds the totals of every model.
+	Sessions int               // Sessions is the number of chat sessions aggregated.
+	Updated  time.Time         // Updated records the time the rollup was computed.
+}
+
+// Aggregate folds the statistics of all chat sessions persisted in the storage
+// into a new Rollup.
+//
+// ctx: The context for the storage operations.
+// storage: The storage holding the per-session statistics.
+// now: The time the rollup is computed for.
+//
+// Returns the computed Rollup and an error if the statistics could not be read.
+func Aggregate(ctx context.Context, storage Storage, now time.Time) (*Rollup, error) {
+	rollup := &Rollup{
+		PerUser:  make(map[int64]Totals),
+		PerModel: make(map[string]Totals),
+		Updated:  now,
+	}
+
+	err := EachStatistics(ctx, storage, func(statistics *Statistics) error {
+		rollup.Global.Add(statistics, now)
+
+		user := rollup.PerUser[statistics.User]
+		user.Add(statistics, now)
+		rollup.PerUser[statistics.User] = user
+
+		model := rollup.PerModel[statistics.Model]
+		model.Add(statistics, now)
+		rollup.PerModel[statistics.Model] = model
+
+		rollup.Sessions++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rollup, nil
+}
+
+// Write serializes the Rollup instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized rollup should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (r *Rollup) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(r)
+}
+
+// Read deserializes the Rollup instance from the provided io.Reader which should contain
+// the rollup in JSON format.
+//
+// r: The reader from which the serialized rollup should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (r *Rollup) Read(rd io.Reader) error {
+	decoder := json.NewDecoder(rd)
+	return decoder.Decode(r)
+}
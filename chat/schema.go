@@ -0,0 +1,105 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Document is a stored object decoded into its top-level JSON fields, which the
+// migrations edit before the object is decoded.
+type Document map[string]json.RawMessage
+
+// Migration upgrades a stored document from one version of its schema to the next one.
+//
+// doc: The document to upgrade in place.
+type Migration func(doc Document) error
+
+// Migrations is a registry of the migrations of a stored type. The migration at
+// index i upgrades the documents of version i to version i+1, so the number of the
+// migrations is the current version of the schema. The documents stored before the
+// versioning have version 0.
+type Migrations struct {
+	kind string
+	list []Migration
+}
+
+// historyMigrations upgrades the stored histories.
+var historyMigrations = &Migrations{
+	kind: "history",
+	list: []Migration{
+		// 0 → 1: the version is stored.
+		func(Document) error { return nil },
+		// 1 → 2: the time of the last change is stored; it is unknown for the older histories.
+		func(Document) error { return nil },
+	},
+}
+
+// statisticsMigrations upgrades the stored statistics.
+var statisticsMigrations = &Migrations{
+	kind: "statistics",
+	list: []Migration{
+		// 0 → 1: the version is stored, and the legacy rolling daily cost and the
+		// monthly costs keyed by the month number are converted into the series.
+		migrateLegacyStatistics,
+	},
+}
+
+// HistoryVersion is the current version of the schema of the stored histories.
+var HistoryVersion = historyMigrations.Version()
+
+// StatisticsVersion is the current version of the schema of the stored statistics.
+var StatisticsVersion = statisticsMigrations.Version()
+
+// Version returns the current version of the schema.
+func (m *Migrations) Version() int {
+	return len(m.list)
+}
+
+// Register adds the migration which upgrades the documents of the current version to
+// the next one.
+//
+// migration: The migration to add.
+func (m *Migrations) Register(migration Migration) {
+	m.list = append(m.list, migration)
+}
+
+// Decode reads a document, upgrades it to the current version of the schema and
+// decodes it into v.
+//
+// r: The reader from which the document should be read.
+// v: The value to decode the upgraded document into.
+//
+// Returns:
+// error: An error if the document could not be read or upgraded, or its version is
+// newer than the current one.
+func (m *Migrations) Decode(r io.Reader, v any) error {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	var version int
+	if raw, ok := doc["Version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return fmt.Errorf("error decoding %s version: %w", m.kind, err)
+		}
+	}
+
+	if version < 0 || version > m.Version() {
+		return fmt.Errorf("unsupported %s version %d, the latest is %d", m.kind, version, m.Version())
+	}
+
+	for ; version < m.Version(); version++ {
+		if err := m.list[version](doc); err != nil {
+			return fmt.Errorf("error upgrading %s from version %d: %w", m.kind, version, err)
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
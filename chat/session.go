@@ -1,6 +1,20 @@
 package chat
 
-import "context"
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrUnavailable is returned by the sessions without calling the chat service while
+// the service is considered down after repeated failures.
+var ErrUnavailable = errors.New("chat service is temporarily unavailable")
+
+// ErrSessionClosed is returned by the sessions closed while the request was waiting,
+// e.g. because the session was evicted or the user's data was deleted. A new session
+// is provided for the next request.
+var ErrSessionClosed = errors.New("chat session is closed")
 
 // Session is an interface that abstracts the operations of a chat session.
 // It defines the contract for a session that can send messages, set prompts,
@@ -15,8 +29,50 @@ type Session interface {
 	// message: The message string to send to the chat service.
 	// reset: A boolean flag indicating whether to clear the conversation history.
 	//
-	// Returns the reply from the chat service as a string and an error if the operation fails.
-	Ask(ctx context.Context, message string, reset bool) (reply string, err error)
+	// Returns the reply from the chat service as a string, the model which answered instead
+	// of the model of the session because it was rate limited or out of quota (empty if
+	// none), and an error if the operation fails.
+	Ask(ctx context.Context, message string, reset bool) (reply, fallback string, err error)
+
+	// See sends a message with attached images to the chat service and returns the
+	// reply, continuing the conversation like Ask.
+	//
+	// ctx: The context for the API call, which allows for deadline control and cancelation.
+	// message: The message accompanying the images, may be empty.
+	// images: The images to send (e.g., JPEG photos).
+	//
+	// Returns the reply from the chat service as a string, the model which answered instead
+	// of the model of the session (empty if none), and an error if the operation fails.
+	See(ctx context.Context, message string, images [][]byte) (reply, fallback string, err error)
+
+	// Draw generates an image from the given prompt and accounts its cost in the
+	// session's statistics. The conversation history is not affected.
+	//
+	// ctx: The context for the API call, which allows for deadline control and cancelation.
+	// prompt: The description of the image to generate.
+	//
+	// Returns the generated image encoded as PNG and an error if the operation fails.
+	Draw(ctx context.Context, prompt string) (image []byte, err error)
+
+	// Transcribe converts the given audio into text and accounts its cost in the
+	// session's statistics. The conversation history is not affected.
+	//
+	// ctx: The context for the API call, which allows for deadline control and cancelation.
+	// audio: The audio content to transcribe.
+	// filename: The name of the audio file; its extension identifies the audio format.
+	// duration: The duration of the audio.
+	//
+	// Returns the transcribed text and an error if the operation fails.
+	Transcribe(ctx context.Context, audio io.Reader, filename string, duration time.Duration) (text string, err error)
+
+	// Speak synthesizes speech from the given text and accounts its cost in the
+	// session's statistics. The conversation history is not affected.
+	//
+	// ctx: The context for the API call, which allows for deadline control and cancelation.
+	// text: The text to synthesize.
+	//
+	// Returns the synthesized speech encoded as OGG/Opus and an error if the operation fails.
+	Speak(ctx context.Context, text string) (speech []byte, err error)
 
 	// Reset terminates the current session and clears any saved state or history associated with it.
 	// This function is intended to restart the session as if it were new, without any memory of previous interactions.
@@ -27,6 +83,32 @@ type Session interface {
 	// or network systems.
 	Reset(ctx context.Context) error
 
+	// Import replaces the conversation history of the session with the given log, e.g.,
+	// a conversation exported from another chat. The prompt is not affected.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancelation.
+	// log: The conversation to load.
+	//
+	// Returns an error if the history could not be saved.
+	Import(ctx context.Context, log []Message) error
+
+	// ResetStatistics zeroes out the statistics associated with the session and persists
+	// the change. The conversation history is not affected.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancelation.
+	//
+	// Returns an error if the statistics could not be loaded or saved.
+	ResetStatistics(ctx context.Context) error
+
+	// Delete deletes the history and the statistics of the session and closes it. It waits
+	// for the running request of the session, and the later requests fail with
+	// ErrSessionClosed, so the deleted data is not saved again.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancelation.
+	//
+	// Returns an error if the data could not be deleted.
+	Delete(ctx context.Context) error
+
 	// SetPrompt updates the prompt for the session to the given string. It affects the
 	// conversation flow and can be used to provide context or instructions that persist across
 	// exchanges in the session.
@@ -1,6 +1,18 @@
 package chat
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// SessionInfo describes a session held by a SessionProvider. It is intended
+// for inspection and debugging purposes.
+type SessionInfo struct {
+	ID                      // ID is the unique identifier of the session.
+	LastAccess    time.Time // LastAccess is the last time the session was provided.
+	HistoryLength int       // HistoryLength is the number of messages in the cached history.
+	Busy          bool      // Busy reports whether the session is currently processing a request.
+}
 
 // SessionProvider is an interface for managing and providing access to user chat sessions.
 // It defines a method for obtaining a Session based on a unique identifier.
@@ -14,4 +26,23 @@ type SessionProvider interface {
 	//
 	// Returns a Session corresponding to the user ID and an error if the retrieval or creation fails.
 	ProvideSession(ctx context.Context, id ID) (Session, error)
+
+	// Sessions returns information about all sessions currently held by the provider.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancellation.
+	//
+	// Returns a slice of SessionInfo sorted by the last access time (most recent first)
+	// and an error if the sessions could not be inspected.
+	Sessions(ctx context.Context) ([]SessionInfo, error)
+
+	// Evict removes the session with the given ID from the provider, so the next
+	// ProvideSession call creates a fresh session loaded from storage. It waits for the
+	// running request of the session, and the later requests made on the evicted session
+	// fail with ErrSessionClosed.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancellation.
+	// id: The unique identifier of the session to evict.
+	//
+	// Returns true if the session was found and evicted and an error if the operation fails.
+	Evict(ctx context.Context, id ID) (bool, error)
 }
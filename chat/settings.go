@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Settings holds the state of the bot the administrators change while it runs, such as
+// the maintenance mode. It is kept in the storage, so all instances of the bot sharing
+// the storage follow it.
+type Settings struct {
+	Maintenance    bool      // Maintenance reports whether only the administrators are served.
+	TelegraphToken string    // TelegraphToken is the token of the Telegraph account created by the bot, empty if none.
+	LedgersFilled  bool      // LedgersFilled reports whether the spending recorded before the ledgers has been added to them.
+	Updated        time.Time // Updated is the time the settings were last changed.
+}
+
+// Write serializes the Settings instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized settings should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (s *Settings) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(s)
+}
+
+// Read deserializes the Settings instance from the provided io.Reader which should contain
+// the settings in JSON format.
+//
+// r: The reader from which the serialized settings should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (s *Settings) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(s)
+}
+
+// Clone creates a copy of the Settings object.
+//
+// Returns:
+// *Settings: A new instance of Settings which is a copy of the original.
+func (s *Settings) Clone() *Settings {
+	clone := *s
+	return &clone
+}
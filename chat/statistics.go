@@ -14,59 +14,198 @@ var Now func() time.Time
 // init initializes the package variables. It sets the Now function to return
 // the current UTC time.
 func init() {
-	Now = time.Now().UTC
+	Now = func() time.Time { return time.Now().UTC() }
 }
 
+// Layouts of the keys of the daily and monthly cost series.
+const (
+	DayLayout   = "2006-01-02" // DayLayout formats the keys of Statistics.Days.
+	MonthLayout = "2006-01"    // MonthLayout formats the keys of Statistics.Months.
+)
+
+// RetentionDays is the number of days the daily cost series is retained for.
+const RetentionDays = 90
+
 // Cost represents the cost associated with a chat operation. It is used to
 // track costs for messages, daily operations, monthly aggregates, and the total
 // cost across the chat session's lifetime.
 type Cost float64
 
+// Tokens holds the number of tokens consumed by chat interactions. Input tokens
+// correspond to the prompt sent to the model (including the conversation history),
+// output tokens correspond to the generated response.
+type Tokens struct {
+	Input  int // Input is the number of prompt tokens.
+	Output int // Output is the number of completion tokens.
+}
+
+// Usage holds the number of chat interactions and the tokens they consumed
+// within a period of time.
+type Usage struct {
+	Messages int    // Messages is the number of chat interactions.
+	Tokens   Tokens // Tokens is the number of tokens consumed by the chat interactions.
+}
+
 // Statistics contains data related to the cost and usage of chat sessions.
 // It embeds the ID type to associate these statistics with a particular chat session.
 type Statistics struct {
-	ID                              // Embedded ID to uniquely identify the chat session.
-	LastMessage Cost                // LastMessage is the cost of the last message in the chat session.
-	Daily       Cost                // Daily is the total cost of the chat session for the current day.
-	Monthly     map[time.Month]Cost // Monthly is a map tracking the cost per month.
-	Total       Cost                // Total is the cumulative cost of the chat session.
-	LastUpdate  time.Time           // LastUpdate records the timestamp of the last time the Statistics were modified.
+	ID                           // Embedded ID to uniquely identify the chat session.
+	Version     int              // Version is the version of the schema of the stored statistics (see StatisticsVersion).
+	LastMessage Cost             // LastMessage is the cost of the last message in the chat session.
+	Days        map[string]Cost  // Days is the cost per day (see DayLayout) for the last RetentionDays days.
+	Months      map[string]Cost  // Months is the cost per month (see MonthLayout).
+	Total       Cost             // Total is the cumulative cost of the chat session.
+	PerModel    map[string]Cost  // PerModel is a map tracking the cumulative cost per model.
+	LastTokens  Tokens           // LastTokens is the number of tokens consumed by the last message.
+	TotalTokens Tokens           // TotalTokens is the cumulative number of tokens consumed by the chat session.
+	MonthUsage  map[string]Usage // MonthUsage is the usage per month (see MonthLayout).
+	LastUpdate  time.Time        // LastUpdate records the timestamp of the last time the Statistics were modified.
 }
 
 // AddCost updates the Statistics instance with a new cost from a chat interaction.
-// It checks if the current day is different from the last update day and resets
-// the daily cost to zero if a new day has started. Then, it adds the new cost to
-// the last message, daily, monthly, and total costs. For monthly tracking, if there
-// is no entry for the current month, it creates one. It also updates the last update
+// It adds the new cost to the last message, the current day, the current month and
+// the total costs, and attributes it to the given model. Days older than
+// RetentionDays are dropped from the daily series. It also updates the last update
 // time to the current time.
 //
-// The method assumes there is a LastUpdate field of type time.Time in the Statistics
-// structure to keep track of when the statistics were last updated.
+// The day and month boundaries are determined in the location of the given time,
+// so passing the current time in the user's time zone makes the daily rollover
+// happen at the user's local midnight.
 //
+// now: The current time in the time zone of the user.
+// model: The name of the model used for the chat interaction.
 // newCost: The cost from the new chat interaction to add to the statistics.
-func (s *Statistics) AddCost(newCost Cost) {
-	now := Now()
+func (s *Statistics) AddCost(now time.Time, model string, newCost Cost) {
+	s.LastMessage = newCost
+	s.Total += newCost
 
-	// Check if a new day has started.
-	if now.Day() != s.LastUpdate.Day() || now.Month() != s.LastUpdate.Month() || now.Year() != s.LastUpdate.Year() {
-		s.Daily = 0
+	if s.Days == nil {
+		s.Days = make(map[string]Cost)
 	}
 
-	s.LastMessage = newCost
-	s.Daily += newCost
-	s.Total += newCost
+	s.Days[now.Format(DayLayout)] += newCost
+
+	if s.Months == nil {
+		s.Months = make(map[string]Cost)
+	}
 
-	currentMonth := now.Month()
+	s.Months[now.Format(MonthLayout)] += newCost
 
-	if s.Monthly == nil {
-		s.Monthly = make(map[time.Month]Cost)
+	if s.PerModel == nil {
+		s.PerModel = make(map[string]Cost)
 	}
 
-	s.Monthly[currentMonth] += newCost
+	s.PerModel[model] += newCost
 	s.LastUpdate = now
+
+	pruneDays(s.Days, now)
+}
+
+// AddTokens updates the Statistics instance with the number of tokens consumed by
+// a new chat interaction. It sets the tokens of the last message, adds them to the
+// cumulative totals and counts the interaction in the usage of the current month.
+//
+// now: The current time in the time zone of the user.
+// tokens: The number of tokens consumed by the new chat interaction.
+func (s *Statistics) AddTokens(now time.Time, tokens Tokens) {
+	s.LastTokens = tokens
+	s.TotalTokens.Input += tokens.Input
+	s.TotalTokens.Output += tokens.Output
+
+	if s.MonthUsage == nil {
+		s.MonthUsage = make(map[string]Usage)
+	}
+
+	usage := s.MonthUsage[now.Format(MonthLayout)]
+	usage.Messages++
+	usage.Tokens.Input += tokens.Input
+	usage.Tokens.Output += tokens.Output
+	s.MonthUsage[now.Format(MonthLayout)] = usage
+}
+
+// Day returns the cost of the chat session for the day of the given time.
+// Days outside of the retention period are reported as zero.
+//
+// t: Any time within the day.
+func (s *Statistics) Day(t time.Time) Cost {
+	return s.Days[t.Format(DayLayout)]
+}
+
+// Month returns the cost of the chat session for the month of the given time.
+//
+// t: Any time within the month.
+func (s *Statistics) Month(t time.Time) Cost {
+	return s.Months[t.Format(MonthLayout)]
+}
+
+// MonthlyUsage returns the usage of the chat session for the month of the given time.
+//
+// t: Any time within the month.
+func (s *Statistics) MonthlyUsage(t time.Time) Usage {
+	return s.MonthUsage[t.Format(MonthLayout)]
+}
+
+// TotalUsage returns the usage of the chat session over all months.
+func (s *Statistics) TotalUsage() Usage {
+	var total Usage
+	for _, usage := range s.MonthUsage {
+		total.Messages += usage.Messages
+		total.Tokens.Input += usage.Tokens.Input
+		total.Tokens.Output += usage.Tokens.Output
+	}
+
+	return total
+}
+
+// Today returns the cost of the chat session for the day of the given time.
+//
+// now: The current time.
+func (s *Statistics) Today(now time.Time) Cost {
+	return s.Day(now)
+}
+
+// Yesterday returns the cost of the chat session for the day before the given time.
+//
+// now: The current time.
+func (s *Statistics) Yesterday(now time.Time) Cost {
+	return s.Day(now.AddDate(0, 0, -1))
+}
+
+// ThisMonth returns the cost of the chat session for the month of the given time.
+//
+// now: The current time.
+func (s *Statistics) ThisMonth(now time.Time) Cost {
+	return s.Month(now)
+}
+
+// DailySeries returns the daily costs of the chat session for the given number of
+// days ending with the day of the given time, in chronological order.
+//
+// now: The current time; its day is the last day of the series.
+// days: The number of days in the series.
+func (s *Statistics) DailySeries(now time.Time, days int) []Cost {
+	series := make([]Cost, days)
+	for i := range series {
+		series[i] = s.Day(now.AddDate(0, 0, i-days+1))
+	}
+
+	return series
+}
+
+// pruneDays removes the days older than RetentionDays from the daily series.
+func pruneDays(days map[string]Cost, now time.Time) {
+	oldest := now.AddDate(0, 0, -RetentionDays+1).Format(DayLayout)
+	for day := range days {
+		// The layout sorts lexicographically in chronological order.
+		if day < oldest {
+			delete(days, day)
+		}
+	}
 }
 
 // Write serializes the Statistics instance and writes it to the provided io.Writer in JSON format.
+// The statistics are written with the current version of the schema; the instance is not
+// modified, so it can be written while being read by other goroutines.
 //
 // w: The writer to which the serialized statistics should be written.
 //
@@ -75,19 +214,88 @@ func (s *Statistics) AddCost(newCost Cost) {
 func (s *Statistics) Write(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "\t")
-	return encoder.Encode(s)
+
+	stamped := *s
+	stamped.Version = StatisticsVersion
+	return encoder.Encode(&stamped)
 }
 
 // Read deserializes the Statistics instance from the provided io.Reader which should contain
-// the statistics in JSON format.
+// the statistics in JSON format. The statistics stored in an older version of the schema
+// are upgraded to the current one.
 //
 // r: The reader from which the serialized statistics should be read.
 //
 // Returns:
 // error: An error if encountered during the deserialization process.
 func (s *Statistics) Read(r io.Reader) error {
-	decoder := json.NewDecoder(r)
-	return decoder.Decode(s)
+	if err := statisticsMigrations.Decode(r, s); err != nil {
+		return err
+	}
+
+	s.Version = StatisticsVersion
+	return nil
+}
+
+// migrateLegacyStatistics converts the legacy rolling daily cost and the monthly costs
+// keyed by the month number only, colliding across years, into the daily and monthly
+// series. Legacy months after the month of the last update are attributed to the
+// previous year.
+//
+// doc: The statistics of version 0.
+func migrateLegacyStatistics(doc Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	var legacy struct {
+		Daily      Cost
+		Monthly    map[time.Month]Cost
+		Days       map[string]Cost
+		Months     map[string]Cost
+		LastUpdate time.Time
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	delete(doc, "Daily")
+	delete(doc, "Monthly")
+
+	if legacy.Daily == 0 && len(legacy.Monthly) == 0 {
+		return nil
+	}
+
+	days, months := legacy.Days, legacy.Months
+	if days == nil {
+		days = make(map[string]Cost)
+	}
+
+	if months == nil {
+		months = make(map[string]Cost)
+	}
+
+	if len(days) == 0 && legacy.Daily != 0 && !legacy.LastUpdate.IsZero() {
+		days[legacy.LastUpdate.Format(DayLayout)] = legacy.Daily
+	}
+
+	if len(months) == 0 {
+		for month, cost := range legacy.Monthly {
+			year := legacy.LastUpdate.Year()
+			if month > legacy.LastUpdate.Month() {
+				year--
+			}
+			months[time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Format(MonthLayout)] = cost
+		}
+	}
+
+	if doc["Days"], err = json.Marshal(days); err != nil {
+		return err
+	}
+
+	doc["Months"], err = json.Marshal(months)
+	return err
 }
 
 // Clone creates a deep copy of the Statistics object. This is particularly useful
@@ -100,15 +308,34 @@ func (s *Statistics) Clone() *Statistics {
 	// Create a new Statistics object with shallow-copied fields.
 	clone := &Statistics{
 		ID:          s.ID, // ID can be shallow copied as it contains only primitive types.
+		Version:     s.Version,
 		LastMessage: s.LastMessage,
-		Daily:       s.Daily,
 		Total:       s.Total,
+		LastTokens:  s.LastTokens,
+		TotalTokens: s.TotalTokens,
+		LastUpdate:  s.LastUpdate,
+	}
+
+	// Make a deep copy of the Days and Months maps to ensure independent manipulation.
+	clone.Days = make(map[string]Cost, len(s.Days))
+	for k, v := range s.Days {
+		clone.Days[k] = v
+	}
+
+	clone.Months = make(map[string]Cost, len(s.Months))
+	for k, v := range s.Months {
+		clone.Months[k] = v
+	}
+
+	clone.MonthUsage = make(map[string]Usage, len(s.MonthUsage))
+	for k, v := range s.MonthUsage {
+		clone.MonthUsage[k] = v
 	}
 
-	// Make a deep copy of the Monthly map to ensure independent manipulation.
-	clone.Monthly = make(map[time.Month]Cost, len(s.Monthly))
-	for k, v := range s.Monthly {
-		clone.Monthly[k] = v
+	// Make a deep copy of the PerModel map as well.
+	clone.PerModel = make(map[string]Cost, len(s.PerModel))
+	for k, v := range s.PerModel {
+		clone.PerModel[k] = v
 	}
 
 	return clone
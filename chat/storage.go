@@ -1,10 +1,21 @@
 package chat
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned when the data is saved to a storage which does not keep
+// that kind of data, i.e. does not implement the optional interface for it.
+var ErrNotSupported = errors.New("not supported by the storage")
 
 // Storage defines an interface for managing the persistence of chat history and statistics.
 // It provides an abstraction over the actual storage mechanism, which could be implemented
 // using various systems such as files, databases, or other storage backends.
+//
+// The storages keeping the other data of the bot implement the optional interfaces
+// below, such as Profiles and Ledgers, which the features using the data detect with a
+// type assertion; see also Wrapper.
 type Storage interface {
 	// SaveHistory persists a given chat history into the storage.
 	// The method ensures that the provided History object is stored and retrievable
@@ -28,9 +39,21 @@ type Storage interface {
 	// for reasons other than the history not being found.
 	LoadHistory(ctx context.Context, id ID) (*History, error)
 
+	// DeleteHistory removes the chat history associated with the given ID from storage.
+	// Deleting a history which does not exist is not an error.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the removal.
+	// id: The unique identifier of the chat history to be removed.
+	//
+	// Returns an error if the history exists but could not be removed.
+	DeleteHistory(ctx context.Context, id ID) error
+
 	// SaveStatistics persists the given chat statistics into the storage.
 	// This method ensures that the provided Statistics object is stored and retrievable
-	// by an identifier. Should the save operation encounter a failure, an error is returned.
+	// by an identifier. If the storage keeps the ledgers (see Ledgers), the growth of the
+	// costs since the stored statistics is added to the ledgers of the user and the group
+	// chat (see LedgerOwners).
+	// Should the save operation encounter a failure, an error is returned.
 	//
 	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
 	// statistics: The Statistics object containing chat statistics data to be saved.
@@ -48,4 +71,219 @@ type Storage interface {
 	// Returns the retrieved or new Statistics object, and an error if the load operation fails
 	// for reasons other than the statistics not being found.
 	LoadStatistics(ctx context.Context, id ID) (*Statistics, error)
+
+	// DeleteStatistics removes the chat statistics associated with the given ID from storage.
+	// Deleting statistics which do not exist is not an error.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the removal.
+	// id: The unique identifier of the chat statistics to be removed.
+	//
+	// Returns an error if the statistics exist but could not be removed.
+	DeleteStatistics(ctx context.Context, id ID) error
+
+	// List enumerates the identifiers of all chat sessions which have a history or
+	// statistics persisted in the storage. Each identifier is returned only once.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the listing.
+	//
+	// Returns the identifiers of the stored chat sessions, and an error if the listing fails.
+	List(ctx context.Context) ([]ID, error)
+
+	// EraseUser removes the data of the user which the storage keeps besides the objects
+	// above, such as the copies of the corrupted files kept for an inspection, e.g. on
+	// the erasure of the user's data. The histories and the statistics are deleted with
+	// their own methods; the profile, the budget and the ledger of the user are kept.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the removal.
+	// user: The unique identifier of the user.
+	//
+	// Returns an error if the data exists but could not be removed.
+	EraseUser(ctx context.Context, user int64) error
+}
+
+// Rollups is implemented by the storages keeping the aggregated statistics, see Aggregate.
+type Rollups interface {
+	// SaveRollup persists the aggregated statistics of all chat sessions, replacing
+	// the previously saved rollup.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// rollup: The Rollup object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveRollup(ctx context.Context, rollup *Rollup) error
+
+	// LoadRollup retrieves the aggregated statistics of all chat sessions.
+	// If no rollup has been saved yet, a new, empty Rollup object with a zero
+	// Updated time is returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	//
+	// Returns the retrieved or new Rollup object, and an error if the load operation fails
+	// for reasons other than the rollup not being found.
+	LoadRollup(ctx context.Context) (*Rollup, error)
+}
+
+// Profiles is implemented by the storages keeping the profiles of the users.
+type Profiles interface {
+	// SaveProfile persists the given user profile into the storage, replacing the
+	// previously saved profile of the same user.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// profile: The Profile object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveProfile(ctx context.Context, profile *Profile) error
+
+	// LoadProfile retrieves the profile of the given user from storage.
+	// If no profile is associated with the user, a new, empty Profile object is returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	// user: The unique identifier of the user.
+	//
+	// Returns the retrieved or new Profile object, and an error if the load operation fails
+	// for reasons other than the profile not being found.
+	LoadProfile(ctx context.Context, user int64) (*Profile, error)
+
+	// ListProfiles enumerates the IDs of all users whose profiles are persisted in the storage.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the listing.
+	//
+	// Returns the IDs of the users, and an error if the listing fails.
+	ListProfiles(ctx context.Context) ([]int64, error)
+}
+
+// Budgets is implemented by the storages keeping the budgets of the users and the group chats.
+type Budgets interface {
+	// SaveBudget persists the given budget into the storage, replacing the previously
+	// saved budget of the same owner.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// budget: The Budget object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveBudget(ctx context.Context, budget *Budget) error
+
+	// LoadBudget retrieves the budget of the given user or group chat from storage.
+	// If no budget is associated with the owner, a new Budget object without a limit
+	// is returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	// owner: The unique identifier of the user or the group chat.
+	//
+	// Returns the retrieved or new Budget object, and an error if the load operation fails
+	// for reasons other than the budget not being found.
+	LoadBudget(ctx context.Context, owner int64) (*Budget, error)
+
+	// ListBudgets enumerates the IDs of all users and group chats whose budgets are
+	// persisted in the storage.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the listing.
+	//
+	// Returns the IDs of the owners of the budgets, and an error if the listing fails.
+	ListBudgets(ctx context.Context) ([]int64, error)
+}
+
+// Ledgers is implemented by the storages keeping the spending ledgers of the users and
+// the group chats, which they update in SaveStatistics.
+type Ledgers interface {
+	// SaveLedger persists the given ledger into the storage, replacing the previously
+	// saved ledger of the same owner. The ledgers are kept up to date by SaveStatistics,
+	// so this is needed only to restore them, e.g. from a backup.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// ledger: The Ledger object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveLedger(ctx context.Context, ledger *Ledger) error
+
+	// LoadLedger retrieves the running spending totals of the given user or group chat
+	// from storage. If nothing has been spent yet, a new, empty Ledger object is returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	// owner: The unique identifier of the user or the group chat.
+	//
+	// Returns the retrieved or new Ledger object, and an error if the load operation fails
+	// for reasons other than the ledger not being found.
+	LoadLedger(ctx context.Context, owner int64) (*Ledger, error)
+
+	// ListLedgers enumerates the IDs of all users and group chats whose ledgers are
+	// persisted in the storage.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the listing.
+	//
+	// Returns the IDs of the owners of the ledgers, and an error if the listing fails.
+	ListLedgers(ctx context.Context) ([]int64, error)
+}
+
+// Invites is implemented by the storages keeping the invites.
+type Invites interface {
+	// SaveInvite persists the given invite into the storage, replacing the previously
+	// saved invite with the same code.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// invite: The Invite object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveInvite(ctx context.Context, invite *Invite) error
+
+	// LoadInvite retrieves the invite with the given code from storage.
+	// If no invite exists with the code, a new Invite object with a zero Created
+	// time is returned, which is not valid.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	// code: The code of the invite.
+	//
+	// Returns the retrieved or new Invite object, and an error if the load operation fails
+	// for reasons other than the invite not being found.
+	LoadInvite(ctx context.Context, code string) (*Invite, error)
+
+	// ListInvites enumerates the codes of all invites persisted in the storage.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the listing.
+	//
+	// Returns the codes of the invites, and an error if the listing fails.
+	ListInvites(ctx context.Context) ([]string, error)
+}
+
+// Progress is implemented by the storages keeping the progress of the bot through the
+// Telegram updates.
+type Progress interface {
+	// SaveUpdates persists the progress of the bot through the Telegram updates,
+	// replacing the previously saved progress.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// updates: The Updates object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveUpdates(ctx context.Context, updates *Updates) error
+
+	// LoadUpdates retrieves the progress of the bot through the Telegram updates.
+	// If no progress has been saved yet, a new Updates object without a processed
+	// update is returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	//
+	// Returns the retrieved or new Updates object, and an error if the load operation fails
+	// for reasons other than the progress not being found.
+	LoadUpdates(ctx context.Context) (*Updates, error)
+}
+
+// Configuration is implemented by the storages keeping the settings of the bot.
+type Configuration interface {
+	// SaveSettings persists the settings of the bot, replacing the previously saved ones.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the save process.
+	// settings: The Settings object to be saved.
+	//
+	// Returns an error if the save operation encounters issues.
+	SaveSettings(ctx context.Context, settings *Settings) error
+
+	// LoadSettings retrieves the settings of the bot. If no settings have been saved
+	// yet, new Settings with the defaults are returned.
+	//
+	// ctx: A context.Context to allow for cancellation and timeout control during the load process.
+	//
+	// Returns the retrieved or new Settings object, and an error if the load operation fails
+	// for reasons other than the settings not being found.
+	LoadSettings(ctx context.Context) (*Settings, error)
 }
@@ -0,0 +1,11 @@
+package chat
+
+// EstimateTokens returns an approximate number of tokens in the text. It assumes
+// about four bytes of UTF-8 text per token, which is close to the tokenizers of the
+// OpenAI models for English and gives about two characters per token for the
+// languages with multibyte characters (e.g., Russian).
+//
+// text: The text to estimate.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,135 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Updates records the progress of the bot through the Telegram updates, so the bot
+// resumes after a restart from the first update it has not processed yet.
+type Updates struct {
+	LastID  int       // LastID is the ID of the last processed update; zero if none.
+	Updated time.Time // Updated records the time the last update was processed.
+
+	// Recent holds the IDs of the processed updates within the window below LastID with
+	// the time they were processed, to recognize the updates Telegram delivers again
+	// out of order.
+	Recent map[int]time.Time
+
+	// Pending holds the IDs of the updates being processed with the time they were
+	// received. They are processed again after a restart, see Resume.
+	Pending map[int]time.Time
+}
+
+// Seen reports whether the update has been processed: the updates within the window
+// below LastID are looked up in Recent, and the older ones are taken as processed.
+//
+// id: The ID of the update.
+// window: The number of the IDs below LastID the processed updates are remembered for.
+func (u *Updates) Seen(id int, window int) bool {
+	if id <= u.LastID-window {
+		return true
+	}
+
+	_, ok := u.Recent[id]
+	return ok
+}
+
+// Add records the update as received and pending until Done is called, and forgets
+// the updates below the window, so Recent holds at most window updates.
+//
+// id: The ID of the received update.
+// now: The time the update was received.
+// window: The number of the IDs below LastID the processed updates are remembered for.
+func (u *Updates) Add(id int, now time.Time, window int) {
+	if u.Recent == nil {
+		u.Recent = make(map[int]time.Time)
+	}
+	if u.Pending == nil {
+		u.Pending = make(map[int]time.Time)
+	}
+
+	u.Recent[id] = now
+	u.Pending[id] = now
+	u.LastID = max(u.LastID, id)
+	u.Updated = now
+
+	for recent := range u.Recent {
+		if recent <= u.LastID-window {
+			delete(u.Recent, recent)
+		}
+	}
+}
+
+// Done records that the processing of the update has finished.
+//
+// id: The ID of the processed update.
+// now: The time the processing finished.
+func (u *Updates) Done(id int, now time.Time) {
+	delete(u.Pending, id)
+	u.Updated = now
+}
+
+// Resume prepares the progress loaded after a restart: the updates whose processing
+// had not finished are forgotten, so they are processed again, and the offset is moved
+// back to the first of them.
+func (u *Updates) Resume() {
+	for id := range u.Pending {
+		delete(u.Recent, id)
+		u.LastID = min(u.LastID, id-1)
+	}
+
+	u.Pending = nil
+}
+
+// Offset returns the ID of the first update which has not been processed yet, or
+// zero if no update has been processed.
+func (u *Updates) Offset() int {
+	if u.LastID == 0 {
+		return 0
+	}
+
+	return u.LastID + 1
+}
+
+// Write serializes the Updates instance and writes it to the provided io.Writer in JSON format.
+//
+// w: The writer to which the serialized updates should be written.
+//
+// Returns:
+// error: An error if encountered during the serialization or writing process.
+func (u *Updates) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(u)
+}
+
+// Read deserializes the Updates instance from the provided io.Reader which should contain
+// the updates in JSON format.
+//
+// r: The reader from which the serialized updates should be read.
+//
+// Returns:
+// error: An error if encountered during the deserialization process.
+func (u *Updates) Read(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	return decoder.Decode(u)
+}
+
+// Clone creates a copy of the Updates object.
+//
+// Returns:
+// *Updates: A new instance of Updates which is a copy of the original.
+func (u *Updates) Clone() *Updates {
+	clone := *u
+	clone.Recent = make(map[int]time.Time, len(u.Recent))
+	for id, processed := range u.Recent {
+		clone.Recent[id] = processed
+	}
+	clone.Pending = make(map[int]time.Time, len(u.Pending))
+	for id, received := range u.Pending {
+		clone.Pending[id] = received
+	}
+	return &clone
+}
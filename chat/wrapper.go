@@ -0,0 +1,221 @@
+package chat
+
+import "context"
+
+// Wrapper exposes the optional interfaces of the storage it wraps. The wrappers of the
+// storages, such as the caches, embed it, so they keep the optional methods of the
+// storage they override the core methods of. If the storage does not implement an
+// optional interface, the loads return the objects as if nothing had been saved, the
+// listings return nothing and the saves fail with ErrNotSupported.
+type Wrapper struct {
+	Storage // Storage is the wrapped storage.
+}
+
+// SaveRollup saves the rollup if the storage implements Rollups.
+//
+// ctx: The context for the storage operation.
+// rollup: The rollup to be saved.
+func (w Wrapper) SaveRollup(ctx context.Context, rollup *Rollup) error {
+	if rollups, ok := w.Storage.(Rollups); ok {
+		return rollups.SaveRollup(ctx, rollup)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadRollup loads the rollup if the storage implements Rollups.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) LoadRollup(ctx context.Context) (*Rollup, error) {
+	if rollups, ok := w.Storage.(Rollups); ok {
+		return rollups.LoadRollup(ctx)
+	}
+
+	return &Rollup{PerUser: map[int64]Totals{}, PerModel: map[string]Totals{}}, nil
+}
+
+// SaveProfile saves the profile if the storage implements Profiles.
+//
+// ctx: The context for the storage operation.
+// profile: The profile to be saved.
+func (w Wrapper) SaveProfile(ctx context.Context, profile *Profile) error {
+	if profiles, ok := w.Storage.(Profiles); ok {
+		return profiles.SaveProfile(ctx, profile)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadProfile loads the profile of the user if the storage implements Profiles.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (w Wrapper) LoadProfile(ctx context.Context, user int64) (*Profile, error) {
+	if profiles, ok := w.Storage.(Profiles); ok {
+		return profiles.LoadProfile(ctx, user)
+	}
+
+	return &Profile{User: user}, nil
+}
+
+// ListProfiles lists the users with a profile if the storage implements Profiles.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) ListProfiles(ctx context.Context) ([]int64, error) {
+	if profiles, ok := w.Storage.(Profiles); ok {
+		return profiles.ListProfiles(ctx)
+	}
+
+	return nil, nil
+}
+
+// SaveBudget saves the budget if the storage implements Budgets.
+//
+// ctx: The context for the storage operation.
+// budget: The budget to be saved.
+func (w Wrapper) SaveBudget(ctx context.Context, budget *Budget) error {
+	if budgets, ok := w.Storage.(Budgets); ok {
+		return budgets.SaveBudget(ctx, budget)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadBudget loads the budget of the owner if the storage implements Budgets.
+//
+// ctx: The context for the storage operation.
+// owner: The ID of the user or the group chat.
+func (w Wrapper) LoadBudget(ctx context.Context, owner int64) (*Budget, error) {
+	if budgets, ok := w.Storage.(Budgets); ok {
+		return budgets.LoadBudget(ctx, owner)
+	}
+
+	return &Budget{Owner: owner}, nil
+}
+
+// ListBudgets lists the owners of the budgets if the storage implements Budgets.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) ListBudgets(ctx context.Context) ([]int64, error) {
+	if budgets, ok := w.Storage.(Budgets); ok {
+		return budgets.ListBudgets(ctx)
+	}
+
+	return nil, nil
+}
+
+// SaveLedger saves the ledger if the storage implements Ledgers.
+//
+// ctx: The context for the storage operation.
+// ledger: The ledger to be saved.
+func (w Wrapper) SaveLedger(ctx context.Context, ledger *Ledger) error {
+	if ledgers, ok := w.Storage.(Ledgers); ok {
+		return ledgers.SaveLedger(ctx, ledger)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadLedger loads the ledger of the owner if the storage implements Ledgers.
+//
+// ctx: The context for the storage operation.
+// owner: The ID of the user or the group chat.
+func (w Wrapper) LoadLedger(ctx context.Context, owner int64) (*Ledger, error) {
+	if ledgers, ok := w.Storage.(Ledgers); ok {
+		return ledgers.LoadLedger(ctx, owner)
+	}
+
+	return &Ledger{Owner: owner, Days: map[string]Cost{}, Months: map[string]Cost{}}, nil
+}
+
+// ListLedgers lists the owners of the ledgers if the storage implements Ledgers.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) ListLedgers(ctx context.Context) ([]int64, error) {
+	if ledgers, ok := w.Storage.(Ledgers); ok {
+		return ledgers.ListLedgers(ctx)
+	}
+
+	return nil, nil
+}
+
+// SaveInvite saves the invite if the storage implements Invites.
+//
+// ctx: The context for the storage operation.
+// invite: The invite to be saved.
+func (w Wrapper) SaveInvite(ctx context.Context, invite *Invite) error {
+	if invites, ok := w.Storage.(Invites); ok {
+		return invites.SaveInvite(ctx, invite)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadInvite loads the invite with the code if the storage implements Invites.
+//
+// ctx: The context for the storage operation.
+// code: The code of the invite.
+func (w Wrapper) LoadInvite(ctx context.Context, code string) (*Invite, error) {
+	if invites, ok := w.Storage.(Invites); ok {
+		return invites.LoadInvite(ctx, code)
+	}
+
+	return &Invite{Code: code}, nil
+}
+
+// ListInvites lists the codes of the invites if the storage implements Invites.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) ListInvites(ctx context.Context) ([]string, error) {
+	if invites, ok := w.Storage.(Invites); ok {
+		return invites.ListInvites(ctx)
+	}
+
+	return nil, nil
+}
+
+// SaveUpdates saves the progress through the updates if the storage implements Progress.
+//
+// ctx: The context for the storage operation.
+// updates: The progress to be saved.
+func (w Wrapper) SaveUpdates(ctx context.Context, updates *Updates) error {
+	if progress, ok := w.Storage.(Progress); ok {
+		return progress.SaveUpdates(ctx, updates)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadUpdates loads the progress through the updates if the storage implements Progress.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) LoadUpdates(ctx context.Context) (*Updates, error) {
+	if progress, ok := w.Storage.(Progress); ok {
+		return progress.LoadUpdates(ctx)
+	}
+
+	return &Updates{}, nil
+}
+
+// SaveSettings saves the settings if the storage implements Configuration.
+//
+// ctx: The context for the storage operation.
+// settings: The settings to be saved.
+func (w Wrapper) SaveSettings(ctx context.Context, settings *Settings) error {
+	if configuration, ok := w.Storage.(Configuration); ok {
+		return configuration.SaveSettings(ctx, settings)
+	}
+
+	return ErrNotSupported
+}
+
+// LoadSettings loads the settings if the storage implements Configuration.
+//
+// ctx: The context for the storage operation.
+func (w Wrapper) LoadSettings(ctx context.Context) (*Settings, error) {
+	if configuration, ok := w.Storage.(Configuration); ok {
+		return configuration.LoadSettings(ctx)
+	}
+
+	return &Settings{}, nil
+}
@@ -0,0 +1,128 @@
+package chatgpt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Transcribe converts the given audio into text with the session's transcription
+// model and adds the cost of the transcription to the session's statistics under the
+// transcription model name. The conversation history is not affected.
+//
+// ctx: The context in which the network operations will be made.
+// audio: The audio content to transcribe.
+// filename: The name of the audio file; its extension tells the API the audio format.
+// duration: The duration of the audio, used to calculate the cost.
+//
+// Returns:
+// The transcribed text and an error if the audio could not be transcribed or the
+// statistics could not be saved.
+func (s *Session) Transcribe(ctx context.Context, audio io.Reader, filename string, duration time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	// Load session cache if necessary.
+	if err := s.loadCacheIfNeeded(ctx); err != nil {
+		return "", err
+	}
+
+	cost, err := CalculateTranscriptionCostByModel(s.params.TranscriptionModel, duration)
+	if err != nil {
+		return "", fmt.Errorf("error calculating the cost: %w", err)
+	}
+
+	callCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	resp, err := s.client.CreateTranscription(callCtx, openai.AudioRequest{
+		Model:    s.params.TranscriptionModel,
+		FilePath: filename,
+		Reader:   audio,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating transcription: %w", err)
+	}
+
+	s.cache.Statistics.AddCost(chat.Now().In(s.loc), s.params.TranscriptionModel, cost)
+	chat.MeterCost(ctx, cost)
+
+	// The cost is saved even if the request is canceled meanwhile.
+	if err := s.storage.SaveStatistics(context.WithoutCancel(ctx), s.cache.Statistics); err != nil {
+		return "", fmt.Errorf("error saving statistics to storage: %w", err)
+	}
+
+	return resp.Text, nil
+}
+
+// Speak synthesizes speech from the given text with the session's speech model and
+// voice, and adds the cost of the synthesis to the session's statistics under the
+// speech model name. The conversation history is not affected.
+//
+// ctx: The context in which the network operations will be made.
+// text: The text to synthesize.
+//
+// Returns:
+// The synthesized speech encoded as OGG/Opus and an error if the speech could not
+// be synthesized or the statistics could not be saved.
+func (s *Session) Speak(ctx context.Context, text string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Load session cache if necessary.
+	if err := s.loadCacheIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+
+	cost, err := CalculateSpeechCostByModel(s.params.SpeechModel, utf8.RuneCountInString(text))
+	if err != nil {
+		return nil, fmt.Errorf("error calculating the cost: %w", err)
+	}
+
+	// The speech is streamed, so the deadline covers reading it as well.
+	callCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	resp, err := s.client.CreateSpeech(callCtx, openai.CreateSpeechRequest{
+		Model:          openai.SpeechModel(s.params.SpeechModel),
+		Input:          text,
+		Voice:          openai.SpeechVoice(s.params.SpeechVoice),
+		ResponseFormat: openai.SpeechResponseFormatOpus,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating speech: %w", err)
+	}
+	defer resp.Close()
+
+	speech, err := io.ReadAll(resp)
+	if err != nil {
+		return nil, fmt.Errorf("error reading speech: %w", err)
+	}
+
+	s.cache.Statistics.AddCost(chat.Now().In(s.loc), s.params.SpeechModel, cost)
+	chat.MeterCost(ctx, cost)
+
+	// The cost is saved even if the request is canceled meanwhile.
+	if err := s.storage.SaveStatistics(context.WithoutCancel(ctx), s.cache.Statistics); err != nil {
+		return nil, fmt.Errorf("error saving statistics to storage: %w", err)
+	}
+
+	return speech, nil
+}
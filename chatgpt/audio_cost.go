@@ -0,0 +1,63 @@
+package chatgpt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// TranscriptionCost provides a mapping from transcription model identifiers to the
+// cost of one minute of transcribed audio.
+var TranscriptionCost = map[string]chat.Cost{
+	openai.Whisper1:          0.006,
+	"gpt-4o-transcribe":      0.006,
+	"gpt-4o-mini-transcribe": 0.003,
+}
+
+// SpeechCost provides a mapping from speech synthesis model identifiers to the
+// cost of 1,000 characters of synthesized text.
+var SpeechCost = map[string]chat.Cost{
+	string(openai.TTSModel1):   0.015,
+	string(openai.TTSModel1HD): 0.03,
+}
+
+// CalculateTranscriptionCostByModel calculates the cost of transcribing audio of the
+// given duration based on the model name. The duration is billed per second.
+//
+// Parameters:
+// - modelName: The identifier of the transcription model.
+// - duration: The duration of the transcribed audio.
+//
+// Returns:
+// - The cost of the transcription.
+// - An error if the model does not exist in the TranscriptionCost map.
+func CalculateTranscriptionCostByModel(modelName string, duration time.Duration) (chat.Cost, error) {
+	costPerMinute, ok := TranscriptionCost[modelName]
+	if !ok {
+		return 0, fmt.Errorf("model name '%s' not found in the transcription cost map", modelName)
+	}
+
+	seconds := duration.Round(time.Second).Seconds()
+	return chat.Cost(seconds / 60 * float64(costPerMinute)), nil
+}
+
+// CalculateSpeechCostByModel calculates the cost of synthesizing speech from a text
+// of the given length based on the model name.
+//
+// Parameters:
+// - modelName: The identifier of the speech synthesis model.
+// - characters: The number of characters of the synthesized text.
+//
+// Returns:
+// - The cost of the speech synthesis.
+// - An error if the model does not exist in the SpeechCost map.
+func CalculateSpeechCostByModel(modelName string, characters int) (chat.Cost, error) {
+	costPer1k, ok := SpeechCost[modelName]
+	if !ok {
+		return 0, fmt.Errorf("model name '%s' not found in the speech cost map", modelName)
+	}
+
+	return chat.Cost(float64(characters) / 1000 * float64(costPer1k)), nil
+}
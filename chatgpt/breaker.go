@@ -0,0 +1,201 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the circuit breaker implements the Client interface
+var _ Client = (*BreakerClient)(nil)
+
+// BreakerClient is a Client which stops calling a failing API. After the given number
+// of consecutive upstream failures the circuit opens, and the requests fail at once
+// with chat.ErrUnavailable. When the cooldown passes, a single request probes the API:
+// its success closes the circuit, its failure opens it again for another cooldown.
+//
+// The client errors, such as a rejected prompt, and the rate limits do not count as
+// failures. Each provider needs its own breaker, so a failing provider does not stop
+// the others.
+type BreakerClient struct {
+	next      Client
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // failures is the number of consecutive upstream failures.
+	openTill time.Time // openTill is the end of the cooldown of the open circuit.
+	probing  bool      // probing is set while a request probes the API after the cooldown.
+}
+
+// NewBreakerClient creates a circuit breaker around the client.
+//
+// next: The client calling the API of the provider.
+// name: The name of the provider, e.g. "openai", used in the logs.
+// threshold: The number of consecutive failures which opens the circuit.
+// cooldown: How long the circuit stays open before the API is probed.
+func NewBreakerClient(next Client, name string, threshold int, cooldown time.Duration) *BreakerClient {
+	return &BreakerClient{next: next, name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// CreateChatCompletion sends the request unless the circuit is open.
+func (b *BreakerClient) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	return guard(b, func() (openai.ChatCompletionResponse, error) {
+		return b.next.CreateChatCompletion(ctx, request)
+	})
+}
+
+// CreateTranscription sends the request unless the circuit is open.
+func (b *BreakerClient) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	return guard(b, func() (openai.AudioResponse, error) {
+		return b.next.CreateTranscription(ctx, request)
+	})
+}
+
+// CreateSpeech sends the request unless the circuit is open.
+func (b *BreakerClient) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	return guard(b, func() (openai.RawResponse, error) {
+		return b.next.CreateSpeech(ctx, request)
+	})
+}
+
+// CreateImage sends the request unless the circuit is open.
+func (b *BreakerClient) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	return guard(b, func() (openai.ImageResponse, error) {
+		return b.next.CreateImage(ctx, request)
+	})
+}
+
+// guard calls the API if the circuit allows it and records the outcome.
+//
+// b: The circuit breaker.
+// call: The request to the API.
+func guard[T any](b *BreakerClient, call func() (T, error)) (T, error) {
+	probe, err := b.allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	resp, err := call()
+	b.record(err, probe)
+
+	return resp, err
+}
+
+// allow returns chat.ErrUnavailable if the circuit is open, or if the cooldown has
+// passed and another request is already probing the API.
+//
+// Returns true if the request probes the API after the cooldown.
+func (b *BreakerClient) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+
+	if wait := time.Until(b.openTill); wait > 0 || b.probing {
+		return false, fmt.Errorf("%w: retry in %v", chat.ErrUnavailable, max(wait, 0).Round(time.Second))
+	}
+
+	b.probing = true
+	return true, nil
+}
+
+// record counts the upstream failures and opens or closes the circuit. The requests
+// sent before the circuit opened may finish while it is open, so only the probe closes
+// it. A request cancelled by the user tells nothing about the API and is not counted.
+//
+// err: The error of the request.
+// probe: Whether the request probed the API.
+func (b *BreakerClient) record(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if !isUpstreamFailure(err) {
+		open := b.failures >= b.threshold
+		if open && !probe {
+			return
+		}
+		if open {
+			slog.Info("circuit closed", slog.String("provider", b.name))
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openTill = time.Now().Add(b.cooldown)
+		slog.Warn(
+			"circuit open",
+			slog.String("provider", b.name),
+			slog.Int("failures", b.failures),
+			slog.Duration("cooldown", b.cooldown),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// isUpstreamFailure reports whether the error shows that the API failed, as opposed to
+// a success, a client error or a request cancelled by the user.
+//
+// err: The error of the request.
+func isUpstreamFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return failedStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return failedStatus(reqErr.HTTPStatusCode)
+	}
+
+	// Network errors and timeouts.
+	return true
+}
+
+// failedStatus reports whether the HTTP status shows a failure of the API: a server
+// error or a timeout. A rate limit is not a failure: it applies to a model or an API key
+// and is handled by the key rotation and the fallback model, which the open circuit
+// would cut off for every model of the provider.
+//
+// status: The HTTP status code of the response.
+func failedStatus(status int) bool {
+	return status >= http.StatusInternalServerError ||
+		status == http.StatusRequestTimeout
+}
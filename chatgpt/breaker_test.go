@@ -0,0 +1,98 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/storage"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestBreakerProbe(t *testing.T) {
+	failure := &openai.APIError{HTTPStatusCode: http.StatusInternalServerError}
+	breaker := NewBreakerClient(nil, "openai", 1, 0)
+
+	// The circuit opens, and the API is probed once the cooldown passes.
+	breaker.record(failure, false)
+	if probe, err := breaker.allow(); !probe || err != nil {
+		t.Fatalf("allow returned %v, %v, want the probe", probe, err)
+	}
+
+	// The request sent before the circuit opened does not end the probe.
+	breaker.record(failure, false)
+	if _, err := breaker.allow(); !errors.Is(err, chat.ErrUnavailable) {
+		t.Fatalf("allow returned %v during the probe, want chat.ErrUnavailable", err)
+	}
+
+	// The successful probe closes the circuit.
+	breaker.record(nil, true)
+	if probe, err := breaker.allow(); probe || err != nil {
+		t.Errorf("allow returned %v, %v, want the closed circuit", probe, err)
+	}
+}
+
+func TestBreakerStaleSuccess(t *testing.T) {
+	failure := &openai.APIError{HTTPStatusCode: http.StatusInternalServerError}
+	breaker := NewBreakerClient(nil, "openai", 1, time.Hour)
+
+	// The circuit opens.
+	breaker.record(failure, false)
+
+	// A request sent before the circuit opened succeeds, and another one is cancelled.
+	breaker.record(nil, false)
+	breaker.record(context.Canceled, false)
+
+	// Neither closes the circuit.
+	if _, err := breaker.allow(); !errors.Is(err, chat.ErrUnavailable) {
+		t.Errorf("allow returned %v after the stale success, want chat.ErrUnavailable", err)
+	}
+}
+
+func TestBreakerCancelledProbe(t *testing.T) {
+	failure := &openai.APIError{HTTPStatusCode: http.StatusInternalServerError}
+	breaker := NewBreakerClient(nil, "openai", 1, 0)
+
+	breaker.record(failure, false)
+	if probe, err := breaker.allow(); !probe || err != nil {
+		t.Fatalf("allow returned %v, %v, want the probe", probe, err)
+	}
+
+	// The cancelled probe neither closes the circuit nor blocks the next probe.
+	breaker.record(context.Canceled, true)
+	if probe, err := breaker.allow(); !probe || err != nil {
+		t.Errorf("allow returned %v, %v after the cancelled probe, want another probe", probe, err)
+	}
+}
+
+func TestBreakerRateLimitFallsBack(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Hello, User!", limited: openai.GPT4o}
+	breaker := NewBreakerClient(client, "openai", 1, time.Hour)
+
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4o}
+	session := NewSession(id, breaker, &storage.FS{BaseDir: t.TempDir()})
+
+	params := DefaultRequestParams
+	params.FallbackModel = openai.GPT4oMini
+	session.SetRequestParams(params)
+
+	// The persistent rate limit of the model does not open the circuit, so every
+	// question is answered by the fallback model.
+	for i := 0; i < 5; i++ {
+		_, fallback, err := session.Ask(ctx, "Hello!", false)
+		if err != nil {
+			t.Fatalf("Ask %d failed: %s", i, err)
+		}
+		if fallback != openai.GPT4oMini {
+			t.Fatalf("The fallback of the question %d is %q, want %q", i, fallback, openai.GPT4oMini)
+		}
+	}
+
+	if _, err := breaker.allow(); err != nil {
+		t.Errorf("allow returned %v after the rate limits, want the closed circuit", err)
+	}
+}
@@ -0,0 +1,26 @@
+package chatgpt
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the OpenAI client implements the Client interface
+var _ Client = (*openai.Client)(nil)
+
+// Client is the subset of the OpenAI API used by the sessions. It is implemented by
+// *openai.Client; tests and alternative transports can provide their own implementation.
+type Client interface {
+	// CreateChatCompletion sends the conversation to the model and returns its reply.
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+
+	// CreateTranscription transcribes the audio to text.
+	CreateTranscription(ctx context.Context, request openai.AudioRequest) (openai.AudioResponse, error)
+
+	// CreateSpeech synthesizes the speech from the text.
+	CreateSpeech(ctx context.Context, request openai.CreateSpeechRequest) (openai.RawResponse, error)
+
+	// CreateImage generates the image from the prompt.
+	CreateImage(ctx context.Context, request openai.ImageRequest) (openai.ImageResponse, error)
+}
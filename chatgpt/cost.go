@@ -1,6 +1,8 @@
 package chatgpt
 
 import (
+	"sync"
+
 	"github.com/muzykantov/tgpt/chat"
 	"github.com/sashabaranov/go-openai"
 )
@@ -10,9 +12,13 @@ import (
 // are approximately equivalent to 750 words. Costs are split into Input
 // and Output to differentiate between the text fed into the model and
 // the text generated by the model, respectively.
+//
+// CachedInput is the discounted cost of input tokens served from the prompt cache.
+// It is zero for models without prompt caching.
 type CostPer1k struct {
-	Input  chat.Cost // Cost for input tokens per 1,000 tokens
-	Output chat.Cost // Cost for output tokens per 1,000 tokens
+	Input       chat.Cost // Cost for input tokens per 1,000 tokens
+	CachedInput chat.Cost // Cost for cached input tokens per 1,000 tokens
+	Output      chat.Cost // Cost for output tokens per 1,000 tokens
 }
 
 // Predefined cost structures for various OpenAI models with different
@@ -45,12 +51,23 @@ var (
 		Input:  0.01,
 		Output: 0.03,
 	} // Cost structure for GPT-4 Turbo with a 128k token context.
+	GPT4oCtx128k = CostPer1k{
+		Input:       0.0025,
+		CachedInput: 0.00125,
+		Output:      0.01,
+	} // Cost structure for GPT-4o with a 128k token context and prompt caching.
+	GPT4oMiniCtx128k = CostPer1k{
+		Input:       0.00015,
+		CachedInput: 0.000075,
+		Output:      0.0006,
+	} // Cost structure for GPT-4o mini with a 128k token context and prompt caching.
 )
 
 // Cost provides a mapping from model identifiers to their respective CostPer1k
 // structures. This map is used to quickly look up the cost of using a particular
 // model based on its identifier, which can be a key factor when selecting
-// a model for use in applications.
+// a model for use in applications. Once the bot runs, the prices are changed with
+// SetCosts only.
 var Cost = map[string]CostPer1k{
 	openai.GPT3Dot5Turbo:    GPT3Dot5TurboCtx4k,      // Maps GPT-3.5 Turbo to its corresponding 4k token context cost structure.
 	openai.GPT3Dot5Turbo16K: GPT3Dot5TurboCtx16k,     // Maps GPT-3.5 Turbo with 16k context to its cost structure.
@@ -58,4 +75,57 @@ var Cost = map[string]CostPer1k{
 	openai.GPT432K:          GPT4Ctx32k,              // Maps GPT-4 with 32k context to its cost structure.
 	"gpt-4-1106-preview":    GPT4Turbo1106Ctx128k,    // Maps GPT-4 Turbo with 128k context to its cost structure.
 	"gpt-3.5-turbo-1106":    GPT3Dot5Turbo1106Ctx16k, // Maps GPT-3.5 Turbo with 16k context to its cost structure.
+	openai.GPT4o:            GPT4oCtx128k,            // Maps GPT-4o to its cost structure.
+	openai.GPT4oMini:        GPT4oMiniCtx128k,        // Maps GPT-4o mini to its cost structure.
+}
+
+// costMu guards Cost against the prices updated while the bot runs.
+var costMu sync.RWMutex
+
+// SetCosts sets the prices of the models, e.g. the prices published by the provider,
+// replacing the prices of the same models.
+//
+// prices: The prices by the model name.
+func SetCosts(prices map[string]CostPer1k) {
+	costMu.Lock()
+	defer costMu.Unlock()
+
+	for model, price := range prices {
+		Cost[model] = price
+	}
+}
+
+// Costs returns a copy of the prices of all models.
+func Costs() map[string]CostPer1k {
+	costMu.RLock()
+	defer costMu.RUnlock()
+
+	prices := make(map[string]CostPer1k, len(Cost))
+	for model, price := range Cost {
+		prices[model] = price
+	}
+
+	return prices
+}
+
+// KnownModel reports whether the price of the model is known, i.e. the usage of the
+// model can be accounted.
+//
+// model: The name of the model.
+func KnownModel(model string) bool {
+	_, ok := costOf(model)
+	return ok
+}
+
+// costOf returns the price of the model.
+//
+// model: The name of the model.
+//
+// Returns false if the price of the model is unknown.
+func costOf(model string) (CostPer1k, bool) {
+	costMu.RLock()
+	defer costMu.RUnlock()
+
+	price, ok := Cost[model]
+	return price, ok
 }
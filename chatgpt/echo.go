@@ -0,0 +1,109 @@
+package chatgpt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the echo client implements the Client interface
+var _ Client = (*EchoClient)(nil)
+
+// errEchoSpeech is returned by the echo client for the speech synthesis, since it has
+// no audio to return.
+var errEchoSpeech = errors.New("speech synthesis is not supported by the echo client")
+
+// EchoClient is a dry-run Client which answers without calling the OpenAI API: it echoes
+// the last message of the user, or replies with a canned text. The usage is estimated
+// from the length of the messages, so the costs are accounted as usual. It lets the
+// operators verify the Telegram wiring, the permissions and the storage without
+// spending the OpenAI credits.
+type EchoClient struct {
+	reply string // reply is the canned reply; empty means echoing the user.
+}
+
+// NewEchoClient creates a dry-run client.
+//
+// reply: The canned reply to every message, or an empty string to echo the messages.
+func NewEchoClient(reply string) *EchoClient {
+	return &EchoClient{reply: reply}
+}
+
+// CreateChatCompletion replies with the canned text or the last message of the user.
+//
+// ctx: The context of the request, unused.
+// request: The chat completion request.
+func (c *EchoClient) CreateChatCompletion(
+	_ context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	var input int
+	var last string
+	for _, msg := range request.Messages {
+		text := msg.Content
+		for _, part := range msg.MultiContent {
+			text += part.Text
+		}
+
+		input += chat.EstimateTokens(text)
+		if msg.Role == openai.ChatMessageRoleUser {
+			last = text
+		}
+	}
+
+	reply := c.reply
+	if reply == "" {
+		reply = "Echo: " + strings.TrimSpace(last)
+	}
+
+	return openai.ChatCompletionResponse{
+		Model: request.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply},
+			FinishReason: openai.FinishReasonStop,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     input,
+			CompletionTokens: chat.EstimateTokens(reply),
+			TotalTokens:      input + chat.EstimateTokens(reply),
+		},
+	}, nil
+}
+
+// CreateTranscription returns a placeholder transcription of the audio.
+//
+// ctx: The context of the request, unused.
+// request: The transcription request.
+func (c *EchoClient) CreateTranscription(context.Context, openai.AudioRequest) (openai.AudioResponse, error) {
+	return openai.AudioResponse{Text: "(dry run transcription)"}, nil
+}
+
+// CreateSpeech always fails, since the echo client has no audio to return.
+//
+// ctx: The context of the request, unused.
+// request: The speech request.
+func (c *EchoClient) CreateSpeech(context.Context, openai.CreateSpeechRequest) (openai.RawResponse, error) {
+	return openai.RawResponse{}, errEchoSpeech
+}
+
+// CreateImage returns a blank image.
+//
+// ctx: The context of the request, unused.
+// request: The image request.
+func (c *EchoClient) CreateImage(context.Context, openai.ImageRequest) (openai.ImageResponse, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+		return openai.ImageResponse{}, err
+	}
+
+	return openai.ImageResponse{
+		Data: []openai.ImageResponseDataInner{{B64JSON: base64.StdEncoding.EncodeToString(buf.Bytes())}},
+	}, nil
+}
@@ -0,0 +1,17 @@
+package chatgpt
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// isRateLimited reports whether the request was rejected with 429 Too Many Requests,
+// which the OpenAI API returns both for the rate limits and for the exhausted quota.
+//
+// err: The error of the request.
+func isRateLimited(err error) bool {
+	var apiErr *openai.APIError
+	return errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusTooManyRequests
+}
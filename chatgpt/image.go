@@ -0,0 +1,83 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Draw generates an image from the given prompt with the session's image model and
+// adds the cost of the image to the session's statistics under the image model name.
+// The conversation history is not affected.
+//
+// ctx: The context in which the network operations will be made.
+// prompt: The description of the image to generate.
+//
+// Returns:
+// The generated image encoded as PNG and an error if the image could not be generated
+// or the statistics could not be saved.
+func (s *Session) Draw(ctx context.Context, prompt string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Load session cache if necessary.
+	if err := s.loadCacheIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+
+	tier := ImageTier{Size: s.params.ImageSize, Quality: s.params.ImageQuality}
+	cost, err := CalculateImageCostByModel(s.params.ImageModel, tier, 1)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating the cost: %w", err)
+	}
+
+	req := openai.ImageRequest{
+		Prompt:  prompt,
+		Model:   s.params.ImageModel,
+		N:       1,
+		Size:    s.params.ImageSize,
+		Quality: s.params.ImageQuality,
+	}
+	// GPT image models always return base64-encoded images and reject the format.
+	if s.params.ImageModel != openai.CreateImageModelGptImage1 {
+		req.ResponseFormat = openai.CreateImageResponseFormatB64JSON
+	}
+
+	callCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	resp, err := s.client.CreateImage(callCtx, req)
+	if err != nil {
+		return nil, fmt.Errorf("error creating image: %w", err)
+	}
+
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("error creating image: empty response")
+	}
+
+	image, err := base64.StdEncoding.DecodeString(resp.Data[0].B64JSON)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %w", err)
+	}
+
+	now := chat.Now().In(s.loc)
+	s.cache.Statistics.AddCost(now, s.params.ImageModel, cost)
+	chat.MeterCost(ctx, cost)
+	s.cache.Statistics.AddTokens(now, chat.Tokens{})
+
+	// The cost is saved even if the request is canceled meanwhile.
+	if err := s.storage.SaveStatistics(context.WithoutCancel(ctx), s.cache.Statistics); err != nil {
+		return nil, fmt.Errorf("error saving statistics to storage: %w", err)
+	}
+
+	return image, nil
+}
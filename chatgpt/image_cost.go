@@ -0,0 +1,86 @@
+package chatgpt
+
+import (
+	"fmt"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ImageTier identifies a price tier of an image generation model. Image models are
+// billed per generated image, and the price depends on the image size and quality.
+type ImageTier struct {
+	Size    string // Size is the size of the image (e.g., "1024x1024").
+	Quality string // Quality is the quality of the image (e.g., "hd"), empty if the model has a single quality.
+}
+
+// ImageCost provides a mapping from image model identifiers to the cost of a single
+// image for each supported price tier.
+var ImageCost = map[string]map[ImageTier]chat.Cost{
+	openai.CreateImageModelDallE2: {
+		{Size: openai.CreateImageSize256x256}:   0.016,
+		{Size: openai.CreateImageSize512x512}:   0.018,
+		{Size: openai.CreateImageSize1024x1024}: 0.02,
+	},
+	openai.CreateImageModelDallE3: {
+		{Size: openai.CreateImageSize1024x1024, Quality: openai.CreateImageQualityStandard}: 0.04,
+		{Size: openai.CreateImageSize1024x1792, Quality: openai.CreateImageQualityStandard}: 0.08,
+		{Size: openai.CreateImageSize1792x1024, Quality: openai.CreateImageQualityStandard}: 0.08,
+		{Size: openai.CreateImageSize1024x1024, Quality: openai.CreateImageQualityHD}:       0.08,
+		{Size: openai.CreateImageSize1024x1792, Quality: openai.CreateImageQualityHD}:       0.12,
+		{Size: openai.CreateImageSize1792x1024, Quality: openai.CreateImageQualityHD}:       0.12,
+	},
+	openai.CreateImageModelGptImage1: {
+		{Size: openai.CreateImageSize1024x1024, Quality: openai.CreateImageQualityLow}:    0.011,
+		{Size: openai.CreateImageSize1024x1536, Quality: openai.CreateImageQualityLow}:    0.016,
+		{Size: openai.CreateImageSize1536x1024, Quality: openai.CreateImageQualityLow}:    0.016,
+		{Size: openai.CreateImageSize1024x1024, Quality: openai.CreateImageQualityMedium}: 0.042,
+		{Size: openai.CreateImageSize1024x1536, Quality: openai.CreateImageQualityMedium}: 0.063,
+		{Size: openai.CreateImageSize1536x1024, Quality: openai.CreateImageQualityMedium}: 0.063,
+		{Size: openai.CreateImageSize1024x1024, Quality: openai.CreateImageQualityHigh}:   0.167,
+		{Size: openai.CreateImageSize1024x1536, Quality: openai.CreateImageQualityHigh}:   0.25,
+		{Size: openai.CreateImageSize1536x1024, Quality: openai.CreateImageQualityHigh}:   0.25,
+	},
+}
+
+// imageQualities holds the quality of the images of each model if none is configured:
+// the cheapest one which still draws well, since the tiers of the models differ.
+var imageQualities = map[string]string{
+	openai.CreateImageModelDallE2:    "",
+	openai.CreateImageModelDallE3:    openai.CreateImageQualityStandard,
+	openai.CreateImageModelGptImage1: openai.CreateImageQualityMedium,
+}
+
+// DefaultImageQuality returns the quality of the images of the model if none is
+// configured, empty for the models with a single quality or unknown.
+//
+// model: The identifier of the image model.
+func DefaultImageQuality(model string) string {
+	return imageQualities[model]
+}
+
+// CalculateImageCostByModel calculates the cost of the images generated by a single
+// request based on the model name and the price tier.
+//
+// Parameters:
+// - modelName: The identifier of the image model.
+// - tier: The size and quality of the generated images.
+// - n: The number of generated images.
+//
+// Returns:
+// - The total cost of the generated images.
+// - An error if the model or the tier does not exist in the ImageCost map.
+func CalculateImageCostByModel(modelName string, tier ImageTier, n int) (chat.Cost, error) {
+	tiers, ok := ImageCost[modelName]
+	if !ok {
+		return 0, fmt.Errorf("model name '%s' not found in the image cost map", modelName)
+	}
+
+	cost, ok := tiers[tier]
+	if !ok {
+		return 0, fmt.Errorf("size '%s' and quality '%s' not found in the image cost map of model '%s'",
+			tier.Size, tier.Quality, modelName)
+	}
+
+	return cost * chat.Cost(n), nil
+}
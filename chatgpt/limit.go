@@ -0,0 +1,85 @@
+package chatgpt
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the limited client implements the Client interface
+var _ Client = (*LimitedClient)(nil)
+
+// LimitedClient is a Client which limits the number of simultaneous requests to the API
+// across all sessions, to stay within the rate limits of the organization during bursts.
+// The excess requests wait for a free slot in the order they arrived, until their
+// context is done.
+type LimitedClient struct {
+	next  Client
+	slots chan struct{}
+}
+
+// NewLimitedClient creates a client allowing at most limit simultaneous requests.
+//
+// next: The client calling the API.
+// limit: The maximum number of simultaneous requests, at least one.
+func NewLimitedClient(next Client, limit int) *LimitedClient {
+	return &LimitedClient{next: next, slots: make(chan struct{}, limit)}
+}
+
+// CreateChatCompletion sends the request once a slot is free.
+func (l *LimitedClient) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	return limit(ctx, l, func() (openai.ChatCompletionResponse, error) {
+		return l.next.CreateChatCompletion(ctx, request)
+	})
+}
+
+// CreateTranscription sends the request once a slot is free.
+func (l *LimitedClient) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	return limit(ctx, l, func() (openai.AudioResponse, error) {
+		return l.next.CreateTranscription(ctx, request)
+	})
+}
+
+// CreateSpeech sends the request once a slot is free. The slot is released when the
+// response starts, not when the speech is read.
+func (l *LimitedClient) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	return limit(ctx, l, func() (openai.RawResponse, error) {
+		return l.next.CreateSpeech(ctx, request)
+	})
+}
+
+// CreateImage sends the request once a slot is free.
+func (l *LimitedClient) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	return limit(ctx, l, func() (openai.ImageResponse, error) {
+		return l.next.CreateImage(ctx, request)
+	})
+}
+
+// limit waits for a free slot and calls the API.
+//
+// ctx: The context of the request; waiting stops when it is done.
+// l: The limited client.
+// call: The request to the API.
+func limit[T any](ctx context.Context, l *LimitedClient, call func() (T, error)) (T, error) {
+	select {
+	case l.slots <- struct{}{}:
+		defer func() { <-l.slots }()
+		return call()
+
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
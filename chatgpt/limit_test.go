@@ -0,0 +1,135 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// blockingClient is a Client whose chat completions wait until release is closed. It
+// counts the requests in progress.
+type blockingClient struct {
+	Client // Client is nil: the tests call only CreateChatCompletion.
+
+	release chan struct{}
+
+	mu      sync.Mutex
+	running int
+	most    int // most is the largest number of the requests in progress at once.
+	calls   int
+}
+
+func (c *blockingClient) CreateChatCompletion(
+	context.Context,
+	openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.running++
+	c.most = max(c.most, c.running)
+	c.mu.Unlock()
+
+	<-c.release
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+
+	return openai.ChatCompletionResponse{}, nil
+}
+
+// waitRunning waits until n requests are in progress.
+func (c *blockingClient) waitRunning(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		running := c.running
+		c.mu.Unlock()
+
+		if running == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests are in progress, want %d", running, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimitedClientCapsConcurrency(t *testing.T) {
+	// Setup.
+	client := &blockingClient{release: make(chan struct{})}
+	limited := NewLimitedClient(client, 2)
+
+	// Execute: five requests are sent at once.
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := limited.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+			errs <- err
+		}()
+	}
+
+	// Assert: only two of them reach the API until the slots are free.
+	client.waitRunning(t, 2)
+	time.Sleep(10 * time.Millisecond)
+	close(client.release)
+
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("CreateChatCompletion failed: %s", err)
+		}
+	}
+	if client.most != 2 || client.calls != 5 {
+		t.Errorf("%d requests were sent, at most %d at once, want 5 and 2", client.calls, client.most)
+	}
+}
+
+func TestLimitedClientCanceledWait(t *testing.T) {
+	// Setup: the only slot is busy.
+	client := &blockingClient{release: make(chan struct{})}
+	limited := NewLimitedClient(client, 1)
+
+	busy := make(chan error, 1)
+	go func() {
+		_, err := limited.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+		busy <- err
+	}()
+	client.waitRunning(t, 1)
+
+	// Execute: the waiting request is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := limited.CreateChatCompletion(ctx, openai.ChatCompletionRequest{})
+		waiting <- err
+	}()
+	cancel()
+
+	// Assert: the waiter gives up without reaching the API and without taking the slot.
+	select {
+	case err := <-waiting:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CreateChatCompletion returned %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The canceled request is still waiting")
+	}
+
+	close(client.release)
+	if err := <-busy; err != nil {
+		t.Fatalf("CreateChatCompletion failed: %s", err)
+	}
+	if _, err := limited.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{}); err != nil {
+		t.Errorf("CreateChatCompletion failed after the cancellation: %s", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("%d requests reached the API, want 2", client.calls)
+	}
+}
@@ -0,0 +1,50 @@
+package chatgpt
+
+import (
+	"context"
+	"strings"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// complexMarkers are the words which suggest that a question needs the reasoning of the
+// flagship model even if it is short.
+var complexMarkers = []string{
+	"why", "explain", "analyze", "analyse", "compare", "prove", "step by step", "code", "debug", "calculate",
+	"почему", "объясни", "проанализируй", "сравни", "докажи", "пошагово", "код", "посчитай", "вычисли",
+}
+
+// routeModel picks the model answering the message: the cheap model for a short simple
+// question without images if the user may use it, or the session's model otherwise.
+//
+// ctx: The context of the request, restricting the permitted models.
+// message: The message of the user.
+// images: The number of the attached images.
+func (s *Session) routeModel(ctx context.Context, message string, images int) string {
+	if s.params.CheapModel == "" || images > 0 || !chat.ModelPermitted(ctx, s.params.CheapModel) ||
+		!isSimple(message, s.params.CheapMaxTokens) {
+		return s.ID.Model
+	}
+
+	return s.params.CheapModel
+}
+
+// isSimple reports whether the message looks like a simple question: it is short, has
+// no code and no words suggesting reasoning.
+//
+// message: The message of the user.
+// maxTokens: The maximum estimated number of tokens of a simple question.
+func isSimple(message string, maxTokens int) bool {
+	if chat.EstimateTokens(message) > maxTokens || strings.Contains(message, "```") {
+		return false
+	}
+
+	lower := strings.ToLower(message)
+	for _, marker := range complexMarkers {
+		if strings.Contains(lower, marker) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,5 +1,11 @@
 package chatgpt
 
+import (
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
 // RequestParams defines the set of parameters used to customize
 // an OpenAI request. These parameters allow for tuning the
 // behavior of the model during the conversation.
@@ -9,6 +15,19 @@ type RequestParams struct {
 	TopP             float32 // TopP is the sampling value to influence token choice; lower values make output more deterministic.
 	PresencePenalty  float32 // PresencePenalty adjusts the model to prefer tokens from the input.
 	FrequencyPenalty float32 // FrequencyPenalty adjusts the model to avoid tokens from the input.
+	ImageModel       string  // ImageModel is the model used to generate images (see ImageCost).
+	ImageSize        string  // ImageSize is the size of the generated images.
+	ImageQuality     string  // ImageQuality is the quality of the generated images, empty if the model has a single quality.
+
+	TranscriptionModel string // TranscriptionModel is the model used to transcribe voice messages (see TranscriptionCost).
+	SpeechModel        string // SpeechModel is the model used to synthesize voice replies (see SpeechCost).
+	SpeechVoice        string // SpeechVoice is the voice of the synthesized replies.
+
+	CheapModel     string // CheapModel answers the short simple questions instead of the session's model; empty disables the routing.
+	CheapMaxTokens int    // CheapMaxTokens is the maximum estimated number of tokens of a question answered by CheapModel.
+	FallbackModel  string // FallbackModel answers instead of a model which is rate limited or out of quota; empty disables the fallback.
+
+	Timeout time.Duration // Timeout limits the duration of a single request to the API; zero means no limit.
 }
 
 // DefaultRequestParams is a predefined set of parameters representing default
@@ -20,4 +39,13 @@ var DefaultRequestParams = RequestParams{
 	TopP:             0.0,
 	PresencePenalty:  0.0,
 	FrequencyPenalty: 0.0,
+	ImageModel:       openai.CreateImageModelDallE3,
+	ImageSize:        openai.CreateImageSize1024x1024,
+	ImageQuality:     openai.CreateImageQualityStandard,
+
+	TranscriptionModel: openai.Whisper1,
+	SpeechModel:        string(openai.TTSModel1),
+	SpeechVoice:        string(openai.VoiceAlloy),
+
+	CheapMaxTokens: 50,
 }
@@ -0,0 +1,167 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the rotating client implements the Client interface
+var _ Client = (*RotatingClient)(nil)
+
+// Keys which hit the rate limit are skipped for a minute, keys which were rejected or
+// ran out of quota are skipped for an hour.
+const (
+	rateLimitCooldown = time.Minute
+	keyCooldown       = time.Hour
+)
+
+// RotatingClient is a Client which spreads the requests over the clients of several API
+// keys in turn. A key rejected with 401 Unauthorized or 429 Too Many Requests is skipped
+// for a while and the request is retried with the next key.
+type RotatingClient struct {
+	clients []Client
+
+	mu       sync.Mutex
+	next     int         // next is the index of the client of the next request.
+	disabled []time.Time // disabled holds the time until which each client is skipped.
+}
+
+// NewRotatingClient creates a client rotating over the clients of the API keys.
+//
+// clients: The clients of the API keys, at least one.
+func NewRotatingClient(clients ...Client) *RotatingClient {
+	return &RotatingClient{
+		clients:  clients,
+		disabled: make([]time.Time, len(clients)),
+	}
+}
+
+// CreateChatCompletion sends the request with the next available key.
+func (r *RotatingClient) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	return rotate(r, func(c Client) (openai.ChatCompletionResponse, error) {
+		return c.CreateChatCompletion(ctx, request)
+	})
+}
+
+// CreateTranscription sends the request with the next available key. The audio is read
+// by the first attempt, so the request is not retried with another key.
+func (r *RotatingClient) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	index := r.pick()
+	resp, err := r.clients[index].CreateTranscription(ctx, request)
+	r.check(index, err)
+
+	return resp, err
+}
+
+// CreateSpeech sends the request with the next available key.
+func (r *RotatingClient) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	return rotate(r, func(c Client) (openai.RawResponse, error) {
+		return c.CreateSpeech(ctx, request)
+	})
+}
+
+// CreateImage sends the request with the next available key.
+func (r *RotatingClient) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	return rotate(r, func(c Client) (openai.ImageResponse, error) {
+		return c.CreateImage(ctx, request)
+	})
+}
+
+// rotate calls the clients in turn until one of them accepts the key, trying every
+// client at most once.
+//
+// r: The rotating client.
+// call: The request to send with a client.
+func rotate[T any](r *RotatingClient, call func(Client) (T, error)) (T, error) {
+	var (
+		resp T
+		err  error
+	)
+
+	for range r.clients {
+		index := r.pick()
+		resp, err = call(r.clients[index])
+		if !r.check(index, err) {
+			return resp, err
+		}
+	}
+
+	return resp, err
+}
+
+// pick returns the index of the next client, skipping the disabled ones unless all
+// of them are disabled.
+func (r *RotatingClient) pick() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for range r.clients {
+		index := r.next
+		r.next = (r.next + 1) % len(r.clients)
+
+		if now.After(r.disabled[index]) {
+			return index
+		}
+	}
+
+	index := r.next
+	r.next = (r.next + 1) % len(r.clients)
+	return index
+}
+
+// check disables the client if the error shows that its key cannot be used for now.
+//
+// index: The index of the client.
+// err: The error of the request.
+//
+// Returns true if the request should be retried with another key.
+func (r *RotatingClient) check(index int, err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	var cooldown time.Duration
+	switch {
+	case apiErr.HTTPStatusCode == http.StatusUnauthorized:
+		cooldown = keyCooldown
+	case apiErr.HTTPStatusCode == http.StatusTooManyRequests && apiErr.Type == "insufficient_quota":
+		cooldown = keyCooldown
+	case apiErr.HTTPStatusCode == http.StatusTooManyRequests:
+		cooldown = rateLimitCooldown
+	default:
+		return false
+	}
+
+	slog.Warn(
+		"openai key disabled",
+		slog.Int("key", index),
+		slog.Duration("cooldown", cooldown),
+		slog.String("error", err.Error()),
+	)
+
+	r.mu.Lock()
+	r.disabled[index] = time.Now().Add(cooldown)
+	r.mu.Unlock()
+
+	return len(r.clients) > 1
+}
@@ -0,0 +1,142 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// keyClient is a Client of an API key which fails every chat completion with err and
+// counts the requests.
+type keyClient struct {
+	Client // Client is nil: the tests call only CreateChatCompletion.
+
+	err   error
+	calls int
+}
+
+func (c *keyClient) CreateChatCompletion(
+	context.Context,
+	openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	c.calls++
+	return openai.ChatCompletionResponse{}, c.err
+}
+
+func TestRotatingClientCooldown(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		cooldown time.Duration // cooldown is how long the first key is skipped; 0 if it is not.
+		retried  bool          // retried is whether the request is sent again with the second key.
+	}{
+		{
+			name:     "unauthorized",
+			err:      &openai.APIError{HTTPStatusCode: http.StatusUnauthorized},
+			cooldown: keyCooldown,
+			retried:  true,
+		},
+		{
+			name:     "insufficient quota",
+			err:      &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Type: "insufficient_quota"},
+			cooldown: keyCooldown,
+			retried:  true,
+		},
+		{
+			name:     "rate limit",
+			err:      &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests},
+			cooldown: rateLimitCooldown,
+			retried:  true,
+		},
+		{
+			name: "server error",
+			err:  &openai.APIError{HTTPStatusCode: http.StatusInternalServerError},
+		},
+		{
+			name: "network error",
+			err:  errors.New("connection reset"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := &keyClient{err: tt.err}, &keyClient{}
+			rotating := NewRotatingClient(first, second)
+
+			start := time.Now()
+			_, err := rotating.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+
+			if tt.retried {
+				if err != nil || second.calls != 1 {
+					t.Errorf("The second key got %d requests with the error %v, want the retry", second.calls, err)
+				}
+			} else if !errors.Is(err, tt.err) || second.calls != 0 {
+				t.Errorf("The second key got %d requests with the error %v, want %v without a retry",
+					second.calls, err, tt.err)
+			}
+
+			disabled := rotating.disabled[0]
+			if tt.cooldown == 0 {
+				if !disabled.IsZero() {
+					t.Errorf("The first key is skipped until %v, want it kept", disabled)
+				}
+				return
+			}
+			if disabled.Before(start.Add(tt.cooldown)) || disabled.After(time.Now().Add(tt.cooldown)) {
+				t.Errorf("The first key is skipped for %v, want %v", disabled.Sub(start), tt.cooldown)
+			}
+		})
+	}
+}
+
+func TestRotatingClientSkipsDisabledKeys(t *testing.T) {
+	// Setup: the first key ran out of quota.
+	quota := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Type: "insufficient_quota"}
+	first, second := &keyClient{err: quota}, &keyClient{}
+	rotating := NewRotatingClient(first, second)
+	ctx := context.Background()
+
+	// Execute.
+	for i := 0; i < 3; i++ {
+		if _, err := rotating.CreateChatCompletion(ctx, openai.ChatCompletionRequest{}); err != nil {
+			t.Fatalf("CreateChatCompletion failed: %s", err)
+		}
+	}
+
+	// Assert: the key is not tried again during its cooldown.
+	if first.calls != 1 || second.calls != 3 {
+		t.Errorf("The keys got %d and %d requests, want 1 and 3", first.calls, second.calls)
+	}
+}
+
+func TestRotatingClientAllKeysCoolingDown(t *testing.T) {
+	// Setup: every key hits the rate limit.
+	limited := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}
+	keys := []*keyClient{{err: limited}, {err: limited}, {err: limited}}
+	rotating := NewRotatingClient(keys[0], keys[1], keys[2])
+	ctx := context.Background()
+
+	// Execute: each key is tried once, and the error of the last one is returned.
+	_, err := rotating.CreateChatCompletion(ctx, openai.ChatCompletionRequest{})
+	if !errors.Is(err, limited) {
+		t.Errorf("CreateChatCompletion returned %v, want the rate limit error", err)
+	}
+	for i, key := range keys {
+		if key.calls != 1 {
+			t.Errorf("The key %d got %d requests, want 1", i, key.calls)
+		}
+	}
+
+	// Assert: while all the keys cool down, the requests still go out with one key.
+	keys[1].err = nil
+	if _, err := rotating.CreateChatCompletion(ctx, openai.ChatCompletionRequest{}); err != nil {
+		t.Errorf("CreateChatCompletion failed while all the keys cool down: %s", err)
+	}
+	if calls := keys[0].calls + keys[1].calls + keys[2].calls; calls < 4 {
+		t.Errorf("The keys got %d requests, want the request sent while they cool down", calls)
+	}
+}
@@ -0,0 +1,67 @@
+package chatgpt
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ensure that the routing client implements the Client interface
+var _ Client = (*RoutingClient)(nil)
+
+// RoutingClient is a Client which sends the requests for some models to another API,
+// e.g. the requests for the Mistral models to the Mistral API, so the chats may use
+// the models of several providers.
+type RoutingClient struct {
+	fallback Client
+	route    func(model string) Client
+}
+
+// NewRoutingClient creates a client routing the requests by their model.
+//
+// fallback: The client of the requests for the models without a route.
+// route: Returns the client of the requests for the model, or nil for the fallback.
+func NewRoutingClient(fallback Client, route func(model string) Client) *RoutingClient {
+	return &RoutingClient{fallback: fallback, route: route}
+}
+
+// client returns the client of the requests for the model.
+func (r *RoutingClient) client(model string) Client {
+	if client := r.route(model); client != nil {
+		return client
+	}
+
+	return r.fallback
+}
+
+// CreateChatCompletion sends the request to the client of its model.
+func (r *RoutingClient) CreateChatCompletion(
+	ctx context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	return r.client(request.Model).CreateChatCompletion(ctx, request)
+}
+
+// CreateTranscription sends the request to the client of its model.
+func (r *RoutingClient) CreateTranscription(
+	ctx context.Context,
+	request openai.AudioRequest,
+) (openai.AudioResponse, error) {
+	return r.client(request.Model).CreateTranscription(ctx, request)
+}
+
+// CreateSpeech sends the request to the client of its model.
+func (r *RoutingClient) CreateSpeech(
+	ctx context.Context,
+	request openai.CreateSpeechRequest,
+) (openai.RawResponse, error) {
+	return r.client(string(request.Model)).CreateSpeech(ctx, request)
+}
+
+// CreateImage sends the request to the client of its model.
+func (r *RoutingClient) CreateImage(
+	ctx context.Context,
+	request openai.ImageRequest,
+) (openai.ImageResponse, error) {
+	return r.client(request.Model).CreateImage(ctx, request)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/muzykantov/tgpt/chat"
 	"github.com/sashabaranov/go-openai"
@@ -18,28 +20,32 @@ var _ chat.Session = (*Session)(nil)
 type Session struct {
 	chat.ID // Embedding chat.ID provides the unique identifiers for the user and the session.
 
-	client  *openai.Client // client is the OpenAI client used to interface with the GPT API.
+	client  Client         // client is the OpenAI client used to interface with the GPT API.
 	storage chat.Storage   // storage is the abstract storage layer for saving and loading history and statistics.
 	params  RequestParams  // params holds the parameters used to customize the OpenAI request.
+	loc     *time.Location // loc is the time zone of the user, used for the statistics day boundaries.
+	locker  chat.Locker    // locker locks the session across the instances sharing the storage; nil if not shared.
 
-	cache *sessionCache // cache holds the session's history and statistics to minimize storage access.
-	mu    *sync.RWMutex // cacheMu is a read/write mutex for thread-safe access to the fields.
+	cache  *sessionCache // cache holds the session's history and statistics to minimize storage access.
+	mu     *sync.RWMutex // cacheMu is a read/write mutex for thread-safe access to the fields.
+	closed atomic.Bool   // closed is set under mu once the session is deleted or evicted; see close.
 }
 
 // NewSession creates a new chat Session with default request parameters.
 //
 // id: A composite identifier that includes the User, Chat, and Model information.
-// client: An instance of the OpenAI Client.
+// client: The OpenAI API client, usually an instance of *openai.Client.
 // storage: An abstraction for the storage backend where session data is saved.
 //
 // Returns:
 // A pointer to a new Session instance.
-func NewSession(id chat.ID, client *openai.Client, storage chat.Storage) *Session {
+func NewSession(id chat.ID, client Client, storage chat.Storage) *Session {
 	return &Session{
 		ID:      id,
 		client:  client,
 		storage: storage,
 		params:  DefaultRequestParams,
+		loc:     chat.DefaultLocation,
 		cache:   &sessionCache{},
 		mu:      &sync.RWMutex{},
 	}
@@ -54,6 +60,16 @@ func (s *Session) SetRequestParams(params RequestParams) {
 	s.params = params
 }
 
+// SetLocation updates the time zone used for the day and month boundaries of the
+// session's statistics.
+//
+// loc: The time zone of the user.
+func (s *Session) SetLocation(loc *time.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loc = loc
+}
+
 // SetPrompt updates the session's prompt with the provided string and persists the updated history.
 // It locks the session for exclusive write access to prevent concurrent read/write issues.
 // The method first ensures that the session's cache is loaded and then proceeds to update
@@ -70,6 +86,12 @@ func (s *Session) SetPrompt(ctx context.Context, prompt string) error {
 	s.mu.Lock()         // Lock the session for exclusive access.
 	defer s.mu.Unlock() // Ensure the session is unlocked after this function returns.
 
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Load the session cache if it's not already loaded.
 	if err := s.loadCacheIfNeeded(ctx); err != nil {
 		return err
@@ -81,6 +103,7 @@ func (s *Session) SetPrompt(ctx context.Context, prompt string) error {
 	}
 
 	s.cache.History.Prompt = prompt // Update the prompt in the history cache.
+	s.cache.History.Updated = chat.Now()
 
 	// Persist the updated history to the storage.
 	if err := s.storage.SaveHistory(ctx, s.cache.History); err != nil {
@@ -103,15 +126,29 @@ func (s *Session) SetPrompt(ctx context.Context, prompt string) error {
 //
 // Returns:
 // reply: The AI-generated response to the message.
+// fallback: The model which answered instead of the model of the session because it was rate
+// limited or out of quota, empty if none.
 // err: Any error encountered during the process. Errors may arise from loading cache, communicating
 // with the OpenAI API, calculating costs, or persisting data to storage.
-func (s *Session) Ask(ctx context.Context, message string, reset bool) (reply string, err error) {
+func (s *Session) Ask(ctx context.Context, message string, reset bool) (reply, fallback string, err error) {
+	return s.ask(ctx, message, nil, reset)
+}
+
+// ask implements Ask and See. The images are attached to the user message; the history
+// records only their number, since the conversation is resent with every request.
+func (s *Session) ask(ctx context.Context, message string, images [][]byte, reset bool) (reply, fallback string, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
 	// Load session cache if necessary.
 	if err := s.loadCacheIfNeeded(ctx); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// Prepare the message history for the API request.
@@ -146,14 +183,23 @@ func (s *Session) Ask(ctx context.Context, message string, reset bool) (reply st
 	}
 
 	// Add the new user message to the history.
-	msgs = append(msgs, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: message,
-	})
+	if len(images) == 0 {
+		msgs = append(msgs, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: message,
+		})
+	} else {
+		msgs = append(msgs, openai.ChatCompletionMessage{
+			Role:         openai.ChatMessageRoleUser,
+			MultiContent: imageParts(message, images),
+		})
+	}
 
-	// Send the message to the OpenAI API.
-	resp, err := s.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:            s.ID.Model,
+	// Short simple questions may be answered by a cheaper model; the history is shared.
+	model := s.routeModel(ctx, message, len(images))
+
+	request := openai.ChatCompletionRequest{
+		Model:            model,
 		Messages:         msgs,
 		MaxTokens:        s.params.MaxTokens,
 		Temperature:      s.params.Temperature,
@@ -162,9 +208,32 @@ func (s *Session) Ask(ctx context.Context, message string, reset bool) (reply st
 		Stream:           false,
 		PresencePenalty:  s.params.PresencePenalty,
 		FrequencyPenalty: s.params.FrequencyPenalty,
-	})
+	}
+
+	complete := func() (openai.ChatCompletionResponse, error) {
+		callCtx, cancel := s.requestContext(ctx)
+		defer cancel()
+
+		return s.client.CreateChatCompletion(callCtx, request)
+	}
+
+	// Send the message to the OpenAI API.
+	resp, err := complete()
+
+	// Retry on the fallback model if the model is rate limited or out of quota, unless
+	// the user may not use the fallback model.
+	if err != nil && isRateLimited(err) && s.params.FallbackModel != "" && model != s.params.FallbackModel &&
+		chat.ModelPermitted(ctx, s.params.FallbackModel) {
+		model, fallback = s.params.FallbackModel, s.params.FallbackModel
+		request.Model = model
+		resp, err = complete()
+	}
 	if err != nil {
-		return "", fmt.Errorf("error creating chat completion: %w", err)
+		return "", "", fmt.Errorf("error creating chat completion: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", "", fmt.Errorf("error creating chat completion: no choices in the response")
 	}
 
 	// Extract the AI's reply from the response.
@@ -175,34 +244,46 @@ func (s *Session) Ask(ctx context.Context, message string, reset bool) (reply st
 		Input:  resp.Usage.PromptTokens,
 		Output: resp.Usage.CompletionTokens,
 	}
+	if details := resp.Usage.PromptTokensDetails; details != nil {
+		usage.CachedInput = details.CachedTokens
+	}
 
-	cost, err := usage.CalculateCostByModel(s.ID.Model)
+	cost, err := usage.CalculateCostByModel(model)
 	if err != nil {
-		return "", fmt.Errorf("error calculating the cost: %w", err)
+		return "", "", fmt.Errorf("error calculating the cost: %w", err)
 	}
 
 	// Update the history and statistics unless we're resetting the history.
 	if !reset {
 		s.cache.History.Add(chat.Message{
-			User:      message,
+			User:      withImageNote(message, len(images)),
 			Assistant: reply,
 		})
 	} else {
 		s.cache.History.Clear()
 	}
 
-	s.cache.Statistics.AddCost(cost)
+	now := chat.Now().In(s.loc)
+	s.cache.History.Updated = now
+	s.cache.Statistics.AddCost(now, model, cost)
+	chat.MeterCost(ctx, cost)
+	s.cache.Statistics.AddTokens(now, chat.Tokens{
+		Input:  usage.Input,
+		Output: usage.Output,
+	})
 
-	// Persist the updated history and statistics.
-	if err := s.storage.SaveHistory(ctx, s.cache.History); err != nil {
-		return "", fmt.Errorf("error saving history to storage: %w", err)
+	// Persist the updated history and statistics. The answer is paid for, so it is
+	// saved even if the request is canceled meanwhile, e.g. on shutdown.
+	saveCtx := context.WithoutCancel(ctx)
+	if err := s.storage.SaveHistory(saveCtx, s.cache.History); err != nil {
+		return "", "", fmt.Errorf("error saving history to storage: %w", err)
 	}
 
-	if err := s.storage.SaveStatistics(ctx, s.cache.Statistics); err != nil {
-		return "", fmt.Errorf("error saving statistics to storage: %w", err)
+	if err := s.storage.SaveStatistics(saveCtx, s.cache.Statistics); err != nil {
+		return "", "", fmt.Errorf("error saving statistics to storage: %w", err)
 	}
 
-	return reply, nil
+	return reply, fallback, nil
 }
 
 // Reset clears the current session's chat history and updates the storage to reflect these changes.
@@ -219,12 +300,19 @@ func (s *Session) Reset(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Load session cache if necessary.
 	if err := s.loadCacheIfNeeded(ctx); err != nil {
 		return err
 	}
 
 	s.cache.History.Clear()
+	s.cache.History.Updated = chat.Now()
 
 	// Persist the updated history and statistics.
 	if err := s.storage.SaveHistory(ctx, s.cache.History); err != nil {
@@ -234,6 +322,118 @@ func (s *Session) Reset(ctx context.Context) error {
 	return nil
 }
 
+// Import replaces the session's conversation history with the given log and persists it.
+// The prompt and the statistics are not affected.
+//
+// ctx: The context for the operation, which allows for deadline control and cancelation.
+// log: The conversation to load.
+//
+// Returns an error if the session cache could not be loaded or the history could not be saved.
+func (s *Session) Import(ctx context.Context, log []chat.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Load session cache if necessary.
+	if err := s.loadCacheIfNeeded(ctx); err != nil {
+		return err
+	}
+
+	s.cache.History.Log = make([]chat.Message, len(log))
+	copy(s.cache.History.Log, log)
+	s.cache.History.Updated = chat.Now()
+
+	if err := s.storage.SaveHistory(ctx, s.cache.History); err != nil {
+		return fmt.Errorf("error saving history to storage: %w", err)
+	}
+
+	return nil
+}
+
+// ResetStatistics replaces the session's statistics with empty ones and persists them.
+// The conversation history is not affected. The method is protected by the session
+// mutex, so the reset cannot be overwritten by a concurrent request.
+//
+// ctx: The context for the operation, which allows for deadline control and cancelation.
+//
+// Returns an error if the session cache could not be loaded or the statistics could not be saved.
+func (s *Session) ResetStatistics(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Load session cache if necessary.
+	if err := s.loadCacheIfNeeded(ctx); err != nil {
+		return err
+	}
+
+	s.cache.Statistics = &chat.Statistics{
+		ID:       s.ID,
+		Days:     map[string]chat.Cost{},
+		Months:   map[string]chat.Cost{},
+		PerModel: map[string]chat.Cost{},
+	}
+
+	if err := s.storage.SaveStatistics(ctx, s.cache.Statistics); err != nil {
+		return fmt.Errorf("error saving statistics to storage: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes the session's history and statistics from the storage and closes the
+// session. It waits for the running request of the session, and the requests made on the
+// session afterwards fail with chat.ErrSessionClosed, so the deleted data is not saved
+// again. The session provider replaces a closed session with a new one.
+//
+// ctx: The context for the operation, which allows for deadline control and cancelation.
+//
+// Returns an error if the history or the statistics could not be deleted; the session is
+// not closed then.
+func (s *Session) Delete(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockShared(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.storage.DeleteHistory(ctx, s.ID); err != nil {
+		return fmt.Errorf("error deleting history from storage: %w", err)
+	}
+
+	if err := s.storage.DeleteStatistics(ctx, s.ID); err != nil {
+		return fmt.Errorf("error deleting statistics from storage: %w", err)
+	}
+
+	s.cache.History, s.cache.Statistics = nil, nil
+	s.closed.Store(true)
+
+	return nil
+}
+
+// close closes the session once its running request is finished, so the requests made
+// on the session afterwards fail with chat.ErrSessionClosed instead of racing with the
+// session which replaces it.
+func (s *Session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed.Store(true)
+}
+
 // History returns a copy of the chat history from the session's cache.
 // If the cache is not loaded, it attempts to load it before returning the history.
 // This function ensures that any modifications to the returned History object
@@ -245,6 +445,11 @@ func (s *Session) Reset(ctx context.Context) error {
 // *chat.History: A copy of the chat history for the session.
 // error: An error if encountered during cache loading.
 func (s *Session) History(ctx context.Context) (*chat.History, error) {
+	// Another instance may have changed the shared storage since the cache was loaded.
+	if s.locker != nil {
+		return s.storage.LoadHistory(ctx, s.ID)
+	}
+
 	s.mu.RLock() // Use read lock to allow concurrent reads.
 	defer s.mu.RUnlock()
 
@@ -268,6 +473,11 @@ func (s *Session) History(ctx context.Context) (*chat.History, error) {
 // *chat.Statistics: A copy of the chat statistics for the session.
 // error: An error if encountered during cache loading.
 func (s *Session) Statistics(ctx context.Context) (*chat.Statistics, error) {
+	// Another instance may have changed the shared storage since the cache was loaded.
+	if s.locker != nil {
+		return s.storage.LoadStatistics(ctx, s.ID)
+	}
+
 	s.mu.RLock() // Use read lock to allow concurrent reads.
 	defer s.mu.RUnlock()
 
@@ -280,6 +490,63 @@ func (s *Session) Statistics(ctx context.Context) (*chat.Statistics, error) {
 	return s.cache.Statistics.Clone(), nil
 }
 
+// inspect returns the number of messages in the cached history and whether the
+// session is currently busy processing a request. It never blocks: if the session
+// is locked by a running operation, it is reported as busy with the history length
+// unknown (zero).
+func (s *Session) inspect() (historyLength int, busy bool) {
+	if !s.mu.TryRLock() {
+		return 0, true
+	}
+	defer s.mu.RUnlock()
+
+	if s.cache.History != nil {
+		historyLength = len(s.cache.History.Log)
+	}
+
+	return historyLength, false
+}
+
+// SetLocker shares the session with the other instances of the bot using the same
+// storage: every change of the session is made under the lock and starts from the
+// history and statistics reloaded from the storage.
+//
+// locker: The Locker of the shared storage; nil if the storage is not shared.
+func (s *Session) SetLocker(locker chat.Locker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locker = locker
+}
+
+// lockShared locks the session in the shared storage and drops the cache, so the next
+// loadCacheIfNeeded reloads the changes made by the other instances. It does nothing
+// if the storage is not shared. The caller must hold the session mutex. Every change of
+// the session starts with it, so it also refuses the changes of a closed session.
+//
+// ctx: The context to stop waiting for the lock.
+//
+// Returns the function releasing the lock, and an error if the session is closed or
+// could not be locked.
+func (s *Session) lockShared(ctx context.Context) (func(), error) {
+	if s.closed.Load() {
+		return nil, chat.ErrSessionClosed
+	}
+
+	if s.locker == nil {
+		return func() {}, nil
+	}
+
+	unlock, err := s.locker.Lock(ctx, s.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error locking the session: %w", err)
+	}
+
+	s.cache.History, s.cache.Statistics = nil, nil
+
+	return unlock, nil
+}
+
 // loadCacheIfNeeded checks if the session cache has been loaded and if not,
 // loads the history and statistics from the storage.
 //
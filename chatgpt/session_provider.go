@@ -2,11 +2,12 @@ package chatgpt
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/muzykantov/tgpt/chat"
-	"github.com/sashabaranov/go-openai"
 )
 
 // ensure that the concrete type Session implements the chat.Session interface
@@ -24,7 +25,7 @@ type SessionProvider struct {
 	ttl time.Duration
 
 	// client is the OpenAI client used to interface with the GPT API for chat interactions.
-	client *openai.Client
+	client Client
 
 	// storage is the abstract storage layer for session data persistence.
 	storage chat.Storage
@@ -32,6 +33,9 @@ type SessionProvider struct {
 	// params hold the parameters used to customize the OpenAI request.
 	params RequestParams
 
+	// locker locks the sessions in the storage shared with other instances; nil if not shared.
+	locker chat.Locker
+
 	// mu provides concurrency control for accessing the sessions map.
 	mu sync.RWMutex
 
@@ -43,7 +47,7 @@ type SessionProvider struct {
 // request parameters, TTL for sessions, and cleanup interval.
 // It starts a background goroutine to periodically clean up expired sessions.
 //
-// client: Instance of the OpenAI Client for API interactions.
+// client: The OpenAI API client, usually an instance of *openai.Client.
 // storage: Storage backend for session data persistence.
 // params: Default request parameters for GPT API interactions.
 // ttl: Time-to-live for sessions to determine their expiration.
@@ -51,7 +55,7 @@ type SessionProvider struct {
 //
 // Returns a pointer to a newly created Provider.
 func NewSessionProvider(
-	client *openai.Client,
+	client Client,
 	storage chat.Storage,
 	params RequestParams,
 	ttl, cleanupInterval time.Duration,
@@ -70,6 +74,18 @@ func NewSessionProvider(
 	return sm
 }
 
+// SetLocker shares the sessions with the other instances of the bot using the same
+// storage. It affects the sessions created afterwards, so it should be called before
+// the provider is used.
+//
+// locker: The Locker of the shared storage.
+func (m *SessionProvider) SetLocker(locker chat.Locker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.locker = locker
+}
+
 // GetOrCreateSession retrieves an existing session associated with the given ID from the session manager,
 // or creates a new one if it does not exist. It ensures that only one session is created or retrieved
 // at a time through mutual exclusion.
@@ -79,28 +95,40 @@ func NewSessionProvider(
 // Returns:
 // chat.Session: The imlementation of retrieved or newly created session.
 // error: An error if encountered during the session creation process.
-func (m *SessionProvider) ProvideSession(_ context.Context, id chat.ID) (chat.Session, error) {
-	return m.GetOrCreateSession(id)
+func (m *SessionProvider) ProvideSession(ctx context.Context, id chat.ID) (chat.Session, error) {
+	return m.GetOrCreateSession(ctx, id)
 }
 
 // GetOrCreateSession retrieves an existing session associated with the given ID from the session manager,
 // or creates a new one if it does not exist. It ensures that only one session is created or retrieved
-// at a time through mutual exclusion.
+// at a time through mutual exclusion. New sessions use the time zone from the user's profile.
+// A closed session, whose data has been deleted, is replaced with a new one.
 //
+// ctx: The context for loading the user's profile from the storage.
 // id: The unique identifier for the chat session.
 //
 // Returns:
 // *Session: A pointer to the retrieved or newly created session.
 // error: An error if encountered during the session creation process.
-func (m *SessionProvider) GetOrCreateSession(id chat.ID) (*Session, error) {
+func (m *SessionProvider) GetOrCreateSession(ctx context.Context, id chat.ID) (*Session, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	sInfo, exists := m.sessions[id] // Check if the session already exists.
-	if !exists {
+	if !exists || sInfo.session.closed.Load() {
 		// If the session does not exist, create a new session.
 		newSession := NewSession(id, m.client, m.storage)
 		newSession.SetRequestParams(m.params) // Set request parameters for the new session.
+		newSession.SetLocker(m.locker)        // Share the session with the other instances.
+
+		// Set the user's time zone for the new session, if the storage keeps the profiles.
+		if profiles, ok := m.storage.(chat.Profiles); ok {
+			profile, err := profiles.LoadProfile(ctx, id.User)
+			if err != nil {
+				return nil, fmt.Errorf("error loading the profile from the storage: %w", err)
+			}
+			newSession.SetLocation(profile.Location())
+		}
 		sInfo = &sessionInfo{
 			session:    newSession, // Assign the new session.
 			lastAccess: chat.Now(), // Set the current time as the last access time.
@@ -114,6 +142,69 @@ func (m *SessionProvider) GetOrCreateSession(id chat.ID) (*Session, error) {
 	return sInfo.session, nil // Return the session.
 }
 
+// Sessions returns information about all sessions currently held in the cache,
+// sorted by the last access time with the most recently used sessions first.
+// Inspecting a session never blocks, so sessions stuck in a request are reported as busy.
+//
+// Returns:
+// []chat.SessionInfo: The information about the cached sessions.
+// error: Always nil; present to satisfy the chat.SessionProvider interface.
+func (m *SessionProvider) Sessions(_ context.Context) ([]chat.SessionInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]chat.SessionInfo, 0, len(m.sessions))
+	for id, sInfo := range m.sessions {
+		historyLength, busy := sInfo.session.inspect()
+		infos = append(infos, chat.SessionInfo{
+			ID:            id,
+			LastAccess:    sInfo.lastAccess,
+			HistoryLength: historyLength,
+			Busy:          busy,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastAccess.After(infos[j].LastAccess)
+	})
+
+	return infos, nil
+}
+
+// Evict removes the session with the given ID from the cache and closes it, waiting
+// for its running request, so the requests still holding the evicted session fail with
+// chat.ErrSessionClosed instead of racing with its replacement. The next call to
+// ProvideSession for the same ID creates a new session which loads its state
+// from the storage.
+//
+// id: The unique identifier of the session to evict.
+//
+// Returns:
+// bool: True if the session was cached and has been evicted.
+// error: Always nil; present to satisfy the chat.SessionProvider interface.
+func (m *SessionProvider) Evict(_ context.Context, id chat.ID) (bool, error) {
+	m.mu.Lock()
+	sInfo, exists := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if !exists {
+		return false, nil
+	}
+
+	// The provider is not locked meanwhile, so the other sessions are served.
+	sInfo.session.close()
+	return true, nil
+}
+
+// Len returns the number of sessions currently held in the cache.
+func (m *SessionProvider) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.sessions)
+}
+
 // Clear terminates all managed sessions within the SessionManager. This is done by clearing
 // the session map which holds all active sessions.
 //
@@ -137,16 +228,23 @@ func (m *SessionProvider) cleanupScheduler() {
 }
 
 // cleanupExpiredSessions iterates through the sessions and deletes any that have not been accessed within the TTL.
+// The deleted sessions are closed like the evicted ones.
 func (m *SessionProvider) cleanupExpiredSessions() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	var expired []*Session
 
+	m.mu.Lock()
 	now := time.Now()
 	for id, sInfo := range m.sessions {
 		if now.Sub(sInfo.lastAccess) > m.ttl {
 			delete(m.sessions, id)
+			expired = append(expired, sInfo.session)
 		}
 	}
+	m.mu.Unlock()
+
+	for _, session := range expired {
+		session.close()
+	}
 }
 
 // sessionInfo holds the data for a session along with the last access timestamp.
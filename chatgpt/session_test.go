@@ -0,0 +1,294 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/storage"
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeClient is a Client which answers the chat completions with a fixed reply and
+// records the requests.
+type fakeClient struct {
+	Client // Client is nil: the tests call only CreateChatCompletion.
+
+	reply    string
+	err      error
+	limited  string // limited is the model rejected with 429 Too Many Requests.
+	requests []openai.ChatCompletionRequest
+}
+
+func (c *fakeClient) CreateChatCompletion(
+	_ context.Context,
+	request openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, request)
+	if c.err != nil {
+		return openai.ChatCompletionResponse{}, c.err
+	}
+	if request.Model == c.limited {
+		return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}
+	}
+
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: c.reply}},
+		},
+		Usage: openai.Usage{PromptTokens: 1000, CompletionTokens: 500},
+	}, nil
+}
+
+func TestSessionAsk(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Hello, User!"}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4oMini}
+	session := NewSession(id, client, &storage.FS{BaseDir: t.TempDir()})
+
+	if err := session.SetPrompt(ctx, "Be brief."); err != nil {
+		t.Fatalf("SetPrompt failed: %s", err)
+	}
+
+	for _, question := range []string{"Hello!", "How are you?"} {
+		reply, _, err := session.Ask(ctx, question, false)
+		if err != nil {
+			t.Fatalf("Ask failed: %s", err)
+		}
+		if reply != client.reply {
+			t.Errorf("Ask returned %q, want %q", reply, client.reply)
+		}
+	}
+
+	// The second request carries the prompt, the first exchange and the new question.
+	if got := len(client.requests[1].Messages); got != 4 {
+		t.Fatalf("The second request has %d messages, want 4", got)
+	}
+	if got := client.requests[1].Messages[0]; got.Role != openai.ChatMessageRoleSystem || got.Content != "Be brief." {
+		t.Errorf("The first message is %+v, want the prompt", got)
+	}
+
+	history, err := session.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %s", err)
+	}
+	if len(history.Log) != 2 {
+		t.Errorf("The history has %d messages, want 2", len(history.Log))
+	}
+
+	stats, err := session.Statistics(ctx)
+	if err != nil {
+		t.Fatalf("Statistics failed: %s", err)
+	}
+	if stats.Total <= 0 {
+		t.Errorf("The total cost is %v, want a positive cost", stats.Total)
+	}
+}
+
+func TestSessionAskError(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{err: errors.New("unavailable")}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4oMini}
+	session := NewSession(id, client, &storage.FS{BaseDir: t.TempDir()})
+
+	if _, _, err := session.Ask(ctx, "Hello!", false); !errors.Is(err, client.err) {
+		t.Fatalf("Ask returned %v, want %v", err, client.err)
+	}
+
+	history, err := session.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %s", err)
+	}
+	if len(history.Log) != 0 {
+		t.Errorf("The history has %d messages after the failed request, want 0", len(history.Log))
+	}
+}
+
+func TestSessionDelete(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Hello, User!"}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4oMini}
+	store := &storage.FS{BaseDir: t.TempDir()}
+	provider := NewSessionProvider(client, store, DefaultRequestParams, time.Hour, time.Hour)
+
+	session, err := provider.GetOrCreateSession(ctx, id)
+	if err != nil {
+		t.Fatalf("GetOrCreateSession failed: %s", err)
+	}
+	if _, _, err := session.Ask(ctx, "Hello!", false); err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+	if err := session.Delete(ctx); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+
+	// The deleted data is not saved again by a request holding the closed session.
+	if _, _, err := session.Ask(ctx, "Hello again!", false); !errors.Is(err, chat.ErrSessionClosed) {
+		t.Errorf("Ask returned %v after the deletion, want %v", err, chat.ErrSessionClosed)
+	}
+	if ids, err := store.List(ctx); err != nil || len(ids) != 0 {
+		t.Errorf("List() = %v, %v after the deletion, want no sessions", ids, err)
+	}
+
+	// The closed session is replaced with a new one.
+	renewed, err := provider.GetOrCreateSession(ctx, id)
+	if err != nil {
+		t.Fatalf("GetOrCreateSession failed: %s", err)
+	}
+	if renewed == session {
+		t.Fatal("GetOrCreateSession returned the closed session")
+	}
+	if _, _, err := renewed.Ask(ctx, "Hello again!", false); err != nil {
+		t.Errorf("Ask failed after the deletion: %s", err)
+	}
+}
+
+func TestSessionProviderEvict(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Hello, User!"}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4oMini}
+	provider := NewSessionProvider(client, &storage.FS{BaseDir: t.TempDir()}, DefaultRequestParams, time.Hour, time.Hour)
+
+	session, err := provider.GetOrCreateSession(ctx, id)
+	if err != nil {
+		t.Fatalf("GetOrCreateSession failed: %s", err)
+	}
+	if evicted, err := provider.Evict(ctx, id); err != nil || !evicted {
+		t.Fatalf("Evict() = %t, %v, want the session evicted", evicted, err)
+	}
+
+	// The request still holding the evicted session does not race with its replacement.
+	if _, _, err := session.Ask(ctx, "Hello!", false); !errors.Is(err, chat.ErrSessionClosed) {
+		t.Errorf("Ask returned %v on the evicted session, want %v", err, chat.ErrSessionClosed)
+	}
+
+	renewed, err := provider.GetOrCreateSession(ctx, id)
+	if err != nil {
+		t.Fatalf("GetOrCreateSession failed: %s", err)
+	}
+	if _, _, err := renewed.Ask(ctx, "Hello!", false); err != nil {
+		t.Errorf("Ask failed on the new session: %s", err)
+	}
+}
+
+func TestSessionAskRoutesSimpleQuestions(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Paris."}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4o}
+	session := NewSession(id, client, &storage.FS{BaseDir: t.TempDir()})
+
+	params := DefaultRequestParams
+	params.CheapModel = openai.GPT4oMini
+	session.SetRequestParams(params)
+
+	questions := map[string]string{
+		"What is the capital of France?":             openai.GPT4oMini,
+		"Explain why the sky is blue.":               openai.GPT4o,
+		strings.Repeat("A very long question. ", 20): openai.GPT4o,
+	}
+	for question, model := range questions {
+		if _, _, err := session.Ask(ctx, question, false); err != nil {
+			t.Fatalf("Ask failed: %s", err)
+		}
+
+		if got := client.requests[len(client.requests)-1].Model; got != model {
+			t.Errorf("%q was answered by %s, want %s", question, got, model)
+		}
+	}
+
+	stats, err := session.Statistics(ctx)
+	if err != nil {
+		t.Fatalf("Statistics failed: %s", err)
+	}
+	if stats.PerModel[openai.GPT4oMini] <= 0 || stats.PerModel[openai.GPT4o] <= 0 {
+		t.Errorf("The costs per model are %v, want the costs of both models", stats.PerModel)
+	}
+
+	// The user restricted to the model of the session is not routed to the cheap model.
+	restricted := chat.WithPermittedModels(ctx, []string{openai.GPT4o})
+	if _, _, err := session.Ask(restricted, "What is the capital of France?", false); err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+	if got := client.requests[len(client.requests)-1].Model; got != openai.GPT4o {
+		t.Errorf("The restricted user was answered by %s, want %s", got, openai.GPT4o)
+	}
+}
+
+func TestSessionAskFallsBack(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{reply: "Hello, User!", limited: openai.GPT4o}
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4o}
+	session := NewSession(id, client, &storage.FS{BaseDir: t.TempDir()})
+
+	params := DefaultRequestParams
+	params.FallbackModel = openai.GPT4oMini
+	session.SetRequestParams(params)
+
+	_, fallback, err := session.Ask(ctx, "Hello!", false)
+	if err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+
+	if len(client.requests) != 2 || client.requests[1].Model != openai.GPT4oMini {
+		t.Fatalf("Sent %d requests, want the retry on %s", len(client.requests), openai.GPT4oMini)
+	}
+	if fallback != openai.GPT4oMini {
+		t.Errorf("The fallback is %q, want %q", fallback, openai.GPT4oMini)
+	}
+
+	// The user restricted to the model of the session is not answered by the fallback.
+	restricted := chat.WithPermittedModels(ctx, []string{openai.GPT4o})
+	if _, _, err := session.Ask(restricted, "Hello!", false); !isRateLimited(err) {
+		t.Errorf("Ask returned %v, want the rate limit error", err)
+	}
+	if len(client.requests) != 3 {
+		t.Errorf("Sent %d requests, want no retry on the model not permitted", len(client.requests))
+	}
+}
+
+func TestSessionWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	fs := &storage.FS{BaseDir: t.TempDir()}
+	buffer := NewWriteBehind(fs, 1)
+	id := chat.ID{User: 123, Chat: 456, Model: openai.GPT4oMini}
+	session := NewSession(id, &fakeClient{reply: "Hello, User!"}, buffer)
+
+	if _, _, err := session.Ask(ctx, "Hello!", false); err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+
+	// The history is read back from the buffer before it is written.
+	if history, err := buffer.LoadHistory(ctx, id); err != nil || len(history.Log) != 1 {
+		t.Errorf("LoadHistory from the buffer returned %+v, %v, want 1 message", history, err)
+	}
+	if history, err := fs.LoadHistory(ctx, id); err != nil || len(history.Log) != 0 {
+		t.Errorf("LoadHistory from the storage returned %+v, %v, want no messages before the flush", history, err)
+	}
+
+	if failed := buffer.Flush(ctx); failed != 0 {
+		t.Fatalf("Flush failed to write %d objects", failed)
+	}
+
+	if history, err := fs.LoadHistory(ctx, id); err != nil || len(history.Log) != 1 {
+		t.Errorf("LoadHistory from the storage returned %+v, %v, want 1 message after the flush", history, err)
+	}
+	if stats, err := fs.LoadStatistics(ctx, id); err != nil || stats.Total <= 0 {
+		t.Errorf("LoadStatistics from the storage returned %+v, %v, want a positive cost", stats, err)
+	}
+
+	// Beyond the limit, the other sessions are written through.
+	if _, _, err := session.Ask(ctx, "Hello again!", false); err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+	other := chat.ID{User: 124, Chat: 456, Model: openai.GPT4oMini}
+	if _, _, err := NewSession(other, &fakeClient{reply: "Hello, User!"}, buffer).Ask(ctx, "Hello!", false); err != nil {
+		t.Fatalf("Ask failed: %s", err)
+	}
+	if history, err := fs.LoadHistory(ctx, other); err != nil || len(history.Log) != 1 {
+		t.Errorf("LoadHistory from the storage returned %+v, %v, want the history written through", history, err)
+	}
+}
@@ -0,0 +1,16 @@
+package chatgpt
+
+import "context"
+
+// requestContext returns the context of a single request to the API, limited by the
+// request timeout of the session, so a hung request does not block the user forever.
+// The caller must call the returned cancel function once the response is read.
+//
+// ctx: The context of the operation.
+func (s *Session) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.params.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, s.params.Timeout)
+}
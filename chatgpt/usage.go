@@ -16,9 +16,13 @@ import (
 //
 // Input tokens typically correspond to the user's query or prompt, while
 // output tokens correspond to the generated response by the assistant.
+//
+// CachedInput is the part of the input tokens served from the prompt cache, which
+// models with prompt caching bill at a discounted price.
 type Usage struct {
-	Input  int // Input represents the number of tokens provided by the user.
-	Output int // Output represents the number of tokens in the assistant's response.
+	Input       int // Input represents the number of tokens provided by the user.
+	CachedInput int // CachedInput represents the number of input tokens served from the prompt cache.
+	Output      int // Output represents the number of tokens in the assistant's response.
 }
 
 // CalculateCost calculates the total cost of the tokens consumed during a chat request.
@@ -31,6 +35,9 @@ type Usage struct {
 // by 1,000 and then multiplying by the corresponding cost per 1,000 tokens (input or output).
 // The function returns the sum of the input and output token costs as a single chat.Cost value.
 //
+// Cached input tokens are billed at the CachedInput price. If the price is not set,
+// the model has no prompt caching discount and they are billed as regular input tokens.
+//
 // Parameters:
 // - costPerToken: A CostPer1k struct containing the cost per 1,000 input and output tokens.
 //
@@ -42,7 +49,14 @@ type Usage struct {
 // uses different costs for input and output tokens, this will be accurately reflected in the
 // total cost.
 func (u *Usage) CalculateCost(costPerToken CostPer1k) chat.Cost {
-	inputCost := float64(u.Input) / 1000 * float64(costPerToken.Input)
+	cachedPrice := costPerToken.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = costPerToken.Input
+	}
+
+	cached := min(u.CachedInput, u.Input)
+	inputCost := float64(u.Input-cached)/1000*float64(costPerToken.Input) +
+		float64(cached)/1000*float64(cachedPrice)
 	outputCost := float64(u.Output) / 1000 * float64(costPerToken.Output)
 	return chat.Cost(inputCost + outputCost)
 }
@@ -60,7 +74,7 @@ func (u *Usage) CalculateCost(costPerToken CostPer1k) chat.Cost {
 // error: An error if the model name does not exist in the GPTCost map or other
 // calculation issues occur.
 func (u *Usage) CalculateCostByModel(modelName string) (chat.Cost, error) {
-	costPer1k, ok := Cost[modelName]
+	costPer1k, ok := costOf(modelName)
 	if !ok {
 		return 0, fmt.Errorf("model name '%s' not found in the cost map", modelName)
 	}
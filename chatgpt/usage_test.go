@@ -0,0 +1,53 @@
+package chatgpt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestCalculateCost(t *testing.T) {
+	cached := CostPer1k{Input: 0.01, CachedInput: 0.005, Output: 0.03}
+	uncached := CostPer1k{Input: 0.01, Output: 0.03}
+
+	tests := []struct {
+		name  string
+		usage Usage
+		price CostPer1k
+		want  chat.Cost
+	}{
+		{
+			name:  "no cached input",
+			usage: Usage{Input: 1000, Output: 1000},
+			price: cached,
+			want:  0.04,
+		},
+		{
+			name:  "cached input",
+			usage: Usage{Input: 1000, CachedInput: 400, Output: 1000},
+			price: cached,
+			want:  0.006 + 0.002 + 0.03,
+		},
+		{
+			name:  "cached input above the input",
+			usage: Usage{Input: 1000, CachedInput: 1500, Output: 1000},
+			price: cached,
+			want:  0.005 + 0.03,
+		},
+		{
+			name:  "no cached price",
+			usage: Usage{Input: 1000, CachedInput: 400, Output: 1000},
+			price: uncached,
+			want:  0.04,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.usage.CalculateCost(tt.price); math.Abs(float64(got-tt.want)) > 1e-9 {
+				t.Errorf("CalculateCost returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// visionPrefixes are the prefixes of the names of the models accepting images.
+var visionPrefixes = []string{
+	"gpt-4o", "chatgpt-4o", "gpt-4-turbo", "gpt-4-vision", "gpt-4.1", "gpt-4.5", "gpt-5",
+	"o1", "o3", "o4", "claude-3", "claude-sonnet-4", "claude-opus-4", "gemini", "pixtral",
+}
+
+// textPrefixes are the prefixes of the names of the models matching visionPrefixes
+// which do not accept images.
+var textPrefixes = []string{"o1-mini", "o1-preview", "o3-mini", "gpt-4o-audio", "gpt-4o-realtime"}
+
+// SupportsVision reports whether the model accepts images, judging by its name. The
+// names of the OpenRouter models are matched without their provider, e.g. "openai/".
+//
+// model: The name of the model.
+func SupportsVision(model string) bool {
+	name := model[strings.LastIndex(model, "/")+1:]
+
+	hasPrefix := func(prefix string) bool { return strings.HasPrefix(name, prefix) }
+	return slices.ContainsFunc(visionPrefixes, hasPrefix) && !slices.ContainsFunc(textPrefixes, hasPrefix)
+}
+
+// See sends a message with attached images to a vision-capable model and updates the
+// session's history and statistics like Ask. The images are priced as input tokens of
+// the model.
+//
+// ctx: The context in which the API call will be made.
+// message: The user message accompanying the images, may be empty.
+// images: The images to send, encoded as JPEG, PNG, GIF or WebP.
+//
+// Returns:
+// reply: The AI-generated response to the message.
+// fallback: The model which answered instead of the model of the session, empty if none.
+// err: Any error encountered while sending the request or persisting the session.
+func (s *Session) See(ctx context.Context, message string, images [][]byte) (reply, fallback string, err error) {
+	return s.ask(ctx, message, images, false)
+}
+
+// imageParts builds the content of a user message with attached images. The images are
+// embedded as data URLs, so the Telegram file links with the bot token never leave the bot.
+//
+// message: The text of the message, may be empty.
+// images: The images to attach.
+func imageParts(message string, images [][]byte) []openai.ChatMessagePart {
+	parts := make([]openai.ChatMessagePart, 0, len(images)+1)
+	if message != "" {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeText,
+			Text: message,
+		})
+	}
+
+	for _, image := range images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image),
+				Detail: openai.ImageURLDetailAuto,
+			},
+		})
+	}
+
+	return parts
+}
+
+// withImageNote appends a note about the attached images to the message stored in the history.
+//
+// message: The text of the message.
+// images: The number of attached images.
+func withImageNote(message string, images int) string {
+	if images == 0 {
+		return message
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s\n[%d image(s) attached]", message, images))
+}
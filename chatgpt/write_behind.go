@@ -0,0 +1,311 @@
+package chatgpt
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that WriteBehind implements the chat.Storage interface
+var _ chat.Storage = (*WriteBehind)(nil)
+
+// WriteBehind is a chat.Storage which keeps the saved histories and statistics in
+// memory and writes them to the underlying storage in batches, so answering a message
+// does not wait for the disk. A session saved several times between the flushes is
+// written once. The pending data is read back by the loads, so the storage stays
+// consistent for its users; the data saved after the last flush is lost if the
+// process crashes, so Flush should be called on shutdown. The number of the pending
+// objects is limited: once the limit is reached, e.g. while the underlying storage
+// keeps failing, the saves of the other sessions are written through.
+type WriteBehind struct {
+	chat.Wrapper // Wrapper wraps the underlying storage, keeping its optional interfaces.
+
+	limit int // limit is the maximum number of the pending histories and statistics each.
+
+	// flushMu serializes the flushes with the deletes, so a flush does not write the
+	// data deleted meanwhile.
+	flushMu sync.Mutex
+
+	mu         sync.Mutex
+	histories  map[chat.ID]*chat.History    // histories holds the histories not written yet.
+	statistics map[chat.ID]*chat.Statistics // statistics holds the statistics not written yet.
+}
+
+// NewWriteBehind creates a WriteBehind over the storage.
+//
+// storage: The underlying storage the data is written to.
+// limit: The maximum number of the pending histories and statistics each; zero means no limit.
+func NewWriteBehind(storage chat.Storage, limit int) *WriteBehind {
+	return &WriteBehind{
+		Wrapper:    chat.Wrapper{Storage: storage},
+		limit:      limit,
+		histories:  make(map[chat.ID]*chat.History),
+		statistics: make(map[chat.ID]*chat.Statistics),
+	}
+}
+
+// Run flushes the pending data at every interval until the context is done.
+//
+// ctx: The context controlling the lifetime of the flushing.
+// interval: The interval between the flushes.
+func (w *WriteBehind) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the pending data to the underlying storage. The data stays pending
+// until it is written, so the loads never miss it, and the data which could not be
+// written is written by the next flush.
+//
+// ctx: The context for the storage operations.
+//
+// Returns the number of the objects which could not be written.
+func (w *WriteBehind) Flush(ctx context.Context) (failed int) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	histories := maps.Clone(w.histories)
+	statistics := maps.Clone(w.statistics)
+	w.mu.Unlock()
+
+	for id, history := range histories {
+		err := w.Storage.SaveHistory(ctx, history)
+		if err != nil {
+			failed++
+			logFlushError(id, err)
+			continue
+		}
+
+		w.mu.Lock()
+		if w.histories[id] == history {
+			delete(w.histories, id)
+		}
+		w.mu.Unlock()
+	}
+
+	for id, stats := range statistics {
+		err := w.Storage.SaveStatistics(ctx, stats)
+		if err != nil {
+			failed++
+			logFlushError(id, err)
+			continue
+		}
+
+		w.mu.Lock()
+		if w.statistics[id] == stats {
+			delete(w.statistics, id)
+		}
+		w.mu.Unlock()
+	}
+
+	return failed
+}
+
+// logFlushError logs the error of writing the data of the session.
+func logFlushError(id chat.ID, err error) {
+	slog.Error(
+		"write-behind flush error",
+		slog.Int64("userID", id.User),
+		slog.Int64("chatID", id.Chat),
+		slog.String("model", id.Model),
+		slog.String("error", err.Error()),
+	)
+}
+
+// SaveHistory keeps a copy of the history until the next flush, or writes it to the
+// underlying storage if the limit of the pending histories is reached.
+//
+// ctx: The context for the storage operation.
+// history: The History object to be saved.
+//
+// Returns:
+// error: An error of the underlying storage if the history is written through; the
+// errors of the flushes are logged by Flush.
+func (w *WriteBehind) SaveHistory(ctx context.Context, history *chat.History) error {
+	w.mu.Lock()
+	if _, ok := w.histories[history.ID]; ok || !w.full(len(w.histories)) {
+		w.histories[history.ID] = history.Clone()
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+
+	return w.Storage.SaveHistory(ctx, history)
+}
+
+// full reports whether the number of the pending objects has reached the limit.
+//
+// pending: The number of the pending objects of a kind.
+func (w *WriteBehind) full(pending int) bool {
+	return w.limit > 0 && pending >= w.limit
+}
+
+// LoadHistory returns a copy of the pending history or loads it from the underlying storage.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat history to be loaded.
+func (w *WriteBehind) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	w.mu.Lock()
+	history, ok := w.histories[id]
+	w.mu.Unlock()
+
+	if ok {
+		return history.Clone(), nil
+	}
+
+	return w.Storage.LoadHistory(ctx, id)
+}
+
+// DeleteHistory drops the pending history and removes it from the underlying storage.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat history to be removed.
+func (w *WriteBehind) DeleteHistory(ctx context.Context, id chat.ID) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	delete(w.histories, id)
+	w.mu.Unlock()
+
+	return w.Storage.DeleteHistory(ctx, id)
+}
+
+// SaveStatistics keeps a copy of the statistics until the next flush, or writes them
+// to the underlying storage if the limit of the pending statistics is reached.
+//
+// ctx: The context for the storage operation.
+// statistics: The chat statistics to be saved.
+//
+// Returns:
+// error: An error of the underlying storage if the statistics are written through; the
+// errors of the flushes are logged by Flush.
+func (w *WriteBehind) SaveStatistics(ctx context.Context, statistics *chat.Statistics) error {
+	w.mu.Lock()
+	if _, ok := w.statistics[statistics.ID]; ok || !w.full(len(w.statistics)) {
+		w.statistics[statistics.ID] = statistics.Clone()
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+
+	return w.Storage.SaveStatistics(ctx, statistics)
+}
+
+// LoadStatistics returns a copy of the pending statistics or loads them from the
+// underlying storage.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat statistics to be loaded.
+func (w *WriteBehind) LoadStatistics(ctx context.Context, id chat.ID) (*chat.Statistics, error) {
+	w.mu.Lock()
+	stats, ok := w.statistics[id]
+	w.mu.Unlock()
+
+	if ok {
+		return stats.Clone(), nil
+	}
+
+	return w.Storage.LoadStatistics(ctx, id)
+}
+
+// DeleteStatistics drops the pending statistics and removes them from the underlying storage.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat statistics to be removed.
+func (w *WriteBehind) DeleteStatistics(ctx context.Context, id chat.ID) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	delete(w.statistics, id)
+	w.mu.Unlock()
+
+	return w.Storage.DeleteStatistics(ctx, id)
+}
+
+// LoadLedger loads the ledger from the underlying storage and adds the spending of
+// the pending statistics of the owner's sessions, which is recorded once they are written.
+//
+// ctx: The context for the storage operations.
+// owner: The ID of the user or the group chat.
+func (w *WriteBehind) LoadLedger(ctx context.Context, owner int64) (*chat.Ledger, error) {
+	ledger, err := w.Wrapper.LoadLedger(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*chat.Statistics
+	w.mu.Lock()
+	for id, stats := range w.statistics {
+		if slices.Contains(chat.LedgerOwners(id), owner) {
+			pending = append(pending, stats.Clone())
+		}
+	}
+	w.mu.Unlock()
+
+	for _, stats := range pending {
+		stored, err := w.Storage.LoadStatistics(ctx, stats.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		ledger.Add(stored, stats)
+	}
+
+	return ledger, nil
+}
+
+// List returns the IDs of the sessions in the underlying storage and of the pending ones.
+//
+// ctx: The context for the storage operation.
+func (w *WriteBehind) List(ctx context.Context) ([]chat.ID, error) {
+	ids, err := w.Storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[chat.ID]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, pending := range []map[chat.ID]bool{keys(w.histories), keys(w.statistics)} {
+		for id := range pending {
+			if !listed[id] {
+				listed[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// keys returns the set of the keys of the map.
+func keys[V any](m map[chat.ID]V) map[chat.ID]bool {
+	set := make(map[chat.ID]bool, len(m))
+	for id := range m {
+		set[id] = true
+	}
+
+	return set
+}
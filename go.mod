@@ -3,8 +3,32 @@ module github.com/muzykantov/tgpt
 go 1.21.3
 
 require (
+	github.com/alicebob/miniredis/v2 v2.33.0
+	github.com/getsentry/sentry-go v0.27.0
+	github.com/go-sql-driver/mysql v1.8.1
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/joho/godotenv v1.5.1
-	github.com/sashabaranov/go-openai v1.16.0
-	golang.org/x/text v0.14.0
+	github.com/redis/go-redis/v9 v9.7.3
+	github.com/sashabaranov/go-openai v1.41.2
+	go.etcd.io/bbolt v1.3.10
+	go.mongodb.org/mongo-driver/v2 v2.0.0
+	golang.org/x/crypto v0.29.0
+	golang.org/x/image v0.14.0
+	golang.org/x/text v0.20.0
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang/snappy v0.0.4 // indirect
+	github.com/klauspost/compress v1.16.7 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/sync v0.9.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
 )
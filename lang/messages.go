@@ -17,13 +17,155 @@ const (
 	MsgNotSupported        = "This type of message is not supported."
 	MsgCommandNotSupported = "This command is not supported."
 	MsgDone                = "Done."
-	MsgStats               = "*Cost statistics*```\nLast message: %s%.2f\nToday       : %s%.2f\nThis month  : %s%.2f\nAll-time    : %s%.2f```"
+	MsgStats               = "*Cost statistics*```\nLast message: %s%.2f\nToday       : %s%.2f\nYesterday   : %s%.2f\nThis month  : %s%.2f\nAll-time    : %s%.2f```"
 	MsgGreeting            = "*Welcome to the %s chatbot!*\n\nSend me a message to start a conversation or choose one of the available commands:\n\n"
 	MsgSupport             = "For support inquiries, please contact %s."
 	MsgCommandHelp         = "Show the help message."
 	MsgCommandStats        = "Get usage statistics."
 	// MsgCommandResend       = "Resend the last message."
-	MsgCommandRestart = "Restart the conversation. Optionally, pass general instructions (for example, /restart you are a helpful assistant)."
+	MsgCommandRestart        = "Restart the conversation. Optionally, pass general instructions (for example, /restart you are a helpful assistant)."
+	MsgCommandVersion        = "Show the bot version."
+	MsgVersion               = "*%s* version```\nVersion: %s\nCommit : %s\nBuilt  : %s\nGo     : %s```"
+	MsgCommandStatus         = "Show the bot self-diagnostics."
+	MsgStatus                = "*Status*```\nUptime: %v\n\n%s```"
+	MsgStatusOK              = "OK"
+	MsgStatusFailed          = "FAIL (%s)"
+	MsgCommandSessions       = "List the cached sessions."
+	MsgCommandEvict          = "Evict a cached session (/evict <user> <chat> [model])."
+	MsgSessions              = "*Cached sessions: %d*```\nuser chat model | idle | history\n%s```"
+	MsgSessionsMore          = "... and %d more\n"
+	MsgSessionsEmpty         = "There are no cached sessions."
+	MsgSessionBusy           = "busy"
+	MsgSessionNotFound       = "The session is not cached."
+	MsgEvictUsage            = "Usage: /evict <user> <chat> [model]"
+	MsgCommandBroadcast      = "Send an announcement to all known users (/broadcast <text>)."
+	MsgBroadcastUsage        = "Usage: /broadcast <text>"
+	MsgBroadcastStarted      = "Broadcasting the announcement to %d users..."
+	MsgBroadcastReport       = "Broadcast finished. Delivered: %d of %d, failed: %d."
+	MsgCommandMaintenance    = "Toggle the maintenance mode (/maintenance on|off)."
+	MsgMaintenance           = "The bot is temporarily unavailable due to maintenance. Please try again later. For support inquiries, please contact %s."
+	MsgMaintenanceOn         = "Maintenance mode is on."
+	MsgMaintenanceOff        = "Maintenance mode is off."
+	MsgMaintenanceUsage      = "Usage: /maintenance on|off"
+	MsgCommandUsage          = "Show the fleet-wide usage report."
+	MsgUsage                 = "*Usage report*```\nToday     : %s%.2f\nThis month: %s%.2f\nAll-time  : %s%.2f```\n*Users: %d* (today / this month / all-time)```\n%s```\n*Models* (today / this month / all-time)```\n%s```\n_Updated: %s_"
+	MsgCommandTop            = "Show the top spenders (/top [N])."
+	MsgTop                   = "*Top %d today*```\n%s```\n*Top %d this month*```\n%s```\n_Updated: %s_"
+	MsgTopUsage              = "Usage: /top [N], where N is from 1 to %d."
+	MsgStatsPerModel         = "\n*Per model*```\n%s```"
+	MsgStatsTokens           = "\n*Tokens* (input / output)```\nLast message: %d / %d\nAll-time    : %d / %d```"
+	MsgCommandResetStats     = "Reset the usage statistics."
+	MsgResetStatsConfirm     = "Are you sure you want to reset the usage statistics of this chat? This cannot be undone."
+	MsgResetStatsUserConfirm = "Are you sure you want to reset all usage statistics of the user %d? This cannot be undone."
+	MsgResetStatsUsage       = "Usage: /resetstats (administrators: /resetstats [user])"
+	MsgResetStatsDone        = "The usage statistics have been reset."
+	MsgConfirm               = "Confirm"
+	MsgCancel                = "Cancel"
+	MsgCancelled             = "Cancelled."
+	MsgNotPermitted          = "You are not permitted to perform this action."
+	MsgCommandExportStats    = "Export the statistics of all users as CSV."
+	MsgExportStatsCaption    = "Usage statistics as of %s. Costs are in USD without the display rate applied."
+	MsgStatsChartTitle       = "Daily spend, last %d days"
+	MsgCommandTimezone       = "Show or set your time zone for statistics (/timezone Europe/Berlin)."
+	MsgTimezone              = "Your time zone: %s."
+	MsgTimezoneUsage         = "Unknown time zone. Usage: /timezone <IANA name> (for example, /timezone Europe/Berlin) or /timezone reset."
+	MsgWeeklyDigest          = "*Weekly digest*```\nSpend       : %s%.2f\nActive users: %d\nErrors      : %d```\n*Top models*```\n%s```"
+	MsgAlertUserDaily        = "Spending alert: user %d spent %s%.2f today, the threshold is %s%.2f."
+	MsgAlertTotalMonthly     = "Spending alert: all users spent %s%.2f this month, the threshold is %s%.2f."
+	MsgSpendingLimit         = "You have reached your daily spending limit. Please try again tomorrow or contact the administrator %s."
+	MsgMonthlyStatement      = "*Your usage statement for %s*```\nMessages     : %d\nInput tokens : %d\nOutput tokens: %d\nCost         : %s%.2f```"
+	MsgCommandImage          = "Generate an image from a description (/image a cat in a spacesuit)."
+	MsgImageUsage            = "Please describe the image after the command, for example: /image a cat in a spacesuit."
+	MsgCommandBudget         = "Show or set the monthly budget of a user or a group chat."
+	MsgBudgetUsage           = "Usage: /budget <user or group chat ID> [monthly amount in USD | off]. Group chat IDs are negative."
+	MsgBudget                = "Budget of %d: spent %s%.2f of %s%.2f this month."
+	MsgBudgetNone            = "%d has no budget, spent %s%.2f this month."
+	MsgBudgetExceeded        = "You have used up your monthly budget. Please contact the administrator %s."
+	MsgGroupBudgetExceeded   = "This chat has used up its monthly budget. Please contact the administrator %s."
+	MsgRateLimited           = "You are sending messages too fast. Please wait a minute and try again."
+	MsgSubscribeRequired     = "This bot is available to the subscribers of %s. Please subscribe to the channel and try again."
+	MsgCommandInvite         = "Create or revoke invite codes (/invite new [uses], /invite revoke <code>)."
+	MsgInviteUsage           = "Usage: /invite new [number of uses] or /invite revoke <code>."
+	MsgInviteCreated         = "Invite code %s created, it can be used %d time(s).\n\nLink: %s"
+	MsgInviteNotFound        = "Invite code not found."
+	MsgInviteRedeemed        = "Welcome! Your invite code has been accepted and you now have access to the bot."
+	MsgCommandAllow          = "Grant a user access, optionally for a limited time (/allow <user> [30d])."
+	MsgAllowUsage            = "Usage: /allow <user ID> [duration], where the duration is given in days (30d), weeks (2w) or hours (12h)."
+	MsgAllowed               = "User %d has been granted access."
+	MsgAllowedUntil          = "User %d has been granted access until %s."
+	MsgAccessExpiring        = "Your access to the bot expires on %s. To extend it, please contact the administrator %s."
+	MsgAccessExpired         = "Your access to the bot has expired. To renew it, please contact the administrator %s."
+	MsgCommandSetRole        = "Assign a role to a user (/setrole <user> <role>)."
+	MsgSetRoleUsage          = "Usage: /setrole <user ID> <role>, where the role is one of: %s. Use \"reset\" to remove the assigned role."
+	MsgRole                  = "User %d now has the role %s."
+	MsgCommandNotAllowed     = "This command is not available with your role. Please contact the administrator %s."
+	MsgCommandBan            = "Ban a user (/ban <user>)."
+	MsgCommandUnban          = "Unban a user (/unban <user>)."
+	MsgBanUsage              = "Usage: /ban <user ID>"
+	MsgUnbanUsage            = "Usage: /unban <user ID>"
+	MsgBanAdmin              = "Administrators from the configuration cannot be banned."
+	MsgUserBanned            = "User %d has been banned."
+	MsgUserUnbanned          = "User %d has been unbanned."
+	MsgBanned                = "You have been banned from using this bot. If you believe this is a mistake, please contact the administrator %s."
+	MsgInputTooLong          = "Your message is too long: about %d tokens, while the limit is %d. Please shorten it or send it in several parts."
+	MsgThinking              = "⏳ Thinking…"
+	MsgFullAnswer            = "📄 The full answer: %s"
+	MsgCommandExport         = "Export the conversation as an HTML document (/export json for a file to /import)."
+	MsgExportEmpty           = "The conversation is empty, there is nothing to export."
+	MsgExportTitle           = "Conversation with %s"
+	MsgCommandImport         = "Load a conversation exported with /export json."
+	MsgImportUsage           = "Send the file exported with /export json captioned with /import, or reply /import to the file."
+	MsgImportInvalid         = "The file is not a valid conversation export."
+	MsgImported              = "The conversation has been loaded: %d messages."
+	MsgCodeAttached          = "📎 The code is attached as %s."
+	MsgPersona               = "🎭 Persona \"%s\" is active. Send /restart to return to the regular assistant."
+	MsgFallbackModel         = "The model is busy, so %s answered instead."
+	MsgServiceDegraded       = "⚠️ The AI service is experiencing problems right now. Please try again in a minute."
+	MsgRequestTimeout        = "⏳ The AI service took too long to answer. Please try again."
+	MsgCommandDeleteMyData   = "delete all your data stored by the bot"
+	MsgDeleteMyDataConfirm   = "Are you sure you want to delete all your data: the conversations and the usage statistics of all your chats, and your preferences? This cannot be undone."
+	MsgDeleteMyDataReceipt   = "Your data has been deleted.\n\nReceipt: %s\nDate: %s\nChat sessions deleted: %d\n\nYour preferences have been reset. Your access rights and the budget set by the administrators are kept."
+	MsgTermsPrompt           = "Before we start, please read and accept the terms of use:\n\n%s"
+	MsgTermsAccept           = "Accept"
+	MsgTermsAccepted         = "%s\n\nYou accepted the terms of use on %s."
+	MsgTermsChanged          = "The terms of use have changed, please read them again."
+	MsgCommandAudit          = "Show the audit trail (/audit [user] [N])."
+	MsgAuditUsage            = "Usage: /audit [user] [N], where N is from 1 to %d."
+	MsgAuditDisabled         = "The audit trail is not enabled."
+	MsgAuditEmpty            = "The audit trail has no entries."
+	MsgAuditQuestion         = "%s — user %d in chat %d asked %s (%s%.4f): %s"
+	MsgAuditAdmin            = "%s — admin %d in chat %d: %s"
+	MsgCommandUserStats      = "Show the statistics and the sessions of a user (/userstats <user>)."
+	MsgUserStatsUsage        = "Usage: /userstats <user>"
+	MsgUserStats             = "*User %d*```\nRole     : %s\nAllowed  : %t\nBanned   : %t\nTime zone: %s```\n*Stored sessions: %d* (today / this month / all-time)```\n%s\nTotal: %s%.2f / %s%.2f / %s%.2f\nTokens: %d input, %d output\nLast message: %s```\n*Active sessions: %d* (idle | history)```\n%s```"
+	MsgCommandCredit         = "Top up or deduct the prepaid balance of a user."
+	MsgCreditUsage           = "Usage: /credit <user> <amount in USD>. A negative amount is deducted."
+	MsgCredit                = "Balance of %d: %s%.2f."
+	MsgCommandBalance        = "Show your prepaid balance."
+	MsgBalance               = "Your prepaid balance: %s%.2f."
+	MsgBalanceNone           = "You have no prepaid balance."
+	MsgBalanceExhausted      = "Your prepaid balance is used up. Please contact the administrator %s to top it up."
+	MsgCommandSetRate        = "Set the display currency and rate of a user (/setrate <user> <rate> [currency])."
+	MsgSetRateUsage          = "Usage: /setrate <user ID> <rate> [currency]. The bot's rate is multiplied by the rate, e.g. 1.2 for a 20%% markup. Use \"off\" instead of the rate to apply the bot's rate and currency."
+	MsgSetRate               = "%d sees the costs in %s at the rate %g."
+	MsgSetRateOff            = "%d sees the costs at the bot's rate."
+	MsgCommandSetModel       = "Pin the model of a user's sessions (/setmodel <user> <model>)."
+	MsgSetModelUsage         = "Usage: /setmodel <user ID> <model>. List several models separated by commas to restrict the user to them instead of pinning one. Use \"reset\" instead of the model to apply the default model."
+	MsgSetModel              = "The sessions of %d use %s."
+	MsgCommandGrantAdmin     = "Make a user an administrator (/grantadmin <user>)."
+	MsgGrantAdminUsage       = "Usage: /grantadmin <user ID>"
+	MsgCommandRevokeAdmin    = "Revoke the administrator role of a user (/revokeadmin <user>)."
+	MsgRevokeAdminUsage      = "Usage: /revokeadmin <user ID>"
+	MsgRevokeAdminConfigured = "%d is an administrator from the configuration and cannot be demoted."
+	MsgRevokeAdminNotAdmin   = "%d is not an administrator."
+	MsgJoinChallenge         = "Welcome, %s! To use the bot in this chat, please answer: how much is %d + %d?"
+	MsgJoinNotYours          = "This question is for another member."
+	MsgJoinFailed            = "%s has not passed the verification and is removed from the chat."
+	MsgJoinVerified          = "%s is verified, welcome!"
+	MsgSetModelUnknown       = "The model %s is not supported."
+	MsgVisionUnsupported     = "The model %s cannot see images. Send the question as text, or ask an administrator for a model which can see images."
+	MsgSessionClosed         = "🔄 The chat was changed while your message was waiting. Please send it again."
+	MsgAdminErrorReport      = "Error report\n\nUser: %d (@%s)\nChat: %d\nOperation: %s\nError: %s\n\nStack:\n%s"
 )
 
 func init() {
@@ -40,6 +182,148 @@ func init() {
 	message.SetString(language.AmericanEnglish, MsgCommandStats, MsgCommandStats)
 	// message.SetString(language.AmericanEnglish, MsgCommandResend, MsgCommandResend)
 	message.SetString(language.AmericanEnglish, MsgCommandRestart, MsgCommandRestart)
+	message.SetString(language.AmericanEnglish, MsgCommandVersion, MsgCommandVersion)
+	message.SetString(language.AmericanEnglish, MsgVersion, MsgVersion)
+	message.SetString(language.AmericanEnglish, MsgCommandStatus, MsgCommandStatus)
+	message.SetString(language.AmericanEnglish, MsgStatus, MsgStatus)
+	message.SetString(language.AmericanEnglish, MsgStatusOK, MsgStatusOK)
+	message.SetString(language.AmericanEnglish, MsgStatusFailed, MsgStatusFailed)
+	message.SetString(language.AmericanEnglish, MsgCommandSessions, MsgCommandSessions)
+	message.SetString(language.AmericanEnglish, MsgCommandEvict, MsgCommandEvict)
+	message.SetString(language.AmericanEnglish, MsgSessions, MsgSessions)
+	message.SetString(language.AmericanEnglish, MsgSessionsMore, MsgSessionsMore)
+	message.SetString(language.AmericanEnglish, MsgSessionsEmpty, MsgSessionsEmpty)
+	message.SetString(language.AmericanEnglish, MsgSessionBusy, MsgSessionBusy)
+	message.SetString(language.AmericanEnglish, MsgSessionNotFound, MsgSessionNotFound)
+	message.SetString(language.AmericanEnglish, MsgEvictUsage, MsgEvictUsage)
+	message.SetString(language.AmericanEnglish, MsgCommandBroadcast, MsgCommandBroadcast)
+	message.SetString(language.AmericanEnglish, MsgBroadcastUsage, MsgBroadcastUsage)
+	message.SetString(language.AmericanEnglish, MsgBroadcastStarted, MsgBroadcastStarted)
+	message.SetString(language.AmericanEnglish, MsgBroadcastReport, MsgBroadcastReport)
+	message.SetString(language.AmericanEnglish, MsgCommandMaintenance, MsgCommandMaintenance)
+	message.SetString(language.AmericanEnglish, MsgMaintenance, MsgMaintenance)
+	message.SetString(language.AmericanEnglish, MsgMaintenanceOn, MsgMaintenanceOn)
+	message.SetString(language.AmericanEnglish, MsgMaintenanceOff, MsgMaintenanceOff)
+	message.SetString(language.AmericanEnglish, MsgMaintenanceUsage, MsgMaintenanceUsage)
+	message.SetString(language.AmericanEnglish, MsgCommandUsage, MsgCommandUsage)
+	message.SetString(language.AmericanEnglish, MsgUsage, MsgUsage)
+	message.SetString(language.AmericanEnglish, MsgCommandTop, MsgCommandTop)
+	message.SetString(language.AmericanEnglish, MsgTop, MsgTop)
+	message.SetString(language.AmericanEnglish, MsgTopUsage, MsgTopUsage)
+	message.SetString(language.AmericanEnglish, MsgStatsPerModel, MsgStatsPerModel)
+	message.SetString(language.AmericanEnglish, MsgStatsTokens, MsgStatsTokens)
+	message.SetString(language.AmericanEnglish, MsgCommandResetStats, MsgCommandResetStats)
+	message.SetString(language.AmericanEnglish, MsgResetStatsConfirm, MsgResetStatsConfirm)
+	message.SetString(language.AmericanEnglish, MsgResetStatsUserConfirm, MsgResetStatsUserConfirm)
+	message.SetString(language.AmericanEnglish, MsgResetStatsUsage, MsgResetStatsUsage)
+	message.SetString(language.AmericanEnglish, MsgResetStatsDone, MsgResetStatsDone)
+	message.SetString(language.AmericanEnglish, MsgConfirm, MsgConfirm)
+	message.SetString(language.AmericanEnglish, MsgCancel, MsgCancel)
+	message.SetString(language.AmericanEnglish, MsgCancelled, MsgCancelled)
+	message.SetString(language.AmericanEnglish, MsgNotPermitted, MsgNotPermitted)
+	message.SetString(language.AmericanEnglish, MsgCommandExportStats, MsgCommandExportStats)
+	message.SetString(language.AmericanEnglish, MsgExportStatsCaption, MsgExportStatsCaption)
+	message.SetString(language.AmericanEnglish, MsgStatsChartTitle, MsgStatsChartTitle)
+	message.SetString(language.AmericanEnglish, MsgCommandTimezone, MsgCommandTimezone)
+	message.SetString(language.AmericanEnglish, MsgTimezone, MsgTimezone)
+	message.SetString(language.AmericanEnglish, MsgTimezoneUsage, MsgTimezoneUsage)
+	message.SetString(language.AmericanEnglish, MsgWeeklyDigest, MsgWeeklyDigest)
+	message.SetString(language.AmericanEnglish, MsgAlertUserDaily, MsgAlertUserDaily)
+	message.SetString(language.AmericanEnglish, MsgAlertTotalMonthly, MsgAlertTotalMonthly)
+	message.SetString(language.AmericanEnglish, MsgSpendingLimit, MsgSpendingLimit)
+	message.SetString(language.AmericanEnglish, MsgMonthlyStatement, MsgMonthlyStatement)
+	message.SetString(language.AmericanEnglish, MsgCommandImage, MsgCommandImage)
+	message.SetString(language.AmericanEnglish, MsgImageUsage, MsgImageUsage)
+	message.SetString(language.AmericanEnglish, MsgCommandBudget, MsgCommandBudget)
+	message.SetString(language.AmericanEnglish, MsgBudgetUsage, MsgBudgetUsage)
+	message.SetString(language.AmericanEnglish, MsgBudget, MsgBudget)
+	message.SetString(language.AmericanEnglish, MsgBudgetNone, MsgBudgetNone)
+	message.SetString(language.AmericanEnglish, MsgBudgetExceeded, MsgBudgetExceeded)
+	message.SetString(language.AmericanEnglish, MsgGroupBudgetExceeded, MsgGroupBudgetExceeded)
+	message.SetString(language.AmericanEnglish, MsgRateLimited, MsgRateLimited)
+	message.SetString(language.AmericanEnglish, MsgSubscribeRequired, MsgSubscribeRequired)
+	message.SetString(language.AmericanEnglish, MsgCommandInvite, MsgCommandInvite)
+	message.SetString(language.AmericanEnglish, MsgInviteUsage, MsgInviteUsage)
+	message.SetString(language.AmericanEnglish, MsgInviteCreated, MsgInviteCreated)
+	message.SetString(language.AmericanEnglish, MsgInviteNotFound, MsgInviteNotFound)
+	message.SetString(language.AmericanEnglish, MsgInviteRedeemed, MsgInviteRedeemed)
+	message.SetString(language.AmericanEnglish, MsgCommandAllow, MsgCommandAllow)
+	message.SetString(language.AmericanEnglish, MsgAllowUsage, MsgAllowUsage)
+	message.SetString(language.AmericanEnglish, MsgAllowed, MsgAllowed)
+	message.SetString(language.AmericanEnglish, MsgAllowedUntil, MsgAllowedUntil)
+	message.SetString(language.AmericanEnglish, MsgAccessExpiring, MsgAccessExpiring)
+	message.SetString(language.AmericanEnglish, MsgAccessExpired, MsgAccessExpired)
+	message.SetString(language.AmericanEnglish, MsgCommandSetRole, MsgCommandSetRole)
+	message.SetString(language.AmericanEnglish, MsgSetRoleUsage, MsgSetRoleUsage)
+	message.SetString(language.AmericanEnglish, MsgRole, MsgRole)
+	message.SetString(language.AmericanEnglish, MsgCommandNotAllowed, MsgCommandNotAllowed)
+	message.SetString(language.AmericanEnglish, MsgCommandBan, MsgCommandBan)
+	message.SetString(language.AmericanEnglish, MsgCommandUnban, MsgCommandUnban)
+	message.SetString(language.AmericanEnglish, MsgBanUsage, MsgBanUsage)
+	message.SetString(language.AmericanEnglish, MsgUnbanUsage, MsgUnbanUsage)
+	message.SetString(language.AmericanEnglish, MsgBanAdmin, MsgBanAdmin)
+	message.SetString(language.AmericanEnglish, MsgUserBanned, MsgUserBanned)
+	message.SetString(language.AmericanEnglish, MsgUserUnbanned, MsgUserUnbanned)
+	message.SetString(language.AmericanEnglish, MsgBanned, MsgBanned)
+	message.SetString(language.AmericanEnglish, MsgInputTooLong, MsgInputTooLong)
+	message.SetString(language.AmericanEnglish, MsgThinking, MsgThinking)
+	message.SetString(language.AmericanEnglish, MsgFullAnswer, MsgFullAnswer)
+	message.SetString(language.AmericanEnglish, MsgCommandExport, MsgCommandExport)
+	message.SetString(language.AmericanEnglish, MsgExportEmpty, MsgExportEmpty)
+	message.SetString(language.AmericanEnglish, MsgExportTitle, MsgExportTitle)
+	message.SetString(language.AmericanEnglish, MsgCommandImport, MsgCommandImport)
+	message.SetString(language.AmericanEnglish, MsgImportUsage, MsgImportUsage)
+	message.SetString(language.AmericanEnglish, MsgImportInvalid, MsgImportInvalid)
+	message.SetString(language.AmericanEnglish, MsgImported, MsgImported)
+	message.SetString(language.AmericanEnglish, MsgCodeAttached, MsgCodeAttached)
+	message.SetString(language.AmericanEnglish, MsgPersona, MsgPersona)
+	message.SetString(language.AmericanEnglish, MsgFallbackModel, MsgFallbackModel)
+	message.SetString(language.AmericanEnglish, MsgServiceDegraded, MsgServiceDegraded)
+	message.SetString(language.AmericanEnglish, MsgRequestTimeout, MsgRequestTimeout)
+	message.SetString(language.AmericanEnglish, MsgCommandDeleteMyData, MsgCommandDeleteMyData)
+	message.SetString(language.AmericanEnglish, MsgDeleteMyDataConfirm, MsgDeleteMyDataConfirm)
+	message.SetString(language.AmericanEnglish, MsgDeleteMyDataReceipt, MsgDeleteMyDataReceipt)
+	message.SetString(language.AmericanEnglish, MsgTermsPrompt, MsgTermsPrompt)
+	message.SetString(language.AmericanEnglish, MsgTermsAccept, MsgTermsAccept)
+	message.SetString(language.AmericanEnglish, MsgTermsAccepted, MsgTermsAccepted)
+	message.SetString(language.AmericanEnglish, MsgTermsChanged, MsgTermsChanged)
+	message.SetString(language.AmericanEnglish, MsgCommandAudit, MsgCommandAudit)
+	message.SetString(language.AmericanEnglish, MsgAuditUsage, MsgAuditUsage)
+	message.SetString(language.AmericanEnglish, MsgAuditDisabled, MsgAuditDisabled)
+	message.SetString(language.AmericanEnglish, MsgAuditEmpty, MsgAuditEmpty)
+	message.SetString(language.AmericanEnglish, MsgAuditQuestion, MsgAuditQuestion)
+	message.SetString(language.AmericanEnglish, MsgAuditAdmin, MsgAuditAdmin)
+	message.SetString(language.AmericanEnglish, MsgCommandUserStats, MsgCommandUserStats)
+	message.SetString(language.AmericanEnglish, MsgUserStatsUsage, MsgUserStatsUsage)
+	message.SetString(language.AmericanEnglish, MsgUserStats, MsgUserStats)
+	message.SetString(language.AmericanEnglish, MsgCommandCredit, MsgCommandCredit)
+	message.SetString(language.AmericanEnglish, MsgCreditUsage, MsgCreditUsage)
+	message.SetString(language.AmericanEnglish, MsgCredit, MsgCredit)
+	message.SetString(language.AmericanEnglish, MsgCommandBalance, MsgCommandBalance)
+	message.SetString(language.AmericanEnglish, MsgBalance, MsgBalance)
+	message.SetString(language.AmericanEnglish, MsgBalanceNone, MsgBalanceNone)
+	message.SetString(language.AmericanEnglish, MsgBalanceExhausted, MsgBalanceExhausted)
+	message.SetString(language.AmericanEnglish, MsgCommandSetRate, MsgCommandSetRate)
+	message.SetString(language.AmericanEnglish, MsgSetRateUsage, MsgSetRateUsage)
+	message.SetString(language.AmericanEnglish, MsgSetRate, MsgSetRate)
+	message.SetString(language.AmericanEnglish, MsgSetRateOff, MsgSetRateOff)
+	message.SetString(language.AmericanEnglish, MsgCommandSetModel, MsgCommandSetModel)
+	message.SetString(language.AmericanEnglish, MsgSetModelUsage, MsgSetModelUsage)
+	message.SetString(language.AmericanEnglish, MsgSetModel, MsgSetModel)
+	message.SetString(language.AmericanEnglish, MsgCommandGrantAdmin, MsgCommandGrantAdmin)
+	message.SetString(language.AmericanEnglish, MsgGrantAdminUsage, MsgGrantAdminUsage)
+	message.SetString(language.AmericanEnglish, MsgCommandRevokeAdmin, MsgCommandRevokeAdmin)
+	message.SetString(language.AmericanEnglish, MsgRevokeAdminUsage, MsgRevokeAdminUsage)
+	message.SetString(language.AmericanEnglish, MsgRevokeAdminConfigured, MsgRevokeAdminConfigured)
+	message.SetString(language.AmericanEnglish, MsgRevokeAdminNotAdmin, MsgRevokeAdminNotAdmin)
+	message.SetString(language.AmericanEnglish, MsgJoinChallenge, MsgJoinChallenge)
+	message.SetString(language.AmericanEnglish, MsgJoinNotYours, MsgJoinNotYours)
+	message.SetString(language.AmericanEnglish, MsgJoinFailed, MsgJoinFailed)
+	message.SetString(language.AmericanEnglish, MsgJoinVerified, MsgJoinVerified)
+	message.SetString(language.AmericanEnglish, MsgSetModelUnknown, MsgSetModelUnknown)
+	message.SetString(language.AmericanEnglish, MsgVisionUnsupported, MsgVisionUnsupported)
+	message.SetString(language.AmericanEnglish, MsgSessionClosed, MsgSessionClosed)
+	message.SetString(language.AmericanEnglish, MsgAdminErrorReport, MsgAdminErrorReport)
 
 	message.SetString(language.Russian, MsgNotAllowed, "Уважаемый пользователь с ID %d, к сожалению, у вас нет доступа к использованию этого чат-бота. Чтобы запросить доступ, пожалуйста, свяжитесь с администратором %s и предоставьте ваш ID пользователя.")
 	message.SetString(language.Russian, MsgUnexpectedError, "Произошла неожиданная ошибка при обработке вашего запроса. Для устранения проблемы, пожалуйста, перешлите это сообщение администратору бота %s.\n\nСообщение об ошибке: %s.")
@@ -47,11 +331,153 @@ func init() {
 	message.SetString(language.Russian, MsgNotSupported, "Этот тип сообщения не поддерживается.")
 	message.SetString(language.Russian, MsgCommandNotSupported, "Эта команда не поддерживается.")
 	message.SetString(language.Russian, MsgDone, "Готово.")
-	message.SetString(language.Russian, MsgStats, "*Статистика расходов*```\nПоследнее сообщ.: %s%.2f\nЗа сегодня      : %s%.2f\nВ этом месяце   : %s%.2f\nЗа все время    : %s%.2f```")
+	message.SetString(language.Russian, MsgStats, "*Статистика расходов*```\nПоследнее сообщ.: %s%.2f\nЗа сегодня      : %s%.2f\nЗа вчера        : %s%.2f\nВ этом месяце   : %s%.2f\nЗа все время    : %s%.2f```")
 	message.SetString(language.Russian, MsgGreeting, "*Вас приветствует %s чат-бот!*\n\nОтправь мне сообщение для начала беседы или выбери одну из доступных команд:\n\n")
 	message.SetString(language.Russian, MsgSupport, "По вопросам поддержки, пожалуйста, обращайтесь к %s.")
 	message.SetString(language.Russian, MsgCommandHelp, "Показать справочное сообщение.")
 	message.SetString(language.Russian, MsgCommandStats, "Получить статистику использования.")
 	// message.SetString(language.Russian, MsgCommandResend, "Повторная отправка последнего сообщения.")
 	message.SetString(language.Russian, MsgCommandRestart, "Перезагрузить разговор. По желанию передай общие инструкции (например, /reset ты полезный помощник).")
+	message.SetString(language.Russian, MsgCommandVersion, "Показать версию бота.")
+	message.SetString(language.Russian, MsgVersion, "Версия *%s*```\nВерсия: %s\nКоммит: %s\nСборка: %s\nGo    : %s```")
+	message.SetString(language.Russian, MsgCommandStatus, "Показать самодиагностику бота.")
+	message.SetString(language.Russian, MsgStatus, "*Состояние*```\nВремя работы: %v\n\n%s```")
+	message.SetString(language.Russian, MsgStatusOK, "ОК")
+	message.SetString(language.Russian, MsgStatusFailed, "ОШИБКА (%s)")
+	message.SetString(language.Russian, MsgCommandSessions, "Показать кэшированные сессии.")
+	message.SetString(language.Russian, MsgCommandEvict, "Выгрузить сессию из кэша (/evict <пользователь> <чат> [модель]).")
+	message.SetString(language.Russian, MsgSessions, "*Сессий в кэше: %d*```\nпольз. чат модель | простой | история\n%s```")
+	message.SetString(language.Russian, MsgSessionsMore, "... и еще %d\n")
+	message.SetString(language.Russian, MsgSessionsEmpty, "В кэше нет сессий.")
+	message.SetString(language.Russian, MsgSessionBusy, "занята")
+	message.SetString(language.Russian, MsgSessionNotFound, "Сессия не найдена в кэше.")
+	message.SetString(language.Russian, MsgEvictUsage, "Использование: /evict <пользователь> <чат> [модель]")
+	message.SetString(language.Russian, MsgCommandBroadcast, "Отправить объявление всем известным пользователям (/broadcast <текст>).")
+	message.SetString(language.Russian, MsgBroadcastUsage, "Использование: /broadcast <текст>")
+	message.SetString(language.Russian, MsgBroadcastStarted, "Рассылка объявления %d пользователям...")
+	message.SetString(language.Russian, MsgBroadcastReport, "Рассылка завершена. Доставлено: %d из %d, ошибок: %d.")
+	message.SetString(language.Russian, MsgCommandMaintenance, "Переключить режим обслуживания (/maintenance on|off).")
+	message.SetString(language.Russian, MsgMaintenance, "Бот временно недоступен из-за технических работ. Пожалуйста, попробуйте позже. По вопросам поддержки обращайтесь к %s.")
+	message.SetString(language.Russian, MsgMaintenanceOn, "Режим обслуживания включен.")
+	message.SetString(language.Russian, MsgMaintenanceOff, "Режим обслуживания выключен.")
+	message.SetString(language.Russian, MsgMaintenanceUsage, "Использование: /maintenance on|off")
+	message.SetString(language.Russian, MsgCommandUsage, "Показать сводный отчет об использовании.")
+	message.SetString(language.Russian, MsgUsage, "*Отчет об использовании*```\nЗа сегодня   : %s%.2f\nВ этом месяце: %s%.2f\nЗа все время : %s%.2f```\n*Пользователей: %d* (сегодня / месяц / все время)```\n%s```\n*Модели* (сегодня / месяц / все время)```\n%s```\n_Обновлено: %s_")
+	message.SetString(language.Russian, MsgCommandTop, "Показать самых активных пользователей по расходам (/top [N]).")
+	message.SetString(language.Russian, MsgTop, "*Топ-%d за сегодня*```\n%s```\n*Топ-%d за месяц*```\n%s```\n_Обновлено: %s_")
+	message.SetString(language.Russian, MsgTopUsage, "Использование: /top [N], где N от 1 до %d.")
+	message.SetString(language.Russian, MsgStatsPerModel, "\n*По моделям*```\n%s```")
+	message.SetString(language.Russian, MsgStatsTokens, "\n*Токены* (вход / выход)```\nПоследнее сообщ.: %d / %d\nЗа все время    : %d / %d```")
+	message.SetString(language.Russian, MsgCommandResetStats, "Сбросить статистику использования.")
+	message.SetString(language.Russian, MsgResetStatsConfirm, "Вы уверены, что хотите сбросить статистику использования этого чата? Это действие нельзя отменить.")
+	message.SetString(language.Russian, MsgResetStatsUserConfirm, "Вы уверены, что хотите сбросить всю статистику использования пользователя %d? Это действие нельзя отменить.")
+	message.SetString(language.Russian, MsgResetStatsUsage, "Использование: /resetstats (администраторы: /resetstats [пользователь])")
+	message.SetString(language.Russian, MsgResetStatsDone, "Статистика использования сброшена.")
+	message.SetString(language.Russian, MsgConfirm, "Подтвердить")
+	message.SetString(language.Russian, MsgCancel, "Отмена")
+	message.SetString(language.Russian, MsgCancelled, "Отменено.")
+	message.SetString(language.Russian, MsgNotPermitted, "У вас нет прав на это действие.")
+	message.SetString(language.Russian, MsgCommandExportStats, "Выгрузить статистику всех пользователей в CSV.")
+	message.SetString(language.Russian, MsgExportStatsCaption, "Статистика использования на %s. Расходы указаны в USD без применения курса отображения.")
+	message.SetString(language.Russian, MsgStatsChartTitle, "Расходы по дням, последние %d дн.")
+	message.SetString(language.Russian, MsgCommandTimezone, "Показать или задать часовой пояс для статистики (/timezone Europe/Moscow).")
+	message.SetString(language.Russian, MsgTimezone, "Ваш часовой пояс: %s.")
+	message.SetString(language.Russian, MsgTimezoneUsage, "Неизвестный часовой пояс. Использование: /timezone <имя IANA> (например, /timezone Europe/Moscow) или /timezone reset.")
+	message.SetString(language.Russian, MsgWeeklyDigest, "*Еженедельная сводка*```\nРасходы          : %s%.2f\nАктивные польз.  : %d\nОшибки           : %d```\n*Топ моделей*```\n%s```")
+	message.SetString(language.Russian, MsgAlertUserDaily, "Превышение расходов: пользователь %d потратил сегодня %s%.2f, порог %s%.2f.")
+	message.SetString(language.Russian, MsgAlertTotalMonthly, "Превышение расходов: все пользователи потратили в этом месяце %s%.2f, порог %s%.2f.")
+	message.SetString(language.Russian, MsgSpendingLimit, "Вы достигли дневного лимита расходов. Пожалуйста, попробуйте завтра или свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgMonthlyStatement, "*Ваша выписка об использовании за %s*```\nСообщения       : %d\nВходные токены  : %d\nВыходные токены : %d\nСтоимость       : %s%.2f```")
+	message.SetString(language.Russian, MsgCommandImage, "Сгенерировать изображение по описанию (/image кот в скафандре).")
+	message.SetString(language.Russian, MsgImageUsage, "Пожалуйста, опишите изображение после команды, например: /image кот в скафандре.")
+	message.SetString(language.Russian, MsgCommandBudget, "Показать или задать месячный бюджет пользователя или группового чата.")
+	message.SetString(language.Russian, MsgBudgetUsage, "Использование: /budget <ID пользователя или группового чата> [месячная сумма в USD | off]. ID групповых чатов отрицательные.")
+	message.SetString(language.Russian, MsgBudget, "Бюджет %d: потрачено %s%.2f из %s%.2f в этом месяце.")
+	message.SetString(language.Russian, MsgBudgetNone, "У %d нет бюджета, потрачено %s%.2f в этом месяце.")
+	message.SetString(language.Russian, MsgBudgetExceeded, "Вы израсходовали свой месячный бюджет. Пожалуйста, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgGroupBudgetExceeded, "Этот чат израсходовал свой месячный бюджет. Пожалуйста, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgRateLimited, "Вы отправляете сообщения слишком часто. Пожалуйста, подождите минуту и попробуйте снова.")
+	message.SetString(language.Russian, MsgSubscribeRequired, "Этот бот доступен подписчикам %s. Пожалуйста, подпишитесь на канал и попробуйте снова.")
+	message.SetString(language.Russian, MsgCommandInvite, "Создать или отозвать коды приглашения (/invite new [количество], /invite revoke <код>).")
+	message.SetString(language.Russian, MsgInviteUsage, "Использование: /invite new [количество использований] или /invite revoke <код>.")
+	message.SetString(language.Russian, MsgInviteCreated, "Код приглашения %s создан, его можно использовать %d раз(а).\n\nСсылка: %s")
+	message.SetString(language.Russian, MsgInviteNotFound, "Код приглашения не найден.")
+	message.SetString(language.Russian, MsgInviteRedeemed, "Добро пожаловать! Ваш код приглашения принят, теперь у вас есть доступ к боту.")
+	message.SetString(language.Russian, MsgCommandAllow, "Предоставить пользователю доступ, при необходимости на ограниченное время (/allow <пользователь> [30d]).")
+	message.SetString(language.Russian, MsgAllowUsage, "Использование: /allow <ID пользователя> [срок], где срок указывается в днях (30d), неделях (2w) или часах (12h).")
+	message.SetString(language.Russian, MsgAllowed, "Пользователю %d предоставлен доступ.")
+	message.SetString(language.Russian, MsgAllowedUntil, "Пользователю %d предоставлен доступ до %s.")
+	message.SetString(language.Russian, MsgAccessExpiring, "Ваш доступ к боту истекает %s. Чтобы продлить его, пожалуйста, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgAccessExpired, "Ваш доступ к боту истек. Чтобы возобновить его, пожалуйста, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgCommandSetRole, "Назначить роль пользователю (/setrole <пользователь> <роль>).")
+	message.SetString(language.Russian, MsgSetRoleUsage, "Использование: /setrole <ID пользователя> <роль>, где роль одна из: %s. Укажите \"reset\", чтобы снять назначенную роль.")
+	message.SetString(language.Russian, MsgRole, "Теперь у пользователя %d роль %s.")
+	message.SetString(language.Russian, MsgCommandNotAllowed, "Эта команда недоступна для вашей роли. Пожалуйста, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgCommandBan, "Заблокировать пользователя (/ban <пользователь>).")
+	message.SetString(language.Russian, MsgCommandUnban, "Разблокировать пользователя (/unban <пользователь>).")
+	message.SetString(language.Russian, MsgBanUsage, "Использование: /ban <ID пользователя>")
+	message.SetString(language.Russian, MsgUnbanUsage, "Использование: /unban <ID пользователя>")
+	message.SetString(language.Russian, MsgBanAdmin, "Администраторов из конфигурации нельзя заблокировать.")
+	message.SetString(language.Russian, MsgUserBanned, "Пользователь %d заблокирован.")
+	message.SetString(language.Russian, MsgUserUnbanned, "Пользователь %d разблокирован.")
+	message.SetString(language.Russian, MsgBanned, "Вы заблокированы в этом боте. Если вы считаете, что это ошибка, свяжитесь с администратором %s.")
+	message.SetString(language.Russian, MsgInputTooLong, "Ваше сообщение слишком длинное: около %d токенов при ограничении %d. Пожалуйста, сократите его или отправьте по частям.")
+	message.SetString(language.Russian, MsgThinking, "⏳ Думаю…")
+	message.SetString(language.Russian, MsgFullAnswer, "📄 Полный ответ: %s")
+	message.SetString(language.Russian, MsgCommandExport, "Экспортировать переписку в HTML-документ (/export json — файл для /import).")
+	message.SetString(language.Russian, MsgExportEmpty, "Переписка пуста, экспортировать нечего.")
+	message.SetString(language.Russian, MsgExportTitle, "Переписка с %s")
+	message.SetString(language.Russian, MsgCommandImport, "Загрузить переписку, экспортированную командой /export json.")
+	message.SetString(language.Russian, MsgImportUsage, "Отправьте файл, экспортированный командой /export json, с подписью /import или ответьте /import на сообщение с файлом.")
+	message.SetString(language.Russian, MsgImportInvalid, "Файл не является корректной выгрузкой переписки.")
+	message.SetString(language.Russian, MsgImported, "Переписка загружена: сообщений — %d.")
+	message.SetString(language.Russian, MsgCodeAttached, "📎 Код приложен файлом %s.")
+	message.SetString(language.Russian, MsgPersona, "🎭 Включён персонаж «%s». Отправьте /restart, чтобы вернуться к обычному ассистенту.")
+	message.SetString(language.Russian, MsgFallbackModel, "Модель перегружена, поэтому ответила %s.")
+	message.SetString(language.Russian, MsgServiceDegraded, "⚠️ Сервис ИИ сейчас работает с перебоями. Пожалуйста, повторите попытку через минуту.")
+	message.SetString(language.Russian, MsgRequestTimeout, "⏳ Сервис ИИ слишком долго не отвечал. Пожалуйста, повторите попытку.")
+	message.SetString(language.Russian, MsgCommandDeleteMyData, "удалить все ваши данные, хранящиеся у бота")
+	message.SetString(language.Russian, MsgDeleteMyDataConfirm, "Вы уверены, что хотите удалить все свои данные: переписку и статистику использования всех ваших чатов, а также ваши настройки? Это действие нельзя отменить.")
+	message.SetString(language.Russian, MsgDeleteMyDataReceipt, "Ваши данные удалены.\n\nКвитанция: %s\nДата: %s\nУдалено сессий чатов: %d\n\nВаши настройки сброшены. Ваши права доступа и бюджет, установленные администраторами, сохранены.")
+	message.SetString(language.Russian, MsgTermsPrompt, "Прежде чем начать, пожалуйста, прочитайте и примите условия использования:\n\n%s")
+	message.SetString(language.Russian, MsgTermsAccept, "Принять")
+	message.SetString(language.Russian, MsgTermsAccepted, "%s\n\nВы приняли условия использования %s.")
+	message.SetString(language.Russian, MsgTermsChanged, "Условия использования изменились, пожалуйста, прочитайте их снова.")
+	message.SetString(language.Russian, MsgCommandAudit, "Показать журнал аудита (/audit [пользователь] [N]).")
+	message.SetString(language.Russian, MsgAuditUsage, "Использование: /audit [пользователь] [N], где N от 1 до %d.")
+	message.SetString(language.Russian, MsgAuditDisabled, "Журнал аудита не включен.")
+	message.SetString(language.Russian, MsgAuditEmpty, "В журнале аудита нет записей.")
+	message.SetString(language.Russian, MsgAuditQuestion, "%s — пользователь %d в чате %d спросил %s (%s%.4f): %s")
+	message.SetString(language.Russian, MsgAuditAdmin, "%s — администратор %d в чате %d: %s")
+	message.SetString(language.Russian, MsgCommandUserStats, "Показать статистику и сессии пользователя (/userstats <пользователь>).")
+	message.SetString(language.Russian, MsgUserStatsUsage, "Использование: /userstats <пользователь>")
+	message.SetString(language.Russian, MsgUserStats, "*Пользователь %d*```\nРоль        : %s\nДоступ      : %t\nЗаблокирован: %t\nЧасовой пояс: %s```\n*Сохраненных сессий: %d* (сегодня / месяц / все время)```\n%s\nВсего: %s%.2f / %s%.2f / %s%.2f\nТокены: %d входных, %d выходных\nПоследнее сообщение: %s```\n*Активных сессий: %d* (простой | история)```\n%s```")
+	message.SetString(language.Russian, MsgCommandCredit, "Пополнить или списать предоплаченный баланс пользователя.")
+	message.SetString(language.Russian, MsgCreditUsage, "Использование: /credit <пользователь> <сумма в USD>. Отрицательная сумма списывается.")
+	message.SetString(language.Russian, MsgCredit, "Баланс %d: %s%.2f.")
+	message.SetString(language.Russian, MsgCommandBalance, "Показать ваш предоплаченный баланс.")
+	message.SetString(language.Russian, MsgBalance, "Ваш предоплаченный баланс: %s%.2f.")
+	message.SetString(language.Russian, MsgBalanceNone, "У вас нет предоплаченного баланса.")
+	message.SetString(language.Russian, MsgBalanceExhausted, "Ваш предоплаченный баланс исчерпан. Пожалуйста, свяжитесь с администратором %s, чтобы пополнить его.")
+	message.SetString(language.Russian, MsgCommandSetRate, "Задать валюту и курс отображения для пользователя (/setrate <пользователь> <курс> [валюта]).")
+	message.SetString(language.Russian, MsgSetRateUsage, "Использование: /setrate <ID пользователя> <курс> [валюта]. Курс бота умножается на указанный курс, например 1.2 для наценки 20%%. Укажите \"off\" вместо курса, чтобы применять курс и валюту бота.")
+	message.SetString(language.Russian, MsgSetRate, "%d видит расходы в %s по курсу %g.")
+	message.SetString(language.Russian, MsgSetRateOff, "%d видит расходы по курсу бота.")
+	message.SetString(language.Russian, MsgCommandSetModel, "Закрепить модель для сессий пользователя (/setmodel <пользователь> <модель>).")
+	message.SetString(language.Russian, MsgSetModelUsage, "Использование: /setmodel <ID пользователя> <модель>. Перечислите несколько моделей через запятую, чтобы ограничить ими пользователя вместо закрепления одной. Укажите \"reset\" вместо модели, чтобы применять модель по умолчанию.")
+	message.SetString(language.Russian, MsgSetModel, "Сессии %d используют %s.")
+	message.SetString(language.Russian, MsgCommandGrantAdmin, "Сделать пользователя администратором (/grantadmin <пользователь>).")
+	message.SetString(language.Russian, MsgGrantAdminUsage, "Использование: /grantadmin <ID пользователя>")
+	message.SetString(language.Russian, MsgCommandRevokeAdmin, "Отозвать у пользователя роль администратора (/revokeadmin <пользователь>).")
+	message.SetString(language.Russian, MsgRevokeAdminUsage, "Использование: /revokeadmin <ID пользователя>")
+	message.SetString(language.Russian, MsgRevokeAdminConfigured, "%d является администратором из конфигурации и не может быть понижен.")
+	message.SetString(language.Russian, MsgRevokeAdminNotAdmin, "%d не является администратором.")
+	message.SetString(language.Russian, MsgJoinChallenge, "Добро пожаловать, %s! Чтобы пользоваться ботом в этом чате, ответьте: сколько будет %d + %d?")
+	message.SetString(language.Russian, MsgJoinNotYours, "Этот вопрос для другого участника.")
+	message.SetString(language.Russian, MsgJoinFailed, "%s не прошел проверку и удаляется из чата.")
+	message.SetString(language.Russian, MsgJoinVerified, "%s, проверка пройдена, добро пожаловать!")
+	message.SetString(language.Russian, MsgSetModelUnknown, "Модель %s не поддерживается.")
+	message.SetString(language.Russian, MsgVisionUnsupported, "Модель %s не умеет распознавать изображения. Отправьте вопрос текстом или попросите администратора назначить модель, которая работает с изображениями.")
+	message.SetString(language.Russian, MsgSessionClosed, "🔄 Чат изменился, пока ваше сообщение ожидало обработки. Пожалуйста, отправьте его снова.")
+	message.SetString(language.Russian, MsgAdminErrorReport, "Отчет об ошибке\n\nПользователь: %d (@%s)\nЧат: %d\nОперация: %s\nОшибка: %s\n\nСтек:\n%s")
 }
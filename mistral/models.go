@@ -0,0 +1,31 @@
+// Package mistral provides the support of the Mistral API, which is compatible with
+// the chat completions of the OpenAI API, so the sessions reach it with the OpenAI
+// client at its own address.
+package mistral
+
+import (
+	"github.com/muzykantov/tgpt/chatgpt"
+)
+
+// BaseURL is the base URL of the Mistral API for the OpenAI client.
+const BaseURL = "https://api.mistral.ai/v1"
+
+// Cost maps the Mistral models to their prices per 1,000 tokens.
+var Cost = map[string]chatgpt.CostPer1k{
+	"mistral-large-latest":  {Input: 0.002, Output: 0.006},     // Maps Mistral Large to its cost structure.
+	"mistral-medium-latest": {Input: 0.0004, Output: 0.002},    // Maps Mistral Medium to its cost structure.
+	"mistral-small-latest":  {Input: 0.0001, Output: 0.0003},   // Maps Mistral Small to its cost structure.
+	"pixtral-large-latest":  {Input: 0.002, Output: 0.006},     // Maps Pixtral Large, the vision model, to its cost structure.
+	"codestral-latest":      {Input: 0.0003, Output: 0.0009},   // Maps Codestral to its cost structure.
+	"ministral-8b-latest":   {Input: 0.0001, Output: 0.0001},   // Maps Ministral 8B to its cost structure.
+	"ministral-3b-latest":   {Input: 0.00004, Output: 0.00004}, // Maps Ministral 3B to its cost structure.
+	"open-mistral-nemo":     {Input: 0.00015, Output: 0.00015}, // Maps Mistral NeMo to its cost structure.
+}
+
+// IsModel reports whether the model is served by the Mistral API.
+//
+// model: The name of the model.
+func IsModel(model string) bool {
+	_, ok := Cost[model]
+	return ok
+}
@@ -0,0 +1,157 @@
+// Package openrouter provides the support of OpenRouter, an OpenAI-compatible API
+// which routes the requests to the models of many providers. The models are named
+// after their providers, e.g. "openai/gpt-4o" or "anthropic/claude-3.5-sonnet".
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/chatgpt"
+)
+
+// BaseURL is the base URL of the OpenRouter API for the OpenAI client.
+const BaseURL = "https://openrouter.ai/api/v1"
+
+// requestTimeout limits the duration of the pricing request.
+const requestTimeout = time.Second * 30
+
+// Headers returns the headers OpenRouter uses to attribute the requests to the app in
+// its rankings.
+//
+// referer: The URL of the app, e.g. its repository or the link to the bot.
+// title: The name of the app.
+func Headers(referer, title string) http.Header {
+	headers := make(http.Header)
+	if referer != "" {
+		headers.Set("HTTP-Referer", referer)
+	}
+	if title != "" {
+		headers.Set("X-Title", title)
+	}
+
+	return headers
+}
+
+// model is a model listed by the models endpoint. The prices are given in USD per
+// token as decimal strings; a negative price means the price varies, e.g. for the
+// router models like "openrouter/auto".
+type model struct {
+	ID      string `json:"id"`
+	Pricing struct {
+		Prompt         string `json:"prompt"`
+		Completion     string `json:"completion"`
+		InputCacheRead string `json:"input_cache_read"`
+	} `json:"pricing"`
+}
+
+// Pricing fetches the prices of the models available on OpenRouter. The models
+// without a fixed price are skipped.
+//
+// ctx: The context of the request.
+// client: The HTTP client, e.g. the one going through the proxy.
+// baseURL: The base URL of the OpenRouter API (see BaseURL).
+//
+// Returns the prices by the model name and an error if the prices could not be fetched.
+func Pricing(ctx context.Context, client *http.Client, baseURL string) (map[string]chatgpt.CostPer1k, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching models: %s", resp.Status)
+	}
+
+	var body struct {
+		Data []model `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding models: %w", err)
+	}
+
+	prices := make(map[string]chatgpt.CostPer1k, len(body.Data))
+	for _, m := range body.Data {
+		input, inputOK := per1k(m.Pricing.Prompt)
+		output, outputOK := per1k(m.Pricing.Completion)
+		if !inputOK || !outputOK {
+			continue
+		}
+
+		cached, _ := per1k(m.Pricing.InputCacheRead)
+		prices[m.ID] = chatgpt.CostPer1k{Input: input, CachedInput: cached, Output: output}
+	}
+
+	return prices, nil
+}
+
+// RunPricing fetches the prices of the models at every interval until the context is
+// cancelled and sets them with chatgpt.SetCosts, so the prices changed by OpenRouter and
+// the new models are picked up without a restart. Errors are logged and the prices set
+// before are kept.
+//
+// ctx: The context controlling the lifecycle of the updates.
+// client: The HTTP client, e.g. the one going through the proxy.
+// baseURL: The base URL of the OpenRouter API (see BaseURL).
+// interval: The interval between the updates, positive.
+func RunPricing(ctx context.Context, client *http.Client, baseURL string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+
+		prices, err := Pricing(ctx, client, baseURL)
+		if err != nil {
+			slog.Error("OpenRouter Pricing error", slog.String("error", err.Error()))
+			continue
+		}
+
+		chatgpt.SetCosts(prices)
+	}
+}
+
+// Fallback returns the static prices of the OpenAI models under their OpenRouter names,
+// e.g. "openai/gpt-4o", used while the published prices cannot be fetched.
+func Fallback() map[string]chatgpt.CostPer1k {
+	prices := make(map[string]chatgpt.CostPer1k)
+	for model, price := range chatgpt.Costs() {
+		prices["openai/"+model] = price
+	}
+
+	return prices
+}
+
+// per1k converts the price per token to the price per 1,000 tokens.
+//
+// price: The price per token as a decimal string.
+//
+// Returns false if the price is missing or not fixed.
+func per1k(price string) (chat.Cost, bool) {
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+
+	return chat.Cost(value * 1000), true
+}
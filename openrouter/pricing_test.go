@@ -0,0 +1,58 @@
+package openrouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chatgpt"
+)
+
+func TestPricing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(`{"data": [
+			{"id": "openai/gpt-4o", "pricing": {"prompt": "0.0000025", "completion": "0.00001", "input_cache_read": "0.00000125"}},
+			{"id": "meta-llama/llama-3-8b-instruct:free", "pricing": {"prompt": "0", "completion": "0"}},
+			{"id": "openrouter/auto", "pricing": {"prompt": "-1", "completion": "-1"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	prices, err := Pricing(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("Pricing failed: %s", err)
+	}
+
+	want := map[string]chatgpt.CostPer1k{
+		"openai/gpt-4o":                       {Input: 0.0025, CachedInput: 0.00125, Output: 0.01},
+		"meta-llama/llama-3-8b-instruct:free": {},
+	}
+	if len(prices) != len(want) {
+		t.Fatalf("Pricing returned %v, want %v", prices, want)
+	}
+	for id, price := range want {
+		got := prices[id]
+		if diff(got.Input, price.Input) || diff(got.CachedInput, price.CachedInput) || diff(got.Output, price.Output) {
+			t.Errorf("The price of %s is %+v, want %+v", id, got, price)
+		}
+	}
+}
+
+// diff reports whether the prices differ beyond the floating point error.
+func diff[T ~float64](a, b T) bool {
+	return a-b > 1e-12 || b-a > 1e-12
+}
+
+func TestFallback(t *testing.T) {
+	prices := Fallback()
+
+	if prices["openai/gpt-4o"] != chatgpt.GPT4oCtx128k {
+		t.Errorf("Fallback price of openai/gpt-4o is %+v, want %+v", prices["openai/gpt-4o"], chatgpt.GPT4oCtx128k)
+	}
+}
@@ -0,0 +1,124 @@
+package privacy
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifierKeys are the keys of the log attributes holding the identifiers of the
+// users and the chats, which are replaced with their pseudonyms.
+var identifierKeys = map[string]bool{
+	"userID":  true,
+	"chatID":  true,
+	"adminID": true,
+}
+
+// redactedKeys are the keys of the log attributes holding the names of the users and
+// the texts of their messages, which are removed.
+var redactedKeys = map[string]bool{
+	"username":    true,
+	"messageText": true,
+	"replyText":   true,
+	"data":        true,
+}
+
+// identifierPattern matches the numbers in the texts of the log attributes which may
+// be the identifiers of the users and the chats, such as in the paths of the stored
+// files or the keys of the locks. The shorter numbers are kept, as they are rather
+// counts, sizes and versions.
+var identifierPattern = regexp.MustCompile(`-?\d{5,}`)
+
+// redacted replaces the values of the removed log attributes.
+const redacted = "[redacted]"
+
+// logHandler is a slog.Handler which pseudonymizes the records before passing them on.
+type logHandler struct {
+	next slog.Handler
+	p    *Pseudonymizer
+}
+
+// Handler wraps the log handler, so the identifiers of the users and the chats are
+// logged as their pseudonyms, in the attributes with the identifier keys as well as
+// inside the texts of the other attributes, and the names of the users and the texts of the messages
+// are not logged.
+//
+// next: The handler receiving the pseudonymized records.
+func (p *Pseudonymizer) Handler(next slog.Handler) slog.Handler {
+	return &logHandler{next: next, p: p}
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	clean := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(h.attr(attr))
+		return true
+	})
+
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		clean[i] = h.attr(attr)
+	}
+
+	return &logHandler{next: h.next.WithAttrs(clean), p: h.p}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{next: h.next.WithGroup(name), p: h.p}
+}
+
+// attr pseudonymizes the attribute and the attributes of a group.
+func (h *logHandler) attr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	switch {
+	case value.Kind() == slog.KindGroup:
+		group := value.Group()
+		clean := make([]any, len(group))
+		for i, member := range group {
+			clean[i] = h.attr(member)
+		}
+		return slog.Group(attr.Key, clean...)
+
+	case identifierKeys[attr.Key] && value.Kind() == slog.KindInt64:
+		return slog.Int64(attr.Key, h.p.ID(value.Int64()))
+
+	case redactedKeys[attr.Key]:
+		return slog.String(attr.Key, redacted)
+
+	case value.Kind() == slog.KindString:
+		return slog.String(attr.Key, h.text(value.String()))
+	}
+
+	return attr
+}
+
+// text replaces the numbers in the text which may be identifiers with their
+// pseudonyms. The pseudonyms are kept, and a minus before a number which is not a
+// pseudonym is kept as a separator, as in the names of the files. The numbers too
+// long to be identifiers are redacted.
+func (h *logHandler) text(s string) string {
+	return identifierPattern.ReplaceAllStringFunc(s, func(number string) string {
+		if id, err := strconv.ParseInt(number, 10, 64); err == nil && IsPseudonym(id) {
+			return number
+		}
+
+		digits := strings.TrimPrefix(number, "-")
+		id, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return number[:len(number)-len(digits)] + redacted
+		}
+
+		return number[:len(number)-len(digits)] + strconv.FormatInt(h.p.ID(id), 10)
+	})
+}
@@ -0,0 +1,81 @@
+package privacy
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPseudonym(t *testing.T) {
+	p := NewPseudonymizer("salt")
+
+	for _, id := range []int64{0, 1, 123456789, -1001234567890} {
+		pseudonym := p.ID(id)
+		if !IsPseudonym(pseudonym) || IsPseudonym(id) {
+			t.Errorf("ID(%d) = %d is not recognized as a pseudonym", id, pseudonym)
+		}
+		if p.ID(id) != pseudonym || p.ID(pseudonym) != pseudonym {
+			t.Errorf("ID(%d) is not stable", id)
+		}
+		if NewPseudonymizer("other").ID(id) == pseudonym {
+			t.Errorf("ID(%d) does not depend on the salt", id)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	p := NewPseudonymizer("salt")
+
+	var buf bytes.Buffer
+	logger := slog.New(p.Handler(slog.NewTextHandler(&buf, nil)))
+	logger.With(slog.Int64("chatID", 42)).Info(
+		"handleMessage started",
+		slog.Int64("userID", 7),
+		slog.String("messageText", "My name is John"),
+		slog.Int("messageID", 3),
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"chatID=42", "userID=7", "John"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("The log %q contains %q", out, leaked)
+		}
+	}
+	for _, kept := range []string{"userID=" + strconv.FormatInt(p.ID(7), 10), "messageID=3"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("The log %q does not contain %q", out, kept)
+		}
+	}
+}
+
+func TestHandlerTextIdentifiers(t *testing.T) {
+	p := NewPseudonymizer("salt")
+	pseudonym := func(id int64) string { return strconv.FormatInt(p.ID(id), 10) }
+
+	var buf bytes.Buffer
+	logger := slog.New(p.Handler(slog.NewTextHandler(&buf, nil)))
+	logger.Info(
+		"lock released",
+		slog.String("lock", "tgpt:lock:"+pseudonym(123456789)+":100123456:gpt-4o"),
+		slog.String("path", "/data/12/123456789/statistics-123456789-100123456-gpt-4o.json"),
+		slog.String("error", "open lock-123456789-0-gpt-4o.lock: file exists"),
+	)
+
+	// Assert: the identifiers are pseudonymized wherever they appear, the pseudonyms
+	// and the short numbers are kept.
+	out := buf.String()
+	if strings.Contains(out, "123456789") || strings.Contains(out, "100123456") {
+		t.Errorf("The log %q contains the identifiers", out)
+	}
+	for _, kept := range []string{
+		"tgpt:lock:" + pseudonym(123456789) + ":" + pseudonym(100123456) + ":gpt-4o",
+		"/data/12/" + pseudonym(123456789) + "/statistics-" + pseudonym(123456789),
+		"lock-" + pseudonym(123456789) + "-0-gpt-4o.lock",
+	} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("The log %q does not contain %q", out, kept)
+		}
+	}
+}
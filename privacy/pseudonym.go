@@ -0,0 +1,52 @@
+// Package privacy provides the pseudonymization of the user identifiers for the
+// deployments which must not store or log them.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// pseudonymBits is the number of the bits of a pseudonym which come from the hash.
+// The pseudonyms lie below -2^62, far from the identifiers used by Telegram, so they
+// are never mistaken for real ones.
+const pseudonymBits = 62
+
+// Pseudonymizer replaces the identifiers of the users and the chats with salted hashes.
+// The same identifier always gets the same pseudonym, so the pseudonymous data stays
+// linked, but the identifier cannot be recovered from the pseudonym without the salt.
+type Pseudonymizer struct {
+	salt []byte
+}
+
+// NewPseudonymizer creates a Pseudonymizer with the secret salt.
+//
+// salt: The secret salt of the hashes; changing it changes all pseudonyms.
+func NewPseudonymizer(salt string) *Pseudonymizer {
+	return &Pseudonymizer{salt: []byte(salt)}
+}
+
+// ID returns the pseudonym of the identifier of a user or a chat. A pseudonym is
+// returned as it is.
+//
+// id: The identifier.
+func (p *Pseudonymizer) ID(id int64) int64 {
+	if IsPseudonym(id) {
+		return id
+	}
+
+	mac := hmac.New(sha256.New, p.salt)
+	binary.Write(mac, binary.BigEndian, id)
+	sum := binary.BigEndian.Uint64(mac.Sum(nil))
+
+	return math.MinInt64 + int64(sum>>(64-pseudonymBits))
+}
+
+// IsPseudonym reports whether the identifier is a pseudonym made by a Pseudonymizer.
+//
+// id: The identifier.
+func IsPseudonym(id int64) bool {
+	return id < math.MinInt64+1<<pseudonymBits
+}
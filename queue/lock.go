@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/redis/go-redis/v9"
+)
+
+// ensure that the Locker implements the chat.Locker interface
+var _ chat.Locker = (*Locker)(nil)
+
+// lockPollInterval is how often a held lock is checked while waiting for it.
+const lockPollInterval = 100 * time.Millisecond
+
+// Locker locks the chat sessions with keys in Redis, so the instances of the bot sharing
+// the storage exclude each other wherever they run, whatever the storage backend. A lock
+// is held with a random token and renewed while it is held; the lock of a crashed
+// instance expires after the TTL.
+type Locker struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// Locker returns the locker of the chat sessions sharing the connection of the queue.
+//
+// ttl: How long the lock of a crashed instance is kept; zero means the locks never expire.
+func (r *Redis) Locker(ttl time.Duration) *Locker {
+	return &Locker{client: r.client, prefix: r.name + ":lock:", ttl: ttl}
+}
+
+// Lock waits until the key of the session can be set and keeps renewing it until the
+// returned function is called.
+//
+// ctx: The context to stop waiting for the lock.
+// id: The ID of the session to lock.
+//
+// Returns:
+// func(): The function releasing the lock.
+// error: An error if the context is done first or Redis could not be reached.
+func (l *Locker) Lock(ctx context.Context, id chat.ID) (func(), error) {
+	key := fmt.Sprintf("%s%d:%d:%s", l.prefix, id.User, id.Chat, id.Model)
+
+	// The token tells this lock from the one of another instance which took it over.
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ok, err := l.client.SetNX(ctx, key, token, l.ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("could not lock the session: %w", err)
+		}
+
+		if ok {
+			renewCtx, cancel := context.WithCancel(context.Background())
+			if l.ttl > 0 {
+				go l.renew(renewCtx, key, token)
+			}
+
+			return func() {
+				cancel()
+				if err := releaseScript.Run(context.Background(), l.client, []string{key}, token).Err(); err != nil {
+					slog.Error("session unlock error", slog.String("lock", key), slog.String("error", err.Error()))
+				}
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not lock the session: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// renew extends the lock until the context is done or the lock is lost.
+//
+// ctx: The context of the held lock.
+// key: The key of the lock.
+// token: The token the lock is held with.
+func (l *Locker) renew(ctx context.Context, key, token string) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(ctx, l.client, []string{key}, token, l.ttl.Milliseconds()).Int()
+		if err != nil {
+			// The lock is kept until it expires, the renewal is tried again.
+			if !errors.Is(err, context.Canceled) {
+				slog.Error("session lock renew error", slog.String("lock", key), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		if renewed == 0 {
+			slog.Warn("session lock lost", slog.String("lock", key))
+			return
+		}
+	}
+}
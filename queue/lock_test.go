@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestLocker(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	queue, server := newTestRedis(t, 1)
+	locker := queue.Locker(300 * time.Millisecond)
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	unlock, err := locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock failed: %s", err)
+	}
+
+	// Execute: the lock is held longer than its TTL, the renewal keeps it.
+	for i := 0; i < 3; i++ {
+		server.FastForward(200 * time.Millisecond)
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 3*lockPollInterval)
+	defer cancel()
+	if _, err := locker.Lock(waitCtx, id); err == nil {
+		t.Fatal("Locked a session which is already locked")
+	}
+
+	// Another session is not affected.
+	unlockOther, err := locker.Lock(ctx, chat.ID{User: 1, Chat: 3, Model: "gpt-4"})
+	if err != nil {
+		t.Fatalf("Lock of another session failed: %s", err)
+	}
+	unlockOther()
+
+	// Assert: the released lock can be taken again.
+	unlock()
+	unlock, err = locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock after unlock failed: %s", err)
+	}
+	unlock()
+}
+
+func TestLockerExpiry(t *testing.T) {
+	// Setup: a lock of a crashed instance, which is not renewed.
+	ctx := context.Background()
+	queue, server := newTestRedis(t, 1)
+	locker := queue.Locker(time.Minute)
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	if err := server.Set(DefaultName+":lock:1:2:gpt-4", "crashed"); err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+	server.SetTTL(DefaultName+":lock:1:2:gpt-4", time.Minute)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 3*lockPollInterval)
+	defer cancel()
+	if _, err := locker.Lock(waitCtx, id); err == nil {
+		t.Fatal("Took over a lock before it expired")
+	}
+
+	// Execute.
+	server.FastForward(time.Minute)
+	unlock, err := locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock after the expiry failed: %s", err)
+	}
+
+	// Assert: releasing the lock does not remove a lock taken over meanwhile.
+	if err := server.Set(DefaultName+":lock:1:2:gpt-4", "other"); err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+	unlock()
+
+	if value, err := server.Get(DefaultName + ":lock:1:2:gpt-4"); err != nil || value != "other" {
+		t.Errorf("The lock of the other instance is %q, %v after the unlock", value, err)
+	}
+}
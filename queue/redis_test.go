@@ -0,0 +1,444 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newTestRedis returns a queue connected to an in-process Redis server.
+func newTestRedis(t *testing.T, partitions int) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	return connectTestRedis(t, server, partitions), server
+}
+
+// connectTestRedis returns another instance of the queue on the server, e.g. a worker.
+func connectTestRedis(t *testing.T, server *miniredis.Miniredis, partitions int) *Redis {
+	t.Helper()
+
+	queue, err := NewRedis(context.Background(), "redis://"+server.Addr(), DefaultName, partitions)
+	if err != nil {
+		t.Fatalf("NewRedis failed: %s", err)
+	}
+	t.Cleanup(func() { queue.Close() })
+
+	// Notice the stops, rebalance and take over the updates of a crashed worker quickly.
+	queue.popWait = time.Second
+	queue.balance = 20 * time.Millisecond
+	queue.settle = 200 * time.Millisecond
+
+	return queue
+}
+
+// newUpdate returns the update with the text message of the user.
+func newUpdate(id int, user int64) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: id,
+		Message: &tgbotapi.Message{
+			From: &tgbotapi.User{ID: user},
+			Chat: &tgbotapi.Chat{ID: user},
+			Text: "hi",
+		},
+	}
+}
+
+// receive returns the next consumed update. A stopped consumer may take its pop timeout
+// to notice it, so the next consumer may wait as long.
+func receive(t *testing.T, updates tgbotapi.UpdatesChannel) tgbotapi.Update {
+	t.Helper()
+
+	select {
+	case update := <-updates:
+		return update
+	case <-time.After(3 * time.Second):
+		t.Fatal("No update was consumed")
+		return tgbotapi.Update{}
+	}
+}
+
+// publish publishes the updates with the IDs from first to last, each of the user with
+// the same ID.
+func publish(t *testing.T, queue *Redis, first, last int) {
+	t.Helper()
+
+	for id := first; id <= last; id++ {
+		if err := queue.Publish(context.Background(), newUpdate(id, int64(id))); err != nil {
+			t.Fatalf("Publish failed: %s", err)
+		}
+	}
+}
+
+// leases returns the number of the partitions held by each token.
+func leases(server *miniredis.Miniredis, partitions int) map[string]int {
+	held := make(map[string]int)
+	for partition := 0; partition < partitions; partition++ {
+		if token, err := server.Get(fmt.Sprintf("%s:%d:lease", DefaultName, partition)); err == nil {
+			held[token]++
+		}
+	}
+
+	return held
+}
+
+// listLength returns the length of the list, zero if it does not exist.
+func listLength(t *testing.T, server *miniredis.Miniredis, key string) int {
+	t.Helper()
+
+	if !server.Exists(key) {
+		return 0
+	}
+
+	list, err := server.List(key)
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+
+	return len(list)
+}
+
+func TestPublishPartitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions int
+		update     tgbotapi.Update
+		wantList   string
+	}{
+		{
+			name:       "single partition uses the name",
+			partitions: 1,
+			update:     newUpdate(1, 3),
+			wantList:   DefaultName,
+		},
+		{
+			name:       "partition of the user",
+			partitions: 2,
+			update:     newUpdate(1, 3),
+			wantList:   DefaultName + ":1",
+		},
+		{
+			name:       "partition of the chat without a sender",
+			partitions: 3,
+			update:     tgbotapi.Update{UpdateID: 1, ChannelPost: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: -100}}},
+			wantList:   DefaultName + ":1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, server := newTestRedis(t, tt.partitions)
+
+			if err := queue.Publish(context.Background(), tt.update); err != nil {
+				t.Fatalf("Publish failed: %s", err)
+			}
+
+			list, err := server.List(tt.wantList)
+			if err != nil || len(list) != 1 {
+				t.Fatalf("The list %s holds %q, %v, want the update", tt.wantList, list, err)
+			}
+
+			var published tgbotapi.Update
+			if err := json.Unmarshal([]byte(list[0]), &published); err != nil || published.UpdateID != tt.update.UpdateID {
+				t.Errorf("The list holds the update %d, %v, want %d", published.UpdateID, err, tt.update.UpdateID)
+			}
+		})
+	}
+}
+
+func TestUpdatesAck(t *testing.T) {
+	// Setup.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 1)
+	worker := connectTestRedis(t, server, 1)
+	for id := 1; id <= 2; id++ {
+		if err := receiver.Publish(ctx, newUpdate(id, 1)); err != nil {
+			t.Fatalf("Publish failed: %s", err)
+		}
+	}
+
+	// Execute: the updates are consumed in the order they were published.
+	updates := worker.Updates(ctx)
+	for id := 1; id <= 2; id++ {
+		if update := receive(t, updates); update.UpdateID != id {
+			t.Fatalf("Consumed the update %d, want %d", update.UpdateID, id)
+		}
+	}
+
+	// Assert: the consumed updates are kept until they are acknowledged.
+	if n := listLength(t, server, DefaultName+":processing"); n != 2 {
+		t.Fatalf("%d updates are processing, want 2", n)
+	}
+
+	if err := worker.Ack(ctx, 1); err != nil {
+		t.Fatalf("Ack failed: %s", err)
+	}
+	if err := worker.Ack(ctx, 1); err != nil {
+		t.Fatalf("Ack of an acknowledged update failed: %s", err)
+	}
+
+	list, err := server.List(DefaultName + ":processing")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("The processing list holds %q, %v, want the update 2", list, err)
+	}
+
+	var update tgbotapi.Update
+	if err := json.Unmarshal([]byte(list[0]), &update); err != nil || update.UpdateID != 2 {
+		t.Errorf("The processing list holds the update %d, %v, want 2", update.UpdateID, err)
+	}
+}
+
+func TestUpdatesRedeliveredAfterCrash(t *testing.T) {
+	// Setup: a worker consumes the updates and crashes before acknowledging them, and
+	// its lease is left behind.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 1)
+	crashed := connectTestRedis(t, server, 1)
+	for id := 1; id <= 3; id++ {
+		if err := receiver.Publish(ctx, newUpdate(id, 1)); err != nil {
+			t.Fatalf("Publish failed: %s", err)
+		}
+	}
+
+	crashCtx, crash := context.WithCancel(ctx)
+	updates := crashed.Updates(crashCtx)
+	receive(t, updates)
+	receive(t, updates)
+
+	crashed.Close()
+	crash()
+
+	if err := crashed.Ack(ctx, 1); err == nil {
+		t.Fatal("The crashed worker acknowledged an update")
+	}
+
+	// Execute: another worker takes over the partition once the lease expires.
+	server.FastForward(leaseTTL)
+	worker := connectTestRedis(t, server, 1)
+	updates = worker.Updates(ctx)
+
+	// Assert: the unacknowledged updates are delivered again first, in order.
+	for id := 1; id <= 3; id++ {
+		update := receive(t, updates)
+		if update.UpdateID != id {
+			t.Fatalf("Consumed the update %d, want %d", update.UpdateID, id)
+		}
+		if err := worker.Ack(ctx, id); err != nil {
+			t.Fatalf("Ack failed: %s", err)
+		}
+	}
+
+	if n := listLength(t, server, DefaultName+":processing"); n != 0 {
+		t.Errorf("%d updates are processing after the acknowledgements, want none", n)
+	}
+}
+
+func TestUpdatesHandedOverOnStop(t *testing.T) {
+	// Setup: a worker holds the partition, the other one stands by.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 1)
+	stopped := connectTestRedis(t, server, 1)
+	standby := connectTestRedis(t, server, 1)
+
+	stopCtx, stop := context.WithCancel(ctx)
+	stoppedUpdates := stopped.Updates(stopCtx)
+	if err := receiver.Publish(ctx, newUpdate(1, 1)); err != nil {
+		t.Fatalf("Publish failed: %s", err)
+	}
+	receive(t, stoppedUpdates)
+
+	standbyCtx, stopStandby := context.WithCancel(ctx)
+	standbyUpdates := standby.Updates(standbyCtx)
+	if err := receiver.Publish(ctx, newUpdate(2, 1)); err != nil {
+		t.Fatalf("Publish failed: %s", err)
+	}
+	receive(t, stoppedUpdates)
+
+	select {
+	case update := <-standbyUpdates:
+		t.Fatalf("The standby worker consumed the update %d of a held partition", update.UpdateID)
+	case <-time.After(100 * time.Millisecond):
+	}
+	stopStandby()
+
+	// Execute: the worker stops without acknowledging the update 2 and releases its lease.
+	if err := stopped.Ack(ctx, 1); err != nil {
+		t.Fatalf("Ack failed: %s", err)
+	}
+	stop()
+
+	// The worker notices the stop once its pop times out.
+	deadline := time.Now().Add(3 * time.Second)
+	for server.Exists(DefaultName + ":lease") {
+		if time.Now().After(deadline) {
+			t.Fatal("The stopped worker did not release its lease")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Assert: the next worker takes over at once and processes the update again.
+	worker := connectTestRedis(t, server, 1)
+	if update := receive(t, worker.Updates(ctx)); update.UpdateID != 2 {
+		t.Errorf("Consumed the update %d, want the unacknowledged update 2", update.UpdateID)
+	}
+}
+
+func TestUpdatesSharedBetweenWorkers(t *testing.T) {
+	// Setup: a single worker consumes all the partitions.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 4)
+	first := connectTestRedis(t, server, 4)
+	firstUpdates := first.Updates(ctx)
+
+	publish(t, receiver, 1, 4)
+	var ids []int
+	for i := 0; i < 4; i++ {
+		ids = append(ids, receive(t, firstUpdates).UpdateID)
+	}
+	sort.Ints(ids)
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Fatalf("Consumed the updates %v, want all of them", ids)
+	}
+
+	// Execute: another worker starts.
+	second := connectTestRedis(t, server, 4)
+	secondUpdates := second.Updates(ctx)
+
+	// The first worker leaves the partitions once its pops time out.
+	balanced := func(held map[string]int) bool {
+		for _, n := range held {
+			if n != 2 {
+				return false
+			}
+		}
+		return len(held) == 2
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for held := leases(server, 4); !balanced(held); held = leases(server, 4) {
+		if time.Now().After(deadline) {
+			t.Fatalf("The workers hold %v partitions, want two each", held)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Assert: each worker consumes the updates of its half of the partitions.
+
+	publish(t, receiver, 5, 8)
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		select {
+		case <-firstUpdates:
+			counts["first"]++
+		case <-secondUpdates:
+			counts["second"]++
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Consumed %v updates, want 4", counts)
+		}
+	}
+	if counts["first"] != 2 || counts["second"] != 2 {
+		t.Errorf("The workers consumed %v updates, want 2 each", counts)
+	}
+}
+
+func TestUpdatesAcknowledgedDuringTakeover(t *testing.T) {
+	// Setup: a worker leaves the partition while it still processes the update 1.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 1)
+	left := connectTestRedis(t, server, 1)
+
+	leftCtx, leave := context.WithCancel(ctx)
+	publish(t, receiver, 1, 1)
+	receive(t, left.Updates(leftCtx))
+	leave()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for server.Exists(DefaultName + ":lease") {
+		if time.Now().After(deadline) {
+			t.Fatal("The worker did not release its lease")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Execute: the next worker waits for the update, which is acknowledged meanwhile.
+	worker := connectTestRedis(t, server, 1)
+	worker.settle = time.Minute
+	updates := worker.Updates(ctx)
+	publish(t, receiver, 2, 2)
+
+	select {
+	case update := <-updates:
+		t.Fatalf("Consumed the update %d while the update 1 is processed", update.UpdateID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := left.Ack(ctx, 1); err != nil {
+		t.Fatalf("Ack failed: %s", err)
+	}
+
+	// Assert: the acknowledged update is not delivered again.
+	if update := receive(t, updates); update.UpdateID != 2 {
+		t.Errorf("Consumed the update %d, want 2", update.UpdateID)
+	}
+}
+
+func TestUpdatesAcknowledgedNotDeliveredAgain(t *testing.T) {
+	// Setup: the update 1 was delivered again after it was acknowledged.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiver, server := newTestRedis(t, 1)
+	publish(t, receiver, 1, 2)
+	if _, err := server.ZAdd(DefaultName+":done", 1, "1"); err != nil {
+		t.Fatalf("ZAdd failed: %s", err)
+	}
+
+	// Execute.
+	worker := connectTestRedis(t, server, 1)
+	update := receive(t, worker.Updates(ctx))
+
+	// Assert.
+	if update.UpdateID != 2 {
+		t.Fatalf("Consumed the update %d, want 2", update.UpdateID)
+	}
+	if n := listLength(t, server, DefaultName+":processing"); n != 1 {
+		t.Errorf("%d updates are processing, want the update 2 only", n)
+	}
+}
+
+func TestUpdatesAdoptedIntoPartitions(t *testing.T) {
+	// Setup: an update was queued before the queue was partitioned.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	legacy, server := newTestRedis(t, 1)
+	publish(t, legacy, 1, 1)
+
+	// Execute.
+	worker := connectTestRedis(t, server, 2)
+	update := receive(t, worker.Updates(ctx))
+
+	// Assert.
+	if update.UpdateID != 1 {
+		t.Errorf("Consumed the update %d, want 1", update.UpdateID)
+	}
+	if n := listLength(t, server, DefaultName); n != 0 {
+		t.Errorf("%d updates are left in the list without partitions", n)
+	}
+}
@@ -0,0 +1,167 @@
+// Package rates provides exchange rates fetched from an HTTP provider, used to
+// display the costs in the configured currency.
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultURL is the endpoint of the default exchange rate provider. It returns the
+// rates of all currencies relative to USD, the currency of the model prices.
+const DefaultURL = "https://open.er-api.com/v6/latest/USD"
+
+// requestTimeout limits the duration of a single rate request.
+const requestTimeout = time.Second * 30
+
+// Updater periodically fetches the exchange rate of a currency from a provider
+// and caches it. Until the first successful fetch, or if the rate could not be
+// fetched, the fallback rate is used.
+//
+// The provider must respond with a JSON object containing a "rates" object which
+// maps currency codes to their rates, e.g. {"rates": {"EUR": 0.92, "RUB": 92.5}}.
+type Updater struct {
+	// client is the HTTP client used to query the provider.
+	client *http.Client
+
+	// url is the endpoint of the exchange rate provider.
+	url string
+
+	// currency is the code of the currency to fetch the rate of (e.g., "RUB").
+	currency string
+
+	// interval specifies how often the rate is fetched.
+	interval time.Duration
+
+	// mu protects rate and updated.
+	mu sync.RWMutex
+
+	// rate is the cached exchange rate.
+	rate float64
+
+	// updated records the time the rate was fetched, zero if the fallback is used.
+	updated time.Time
+}
+
+// NewUpdater creates a new Updater.
+//
+// url: The endpoint of the exchange rate provider (see DefaultURL).
+// currency: The code of the currency to fetch the rate of (e.g., "RUB").
+// fallback: The rate used until the first successful fetch.
+// interval: How often the rate is fetched by Run; zero or less fetches it only once.
+//
+// Returns a pointer to the newly created Updater.
+func NewUpdater(url, currency string, fallback float64, interval time.Duration) *Updater {
+	return &Updater{
+		client:   &http.Client{Timeout: requestTimeout},
+		url:      url,
+		currency: currency,
+		interval: interval,
+		rate:     fallback,
+	}
+}
+
+// Run fetches the rate immediately and then at every interval until the context
+// is cancelled. Errors are logged and the last known rate is kept. It returns after
+// the first fetch if the periodic fetching is disabled.
+//
+// ctx: The context controlling the lifecycle of the updater.
+func (u *Updater) Run(ctx context.Context) {
+	if u.interval <= 0 {
+		u.refresh(ctx)
+		return
+	}
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		u.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches the rate and logs the error, if any.
+//
+// ctx: The context for the request.
+func (u *Updater) refresh(ctx context.Context) {
+	if err := u.Refresh(ctx); err != nil {
+		slog.Error(
+			"Updater Refresh error",
+			slog.String("currency", u.currency),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Refresh fetches the rate from the provider and caches it.
+//
+// ctx: The context for the request.
+//
+// Returns an error if the rate could not be fetched.
+func (u *Updater) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("error decoding rates: %w", err)
+	}
+
+	rate, exists := body.Rates[u.currency]
+	if !exists || rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return fmt.Errorf("no valid rate for currency %q", u.currency)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rate = rate
+	u.updated = time.Now()
+
+	return nil
+}
+
+// Rate returns the last fetched rate, or the fallback rate if no rate has been
+// fetched yet.
+func (u *Updater) Rate() float64 {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.rate
+}
+
+// Updated returns the time the rate was last fetched, or the zero time if the
+// fallback rate is in use.
+func (u *Updater) Updated() time.Time {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.updated
+}
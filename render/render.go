@@ -0,0 +1,151 @@
+// Package render turns the parts of model answers which Telegram cannot display,
+// such as LaTeX formulas, into images.
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout limits the duration of a single render request.
+const requestTimeout = time.Second * 30
+
+// maxImageSize limits the size of a rendered image.
+const maxImageSize = 10 << 20
+
+// Kind identifies the language of a rendered block.
+type Kind string
+
+// Supported kinds of blocks.
+const (
+	KindLaTeX    Kind = "latex"    // KindLaTeX is a display LaTeX formula: $$...$$ or \[...\].
+	KindMermaid  Kind = "mermaid"  // KindMermaid is a Mermaid diagram in a mermaid code block.
+	KindGraphviz Kind = "graphviz" // KindGraphviz is a Graphviz diagram in a dot or graphviz code block.
+)
+
+// Part is a piece of an answer: either text to send as is, or a block to render.
+type Part struct {
+	Kind   Kind   // Kind is the language of the block; empty for text.
+	Source string // Source is the text, or the source of the block.
+}
+
+// Renderer renders the source of a block into a PNG image.
+type Renderer interface {
+	// Render renders the source into a PNG image.
+	//
+	// ctx: The context for the rendering.
+	// source: The source of the block, e.g., a LaTeX formula.
+	//
+	// Returns the PNG image and an error if the source could not be rendered.
+	Render(ctx context.Context, source string) ([]byte, error)
+}
+
+// DefaultLaTeXURL is the default endpoint of the LaTeX rendering service. The URL-escaped
+// formula is appended to it.
+const DefaultLaTeXURL = `https://latex.codecogs.com/png.image?%5Cdpi%7B200%7D%5Cbg%7Bwhite%7D`
+
+// LaTeX renders formulas with an HTTP service which returns the image of the formula
+// appended to its URL, such as CodeCogs.
+type LaTeX struct {
+	client *http.Client
+	url    string
+}
+
+// NewLaTeX creates a new LaTeX renderer.
+//
+// endpoint: The endpoint of the rendering service (see DefaultLaTeXURL).
+//
+// Returns a pointer to the newly created LaTeX renderer.
+func NewLaTeX(endpoint string) *LaTeX {
+	return &LaTeX{
+		client: &http.Client{Timeout: requestTimeout},
+		url:    endpoint,
+	}
+}
+
+// Render renders the formula into an image.
+//
+// ctx: The context for the request.
+// source: The LaTeX formula without the delimiters.
+func (l *LaTeX) Render(ctx context.Context, source string) ([]byte, error) {
+	source = strings.Join(strings.Fields(source), " ")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url+strings.ReplaceAll(url.QueryEscape(source), "+", "%20"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	return fetch(l.client, req)
+}
+
+// DefaultKrokiURL is the endpoint of the public Kroki diagram rendering service.
+const DefaultKrokiURL = "https://kroki.io"
+
+// Kroki renders diagrams with a Kroki server (https://kroki.io), which supports many
+// diagram languages including Mermaid and Graphviz.
+type Kroki struct {
+	client *http.Client
+	url    string
+	kind   Kind
+}
+
+// NewKroki creates a new Kroki renderer for the diagrams of the given kind.
+//
+// endpoint: The endpoint of the Kroki server (see DefaultKrokiURL).
+// kind: The kind of the diagrams, KindMermaid or KindGraphviz.
+//
+// Returns a pointer to the newly created Kroki renderer.
+func NewKroki(endpoint string, kind Kind) *Kroki {
+	return &Kroki{
+		client: &http.Client{Timeout: requestTimeout},
+		url:    strings.TrimSuffix(endpoint, "/"),
+		kind:   kind,
+	}
+}
+
+// Render renders the diagram into an image.
+//
+// ctx: The context for the request.
+// source: The source of the diagram.
+func (k *Kroki) Render(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url+"/"+string(k.kind)+"/png", strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	return fetch(k.client, req)
+}
+
+// fetch executes the request and returns the image in the response.
+func fetch(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error rendering: %s", resp.Status)
+	}
+
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading image: %w", err)
+	}
+
+	if len(image) > maxImageSize {
+		return nil, fmt.Errorf("error reading image: larger than %d bytes", maxImageSize)
+	}
+
+	if contentType := http.DetectContentType(image); !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("error rendering: unexpected content type %s", contentType)
+	}
+
+	return image, nil
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"slices"
+	"strings"
+)
+
+// delimiters maps the opening delimiters of the display formulas to the closing ones.
+var delimiters = []struct{ open, close string }{
+	{open: "$$", close: "$$"},
+	{open: `\[`, close: `\]`},
+}
+
+// fences maps the languages of the code blocks to the kinds of the diagrams.
+var fences = map[string]Kind{
+	"mermaid":  KindMermaid,
+	"dot":      KindGraphviz,
+	"graphviz": KindGraphviz,
+}
+
+// Split splits the text into the text parts and the blocks of the given kinds. The
+// code blocks are never split, so formulas in code examples are kept as text. A diagram
+// code block is kept in the text, and the diagram follows it as a separate block. Empty
+// text parts are omitted.
+//
+// text: The text to split.
+// kinds: The kinds of blocks to extract.
+func Split(text string, kinds ...Kind) []Part {
+	var (
+		parts  []Part
+		buf    strings.Builder
+		inCode bool
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			parts = append(parts, Part{Source: s})
+		}
+		buf.Reset()
+	}
+
+	latex := slices.Contains(kinds, KindLaTeX)
+
+	var (
+		diagram Kind
+		source  []string
+	)
+
+	for rest := text; rest != ""; {
+		line, next, _ := strings.Cut(rest, "\n")
+
+		if fence := strings.TrimSpace(line); strings.HasPrefix(fence, "```") {
+			if inCode && diagram != "" {
+				// The diagram follows its code block.
+				buf.WriteString(line + "\n")
+				flush()
+				parts = append(parts, Part{Kind: diagram, Source: strings.Join(source, "\n")})
+				diagram, source = "", nil
+				inCode = false
+				rest = next
+				continue
+			}
+
+			if !inCode {
+				if kind, ok := fences[strings.ToLower(strings.TrimPrefix(fence, "```"))]; ok && slices.Contains(kinds, kind) {
+					diagram = kind
+				}
+			}
+
+			inCode = !inCode
+		} else if diagram != "" {
+			source = append(source, line)
+		}
+
+		if !inCode && latex {
+			if source, remaining, ok := cutFormula(rest); ok {
+				flush()
+				parts = append(parts, Part{Kind: KindLaTeX, Source: source})
+				rest = remaining
+				continue
+			}
+		}
+
+		buf.WriteString(line)
+		if next != "" || strings.HasSuffix(rest, "\n") {
+			buf.WriteString("\n")
+		}
+		rest = next
+	}
+	flush()
+
+	return parts
+}
+
+// cutFormula extracts a display formula at the beginning of a line of the text.
+//
+// text: The text starting with the line to check.
+//
+// Returns the source of the formula, the text after it, and false if the line does
+// not start a formula.
+func cutFormula(text string) (string, string, bool) {
+	trimmed := strings.TrimLeft(text, " \t")
+
+	for _, d := range delimiters {
+		if !strings.HasPrefix(trimmed, d.open) {
+			continue
+		}
+
+		body := trimmed[len(d.open):]
+		end := strings.Index(body, d.close)
+		if end < 0 {
+			return "", "", false
+		}
+
+		source := strings.TrimSpace(body[:end])
+		if source == "" {
+			return "", "", false
+		}
+
+		return source, strings.TrimLeft(body[end+len(d.close):], " \t\n"), true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	text := "The area is\n$$\nA = \\pi r^2\n$$\nwhere r is the radius.\n\\[ E = mc^2 \\]\n```\n$$ not a formula $$\n```"
+
+	want := []Part{
+		{Source: "The area is"},
+		{Kind: KindLaTeX, Source: `A = \pi r^2`},
+		{Source: "where r is the radius."},
+		{Kind: KindLaTeX, Source: "E = mc^2"},
+		{Source: "```\n$$ not a formula $$\n```"},
+	}
+
+	if got := Split(text, KindLaTeX); !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %#v, want %#v", got, want)
+	}
+
+	if got := Split(text); len(got) != 1 || got[0].Source != text {
+		t.Errorf("Split() without kinds = %#v, want the whole text", got)
+	}
+}
+
+func TestSplitDiagrams(t *testing.T) {
+	text := "Flow:\n```mermaid\ngraph TD\n  A-->B\n```\nDone."
+
+	want := []Part{
+		{Source: "Flow:\n```mermaid\ngraph TD\n  A-->B\n```"},
+		{Kind: KindMermaid, Source: "graph TD\n  A-->B"},
+		{Source: "Done."},
+	}
+
+	if got := Split(text, KindMermaid); !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+// Package replay provides an HTTP transport which records the responses to disk and
+// replays them deterministically, for offline development and regression tests of
+// the OpenAI requests.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+)
+
+// Mode selects how the transport handles the requests.
+type Mode string
+
+const (
+	// ModeRecord sends the requests and records the responses.
+	ModeRecord Mode = "record"
+	// ModeReplay answers the requests with the recorded responses only.
+	ModeReplay Mode = "replay"
+	// ModeAuto replays the recorded responses and records the missing ones.
+	ModeAuto Mode = "auto"
+)
+
+// ErrNotRecorded is returned in the replay mode for a request without a recorded response.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Transport is an http.RoundTripper which records the responses to the requests in a
+// directory and replays them. A request is identified by its method, URL and body,
+// so the headers, including the credentials, neither affect the replay nor are recorded.
+// The requests with random content, such as multipart uploads, are not replayed.
+type Transport struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+}
+
+// NewTransport creates a recording transport.
+//
+// mode: How the requests are handled.
+// dir: The directory of the recorded responses.
+// next: The transport which sends the requests; nil means http.DefaultTransport.
+func NewTransport(mode Mode, dir string, next http.RoundTripper) (*Transport, error) {
+	switch mode {
+	case ModeRecord, ModeReplay, ModeAuto:
+	default:
+		return nil, fmt.Errorf("unknown replay mode: %q", mode)
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{mode: mode, dir: dir, next: next}, nil
+}
+
+// RoundTrip answers the request with the recorded response, or sends it and records
+// the response, depending on the mode.
+//
+// req: The request to answer.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(t.dir, key(req, body)+".http")
+
+	if t.mode != ModeRecord {
+		resp, err := load(path, req)
+		if err == nil || t.mode == ModeReplay {
+			return resp, err
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return save(path, resp)
+}
+
+// readBody reads the body of the request and restores it for sending.
+//
+// req: The request.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	req.Body.Close()
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// key identifies the request by its method, URL and body.
+//
+// req: The request.
+// body: The body of the request.
+func key(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.String())
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// load reads the recorded response to the request.
+//
+// path: The path of the recorded response.
+// req: The request.
+func load(path string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading recorded response: %w", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing recorded response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// save records the response and returns a copy of it for the caller.
+//
+// path: The path of the recorded response.
+// resp: The response.
+func save(path string, resp *http.Response) (*http.Response, error) {
+	data, err := httputil.DumpResponse(resp, true)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating recordings directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("error writing recorded response: %w", err)
+	}
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), resp.Request)
+}
@@ -0,0 +1,60 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	post := func(mode Mode, body string) (string, error) {
+		transport, err := NewTransport(mode, dir, nil)
+		if err != nil {
+			t.Fatalf("NewTransport failed: %s", err)
+		}
+
+		client := &http.Client{Transport: transport}
+		resp, err := client.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	recorded, err := post(ModeRecord, `"hello"`)
+	if err != nil {
+		t.Fatalf("Recording failed: %s", err)
+	}
+
+	replayed, err := post(ModeReplay, `"hello"`)
+	if err != nil {
+		t.Fatalf("Replaying failed: %s", err)
+	}
+	if replayed != recorded || hits != 1 {
+		t.Errorf("Replayed %q after %d requests, want %q after 1 request", replayed, hits, recorded)
+	}
+
+	if _, err := post(ModeReplay, `"bye"`); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Replaying an unknown request returned %v, want %v", err, ErrNotRecorded)
+	}
+
+	if _, err := post(ModeAuto, `"bye"`); err != nil || hits != 2 {
+		t.Errorf("Auto mode returned %v after %d requests, want recording on the 2nd request", err, hits)
+	}
+}
@@ -0,0 +1,85 @@
+// Package sentry provides an implementation of telegram.ErrorReporter which
+// delivers bot incidents to Sentry (https://sentry.io).
+package sentry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/muzykantov/tgpt/telegram"
+)
+
+// ensure that the concrete type Reporter implements the telegram.ErrorReporter interface
+var _ telegram.ErrorReporter = (*Reporter)(nil)
+
+// Reporter sends handler errors and panics to Sentry. Every report is captured
+// in its own scope tagged with the user, chat and operation it belongs to.
+type Reporter struct {
+	hub *sentrygo.Hub // hub is the Sentry hub bound to the configured client.
+}
+
+// NewReporter creates a new Reporter for the given Sentry DSN.
+//
+// dsn: The Sentry Data Source Name of the project to report to.
+// environment: The deployment environment (e.g., "production").
+// release: The release identifier of the running bot.
+//
+// Returns:
+// *Reporter: A pointer to the newly created Reporter.
+// error: An error if the Sentry client could not be created.
+func NewReporter(dsn, environment, release string) (*Reporter, error) {
+	client, err := sentrygo.NewClient(sentrygo.ClientOptions{
+		Dsn:         dsn,
+		Environment: environment,
+		Release:     release,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating sentry client: %w", err)
+	}
+
+	return &Reporter{
+		hub: sentrygo.NewHub(client, sentrygo.NewScope()),
+	}, nil
+}
+
+// Report captures the given incident in Sentry. Panics are captured as recovered
+// values while regular errors are captured as exceptions.
+//
+// ctx: The context of the message processing that failed.
+// report: The details of the incident.
+func (r *Reporter) Report(ctx context.Context, report telegram.ErrorReport) {
+	hub := r.hub.Clone()
+
+	hub.WithScope(func(scope *sentrygo.Scope) {
+		scope.SetUser(sentrygo.User{
+			ID:       strconv.FormatInt(report.UserID, 10),
+			Username: report.Username,
+		})
+		scope.SetTag("operation", report.Operation)
+		scope.SetTag("chat_id", strconv.FormatInt(report.ChatID, 10))
+		scope.SetExtra("message_id", report.MessageID)
+		scope.SetExtra("message_text", report.MessageText)
+
+		if report.Panic != nil {
+			scope.SetExtra("stack", string(report.Stack))
+			hub.RecoverWithContext(ctx, report.Panic)
+			return
+		}
+
+		hub.CaptureException(report.Err)
+	})
+}
+
+// Flush waits until all buffered events are sent to Sentry or the timeout expires.
+// It should be called before the application exits.
+//
+// timeout: The maximum amount of time to wait.
+//
+// Returns:
+// bool: True if all events were sent before the timeout expired.
+func (r *Reporter) Flush(timeout time.Duration) bool {
+	return r.hub.Flush(timeout)
+}
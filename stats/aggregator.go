@@ -0,0 +1,121 @@
+// Package stats provides the aggregation of per-session chat statistics into
+// fleet-wide rollups used for administrative reporting.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// Aggregator periodically folds the statistics of all chat sessions into a
+// chat.Rollup and persists it in the storage, so reports can be served from
+// a single rollup instead of reading the statistics of every session.
+type Aggregator struct {
+	// storage is the storage holding the per-session statistics and the rollup.
+	storage chat.Storage
+
+	// interval specifies how often the rollup is recomputed.
+	interval time.Duration
+
+	// mu serializes the rollup computations.
+	mu sync.Mutex
+}
+
+// NewAggregator creates a new Aggregator for the given storage.
+//
+// storage: The storage holding the per-session statistics and the rollup.
+// interval: How often the rollup is recomputed by Run; zero or less disables the
+// periodic recomputation, so the rollup is recomputed whenever it is requested.
+//
+// Returns a pointer to the newly created Aggregator.
+func NewAggregator(storage chat.Storage, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		storage:  storage,
+		interval: interval,
+	}
+}
+
+// Run recomputes the rollup immediately and then at every interval until the
+// context is cancelled. Errors are logged and do not stop the aggregation. It returns
+// at once if the periodic recomputation is disabled.
+//
+// ctx: The context controlling the lifecycle of the aggregation.
+func (a *Aggregator) Run(ctx context.Context) {
+	if a.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.Refresh(ctx); err != nil {
+			slog.Error("Aggregator Refresh error", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh recomputes the rollup from the per-session statistics and persists it, if
+// the storage keeps the rollups (see chat.Rollups).
+//
+// ctx: The context for the storage operations.
+//
+// Returns the computed rollup and an error if the statistics could not be
+// aggregated or the rollup could not be saved.
+func (a *Aggregator) Refresh(ctx context.Context) (*chat.Rollup, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rollup, err := chat.Aggregate(ctx, a.storage, chat.Now().In(chat.DefaultLocation))
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating statistics: %w", err)
+	}
+
+	rollups, ok := a.storage.(chat.Rollups)
+	if !ok {
+		return rollup, nil
+	}
+
+	if err := rollups.SaveRollup(ctx, rollup); err != nil {
+		return nil, fmt.Errorf("error saving the rollup to the storage: %w", err)
+	}
+
+	return rollup, nil
+}
+
+// Rollup returns the latest persisted rollup. If the persisted rollup is missing
+// or older than the aggregation interval, the periodic recomputation is disabled, or
+// the storage does not keep the rollups, it is recomputed first.
+//
+// ctx: The context for the storage operations.
+//
+// Returns the rollup and an error if it could not be loaded or recomputed.
+func (a *Aggregator) Rollup(ctx context.Context) (*chat.Rollup, error) {
+	rollups, ok := a.storage.(chat.Rollups)
+	if !ok {
+		return a.Refresh(ctx)
+	}
+
+	rollup, err := rollups.LoadRollup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the rollup from the storage: %w", err)
+	}
+
+	if a.interval <= 0 || chat.Now().Sub(rollup.Updated) > a.interval {
+		return a.Refresh(ctx)
+	}
+
+	return rollup, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/privacy"
+)
+
+// ensure that Anonymized implements the chat.Storage interface
+var _ chat.Storage = (*Anonymized)(nil)
+
+// Anonymized is a chat.Storage which keys the histories by the pseudonyms of the users
+// and the chats, so the conversations in the storage cannot be linked to the Telegram
+// accounts without the salt. The other data, such as the statistics needed for the
+// budgets, is keyed by the real identifiers.
+//
+// The sessions are listed by their statistics; a history whose statistics have been
+// deleted is no longer listed.
+type Anonymized struct {
+	chat.Wrapper // Wrapper wraps the underlying storage, keeping its optional interfaces.
+
+	p *privacy.Pseudonymizer
+}
+
+// NewAnonymized wraps the storage with the pseudonymization of the histories.
+//
+// backend: The underlying storage.
+// p: The Pseudonymizer making the keys of the histories.
+func NewAnonymized(backend chat.Storage, p *privacy.Pseudonymizer) *Anonymized {
+	return &Anonymized{Wrapper: chat.Wrapper{Storage: backend}, p: p}
+}
+
+// historyID returns the pseudonymous key of the history of the session.
+func (s *Anonymized) historyID(id chat.ID) chat.ID {
+	return chat.ID{User: s.p.ID(id.User), Chat: s.p.ID(id.Chat), Model: id.Model}
+}
+
+// SaveHistory persists the history under the pseudonyms of its user and chat.
+func (s *Anonymized) SaveHistory(ctx context.Context, history *chat.History) error {
+	anonymous := history.Clone()
+	anonymous.ID = s.historyID(history.ID)
+
+	return s.Storage.SaveHistory(ctx, anonymous)
+}
+
+// LoadHistory retrieves the history saved under the pseudonyms of the user and the chat.
+func (s *Anonymized) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	history, err := s.Storage.LoadHistory(ctx, s.historyID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	history.ID = id
+	return history, nil
+}
+
+// DeleteHistory removes the history saved under the pseudonyms of the user and the chat.
+func (s *Anonymized) DeleteHistory(ctx context.Context, id chat.ID) error {
+	return s.Storage.DeleteHistory(ctx, s.historyID(id))
+}
+
+// List enumerates the sessions by their statistics, leaving out the pseudonymous keys
+// of the histories.
+func (s *Anonymized) List(ctx context.Context) ([]chat.ID, error) {
+	ids, err := s.Storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := ids[:0]
+	for _, id := range ids {
+		if !privacy.IsPseudonym(id.User) {
+			listed = append(listed, id)
+		}
+	}
+
+	return listed, nil
+}
+
+// EraseUser removes the remaining data kept under both the real ID and the pseudonym
+// of the user.
+func (s *Anonymized) EraseUser(ctx context.Context, user int64) error {
+	if err := s.Storage.EraseUser(ctx, user); err != nil {
+		return err
+	}
+
+	return s.Storage.EraseUser(ctx, s.p.ID(user))
+}
+
+// ensure that AnonymizedLocker implements the chat.Locker interface
+var _ chat.Locker = (*AnonymizedLocker)(nil)
+
+// AnonymizedLocker is a chat.Locker which locks the sessions under the pseudonyms of
+// their users and chats, so the keys of the locks, such as the names of the lock files,
+// cannot be linked to the Telegram accounts.
+type AnonymizedLocker struct {
+	next chat.Locker
+	p    *privacy.Pseudonymizer
+}
+
+// NewAnonymizedLocker wraps the locker with the pseudonymization of the sessions.
+//
+// next: The underlying locker.
+// p: The Pseudonymizer making the keys of the locks.
+func NewAnonymizedLocker(next chat.Locker, p *privacy.Pseudonymizer) *AnonymizedLocker {
+	return &AnonymizedLocker{next: next, p: p}
+}
+
+// Lock locks the session under the pseudonyms of its user and chat.
+func (l *AnonymizedLocker) Lock(ctx context.Context, id chat.ID) (func(), error) {
+	return l.next.Lock(ctx, chat.ID{User: l.p.ID(id.User), Chat: l.p.ID(id.Chat), Model: id.Model})
+}
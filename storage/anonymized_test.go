@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/privacy"
+)
+
+func TestAnonymized(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory()
+	store := NewAnonymized(memory, privacy.NewPseudonymizer("salt"))
+
+	id := chat.ID{User: 123, Chat: -456, Model: "gpt-4"}
+	history := &chat.History{ID: id, Log: []chat.Message{{User: "Hello", Assistant: "Hi"}}}
+	if err := store.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+	if err := store.SaveStatistics(ctx, &chat.Statistics{ID: id}); err != nil {
+		t.Fatalf("SaveStatistics: %v", err)
+	}
+
+	// The history is stored under the pseudonyms only.
+	names, err := memory.store.List(ctx, "history-")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(names) != 1 || strings.Contains(names[0], "123") || strings.Contains(names[0], "456") {
+		t.Errorf("The history is stored as %q", names)
+	}
+
+	loaded, err := store.LoadHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if loaded.ID != id || len(loaded.Log) != 1 {
+		t.Errorf("LoadHistory() = %+v, want the saved history", loaded)
+	}
+
+	ids, err := store.List(ctx)
+	if err != nil || len(ids) != 1 || ids[0] != id {
+		t.Errorf("List() = %v, %v, want only the real ID", ids, err)
+	}
+
+	if err := store.DeleteHistory(ctx, id); err != nil {
+		t.Fatalf("DeleteHistory: %v", err)
+	}
+	if names, _ := memory.store.List(ctx, "history-"); len(names) != 0 {
+		t.Errorf("The history %q was not deleted", names)
+	}
+}
+
+func TestAnonymizedLocker(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	locker := NewAnonymizedLocker(&FileLocker{BaseDir: dir}, privacy.NewPseudonymizer("salt"))
+
+	unlock, err := locker.Lock(ctx, chat.ID{User: 123456, Chat: -100456789, Model: "gpt-4"})
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	defer unlock()
+
+	// The lock file is named by the pseudonyms only.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || strings.Contains(entries[0].Name(), "123456") ||
+		strings.Contains(entries[0].Name(), "100456789") {
+		t.Errorf("The lock is stored as %v", entries)
+	}
+
+	// The same session is locked under the same pseudonyms.
+	lockCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Lock(lockCtx, chat.ID{User: 123456, Chat: -100456789, Model: "gpt-4"}); err == nil {
+		t.Error("The session was locked twice")
+	}
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFile replaces the file atomically: the content is written to a temporary file
+// in the same directory, synced to the disk and renamed over the file, so a crash in
+// the middle of a write leaves the previous version of the file intact. The directory
+// of the file is locked exclusively for the time of the write. If the storage
+// compresses the files, the content is compressed and ".gz" is added to the path; if
+// it encrypts the files, the content is encrypted after the compression. Once the
+// context is done, the writing stops and the file is not replaced.
+//
+// ctx: The context of the write.
+// path: The path of the file.
+// write: The function writing the content.
+//
+// Returns:
+// error: An error if the content could not be written or the file could not be replaced.
+func (fs *FS) writeFile(ctx context.Context, path string, write func(w io.Writer) error) (err error) {
+	unlock, err := fs.lockFilesIn(ctx, filepath.Dir(path), true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if fs.Compress {
+		write = gzipWriter(write)
+	}
+	if fs.Encryption != nil {
+		write = fs.encryptWriter(write, path)
+	}
+
+	return fs.replaceFile(ctx, path, write)
+}
+
+// replaceFile writes the content to a temporary file, syncs it and renames it over the
+// file, removing the other variant of the file. The caller must hold the exclusive lock
+// of the directory of the file and wrap the writing with the compression and the
+// encryption.
+//
+// ctx: The context of the write.
+// path: The path of the uncompressed file.
+// write: The function writing the content as it is stored.
+//
+// Returns:
+// error: An error if the content could not be written or the file could not be replaced.
+func (fs *FS) replaceFile(ctx context.Context, path string, write func(w io.Writer) error) error {
+	path, stale := fs.variants(path)
+
+	if err := replaceFile(ctx, path, 0644, write); err != nil {
+		return err
+	}
+
+	// Remove the file saved before the compression was switched.
+	switch err := os.Remove(stale); {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("could not remove the stale file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// replaceFile writes the content to a temporary file in the directory of the file, syncs
+// it, renames it over the file and syncs the directory, so a crash leaves either the
+// previous or the new content of the file, and the rename survives it. Once the context
+// is done, the writing stops and the file is not replaced.
+//
+// ctx: The context of the write.
+// path: The path of the file.
+// perm: The mode of the new file.
+// write: The function writing the content.
+//
+// Returns:
+// error: An error if the content could not be written or the file could not be replaced.
+func replaceFile(ctx context.Context, path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create the directory: %w", err)
+	}
+
+	// The temporary file does not end with ".json", so List ignores it.
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create the temporary file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	if err := write(contextWriter{ctx, file}); err != nil {
+		return err
+	}
+
+	// Do not spend time on syncing the file which would not replace the old one.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("could not sync the temporary file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close the temporary file: %w", err)
+	}
+
+	// CreateTemp makes the file readable only by the owner.
+	if err := os.Chmod(file.Name(), perm); err != nil {
+		return fmt.Errorf("could not set the file mode: %w", err)
+	}
+
+	// The rename is the point of no return.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("could not replace the file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs the directory to the disk, so the files renamed or removed in it stay
+// renamed or removed after a crash.
+//
+// dir: The path of the directory.
+//
+// Returns:
+// error: An error if the directory could not be opened or synced.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("could not open the directory: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("could not sync the directory: %w", err)
+	}
+
+	return nil
+}
+
+// removeFile removes both the compressed and the uncompressed variant of the file under
+// the exclusive lock of its directory. Missing files are not an error.
+//
+// ctx: The context of the removal.
+// path: The path of the uncompressed file.
+//
+// Returns:
+// error: An error if a file exists but could not be removed.
+func (fs *FS) removeFile(ctx context.Context, path string) error {
+	unlock, err := fs.lockFilesIn(ctx, filepath.Dir(path), true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, variant := range []string{path, path + gzipExt} {
+		if err := os.Remove(variant); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove the file: %w", err)
+		}
+	}
+
+	return nil
+}
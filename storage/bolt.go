@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ensure that Bolt implements the ObjectStore interface
+var _ ObjectStore = (*Bolt)(nil)
+
+// Bolt is an ObjectStore keeping the objects in a single bbolt database file, a bucket
+// per kind of the objects. Every change is a transaction synced to the disk, so the
+// store is durable without an external database and does not spread the data over a
+// file per session. The file is locked by the process which opened it.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens the database file, creating it if it does not exist.
+//
+// path: The path of the database file.
+//
+// Returns:
+// *Bolt: The store of the objects in the file.
+// error: An error if the file could not be opened, e.g. it is locked by another process.
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening the database: %w", err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Get returns the content of the object.
+//
+// name: The name of the object.
+//
+// Returns the content of the object, or ErrNotFound if it does not exist.
+func (b *Bolt) Get(ctx context.Context, name string) (data []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kindOf(name)))
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		value := bucket.Get([]byte(name))
+		if value == nil {
+			return ErrNotFound
+		}
+
+		// The value is valid only during the transaction.
+		data = bytes.Clone(value)
+		return nil
+	})
+
+	return data, err
+}
+
+// Put creates or replaces the object.
+//
+// name: The name of the object.
+// data: The content of the object.
+func (b *Bolt) Put(ctx context.Context, name string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(kindOf(name)))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(name), data)
+	})
+}
+
+// Delete removes the object; removing a missing object is not an error.
+//
+// name: The name of the object.
+func (b *Bolt) Delete(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kindOf(name)))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete([]byte(name))
+	})
+}
+
+// List returns the names of the objects starting with the prefix, in the order of
+// the names.
+//
+// prefix: The prefix of the names.
+func (b *Bolt) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(kindOf(prefix)))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, _ = cursor.Next() {
+			names = append(names, string(key))
+		}
+
+		return nil
+	})
+
+	return names, err
+}
+
+// Ping checks that the database is open.
+func (b *Bolt) Ping(_ context.Context) error {
+	return b.db.View(func(*bolt.Tx) error { return nil })
+}
+
+// Close closes the database file.
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
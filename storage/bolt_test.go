@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestBoltStorage(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tgpt.db")
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	db, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("OpenBolt failed: %s", err)
+	}
+
+	store := NewObjects(db)
+	history := &chat.History{ID: id, Version: chat.HistoryVersion, Log: []chat.Message{{User: "Hello", Assistant: "Hi"}}}
+	if err := store.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	if err := store.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "Europe/Moscow"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+
+	// Execute: the data survives reopening the file.
+	db, err = OpenBolt(path)
+	if err != nil {
+		t.Fatalf("OpenBolt failed: %s", err)
+	}
+	defer db.Close()
+	store = NewObjects(db)
+
+	// Assert.
+	loaded, err := store.LoadHistory(ctx, id)
+	if err != nil || !reflect.DeepEqual(loaded, history) {
+		t.Errorf("LoadHistory returned %+v, %v, want %+v", loaded, err, history)
+	}
+
+	ids, err := store.List(ctx)
+	if err != nil || !reflect.DeepEqual(ids, []chat.ID{id}) {
+		t.Errorf("List returned %+v, %v, want %+v", ids, err, []chat.ID{id})
+	}
+
+	profiles, err := store.ListProfiles(ctx)
+	if err != nil || !reflect.DeepEqual(profiles, []int64{1}) {
+		t.Errorf("ListProfiles returned %v, %v, want [1]", profiles, err)
+	}
+
+	if err := store.DeleteHistory(ctx, id); err != nil {
+		t.Fatalf("DeleteHistory failed: %s", err)
+	}
+	if ids, _ := store.List(ctx); len(ids) != 0 {
+		t.Errorf("List returned %+v after the delete, want no sessions", ids)
+	}
+}
+
+func TestObjectsSharedLedger(t *testing.T) {
+	// Setup: two instances sharing the store, each saving the statistics of its session
+	// in the same group chat.
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := OpenBolt(filepath.Join(dir, "tgpt.db"))
+	if err != nil {
+		t.Fatalf("OpenBolt failed: %s", err)
+	}
+	defer db.Close()
+
+	now := chat.Now()
+	const saves = 20
+	var wg sync.WaitGroup
+	for user := int64(1); user <= 2; user++ {
+		store := NewObjects(slowStore{db})
+		store.SetLocker(&FileLocker{BaseDir: dir})
+
+		wg.Add(1)
+		go func(user int64) {
+			defer wg.Done()
+
+			stats := &chat.Statistics{ID: chat.ID{User: user, Chat: -100, Model: "gpt-4"}}
+			for i := 0; i < saves; i++ {
+				stats.AddCost(now, "gpt-4", 1)
+				if err := store.SaveStatistics(ctx, stats); err != nil {
+					t.Errorf("SaveStatistics failed: %s", err)
+					return
+				}
+			}
+		}(user)
+	}
+
+	// Execute.
+	wg.Wait()
+
+	// Assert that the ledger of the group chat lost no update.
+	ledger, err := NewObjects(db).LoadLedger(ctx, -100)
+	if err != nil {
+		t.Fatalf("LoadLedger failed: %s", err)
+	}
+	if ledger.Total != 2*saves {
+		t.Errorf("The ledger of the chat holds %v, want %v", ledger.Total, 2*saves)
+	}
+}
+
+// slowStore is an ObjectStore reading slowly, which widens the window between reading
+// and writing an object.
+type slowStore struct {
+	ObjectStore
+}
+
+func (s slowStore) Get(ctx context.Context, name string) ([]byte, error) {
+	time.Sleep(time.Millisecond)
+	return s.ObjectStore.Get(ctx, name)
+}
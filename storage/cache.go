@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that Cache implements the chat.Storage interface
+var _ chat.Storage = (*Cache)(nil)
+
+// Cache is a chat.Storage layered over a persistent backend: the histories, the
+// statistics, the profiles and the budgets read recently are served from memory, and
+// the writes go through to the backend before the memory is updated, so the hot
+// sessions avoid the disk reads while all the data stays durable. The least recently
+// used objects are dropped when the cache is full. The other data is read from the
+// backend directly. The cache does not see the changes made by other processes, so
+// it must not be used with a shared storage.
+type Cache struct {
+	chat.Wrapper // Wrapper wraps the persistent backend, keeping its optional interfaces.
+
+	mu      sync.Mutex
+	size    int                      // size is the maximum number of the cached objects.
+	lru     *list.List               // lru holds the cached objects, the most recently used first.
+	entries map[string]*list.Element // entries holds the elements of lru by the keys.
+
+	// generation is incremented on every delete, so an object loaded before a delete
+	// is not cached after it.
+	generation uint64
+}
+
+// cacheEntry is a cached object.
+type cacheEntry struct {
+	key   string
+	value any
+}
+
+// cloner is an object the Cache keeps copies of.
+type cloner[T any] interface {
+	Clone() T
+}
+
+// NewCache creates a Cache over the backend.
+//
+// backend: The persistent storage.
+// size: The maximum number of the cached objects.
+func NewCache(backend chat.Storage, size int) *Cache {
+	return &Cache{
+		Wrapper: chat.Wrapper{Storage: backend},
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// SaveHistory writes the history to the backend and caches it.
+//
+// ctx: The context for the storage operation.
+// history: The History object to be saved.
+func (c *Cache) SaveHistory(ctx context.Context, history *chat.History) error {
+	return cachedSave(ctx, c, "history"+keyOf(history.ID), history, c.Storage.SaveHistory)
+}
+
+// LoadHistory returns the cached history or loads it from the backend.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat history to be loaded.
+func (c *Cache) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	return cachedLoad(c, "history"+keyOf(id), func() (*chat.History, error) {
+		return c.Storage.LoadHistory(ctx, id)
+	})
+}
+
+// DeleteHistory removes the history from the cache and the backend.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat history to be removed.
+func (c *Cache) DeleteHistory(ctx context.Context, id chat.ID) error {
+	c.drop("history" + keyOf(id))
+	return c.Storage.DeleteHistory(ctx, id)
+}
+
+// SaveStatistics writes the statistics to the backend and caches them.
+//
+// ctx: The context for the storage operation.
+// statistics: The chat statistics to be saved.
+func (c *Cache) SaveStatistics(ctx context.Context, statistics *chat.Statistics) error {
+	return cachedSave(ctx, c, "statistics"+keyOf(statistics.ID), statistics, c.Storage.SaveStatistics)
+}
+
+// LoadStatistics returns the cached statistics or loads them from the backend.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat statistics to be loaded.
+func (c *Cache) LoadStatistics(ctx context.Context, id chat.ID) (*chat.Statistics, error) {
+	return cachedLoad(c, "statistics"+keyOf(id), func() (*chat.Statistics, error) {
+		return c.Storage.LoadStatistics(ctx, id)
+	})
+}
+
+// DeleteStatistics removes the statistics from the cache and the backend.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat statistics to be removed.
+func (c *Cache) DeleteStatistics(ctx context.Context, id chat.ID) error {
+	c.drop("statistics" + keyOf(id))
+	return c.Storage.DeleteStatistics(ctx, id)
+}
+
+// SaveProfile writes the profile to the backend and caches it.
+//
+// ctx: The context for the storage operation.
+// profile: The profile to be saved.
+func (c *Cache) SaveProfile(ctx context.Context, profile *chat.Profile) error {
+	return cachedSave(ctx, c, fmt.Sprintf("profile-%d", profile.User), profile, c.Wrapper.SaveProfile)
+}
+
+// LoadProfile returns the cached profile or loads it from the backend.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (c *Cache) LoadProfile(ctx context.Context, user int64) (*chat.Profile, error) {
+	return cachedLoad(c, fmt.Sprintf("profile-%d", user), func() (*chat.Profile, error) {
+		return c.Wrapper.LoadProfile(ctx, user)
+	})
+}
+
+// SaveBudget writes the budget to the backend and caches it.
+//
+// ctx: The context for the storage operation.
+// budget: The budget to be saved.
+func (c *Cache) SaveBudget(ctx context.Context, budget *chat.Budget) error {
+	return cachedSave(ctx, c, fmt.Sprintf("budget-%d", budget.Owner), budget, c.Wrapper.SaveBudget)
+}
+
+// LoadBudget returns the cached budget or loads it from the backend.
+//
+// ctx: The context for the storage operation.
+// owner: The ID of the user or the group chat.
+func (c *Cache) LoadBudget(ctx context.Context, owner int64) (*chat.Budget, error) {
+	return cachedLoad(c, fmt.Sprintf("budget-%d", owner), func() (*chat.Budget, error) {
+		return c.Wrapper.LoadBudget(ctx, owner)
+	})
+}
+
+// Len returns the number of the cached objects.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// cachedSave writes the object to the backend and caches a copy of it.
+func cachedSave[T cloner[T]](ctx context.Context, c *Cache, key string, obj T, save func(context.Context, T) error) error {
+	if err := save(ctx, obj); err != nil {
+		c.drop(key)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(key, obj.Clone())
+	return nil
+}
+
+// cachedLoad returns a copy of the cached object or loads it from the backend and
+// caches a copy of it, unless it has been saved or deleted meanwhile.
+func cachedLoad[T cloner[T]](c *Cache, key string, load func() (T, error)) (T, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		obj := elem.Value.(*cacheEntry).value.(T)
+		c.mu.Unlock()
+		return obj.Clone(), nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	obj, err := load()
+	if err != nil {
+		return obj, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, saved := c.entries[key]; !saved && c.generation == generation {
+		c.put(key, obj.Clone())
+	}
+
+	return obj, nil
+}
+
+// put caches the object, dropping the least recently used one if the cache is full.
+// The caller must hold the lock.
+func (c *Cache) put(key string, value any) {
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).value = value
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: value})
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// drop removes the object from the cache.
+func (c *Cache) drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// keyOf returns the part of the cache keys identifying the session.
+func keyOf(id chat.ID) string {
+	return fmt.Sprintf("-%d-%d-%s", id.User, id.Chat, id.Model)
+}
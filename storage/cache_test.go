@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// countingStorage counts the histories loaded from the storage.
+type countingStorage struct {
+	*Memory
+	loads int
+}
+
+func (s *countingStorage) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	s.loads++
+	return s.Memory.LoadHistory(ctx, id)
+}
+
+func TestCache(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	backend := &countingStorage{Memory: NewMemory()}
+	cache := NewCache(backend, 1)
+	first := chat.ID{User: 1, Chat: 1, Model: "gpt-4"}
+	second := chat.ID{User: 2, Chat: 2, Model: "gpt-4"}
+
+	history := &chat.History{ID: first, Log: []chat.Message{{User: "Hello", Assistant: "Hi"}}}
+	if err := cache.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// The saved history is written through and served from the memory.
+	if stored, _ := backend.Memory.LoadHistory(ctx, first); len(stored.Log) != 1 {
+		t.Errorf("The backend has %+v, want the saved history", stored)
+	}
+
+	loaded, err := cache.LoadHistory(ctx, first)
+	if err != nil || len(loaded.Log) != 1 || backend.loads != 0 {
+		t.Errorf("LoadHistory returned %+v, %v after %d backend loads, want the cached history", loaded, err, backend.loads)
+	}
+
+	// The cached copy does not change with the returned one.
+	loaded.Log[0].User = "Changed"
+	if again, _ := cache.LoadHistory(ctx, first); again.Log[0].User != "Hello" {
+		t.Errorf("The cached history changed to %+v", again)
+	}
+
+	// The least recently used history is dropped when the cache is full.
+	if _, err := cache.LoadHistory(ctx, second); err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if _, err := cache.LoadHistory(ctx, first); err != nil || backend.loads != 2 || cache.Len() != 1 {
+		t.Errorf("Loaded %d times from the backend with %d cached, want 2 loads and 1 cached", backend.loads, cache.Len())
+	}
+
+	// The deleted history is not served from the memory.
+	if err := cache.DeleteHistory(ctx, first); err != nil {
+		t.Fatalf("DeleteHistory failed: %s", err)
+	}
+	if deleted, _ := cache.LoadHistory(ctx, first); len(deleted.Log) != 0 {
+		t.Errorf("LoadHistory returned %+v after the delete, want an empty history", deleted)
+	}
+}
+
+// coreStorage is a storage implementing only the chat.Storage interface, without the
+// optional ones.
+type coreStorage struct {
+	chat.Storage
+}
+
+func TestWrappersKeepOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		backend chat.Storage
+		wantErr error
+	}{
+		{name: "full storage", backend: NewMemory()},
+		{name: "core storage", backend: coreStorage{NewMemory()}, wantErr: chat.ErrNotSupported},
+	}
+
+	for _, tt := range tests {
+		for wrapper, storage := range map[string]chat.Profiles{
+			"cache":        NewCache(tt.backend, 10),
+			"instrumented": NewInstrumented(tt.backend),
+		} {
+			t.Run(tt.name+" "+wrapper, func(t *testing.T) {
+				// Execute.
+				err := storage.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "Europe/Berlin"})
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SaveProfile returned %v, want %v", err, tt.wantErr)
+				}
+
+				// Assert: a storage without the profiles reads as if none had been saved.
+				profile, err := storage.LoadProfile(ctx, 1)
+				if err != nil {
+					t.Fatalf("LoadProfile failed: %s", err)
+				}
+				if saved := profile.Timezone != ""; saved != (tt.wantErr == nil) {
+					t.Errorf("LoadProfile returned %+v, want the saved profile %v", profile, tt.wantErr == nil)
+				}
+			})
+		}
+	}
+}
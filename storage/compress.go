@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipExt is the extension added to the names of the compressed files.
+const gzipExt = ".gz"
+
+// variants returns the paths of the file with and without the compression, the one
+// written by the storage first.
+//
+// path: The path of the uncompressed file.
+func (fs *FS) variants(path string) (preferred, other string) {
+	if fs.Compress {
+		return path + gzipExt, path
+	}
+
+	return path, path + gzipExt
+}
+
+// gzipWriter wraps the function writing the content of a file with the compression.
+//
+// write: The function writing the uncompressed content.
+func gzipWriter(write func(w io.Writer) error) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if err := write(zw); err != nil {
+			return err
+		}
+
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("could not compress the file: %w", err)
+		}
+
+		return nil
+	}
+}
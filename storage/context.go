@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// sizeKey is the key of the context holding the counter of the bytes the storage writes
+// and reads.
+type sizeKey struct{}
+
+// withSizeCounter returns the context counting the bytes the storage writes and reads
+// on its behalf, and the counter.
+func withSizeCounter(ctx context.Context) (context.Context, *atomic.Int64) {
+	counter := new(atomic.Int64)
+	return context.WithValue(ctx, sizeKey{}, counter), counter
+}
+
+// countSize adds the bytes written or read to the counter of the context, if any.
+func countSize(ctx context.Context, n int) {
+	if counter, ok := ctx.Value(sizeKey{}).(*atomic.Int64); ok {
+		counter.Add(int64(n))
+	}
+}
+
+// contextWriter is a writer failing once the context is done, so a cancelled save stops
+// writing the file halfway.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := w.w.Write(p)
+	countSize(w.ctx, n)
+	return n, err
+}
+
+// contextReader is a reader failing once the context is done, so a cancelled load stops
+// reading the file halfway.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.r.Read(p)
+	countSize(r.ctx, n)
+	return n, err
+}
@@ -0,0 +1,584 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// The magics start the content of the encrypted files, which tells them from the
+// plaintext files saved before the encryption was enabled and tells the key.
+var (
+	encryptedMagic     = []byte("TGPTENC1") // encryptedMagic marks the files encrypted with the master key.
+	userEncryptedMagic = []byte("TGPTENC2") // userEncryptedMagic marks the files encrypted with the key of a user.
+)
+
+// saltSize is the size of the random salt of the key of a user.
+const saltSize = 32
+
+// ErrKeyDestroyed is returned when the file is encrypted with the key of a user which
+// has been destroyed, so the file can never be decrypted again. The storage reads such
+// a file as missing, so the next save replaces it.
+var ErrKeyDestroyed = errors.New("encryption key of the user has been destroyed")
+
+// Encryption encrypts the files of the storage at rest with AES-GCM. The files of each
+// user are encrypted with a key derived from the master key and a random salt of the
+// user (HKDF-SHA256), so destroying the salt irreversibly shreds the data of the user;
+// the other files are encrypted with the master key. The name of the file is
+// authenticated along with the content, so an encrypted file cannot be passed off as
+// the file of another user.
+//
+// The salts are kept in a key directory apart from the data, so the backups of the data
+// directory do not hold the keys, and the data of an erased user cannot be decrypted
+// from an earlier backup once the salt is destroyed.
+type Encryption struct {
+	master []byte
+	aead   cipher.AEAD
+	keyDir string
+
+	mu    sync.Mutex
+	users map[int64]userCipher // users caches the ciphers of the users.
+}
+
+// userCipher is the cached cipher of the key of a user.
+type userCipher struct {
+	aead cipher.AEAD
+	salt os.FileInfo // salt is the file of the salt the key is derived from.
+}
+
+// NewEncryption creates the encryption with the master key.
+//
+// key: The AES key of 16, 24 or 32 bytes.
+// keyDir: The directory of the salts of the users' keys, outside of the data directory;
+// empty keeps each salt in the directory of its user. The salts left in the directories
+// of the users are moved to the key directory when they are first used.
+//
+// Returns:
+// *Encryption: The encryption of the storage.
+// error: An error if the key has an invalid length.
+func NewEncryption(key []byte, keyDir string) (*Encryption, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryption{
+		master: bytes.Clone(key),
+		aead:   aead,
+		keyDir: keyDir,
+		users:  make(map[int64]userCipher),
+	}, nil
+}
+
+// newAEAD creates the AES-GCM cipher with the key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+// seal encrypts the content of the file.
+//
+// aead: The cipher of the key.
+// magic: The magic of the key.
+// plain: The content of the file.
+// name: The name of the file, authenticated along with the content.
+//
+// Returns the magic, the random nonce and the encrypted content.
+func seal(aead cipher.AEAD, magic, plain []byte, name string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate the nonce: %w", err)
+	}
+
+	sealed := append(append([]byte{}, magic...), nonce...)
+	return aead.Seal(sealed, nonce, plain, []byte(name)), nil
+}
+
+// open decrypts the content of the file sealed by seal.
+//
+// aead: The cipher of the key.
+// sealed: The content of the encrypted file without the magic.
+// name: The name of the file.
+//
+// Returns the content of the file and an error if the key is wrong or the file has
+// been damaged or tampered with.
+func open(aead cipher.AEAD, sealed []byte, name string) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted file is truncated")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the file: %w", err)
+	}
+
+	return plain, nil
+}
+
+// userAEAD returns the cipher of the key of the user. The caller must hold the lock of
+// the directory of the user. The cached cipher is used only while the file of the salt
+// is the same, since another instance sharing the storage may have destroyed or
+// replaced the salt meanwhile.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+// create: Whether to create the salt if the user has none yet.
+//
+// Returns the cipher and ErrKeyDestroyed if the user has no salt and create is false.
+func (e *Encryption) userAEAD(dir string, user int64, create bool) (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	path := e.saltPath(dir, user)
+	if cached, ok := e.users[user]; ok {
+		info, err := os.Stat(path)
+		if err == nil && os.SameFile(info, cached.salt) && info.ModTime().Equal(cached.salt.ModTime()) {
+			return cached.aead, nil
+		}
+		delete(e.users, user)
+	}
+
+	salt, err := e.readSalt(dir, user)
+	switch {
+	case os.IsNotExist(err) && create:
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("could not generate the salt: %w", err)
+		}
+		if err := writeSalt(path, salt); err != nil {
+			return nil, err
+		}
+	case os.IsNotExist(err):
+		return nil, ErrKeyDestroyed
+	case err != nil:
+		return nil, fmt.Errorf("could not read the salt: %w", err)
+	}
+
+	aead, err := e.deriveAEAD(user, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		e.users[user] = userCipher{aead: aead, salt: info}
+	}
+	return aead, nil
+}
+
+// pendingAEAD returns the cipher of the new key of the user written by DestroyKey before
+// the old salt is replaced. It is left only if DestroyKey was interrupted, and then
+// decrypts the kept files already encrypted with the new key. The caller must hold the
+// lock of the directory of the user.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+//
+// Returns the cipher and the error of os.ReadFile if there is no new key.
+func (e *Encryption) pendingAEAD(dir string, user int64) (cipher.AEAD, error) {
+	salt, err := os.ReadFile(e.pendingSaltPath(dir, user))
+	if err != nil {
+		return nil, err
+	}
+
+	return e.deriveAEAD(user, salt)
+}
+
+// deriveAEAD derives the key of the user from the master key and the salt and creates
+// its cipher.
+//
+// user: The ID of the user.
+// salt: The salt of the user's key.
+func (e *Encryption) deriveAEAD(user int64, salt []byte) (cipher.AEAD, error) {
+	info := []byte("tgpt user " + strconv.FormatInt(user, 10))
+	key := make([]byte, len(e.master))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, e.master, salt, info), key); err != nil {
+		return nil, fmt.Errorf("could not derive the key: %w", err)
+	}
+
+	return newAEAD(key)
+}
+
+// readSalt reads the salt of the user's key. The salt left in the directory of the user
+// is moved to the key directory.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+//
+// Returns the salt and the error of os.ReadFile, which is not wrapped to be checked with
+// os.IsNotExist.
+func (e *Encryption) readSalt(dir string, user int64) ([]byte, error) {
+	path := e.saltPath(dir, user)
+	salt, err := os.ReadFile(path)
+	if !os.IsNotExist(err) || e.keyDir == "" {
+		return salt, err
+	}
+
+	legacy := filepath.Join(dir, saltFilename(user))
+	if salt, err = os.ReadFile(legacy); err != nil {
+		return nil, err
+	}
+
+	if err := writeSalt(path, salt); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not remove the salt from the data directory: %w", err)
+	}
+
+	slog.Info("encryption moved the salt to the key directory", slog.Int64("userID", user))
+	return salt, nil
+}
+
+// writeSalt writes the salt of the user's key atomically and durably.
+//
+// path: The path of the salt.
+// salt: The salt.
+func writeSalt(path string, salt []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create the directory of the salt: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("could not write the salt: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(salt); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write the salt: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write the salt: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write the salt: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not write the salt: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// replaceSalt replaces the salt of the user's key with the new salt written by DestroyKey,
+// which irreversibly destroys the old key, and drops the cached cipher. The caller must
+// hold the lock of the directory of the user.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+func (e *Encryption) replaceSalt(dir string, user int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.users, user)
+
+	if err := os.Rename(e.pendingSaltPath(dir, user), e.saltPath(dir, user)); err != nil {
+		return fmt.Errorf("could not replace the salt: %w", err)
+	}
+
+	// The salt left in the directory of the user by an older version.
+	if legacy := filepath.Join(dir, saltFilename(user)); legacy != e.saltPath(dir, user) {
+		if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove the salt: %w", err)
+		}
+	}
+
+	return syncDir(filepath.Dir(e.saltPath(dir, user)))
+}
+
+// pendingSaltPath returns the path of the new salt of the user's key written by
+// DestroyKey before it replaces the old one.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+func (e *Encryption) pendingSaltPath(dir string, user int64) string {
+	return e.saltPath(dir, user) + ".new"
+}
+
+// saltPath returns the path of the salt of the user's key.
+//
+// dir: The directory of the user.
+// user: The ID of the user.
+func (e *Encryption) saltPath(dir string, user int64) string {
+	if e.keyDir == "" {
+		return filepath.Join(dir, saltFilename(user))
+	}
+
+	return filepath.Join(shardDir(e.keyDir, user), saltFilename(user))
+}
+
+// saltFilename returns the name of the file of the salt of the user's key. It does not
+// end with ".json", so List ignores it.
+func saltFilename(user int64) string {
+	return fmt.Sprintf("key-%d.salt", user)
+}
+
+// encryptWriter wraps the function writing the content of a file with the encryption.
+// The caller must hold the lock of the directory of the file.
+//
+// write: The function writing the plain content.
+// path: The path of the uncompressed file.
+func (fs *FS) encryptWriter(write func(w io.Writer) error, path string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		aead, magic := fs.Encryption.aead, encryptedMagic
+		if user, ok := fs.ownerOf(path); ok {
+			var err error
+			if aead, err = fs.Encryption.userAEAD(filepath.Dir(path), user, true); err != nil {
+				return err
+			}
+			magic = userEncryptedMagic
+		}
+
+		return sealWriter(write, aead, magic, path)(w)
+	}
+}
+
+// sealWriter wraps the function writing the content of a file with the encryption with
+// the given key.
+//
+// write: The function writing the plain content.
+// aead: The cipher of the key.
+// magic: The magic of the key.
+// path: The path of the uncompressed file.
+func sealWriter(write func(w io.Writer) error, aead cipher.AEAD, magic []byte, path string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		var plain bytes.Buffer
+		if err := write(&plain); err != nil {
+			return err
+		}
+
+		sealed, err := seal(aead, magic, plain.Bytes(), filepath.Base(path))
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(sealed); err != nil {
+			return fmt.Errorf("could not write the encrypted file: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// decryptReader returns the reader of the plain content of the file. The plaintext
+// files saved before the encryption was enabled are read as they are. The caller must
+// hold the lock of the directory of the file.
+//
+// r: The reader of the file.
+// path: The path of the uncompressed file.
+func (fs *FS) decryptReader(r io.Reader, path string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	head, _ := br.Peek(len(encryptedMagic))
+	if !bytes.Equal(head, encryptedMagic) && !bytes.Equal(head, userEncryptedMagic) {
+		return br, nil
+	}
+
+	if fs.Encryption == nil {
+		return nil, errors.New("file is encrypted, but no encryption key is configured")
+	}
+
+	sealed, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the encrypted file: %w", err)
+	}
+
+	if !bytes.Equal(head, userEncryptedMagic) {
+		plain, err := open(fs.Encryption.aead, sealed[len(head):], filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(plain), nil
+	}
+
+	user, ok := fs.ownerOf(path)
+	if !ok {
+		return nil, errors.New("file is encrypted with the key of a user, but is not in the directory of a user")
+	}
+
+	aead, err := fs.Encryption.userAEAD(filepath.Dir(path), user, false)
+	if err != nil && !errors.Is(err, ErrKeyDestroyed) {
+		return nil, err
+	}
+
+	var plain []byte
+	if aead != nil {
+		plain, err = open(aead, sealed[len(head):], filepath.Base(path))
+	}
+	if err != nil {
+		// The file kept by an interrupted DestroyKey is encrypted with the new key.
+		if pending, pendingErr := fs.Encryption.pendingAEAD(filepath.Dir(path), user); pendingErr == nil {
+			if recovered, pendingErr := open(pending, sealed[len(head):], filepath.Base(path)); pendingErr == nil {
+				return bytes.NewReader(recovered), nil
+			}
+		}
+		return nil, err
+	}
+
+	return bytes.NewReader(plain), nil
+}
+
+// DestroyKey irreversibly shreds the data of the user: it destroys the salt of the
+// user's encryption key, so the copies of the files in the backups can never be
+// decrypted again, and removes the files of the user. The profile, the budget and the
+// ledger, which hold the access, the limits and the spending of the user rather than
+// their conversations, are kept and encrypted with a new key. They are written under
+// the new key before the old salt is replaced, so they are not lost if the shredding
+// is interrupted.
+//
+// ctx: The context of the removal.
+// user: The ID of the user or the group chat.
+//
+// Returns:
+// error: An error if the storage is not encrypted or the files could not be removed
+// or saved again.
+func (fs *FS) DestroyKey(ctx context.Context, user int64) error {
+	if fs.Encryption == nil {
+		return errors.New("storage is not encrypted")
+	}
+
+	// Keep the spending from being recorded while the ledger is rewritten.
+	unlock, err := fs.lockLedgers(ctx, []int64{user})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	kept := make(map[string]func(w io.Writer) error)
+	if filename := fmt.Sprintf("profile-%d.json", user); fs.stored(filename, user) {
+		profile, err := fs.LoadProfile(ctx, user)
+		if err != nil {
+			return err
+		}
+		kept[filename] = profile.Write
+	}
+	if filename := fmt.Sprintf("budget-%d.json", user); fs.stored(filename, user) {
+		budget, err := fs.LoadBudget(ctx, user)
+		if err != nil {
+			return err
+		}
+		kept[filename] = budget.Write
+	}
+	if filename := fmt.Sprintf("ledger-%d.json", user); fs.stored(filename, user) {
+		ledger, err := fs.LoadLedger(ctx, user)
+		if err != nil {
+			return err
+		}
+		kept[filename] = ledger.Write
+	}
+
+	return fs.shred(ctx, user, kept)
+}
+
+// stored reports whether the file of the user exists in either variant.
+//
+// filename: The name of the uncompressed file.
+// user: The ID of the user.
+func (fs *FS) stored(filename string, user int64) bool {
+	preferred, other := fs.variants(filepath.Join(fs.userDir(user), filename))
+	for _, path := range []string{preferred, other} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shred replaces the salt of the user's key with a new one and removes all files of the
+// user but the lock files and the kept files, which are encrypted with the new key.
+// The new salt and the kept files are written first, so until the old salt is replaced,
+// the kept files are decrypted with the new salt (see pendingAEAD).
+//
+// ctx: The context of the removal.
+// user: The ID of the user.
+// kept: The functions writing the plain content of the kept files by their names.
+func (fs *FS) shred(ctx context.Context, user int64, kept map[string]func(w io.Writer) error) error {
+	dir := fs.userDir(user)
+	unlock, err := fs.lockFilesIn(ctx, dir, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("could not generate the salt: %w", err)
+	}
+	if err := writeSalt(fs.Encryption.pendingSaltPath(dir, user), salt); err != nil {
+		return err
+	}
+
+	aead, err := fs.Encryption.deriveAEAD(user, salt)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for filename, write := range kept {
+		path := filepath.Join(dir, filename)
+		if fs.Compress {
+			write = gzipWriter(write)
+		}
+
+		if err := fs.replaceFile(ctx, path, sealWriter(write, aead, userEncryptedMagic, path)); err != nil {
+			return err
+		}
+
+		preferred, _ := fs.variants(path)
+		keep[filepath.Base(preferred)] = true
+	}
+
+	// The point of no return: the old key is destroyed.
+	if err := fs.Encryption.replaceSalt(dir, user); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("could not read the directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || keep[name] || name == saltFilename(user) ||
+			strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".lock") {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove the file: %w", err)
+		}
+	}
+
+	return nil
+}
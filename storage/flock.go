@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lockFilename is the name of the file locked in the storage directory. The operations
+// on the files of a directory hold it shared, the operations moving the files between
+// the directories hold it exclusively. The data files are replaced by renaming, so they
+// cannot carry the locks themselves.
+const lockFilename = ".lock"
+
+// dirLockFilename is the name of the file locked in a directory of the storage, e.g. the
+// directory of a user, while its files are read or written, so the users do not wait
+// for each other.
+const dirLockFilename = ".dir.lock"
+
+// ledgerLockFilename is the name of the file locked in the directory of the owner of
+// a ledger while the spending is added to it.
+const ledgerLockFilename = ".ledger.lock"
+
+// lockDir takes an advisory lock on the directory, so several processes using the same
+// directory do not interleave their reads and writes. Readers share the lock, a writer
+// holds it exclusively. The wait for the lock ends when the context is done; the lock
+// taken after that is released right away.
+//
+// ctx: The context for the wait.
+// dir: The storage directory.
+// exclusive: Whether to lock for writing.
+//
+// Returns:
+// func(): The function releasing the lock.
+// error: An error if the lock file could not be opened or locked, or the context is done.
+func lockDir(ctx context.Context, dir string, exclusive bool) (func(), error) {
+	return lockFile(ctx, filepath.Join(dir, lockFilename), exclusive)
+}
+
+// lockFilesIn takes the locks of the files of the directory: the lock of the storage
+// shared and the lock of the directory. Readers share the lock of the directory, a
+// writer holds it exclusively. A missing directory has no files to read, so the readers
+// do not create it.
+//
+// ctx: The context for the wait.
+// dir: The directory of the files, e.g. the directory of a user or the BaseDir.
+// exclusive: Whether to lock for writing.
+//
+// Returns:
+// func(): The function releasing the locks.
+// error: An error if a lock file could not be opened or locked, or the context is done.
+func (fs *FS) lockFilesIn(ctx context.Context, dir string, exclusive bool) (func(), error) {
+	unlockStorage, err := lockDir(ctx, fs.BaseDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exclusive {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return unlockStorage, nil
+		}
+	}
+
+	unlockDir, err := lockFile(ctx, filepath.Join(dir, dirLockFilename), exclusive)
+	if err != nil {
+		unlockStorage()
+		return nil, err
+	}
+
+	return func() {
+		unlockDir()
+		unlockStorage()
+	}, nil
+}
+
+// lockFile takes an advisory lock on the file, creating it and its directory if needed.
+// The locks of different files, and of the same file opened twice, exclude each other
+// independently of the processes and the goroutines holding them.
+//
+// ctx: The context for the wait.
+// path: The path of the lock file.
+// exclusive: Whether to lock for writing.
+//
+// Returns:
+// func(): The function releasing the lock.
+// error: An error if the lock file could not be opened or locked, or the context is done.
+func lockFile(ctx context.Context, path string, exclusive bool) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("could not lock the storage: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create the directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the lock file: %w", err)
+	}
+
+	// flock(2) cannot be interrupted, so it waits in the background.
+	locked := make(chan error, 1)
+	go func() {
+		locked <- flock(file, exclusive)
+	}()
+
+	select {
+	case err := <-locked:
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not lock the storage: %w", err)
+		}
+
+	case <-ctx.Done():
+		go func() {
+			if <-locked == nil {
+				funlock(file)
+			}
+			file.Close()
+		}()
+
+		return nil, fmt.Errorf("could not lock the storage: %w", ctx.Err())
+	}
+
+	return func() {
+		funlock(file)
+		file.Close()
+	}, nil
+}
+
+// lockLedgers locks the ledgers of the owners in their directories, so the saves of
+// the statistics of all processes using the storage add to them one at a time.
+//
+// ctx: The context for the wait.
+// owners: The IDs of the users and the group chats whose ledgers are locked.
+//
+// Returns:
+// func(): The function releasing the locks.
+// error: An error if a ledger could not be locked.
+func (fs *FS) lockLedgers(ctx context.Context, owners []int64) (func(), error) {
+	unlocks := make([]func(), 0, len(owners))
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+
+	for _, owner := range owners {
+		release, err := lockFile(ctx, filepath.Join(fs.userDir(owner), ledgerLockFilename), true)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, release)
+	}
+
+	return unlock, nil
+}
+
+// lockedFile is a file opened for reading under the shared lock of its directory.
+type lockedFile struct {
+	io.Reader // Reader reads the content of the file, decrypting and decompressing it if needed.
+
+	file   *os.File
+	unlock func()
+}
+
+// openLocked opens the file for reading under the shared lock of its directory. The
+// compressed variant of the file is read as well, whichever exists, and the encrypted
+// files are decrypted.
+//
+// ctx: The context of the read; the reads fail once it is done.
+// path: The path of the uncompressed file.
+//
+// Returns:
+// *lockedFile: The opened file; closing it releases the lock.
+// error: The error of os.Open, which is not wrapped to be checked with os.IsNotExist,
+// also returned for a file encrypted with a destroyed key (see ErrKeyDestroyed), or an
+// error if the storage could not be locked.
+func (fs *FS) openLocked(ctx context.Context, path string) (*lockedFile, error) {
+	unlock, err := fs.lockFilesIn(ctx, filepath.Dir(path), false)
+	if err != nil {
+		return nil, err
+	}
+
+	preferred, other := fs.variants(path)
+
+	file, err := os.Open(preferred)
+	if os.IsNotExist(err) {
+		file, err = os.Open(other)
+		if os.IsNotExist(err) {
+			// Report the missing file with the path of the preferred variant.
+			_, err = os.Open(preferred)
+		}
+	}
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	locked := &lockedFile{Reader: contextReader{ctx, file}, file: file, unlock: unlock}
+
+	locked.Reader, err = fs.decryptReader(locked.Reader, path)
+	if errors.Is(err, ErrKeyDestroyed) {
+		// The file of an erased user left behind is read as missing.
+		locked.Close()
+		slog.Warn("storage ignored a file encrypted with a destroyed key", slog.String("path", path))
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	if err != nil {
+		locked.Close()
+		return nil, err
+	}
+
+	if strings.HasSuffix(file.Name(), gzipExt) {
+		zr, err := gzip.NewReader(locked.Reader)
+		if err != nil {
+			locked.Close()
+			return nil, fmt.Errorf("could not decompress the file: %w", err)
+		}
+		locked.Reader = zr
+	}
+
+	return locked, nil
+}
+
+// Close closes the file and releases the lock.
+func (f *lockedFile) Close() error {
+	defer f.unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// flock does nothing on the systems without flock(2); the storage directory must not
+// be shared by several processes there.
+func flock(*os.File, bool) error {
+	return nil
+}
+
+// funlock does nothing on the systems without flock(2).
+func funlock(*os.File) {}
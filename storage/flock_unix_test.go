@@ -0,0 +1,114 @@
+//go:build unix
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Readers share the lock.
+	unlockReader, err := lockDir(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("lockDir failed: %s", err)
+	}
+	unlockOther, err := lockDir(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("lockDir of the second reader failed: %s", err)
+	}
+
+	// A writer waits for the readers.
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := lockDir(ctx, dir, true)
+		if err != nil {
+			t.Errorf("lockDir of the writer failed: %s", err)
+		} else {
+			unlock()
+		}
+		close(locked)
+	}()
+
+	unlockReader()
+	select {
+	case <-locked:
+		t.Fatal("The writer did not wait for the readers")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockOther()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("The writer did not get the lock after the readers")
+	}
+}
+
+func TestLockDirCancelled(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	unlock, err := lockDir(ctx, dir, true)
+	if err != nil {
+		t.Fatalf("lockDir failed: %s", err)
+	}
+
+	// The wait for the lock ends with the context.
+	waiting, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := lockDir(waiting, dir, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lockDir returned %v instead of the deadline error", err)
+	}
+
+	// The lock taken after the wait ended is released.
+	unlock()
+	locked := make(chan struct{})
+	go func() {
+		if unlock, err := lockDir(ctx, dir, true); err == nil {
+			unlock()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("The lock taken after the wait ended was not released")
+	}
+}
+
+func TestLockFilesIn(t *testing.T) {
+	ctx := context.Background()
+	fs := &FS{BaseDir: t.TempDir()}
+
+	unlock, err := fs.lockFilesIn(ctx, fs.userDir(1), true)
+	if err != nil {
+		t.Fatalf("lockFilesIn failed: %s", err)
+	}
+	defer unlock()
+
+	// The files of another user do not wait for the writer.
+	quick, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	unlockOther, err := fs.lockFilesIn(quick, fs.userDir(2), true)
+	if err != nil {
+		t.Fatalf("lockFilesIn of another user failed: %s", err)
+	}
+	unlockOther()
+
+	// The reader of the same user and the move of the files wait for the writer.
+	waiting, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := fs.lockFilesIn(waiting, fs.userDir(1), false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("lockFilesIn of the reader returned %v instead of the deadline error", err)
+	}
+	if _, err := lockDir(waiting, fs.BaseDir, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("lockDir of the storage returned %v instead of the deadline error", err)
+	}
+}
@@ -3,17 +3,44 @@ package storage
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/muzykantov/tgpt/chat"
 )
 
+// ensure that FS implements the chat.Storage interface and all its optional interfaces
+var (
+	_ chat.Storage       = (*FS)(nil)
+	_ chat.Rollups       = (*FS)(nil)
+	_ chat.Profiles      = (*FS)(nil)
+	_ chat.Budgets       = (*FS)(nil)
+	_ chat.Ledgers       = (*FS)(nil)
+	_ chat.Invites       = (*FS)(nil)
+	_ chat.Progress      = (*FS)(nil)
+	_ chat.Configuration = (*FS)(nil)
+)
+
 // FS represents a file-based storage system that provides methods to persist and retrieve
 // chat-related data structures like History and Statistics to and from the file system.
+// The files are replaced atomically, so a crash during a save never leaves a truncated file,
+// and the directory is locked with flock(2) while a file is read or written, so several
+// processes can use the same directory. The operations stop once their context is done,
+// and a cancelled save leaves the previous version of the file intact.
 type FS struct {
 	BaseDir string // BaseDir is the base directory for storing and retrieving data files.
+
+	// Compress writes the files compressed with gzip, adding ".gz" to their names.
+	// Both the compressed and the uncompressed files are read regardless of the flag.
+	Compress bool
+
+	// Encryption encrypts the files at rest, each user's with a distinct key; nil stores
+	// them in plaintext. The plaintext files saved before the encryption was enabled are
+	// still read.
+	Encryption *Encryption
 }
 
 // SaveHistory persists a given History object to the file system.
@@ -24,26 +51,18 @@ type FS struct {
 //
 // Returns:
 // error: An error if encountered during file operations or serialization.
-func (fs *FS) SaveHistory(_ context.Context, history *chat.History) error {
+func (fs *FS) SaveHistory(ctx context.Context, history *chat.History) error {
 	// Generate the path to save the history using the ID.
 	filename := fmt.Sprintf(
 		"history-%d-%d-%s.json",
 		history.ID.User,
 		history.ID.Chat,
-		history.ID.Model,
+		escapeModel(history.ID.Model),
 	)
-	path := filepath.Join(fs.BaseDir, filename)
-
-	// Open or create the file.
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("could not open or create the file: %w", err)
-	}
-	defer file.Close()
+	path := fs.userPath(history.ID.User, filename)
 
-	// Write the history to the file in JSON format.
-	err = history.Write(file)
-	if err != nil {
+	// Write the history to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, history.Write); err != nil {
 		return fmt.Errorf("error writing the history to the file: %w", err)
 	}
 
@@ -51,7 +70,8 @@ func (fs *FS) SaveHistory(_ context.Context, history *chat.History) error {
 }
 
 // LoadHistory retrieves a chat history from the file system using the provided ID.
-// If the file does not exist, a new History instance is returned.
+// If the file does not exist, a new History instance is returned. A corrupted file is
+// renamed with the ".corrupt" extension and the history recovered from it is returned.
 // It constructs a file name from the ID and tries to open the corresponding JSON file
 // within the BaseDir, then deserializes the file's content into a History object.
 //
@@ -60,74 +80,87 @@ func (fs *FS) SaveHistory(_ context.Context, history *chat.History) error {
 // Returns:
 // *History: A pointer to the retrieved or newly created History object.
 // error: An error if encountered during file operations or deserialization, except for file not found error.
-func (fs *FS) LoadHistory(_ context.Context, id chat.ID) (*chat.History, error) {
+func (fs *FS) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
 	// Generate the path to load the history using the ID.
-	filename := fmt.Sprintf("history-%d-%d-%s.json", id.User, id.Chat, id.Model)
-	path := filepath.Join(fs.BaseDir, filename)
+	filename := fmt.Sprintf("history-%d-%d-%s.json", id.User, id.Chat, escapeModel(id.Model))
+	path := fs.userPath(id.User, filename)
 
-	// Open the file.
-	file, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// If the file does not exist, return a new instance of chat.History.
-			return &chat.History{
-				ID:     id,
-				Prompt: "",
-				Log:    []chat.Message{},
-			}, nil
+	// Read the file, or recover it if corrupted.
+	history, err := readRecovering(ctx, fs, path, func() *chat.History {
+		return &chat.History{
+			ID:     id,
+			Prompt: "",
+			Log:    []chat.Message{},
 		}
-		// For other errors, return an error.
-		return nil, fmt.Errorf("could not open the file: %w", err)
-	}
-	defer file.Close()
-
-	// Decode the history from the file.
-	history := new(chat.History)
-	err = history.Read(file)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error reading the history from the file: %w", err)
 	}
 
+	// The name of the file identifies the history even if it has been recovered.
+	history.ID = id
+
 	return history, nil
 }
 
+// DeleteHistory removes the chat history with the provided ID from the file system,
+// whether it is compressed or not.
+//
+// id: The ID of the chat history to be removed.
+//
+// Returns:
+// error: An error if the file exists but could not be removed.
+func (fs *FS) DeleteHistory(ctx context.Context, id chat.ID) error {
+	filename := fmt.Sprintf("history-%d-%d-%s.json", id.User, id.Chat, escapeModel(id.Model))
+	path := fs.userPath(id.User, filename)
+
+	if err := fs.removeFile(ctx, path); err != nil {
+		return fmt.Errorf("error removing the history: %w", err)
+	}
+
+	return nil
+}
+
 // SaveStatistics persists the given chat statistics to the file system.
 // This method generates a unique filename based on the ID of the chat statistics
 // and writes the statistics to a JSON file within the BaseDir.
 // If a file with the same name already exists, it will be overwritten.
+// The growth of the costs is added to the ledgers of the user and the group chat,
+// which are locked meanwhile.
 //
 // statistics: The chat statistics to be saved.
 //
 // Returns:
 // error: An error if encountered during file operations or serialization.
-func (fs *FS) SaveStatistics(_ context.Context, statistics *chat.Statistics) error {
+func (fs *FS) SaveStatistics(ctx context.Context, statistics *chat.Statistics) error {
 	// Generate the path to save the statistics using the ID.
 	filename := fmt.Sprintf(
 		"statistics-%d-%d-%s.json",
 		statistics.ID.User,
 		statistics.ID.Chat,
-		statistics.ID.Model,
+		escapeModel(statistics.ID.Model),
 	)
-	path := filepath.Join(fs.BaseDir, filename)
+	path := fs.userPath(statistics.ID.User, filename)
 
-	// Open or create the file.
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	unlock, err := fs.lockLedgers(ctx, chat.LedgerOwners(statistics.ID))
 	if err != nil {
-		return fmt.Errorf("could not open or create the file: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer unlock()
 
-	// Write the statistics to the file in JSON format.
-	err = statistics.Write(file)
-	if err != nil {
-		return fmt.Errorf("error writing the statistics to the file: %w", err)
-	}
+	return recordSpending(ctx, fs, statistics, func() error {
+		// Write the statistics to the file atomically in JSON format.
+		if err := fs.writeFile(ctx, path, statistics.Write); err != nil {
+			return fmt.Errorf("error writing the statistics to the file: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // LoadStatistics retrieves a chat statistics from the file system using the provided ID.
-// If the file does not exist, a new Statistics instance is returned.
+// If the file does not exist, a new Statistics instance is returned. A corrupted file is
+// renamed with the ".corrupt" extension and the statistics recovered from it are returned.
 // It constructs a file name from the ID and tries to open the corresponding JSON file
 // within the BaseDir, then deserializes the file's content into a Statistics object.
 //
@@ -136,22 +169,125 @@ func (fs *FS) SaveStatistics(_ context.Context, statistics *chat.Statistics) err
 // Returns:
 // *Statistics: A pointer to the retrieved or newly created Statistics object.
 // error: An error if encountered during file operations or deserialization, except for file not found error.
-func (fs *FS) LoadStatistics(_ context.Context, id chat.ID) (*chat.Statistics, error) {
+func (fs *FS) LoadStatistics(ctx context.Context, id chat.ID) (*chat.Statistics, error) {
 	// Generate the path to load the statistics using the ID.
-	filename := fmt.Sprintf("statistics-%d-%d-%s.json", id.User, id.Chat, id.Model)
-	path := filepath.Join(fs.BaseDir, filename)
+	filename := fmt.Sprintf("statistics-%d-%d-%s.json", id.User, id.Chat, escapeModel(id.Model))
+	path := fs.userPath(id.User, filename)
+
+	// Read the file, or recover it if corrupted.
+	statistics, err := readRecovering(ctx, fs, path, func() *chat.Statistics {
+		return &chat.Statistics{
+			ID:          id,
+			LastMessage: 0,
+			Days:        map[string]chat.Cost{},
+			Months:      map[string]chat.Cost{},
+			Total:       0,
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the statistics from the file: %w", err)
+	}
+
+	// The name of the file identifies the statistics even if they have been recovered.
+	statistics.ID = id
+
+	return statistics, nil
+}
+
+// DeleteStatistics removes the chat statistics with the provided ID from the file
+// system, whether they are compressed or not.
+//
+// id: The ID of the chat statistics to be removed.
+//
+// Returns:
+// error: An error if the file exists but could not be removed.
+func (fs *FS) DeleteStatistics(ctx context.Context, id chat.ID) error {
+	filename := fmt.Sprintf("statistics-%d-%d-%s.json", id.User, id.Chat, escapeModel(id.Model))
+	path := fs.userPath(id.User, filename)
+
+	if err := fs.removeFile(ctx, path); err != nil {
+		return fmt.Errorf("error removing the statistics: %w", err)
+	}
+
+	return nil
+}
+
+// EraseUser removes the quarantined copies of the corrupted files of the user (see
+// readRecovering). With the encryption, the key of the user is destroyed as well, which
+// shreds all files of the user (see DestroyKey).
+//
+// user: The ID of the user.
+//
+// Returns:
+// error: An error if the directory of the user could not be read or a file could not be removed.
+func (fs *FS) EraseUser(ctx context.Context, user int64) error {
+	if fs.Encryption != nil {
+		return fs.DestroyKey(ctx, user)
+	}
+
+	dir := fs.userDir(user)
+	unlock, err := fs.lockFilesIn(ctx, dir, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), corruptExt) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove the corrupted file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveRollup persists the aggregated statistics to the rollup.json file within the BaseDir.
+// If the file already exists, it will be overwritten.
+//
+// rollup: The rollup to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveRollup(ctx context.Context, rollup *chat.Rollup) error {
+	path := filepath.Join(fs.BaseDir, "rollup.json")
+
+	// Write the rollup to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, rollup.Write); err != nil {
+		return fmt.Errorf("error writing the rollup to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRollup retrieves the aggregated statistics from the rollup.json file within the BaseDir.
+// If the file does not exist, a new, empty Rollup instance is returned.
+//
+// Returns:
+// *Rollup: A pointer to the retrieved or newly created Rollup object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadRollup(ctx context.Context) (*chat.Rollup, error) {
+	path := filepath.Join(fs.BaseDir, "rollup.json")
 
 	// Open the file.
-	file, err := os.Open(path)
+	file, err := fs.openLocked(ctx, path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// If the file does not exist, return a new instance of chat.History.
-			return &chat.Statistics{
-				ID:          id,
-				LastMessage: 0,
-				Daily:       0,
-				Monthly:     map[time.Month]chat.Cost{},
-				Total:       0,
+			// If the file does not exist, return a new instance of chat.Rollup.
+			return &chat.Rollup{
+				PerUser:  map[int64]chat.Totals{},
+				PerModel: map[string]chat.Totals{},
 			}, nil
 		}
 		// For other errors, return an error.
@@ -159,12 +295,385 @@ func (fs *FS) LoadStatistics(_ context.Context, id chat.ID) (*chat.Statistics, e
 	}
 	defer file.Close()
 
-	// Decode the statistics from the file.
-	statistics := new(chat.Statistics)
-	err = statistics.Read(file)
+	// Decode the rollup from the file.
+	rollup := new(chat.Rollup)
+	err = rollup.Read(file)
 	if err != nil {
-		return nil, fmt.Errorf("error reading the statistics from the file: %w", err)
+		return nil, fmt.Errorf("error reading the rollup from the file: %w", err)
 	}
 
-	return statistics, nil
+	return rollup, nil
+}
+
+// SaveProfile persists the given user profile to the file system.
+// It creates a JSON file named after the user ID within the BaseDir.
+// If a file with the same name exists, it will be overwritten.
+//
+// profile: The user profile to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveProfile(ctx context.Context, profile *chat.Profile) error {
+	// Generate the path to save the profile using the user ID.
+	filename := fmt.Sprintf("profile-%d.json", profile.User)
+	path := fs.userPath(profile.User, filename)
+
+	// Write the profile to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, profile.Write); err != nil {
+		return fmt.Errorf("error writing the profile to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadProfile retrieves the profile of the given user from the file system.
+// If the file does not exist, a new Profile instance is returned.
+//
+// user: The ID of the user whose profile is to be loaded.
+//
+// Returns:
+// *Profile: A pointer to the retrieved or newly created Profile object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadProfile(ctx context.Context, user int64) (*chat.Profile, error) {
+	// Generate the path to load the profile using the user ID.
+	filename := fmt.Sprintf("profile-%d.json", user)
+	path := fs.userPath(user, filename)
+
+	// Open the file.
+	file, err := fs.openLocked(ctx, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// If the file does not exist, return a new instance of chat.Profile.
+			return &chat.Profile{User: user}, nil
+		}
+		// For other errors, return an error.
+		return nil, fmt.Errorf("could not open the file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode the profile from the file.
+	profile := new(chat.Profile)
+	err = profile.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the profile from the file: %w", err)
+	}
+
+	return profile, nil
+}
+
+// SaveBudget persists the given budget to the file system.
+// It creates a JSON file named after the owner ID within the BaseDir.
+// If a file with the same name exists, it will be overwritten.
+//
+// budget: The budget to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveBudget(ctx context.Context, budget *chat.Budget) error {
+	// Generate the path to save the budget using the owner ID.
+	filename := fmt.Sprintf("budget-%d.json", budget.Owner)
+	path := fs.userPath(budget.Owner, filename)
+
+	// Write the budget to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, budget.Write); err != nil {
+		return fmt.Errorf("error writing the budget to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadBudget retrieves the budget of the given user or group chat from the file system.
+// If the file does not exist, a new Budget instance without a limit is returned.
+//
+// owner: The ID of the user or the group chat whose budget is to be loaded.
+//
+// Returns:
+// *Budget: A pointer to the retrieved or newly created Budget object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadBudget(ctx context.Context, owner int64) (*chat.Budget, error) {
+	// Generate the path to load the budget using the owner ID.
+	filename := fmt.Sprintf("budget-%d.json", owner)
+	path := fs.userPath(owner, filename)
+
+	// Open the file.
+	file, err := fs.openLocked(ctx, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// If the file does not exist, return a new instance of chat.Budget.
+			return &chat.Budget{Owner: owner}, nil
+		}
+		// For other errors, return an error.
+		return nil, fmt.Errorf("could not open the file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode the budget from the file.
+	budget := new(chat.Budget)
+	err = budget.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the budget from the file: %w", err)
+	}
+
+	return budget, nil
+}
+
+// SaveLedger persists the given ledger to the file system.
+// It creates a JSON file named after the owner ID within the BaseDir.
+// If a file with the same name exists, it will be overwritten.
+//
+// ledger: The ledger to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveLedger(ctx context.Context, ledger *chat.Ledger) error {
+	// Generate the path to save the ledger using the owner ID.
+	filename := fmt.Sprintf("ledger-%d.json", ledger.Owner)
+	path := fs.userPath(ledger.Owner, filename)
+
+	// Write the ledger to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, ledger.Write); err != nil {
+		return fmt.Errorf("error writing the ledger to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadLedger retrieves the ledger of the given user or group chat from the file system.
+// If the file does not exist, a new, empty Ledger instance is returned.
+//
+// owner: The ID of the user or the group chat whose ledger is to be loaded.
+//
+// Returns:
+// *Ledger: A pointer to the retrieved or newly created Ledger object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadLedger(ctx context.Context, owner int64) (*chat.Ledger, error) {
+	// Generate the path to load the ledger using the owner ID.
+	filename := fmt.Sprintf("ledger-%d.json", owner)
+	path := fs.userPath(owner, filename)
+
+	// Read the file, or recover it if corrupted.
+	ledger, err := readRecovering(ctx, fs, path, func() *chat.Ledger {
+		return &chat.Ledger{
+			Owner:  owner,
+			Days:   map[string]chat.Cost{},
+			Months: map[string]chat.Cost{},
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the ledger from the file: %w", err)
+	}
+
+	// The name of the file identifies the ledger even if it has been recovered.
+	ledger.Owner = owner
+
+	return ledger, nil
+}
+
+// SaveInvite persists the given invite to the file system.
+// It creates a JSON file named after the invite code within the BaseDir.
+// If a file with the same name exists, it will be overwritten.
+//
+// invite: The invite to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveInvite(ctx context.Context, invite *chat.Invite) error {
+	if !validInviteCode(invite.Code) {
+		return fmt.Errorf("invalid invite code: %q", invite.Code)
+	}
+
+	// Generate the path to save the invite using the code.
+	filename := fmt.Sprintf("invite-%s.json", invite.Code)
+	path := filepath.Join(fs.BaseDir, filename)
+
+	// Write the invite to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, invite.Write); err != nil {
+		return fmt.Errorf("error writing the invite to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadInvite retrieves the invite with the given code from the file system.
+// If the file does not exist, a new, invalid Invite instance is returned.
+//
+// code: The code of the invite to be loaded.
+//
+// Returns:
+// *Invite: A pointer to the retrieved or newly created Invite object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadInvite(ctx context.Context, code string) (*chat.Invite, error) {
+	// The code comes from users, never let it escape the BaseDir.
+	if !validInviteCode(code) {
+		return &chat.Invite{Code: code}, nil
+	}
+
+	// Generate the path to load the invite using the code.
+	filename := fmt.Sprintf("invite-%s.json", code)
+	path := filepath.Join(fs.BaseDir, filename)
+
+	// Open the file.
+	file, err := fs.openLocked(ctx, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// If the file does not exist, return a new instance of chat.Invite.
+			return &chat.Invite{Code: code}, nil
+		}
+		// For other errors, return an error.
+		return nil, fmt.Errorf("could not open the file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode the invite from the file.
+	invite := new(chat.Invite)
+	err = invite.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the invite from the file: %w", err)
+	}
+
+	return invite, nil
+}
+
+// SaveUpdates persists the progress through the Telegram updates to the updates.json
+// file within the BaseDir. If the file already exists, it will be overwritten.
+//
+// updates: The progress to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveUpdates(ctx context.Context, updates *chat.Updates) error {
+	path := filepath.Join(fs.BaseDir, "updates.json")
+
+	// Write the progress to the file atomically in JSON format.
+	if err := fs.writeFile(ctx, path, updates.Write); err != nil {
+		return fmt.Errorf("error writing the updates to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadUpdates retrieves the progress through the Telegram updates from the updates.json
+// file within the BaseDir. If the file does not exist, a new Updates instance without
+// a processed update is returned.
+//
+// Returns:
+// *Updates: A pointer to the retrieved or newly created Updates object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadUpdates(ctx context.Context) (*chat.Updates, error) {
+	path := filepath.Join(fs.BaseDir, "updates.json")
+
+	// Open the file.
+	file, err := fs.openLocked(ctx, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// If the file does not exist, return a new instance of chat.Updates.
+			return &chat.Updates{}, nil
+		}
+		// For other errors, return an error.
+		return nil, fmt.Errorf("could not open the file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode the progress from the file.
+	updates := new(chat.Updates)
+	err = updates.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the updates from the file: %w", err)
+	}
+
+	return updates, nil
+}
+
+// SaveSettings persists the settings of the bot to the settings.json file within the
+// BaseDir. If the file already exists, it will be overwritten.
+//
+// settings: The settings to be saved.
+//
+// Returns:
+// error: An error if encountered during file operations or serialization.
+func (fs *FS) SaveSettings(ctx context.Context, settings *chat.Settings) error {
+	path := filepath.Join(fs.BaseDir, "settings.json")
+
+	if err := fs.writeFile(ctx, path, settings.Write); err != nil {
+		return fmt.Errorf("error writing the settings to the file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSettings retrieves the settings of the bot from the settings.json file within the
+// BaseDir. If the file does not exist, new Settings with the defaults are returned.
+//
+// Returns:
+// *Settings: A pointer to the retrieved or newly created Settings object.
+// error: An error if encountered during file operations or deserialization, except for file not found error.
+func (fs *FS) LoadSettings(ctx context.Context) (*chat.Settings, error) {
+	path := filepath.Join(fs.BaseDir, "settings.json")
+
+	settings, err := readRecovering(ctx, fs, path, func() *chat.Settings {
+		return &chat.Settings{}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the settings from the file: %w", err)
+	}
+
+	return settings, nil
+}
+
+// validInviteCode reports whether the invite code is safe to use in a file name:
+// it must be non-empty and contain only letters, digits, "-" and "_".
+func validInviteCode(code string) bool {
+	if code == "" {
+		return false
+	}
+
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// escapeModel escapes the model name for a file name. The names of the OpenRouter
+// models contain a slash, e.g. "openai/gpt-4o", which would be taken for a directory;
+// parseFilename unescapes them.
+//
+// model: The name of the model.
+func escapeModel(model string) string {
+	return url.PathEscape(model)
+}
+
+// Ping checks that the storage directory is readable and writable. It writes a
+// probe file into the BaseDir, reads it back and removes it.
+//
+// Returns:
+// error: An error if the probe file could not be written, read or removed.
+func (fs *FS) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := filepath.Join(fs.BaseDir, ".ping")
+	probe := []byte(time.Now().Format(time.RFC3339Nano))
+
+	if err := os.WriteFile(path, probe, 0644); err != nil {
+		return fmt.Errorf("could not write the probe file: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read the probe file: %w", err)
+	}
+
+	if string(data) != string(probe) {
+		return fmt.Errorf("probe file content mismatch")
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("could not remove the probe file: %w", err)
+	}
+
+	return nil
 }
@@ -1,9 +1,18 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,6 +30,7 @@ func TestSaveAndLoadHistory(t *testing.T) {
 
 	fs := FS{BaseDir: baseDir}
 	history := &chat.History{
+		Version: chat.HistoryVersion,
 		ID: chat.ID{
 			User:  123,
 			Chat:  456,
@@ -60,14 +70,15 @@ func TestSaveAndLoadStatistics(t *testing.T) {
 
 	fs := FS{BaseDir: baseDir}
 	statistics := &chat.Statistics{
+		Version: chat.StatisticsVersion,
 		ID: chat.ID{
 			User:  789,
 			Chat:  1011,
 			Model: "test-model",
 		},
 		LastMessage: 0.5,
-		Daily:       5.0,
-		Monthly:     map[time.Month]chat.Cost{time.January: 150.0},
+		Days:        map[string]chat.Cost{"2024-01-31": 5.0},
+		Months:      map[string]chat.Cost{"2024-01": 150.0},
 		Total:       155.5,
 	}
 
@@ -92,3 +103,1091 @@ func TestSaveAndLoadStatistics(t *testing.T) {
 		)
 	}
 }
+
+func TestSaveAndLoadRollup(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_rollup")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	fs := FS{BaseDir: baseDir}
+	rollup := &chat.Rollup{
+		Global:   chat.Totals{Today: 1.5, ThisMonth: 10, Total: 100},
+		PerUser:  map[int64]chat.Totals{123: {Today: 1.5, ThisMonth: 10, Total: 100}},
+		PerModel: map[string]chat.Totals{"test-model": {Today: 1.5, ThisMonth: 10, Total: 100}},
+		Sessions: 1,
+		Updated:  time.Date(2023, time.November, 20, 12, 0, 0, 0, time.UTC),
+	}
+
+	// Execute SaveRollup.
+	err = fs.SaveRollup(ctx, rollup)
+	if err != nil {
+		t.Fatalf("SaveRollup failed: %s", err)
+	}
+
+	// Execute LoadRollup.
+	loadedRollup, err := fs.LoadRollup(ctx)
+	if err != nil {
+		t.Fatalf("LoadRollup failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(rollup, loadedRollup) {
+		t.Errorf("Loaded rollup %+v does not match saved rollup %+v", loadedRollup, rollup)
+	}
+}
+
+func TestLoadLegacyStatistics(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_legacy_statistics")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	legacy := `{
+		"User": 1, "Chat": 2, "Model": "test-model",
+		"LastMessage": 0.5,
+		"Daily": 2.5,
+		"Monthly": {"1": 10, "12": 20},
+		"Total": 30,
+		"LastUpdate": "2024-01-15T10:00:00Z"
+	}`
+	path := filepath.Join(baseDir, "statistics-1-2-test-model.json")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy statistics: %s", err)
+	}
+
+	fs := FS{BaseDir: baseDir}
+
+	if _, err := fs.MigrateLayout(ctx); err != nil {
+		t.Fatalf("MigrateLayout failed: %s", err)
+	}
+
+	// Execute LoadStatistics.
+	statistics, err := fs.LoadStatistics(ctx, chat.ID{User: 1, Chat: 2, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("LoadStatistics failed: %s", err)
+	}
+
+	// Assert.
+	wantDays := map[string]chat.Cost{"2024-01-15": 2.5}
+	if !reflect.DeepEqual(statistics.Days, wantDays) {
+		t.Errorf("Migrated days %+v do not match %+v", statistics.Days, wantDays)
+	}
+
+	wantMonths := map[string]chat.Cost{"2024-01": 10, "2023-12": 20}
+	if !reflect.DeepEqual(statistics.Months, wantMonths) {
+		t.Errorf("Migrated months %+v do not match %+v", statistics.Months, wantMonths)
+	}
+}
+
+func TestSaveAndLoadProfile(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_profile")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	fs := FS{BaseDir: baseDir}
+	profile := &chat.Profile{
+		User:     123,
+		Timezone: "Europe/Berlin",
+	}
+
+	// Execute SaveProfile.
+	err = fs.SaveProfile(ctx, profile)
+	if err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	// Execute LoadProfile.
+	loadedProfile, err := fs.LoadProfile(ctx, profile.User)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(profile, loadedProfile) {
+		t.Errorf("Loaded profile %+v does not match saved profile %+v", loadedProfile, profile)
+	}
+}
+
+func TestSaveAndLoadBudget(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_budget")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	fs := FS{BaseDir: baseDir}
+	budget := &chat.Budget{
+		Owner:   -100123,
+		Monthly: 25,
+	}
+
+	// Execute SaveBudget.
+	err = fs.SaveBudget(ctx, budget)
+	if err != nil {
+		t.Fatalf("SaveBudget failed: %s", err)
+	}
+
+	// Execute LoadBudget.
+	loadedBudget, err := fs.LoadBudget(ctx, budget.Owner)
+	if err != nil {
+		t.Fatalf("LoadBudget failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(budget, loadedBudget) {
+		t.Errorf("Loaded budget %+v does not match saved budget %+v", loadedBudget, budget)
+	}
+
+	// A missing budget has no limit.
+	missing, err := fs.LoadBudget(ctx, 456)
+	if err != nil {
+		t.Fatalf("LoadBudget failed: %s", err)
+	}
+	if missing.Owner != 456 || missing.Monthly != 0 {
+		t.Errorf("Unexpected budget for a missing file: %+v", missing)
+	}
+}
+
+func TestSaveAndLoadInvite(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_invite")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	fs := FS{BaseDir: baseDir}
+	invite := &chat.Invite{
+		Code:      "a1b2c3d4",
+		MaxUses:   3,
+		Used:      1,
+		CreatedBy: 123,
+		Created:   time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	// Execute SaveInvite.
+	err = fs.SaveInvite(ctx, invite)
+	if err != nil {
+		t.Fatalf("SaveInvite failed: %s", err)
+	}
+
+	// Execute LoadInvite.
+	loadedInvite, err := fs.LoadInvite(ctx, invite.Code)
+	if err != nil {
+		t.Fatalf("LoadInvite failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(invite, loadedInvite) {
+		t.Errorf("Loaded invite %+v does not match saved invite %+v", loadedInvite, invite)
+	}
+
+	// Unknown and malformed codes are not valid.
+	for _, code := range []string{"unknown", "../a1b2c3d4", ""} {
+		loaded, err := fs.LoadInvite(ctx, code)
+		if err != nil {
+			t.Fatalf("LoadInvite(%q) failed: %s", code, err)
+		}
+		if loaded.Valid() {
+			t.Errorf("LoadInvite(%q) returned a valid invite", code)
+		}
+	}
+}
+
+func TestSaveAndLoadUpdates(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+
+	// A missing file means no update has been processed.
+	missing, err := fs.LoadUpdates(ctx)
+	if err != nil {
+		t.Fatalf("LoadUpdates failed: %s", err)
+	}
+	if missing.Offset() != 0 {
+		t.Errorf("Unexpected offset for a missing file: %d", missing.Offset())
+	}
+
+	updates := &chat.Updates{
+		LastID:  123456,
+		Updated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	// Execute SaveUpdates.
+	if err := fs.SaveUpdates(ctx, updates); err != nil {
+		t.Fatalf("SaveUpdates failed: %s", err)
+	}
+
+	// Execute LoadUpdates.
+	loadedUpdates, err := fs.LoadUpdates(ctx)
+	if err != nil {
+		t.Fatalf("LoadUpdates failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(updates, loadedUpdates) {
+		t.Errorf("Loaded updates %+v do not match saved updates %+v", loadedUpdates, updates)
+	}
+	if loadedUpdates.Offset() != 123457 {
+		t.Errorf("Unexpected offset: %d", loadedUpdates.Offset())
+	}
+}
+
+func TestSaveAndLoadSettings(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+
+	// A missing file means the defaults.
+	if missing, err := fs.LoadSettings(ctx); err != nil || missing.Maintenance {
+		t.Fatalf("LoadSettings returned %+v, %v, want the defaults", missing, err)
+	}
+
+	settings := &chat.Settings{Maintenance: true, Updated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	// Execute SaveSettings.
+	if err := fs.SaveSettings(ctx, settings); err != nil {
+		t.Fatalf("SaveSettings failed: %s", err)
+	}
+
+	// Execute LoadSettings.
+	loaded, err := fs.LoadSettings(ctx)
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %s", err)
+	}
+
+	// Assert.
+	if !reflect.DeepEqual(settings, loaded) {
+		t.Errorf("Loaded settings %+v do not match saved settings %+v", loaded, settings)
+	}
+}
+
+func TestFileLocker(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	locker := &FileLocker{BaseDir: t.TempDir(), TTL: time.Hour}
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	unlock, err := locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock failed: %s", err)
+	}
+
+	// The session is held, so the second lock waits until the context is done.
+	waitCtx, cancel := context.WithTimeout(ctx, 3*lockPollInterval)
+	defer cancel()
+	if _, err := locker.Lock(waitCtx, id); err == nil {
+		t.Fatal("Locked a session which is already locked")
+	}
+
+	// Another session is not affected, also with a model named after its provider.
+	unlockOther, err := locker.Lock(ctx, chat.ID{User: 1, Chat: 3, Model: "openai/gpt-4o"})
+	if err != nil {
+		t.Fatalf("Lock of another session failed: %s", err)
+	}
+	unlockOther()
+
+	unlock()
+	unlock, err = locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock after unlock failed: %s", err)
+	}
+	unlock()
+}
+
+func TestFileLockerTakeOver(t *testing.T) {
+	// Setup: a lock abandoned by a crashed instance.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+	path := filepath.Join(baseDir, "lock-1-2-gpt-4.lock")
+	if err := os.WriteFile(path, []byte("crashed"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	abandoned := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, abandoned, abandoned); err != nil {
+		t.Fatalf("Chtimes failed: %s", err)
+	}
+
+	// Execute: the instances find the lock abandoned at the same time.
+	var (
+		wg              sync.WaitGroup
+		holders, failed atomic.Int32
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			locker := &FileLocker{BaseDir: baseDir, TTL: time.Hour}
+			unlock, err := locker.Lock(ctx, id)
+			if err != nil {
+				t.Errorf("Lock failed: %s", err)
+				return
+			}
+			defer unlock()
+
+			if holders.Add(1) > 1 {
+				failed.Store(1)
+			}
+			time.Sleep(10 * time.Millisecond)
+			holders.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	// Assert.
+	if failed.Load() != 0 {
+		t.Error("The abandoned lock was taken over by several instances at once")
+	}
+}
+
+func TestFileLockerRefresh(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+	locker := &FileLocker{BaseDir: baseDir, TTL: 150 * time.Millisecond}
+
+	unlock, err := locker.Lock(ctx, id)
+	if err != nil {
+		t.Fatalf("Lock failed: %s", err)
+	}
+
+	// Execute: the lock is held much longer than its TTL.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*locker.TTL)
+	defer cancel()
+	if _, err := locker.Lock(waitCtx, id); err == nil {
+		t.Fatal("Took over a lock which is still held")
+	}
+
+	// Assert: a lock taken over meanwhile is not removed by the previous holder.
+	path := filepath.Join(baseDir, "lock-1-2-gpt-4.lock")
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	unlock()
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "other" {
+		t.Errorf("The lock of the other instance is %q, %v after the unlock", data, err)
+	}
+}
+
+func TestWriteFileKeepsPreviousVersion(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	fs := FS{BaseDir: baseDir}
+
+	history := &chat.History{
+		Version: chat.HistoryVersion,
+		ID:      chat.ID{User: 1, Chat: 2, Model: "gpt-4"},
+		Log:     []chat.Message{{User: "Hello", Assistant: "Hi"}},
+	}
+	if err := fs.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// A write failing halfway, like a crash, must not touch the saved file.
+	path := filepath.Join(baseDir, "1", "1", "history-1-2-gpt-4.json")
+	err := fs.writeFile(ctx, path, func(w io.Writer) error {
+		w.Write([]byte(`{"ID": {"User": 1`))
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("writeFile succeeded with a failing write")
+	}
+
+	loadedHistory, err := fs.LoadHistory(ctx, history.ID)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if !reflect.DeepEqual(history, loadedHistory) {
+		t.Errorf("Loaded history %+v does not match saved history %+v", loadedHistory, history)
+	}
+
+	// No temporary file is left behind.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %s", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Found the temporary file %s", entry.Name())
+		}
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+
+	history := &chat.History{
+		Version: chat.HistoryVersion,
+		ID:      chat.ID{User: 1, Chat: 2, Model: "gpt-4"},
+		Log:     []chat.Message{{User: "Hello", Assistant: "Hi"}},
+	}
+	if err := fs.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	// A cancelled save must not replace the saved file.
+	changed := history.Clone()
+	changed.Add(chat.Message{User: "Bye", Assistant: "Bye"})
+	if err := fs.SaveHistory(cancelled, changed); !errors.Is(err, context.Canceled) {
+		t.Errorf("SaveHistory with a cancelled context returned %v", err)
+	}
+	if _, err := fs.LoadHistory(cancelled, history.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadHistory with a cancelled context returned %v", err)
+	}
+	if _, err := fs.List(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("List with a cancelled context returned %v", err)
+	}
+
+	loadedHistory, err := fs.LoadHistory(ctx, history.ID)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if !reflect.DeepEqual(history, loadedHistory) {
+		t.Errorf("Loaded history %+v does not match saved history %+v", loadedHistory, history)
+	}
+
+	// A write cancelled halfway is abandoned.
+	halfway, cancel := context.WithCancel(ctx)
+	path := filepath.Join(fs.userDir(1), "history-1-2-gpt-4.json")
+	err = fs.writeFile(halfway, path, func(w io.Writer) error {
+		w.Write([]byte(`{"ID": {"User": 1`))
+		cancel()
+		_, err := w.Write([]byte(`}}`))
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("writeFile cancelled halfway returned %v", err)
+	}
+	if loadedHistory, err = fs.LoadHistory(ctx, history.ID); err != nil || !reflect.DeepEqual(history, loadedHistory) {
+		t.Errorf("LoadHistory after a cancelled write returned %+v, %v", loadedHistory, err)
+	}
+}
+
+func TestLoadCorruptedFiles(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	fs := FS{BaseDir: baseDir}
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	// Truncated files written by an older version without the atomic writes.
+	files := map[string]string{
+		"history-1-2-gpt-4.json":    `{"ID": {"User": 1, "Chat": 2, "Model": "gpt-4"}, "Log": [{"User": "Hi", "Assistant": "Hello"}, {"User": "Bye", "Assis`,
+		"statistics-1-2-gpt-4.json": `{"ID": {"User": 1, "Ch`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %s", err)
+		}
+	}
+
+	if _, err := fs.MigrateLayout(ctx); err != nil {
+		t.Fatalf("MigrateLayout failed: %s", err)
+	}
+
+	// The complete messages of the history are recovered.
+	history, err := fs.LoadHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if history.ID != id || len(history.Log) != 1 || history.Log[0].Assistant != "Hello" {
+		t.Errorf("LoadHistory recovered %+v, want the first message", history)
+	}
+
+	// Nothing is recovered from the statistics, new ones are returned.
+	statistics, err := fs.LoadStatistics(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadStatistics failed: %s", err)
+	}
+	if statistics.ID != id || statistics.Total != 0 {
+		t.Errorf("LoadStatistics recovered %+v, want new statistics", statistics)
+	}
+
+	// The corrupted files are kept aside and not listed.
+	corrupted, _ := filepath.Glob(filepath.Join(fs.userDir(1), "*"+corruptExt))
+	if len(corrupted) != 2 {
+		t.Errorf("Quarantined %v, want both files", corrupted)
+	}
+
+	ids, err := fs.List(ctx)
+	if err != nil || len(ids) != 0 {
+		t.Errorf("Listed %+v, %v, want no sessions", ids, err)
+	}
+
+	// The corrupted files are removed on the erasure of the user's data.
+	if err := fs.EraseUser(ctx, 1); err != nil {
+		t.Fatalf("EraseUser failed: %s", err)
+	}
+	if corrupted, _ := filepath.Glob(filepath.Join(fs.userDir(1), "*"+corruptExt)); len(corrupted) != 0 {
+		t.Errorf("Kept %v after EraseUser", corrupted)
+	}
+}
+
+func TestShardedLayout(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	fs := FS{BaseDir: baseDir}
+
+	// A profile saved in the flat layout by an older version.
+	flat := filepath.Join(baseDir, "profile-12345.json")
+	if err := os.WriteFile(flat, []byte(`{"User": 12345, "Timezone": "Europe/Moscow"}`), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+
+	// Execute MigrateLayout and LoadProfile.
+	if moved, err := fs.MigrateLayout(ctx); err != nil || moved != 1 {
+		t.Fatalf("MigrateLayout returned %d, %v, want 1 moved file", moved, err)
+	}
+	profile, err := fs.LoadProfile(ctx, 12345)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %s", err)
+	}
+
+	// Assert.
+	if profile.Timezone != "Europe/Moscow" {
+		t.Errorf("Loaded profile %+v, want the one saved in the flat layout", profile)
+	}
+	if _, err := os.Stat(flat); !os.IsNotExist(err) {
+		t.Errorf("The flat file was not moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "12", "12345", "profile-12345.json")); err != nil {
+		t.Errorf("The file was not moved to the shard: %s", err)
+	}
+
+	// The group chats are sharded as well.
+	if err := fs.SaveBudget(ctx, &chat.Budget{Owner: -100123, Monthly: 5}); err != nil {
+		t.Fatalf("SaveBudget failed: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "10", "-100123", "budget--100123.json")); err != nil {
+		t.Errorf("The budget was not saved to the shard: %s", err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	plain := FS{BaseDir: baseDir}
+	compressed := FS{BaseDir: baseDir, Compress: true}
+
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+	history := &chat.History{
+		Version: chat.HistoryVersion,
+		ID:      id,
+		Log:     []chat.Message{{User: "Hello", Assistant: "Hi"}},
+	}
+
+	// A history saved before the compression was enabled is still read.
+	if err := plain.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	loadedHistory, err := compressed.LoadHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if !reflect.DeepEqual(history, loadedHistory) {
+		t.Errorf("Loaded history %+v does not match saved history %+v", loadedHistory, history)
+	}
+
+	// Saving with the compression replaces the uncompressed file.
+	history.Log = append(history.Log, chat.Message{User: "Bye", Assistant: "Bye"})
+	if err := compressed.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	path := filepath.Join(baseDir, "1", "1", "history-1-2-gpt-4.json")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("The uncompressed file was not removed: %v", err)
+	}
+	if _, err := os.Stat(path + ".gz"); err != nil {
+		t.Errorf("The compressed file was not written: %s", err)
+	}
+
+	// Both storages read the compressed file.
+	for _, fs := range []FS{plain, compressed} {
+		loadedHistory, err := fs.LoadHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("LoadHistory failed: %s", err)
+		}
+		if !reflect.DeepEqual(history, loadedHistory) {
+			t.Errorf("Loaded history %+v does not match saved history %+v", loadedHistory, history)
+		}
+	}
+
+	// The compressed files are listed.
+	listed, err := compressed.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+	if !reflect.DeepEqual(listed, []chat.ID{id}) {
+		t.Errorf("Listed IDs %+v, want %+v", listed, []chat.ID{id})
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+
+	encryption, err := NewEncryption(bytes.Repeat([]byte{1}, 32), "")
+	if err != nil {
+		t.Fatalf("NewEncryption failed: %s", err)
+	}
+	fs := FS{BaseDir: baseDir, Encryption: encryption, Compress: true}
+
+	// A profile saved before the encryption was enabled is still read.
+	plain := FS{BaseDir: baseDir}
+	if err := plain.SaveProfile(ctx, &chat.Profile{User: 2, Timezone: "UTC"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+	if profile, err := fs.LoadProfile(ctx, 2); err != nil || profile.Timezone != "UTC" {
+		t.Errorf("LoadProfile returned %+v, %v, want the plaintext profile", profile, err)
+	}
+
+	history := &chat.History{
+		Version: chat.HistoryVersion,
+		ID:      chat.ID{User: 1, Chat: 2, Model: "gpt-4"},
+		Log:     []chat.Message{{User: "My secret", Assistant: "Safe with me"}},
+	}
+	if err := fs.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	loadedHistory, err := fs.LoadHistory(ctx, history.ID)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if !reflect.DeepEqual(history, loadedHistory) {
+		t.Errorf("Loaded history %+v does not match saved history %+v", loadedHistory, history)
+	}
+
+	// The file on the disk is not plaintext.
+	path := filepath.Join(baseDir, "1", "1", "history-1-2-gpt-4.json.gz")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %s", err)
+	}
+	if !bytes.HasPrefix(data, userEncryptedMagic) {
+		t.Error("The history is not encrypted with the key of the user")
+	}
+
+	// The file cannot be read without the key or with another key.
+	if _, err := plain.LoadHistory(ctx, history.ID); err == nil {
+		t.Error("Loaded the encrypted history without the key")
+	}
+	other, _ := NewEncryption(bytes.Repeat([]byte{2}, 32), "")
+	if _, err := (&FS{BaseDir: baseDir, Encryption: other}).LoadHistory(ctx, history.ID); err == nil {
+		t.Error("Loaded the encrypted history with another key")
+	}
+
+	// The file cannot be passed off as the history of another chat.
+	moved := filepath.Join(baseDir, "1", "1", "history-1-3-gpt-4.json.gz")
+	if err := os.WriteFile(moved, data, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	if _, err := fs.LoadHistory(ctx, chat.ID{User: 1, Chat: 3, Model: "gpt-4"}); err == nil {
+		t.Error("Loaded the history renamed to another chat")
+	}
+}
+
+func TestDestroyKey(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, keyDir := t.TempDir(), t.TempDir()
+
+	// The salts saved in the user directories by an older version are moved.
+	legacy := FS{BaseDir: baseDir, Encryption: must(NewEncryption(bytes.Repeat([]byte{1}, 32), ""))}
+	if err := legacy.SaveProfile(ctx, &chat.Profile{User: 2, Timezone: "UTC"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	encryption, err := NewEncryption(bytes.Repeat([]byte{1}, 32), keyDir)
+	if err != nil {
+		t.Fatalf("NewEncryption failed: %s", err)
+	}
+	fs := FS{BaseDir: baseDir, Encryption: encryption}
+
+	if err := fs.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "UTC"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+	history := &chat.History{ID: chat.ID{User: 1, Chat: 1, Model: "gpt-4"}, Log: []chat.Message{{User: "Hi"}}}
+	if err := fs.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	if err := fs.SaveStatistics(ctx, &chat.Statistics{ID: history.ID, Total: 1}); err != nil {
+		t.Fatalf("SaveStatistics failed: %s", err)
+	}
+	rollup := &chat.Rollup{PerUser: map[int64]chat.Totals{1: {Total: 1}}, PerModel: map[string]chat.Totals{}}
+	if err := fs.SaveRollup(ctx, rollup); err != nil {
+		t.Fatalf("SaveRollup failed: %s", err)
+	}
+	if profile, err := fs.LoadProfile(ctx, 2); err != nil || profile.Timezone != "UTC" {
+		t.Errorf("LoadProfile with the moved salt returned %+v, %v", profile, err)
+	}
+	for _, user := range []int64{1, 2} {
+		if _, err := os.Stat(filepath.Join(keyDir, strconv.FormatInt(user, 10), strconv.FormatInt(user, 10), saltFilename(user))); err != nil {
+			t.Errorf("The salt of the user %d is not in the key directory: %s", user, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "2", "2", saltFilename(2))); !os.IsNotExist(err) {
+		t.Errorf("The salt is left in the data directory: %v", err)
+	}
+
+	// A copy of the history, e.g. in a backup, taken before the erasure.
+	path := filepath.Join(baseDir, "1", "1", "history-1-1-gpt-4.json")
+	backup, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %s", err)
+	}
+
+	// Execute EraseUser.
+	if err := fs.EraseUser(ctx, 1); err != nil {
+		t.Fatalf("EraseUser failed: %s", err)
+	}
+
+	// Assert: the conversations are removed, the profile and the spending are kept.
+	if loaded, err := fs.LoadHistory(ctx, history.ID); err != nil || len(loaded.Log) != 0 {
+		t.Errorf("LoadHistory of the erased user returned %+v, %v, want an empty history", loaded, err)
+	}
+	if ids, err := fs.List(ctx); err != nil || len(ids) != 0 {
+		t.Errorf("List returned %v, %v, want no sessions", ids, err)
+	}
+	if profile, err := fs.LoadProfile(ctx, 1); err != nil || profile.Timezone != "UTC" {
+		t.Errorf("LoadProfile of the erased user returned %+v, %v, want the kept profile", profile, err)
+	}
+	if ledger, err := fs.LoadLedger(ctx, 1); err != nil || ledger.Total != 1 {
+		t.Errorf("LoadLedger of the erased user returned %+v, %v, want the kept ledger", ledger, err)
+	}
+	if profile, err := fs.LoadProfile(ctx, 2); err != nil || profile.Timezone != "UTC" {
+		t.Errorf("LoadProfile of another user returned %+v, %v", profile, err)
+	}
+	if loaded, err := fs.LoadRollup(ctx); err != nil || !reflect.DeepEqual(loaded, rollup) {
+		t.Errorf("LoadRollup returned %+v, %v, want %+v", loaded, err, rollup)
+	}
+
+	// The copy taken before the erasure cannot be decrypted with the new key, even after a restart.
+	if err := os.WriteFile(path, backup, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	restarted := FS{BaseDir: baseDir, Encryption: must(NewEncryption(bytes.Repeat([]byte{1}, 32), keyDir))}
+	if loaded, err := restarted.LoadHistory(ctx, history.ID); err == nil && len(loaded.Log) != 0 {
+		t.Error("Loaded the history of the erased user")
+	}
+}
+
+func TestDestroyKeyInterrupted(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, keyDir := t.TempDir(), t.TempDir()
+	key := bytes.Repeat([]byte{1}, 32)
+	fs := FS{BaseDir: baseDir, Encryption: must(NewEncryption(key, keyDir))}
+
+	profile := &chat.Profile{User: 1, Timezone: "UTC"}
+	if err := fs.SaveProfile(ctx, profile); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+	id := chat.ID{User: 1, Chat: 1, Model: "gpt-4"}
+	if err := fs.SaveHistory(ctx, &chat.History{ID: id, Log: []chat.Message{{User: "Hi"}}}); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// Execute: the shredding stops after the profile is written under the new key,
+	// before the old salt is replaced.
+	dir := fs.userDir(1)
+	salt := bytes.Repeat([]byte{2}, saltSize)
+	if err := writeSalt(fs.Encryption.pendingSaltPath(dir, 1), salt); err != nil {
+		t.Fatalf("writeSalt failed: %s", err)
+	}
+	aead := must(fs.Encryption.deriveAEAD(1, salt))
+	path := filepath.Join(dir, "profile-1.json")
+	if err := fs.replaceFile(ctx, path, sealWriter(profile.Write, aead, userEncryptedMagic, path)); err != nil {
+		t.Fatalf("replaceFile failed: %s", err)
+	}
+
+	// Assert: after a restart both the kept profile and the history are readable, and
+	// the erasure completes.
+	restarted := FS{BaseDir: baseDir, Encryption: must(NewEncryption(key, keyDir))}
+	if loaded, err := restarted.LoadProfile(ctx, 1); err != nil || loaded.Timezone != "UTC" {
+		t.Errorf("LoadProfile after the interruption returned %+v, %v", loaded, err)
+	}
+	if loaded, err := restarted.LoadHistory(ctx, id); err != nil || len(loaded.Log) != 1 {
+		t.Errorf("LoadHistory after the interruption returned %+v, %v", loaded, err)
+	}
+
+	if err := restarted.DestroyKey(ctx, 1); err != nil {
+		t.Fatalf("DestroyKey failed: %s", err)
+	}
+	if loaded, err := restarted.LoadProfile(ctx, 1); err != nil || loaded.Timezone != "UTC" {
+		t.Errorf("LoadProfile after the erasure returned %+v, %v", loaded, err)
+	}
+	if loaded, err := restarted.LoadHistory(ctx, id); err != nil || len(loaded.Log) != 0 {
+		t.Errorf("LoadHistory after the erasure returned %+v, %v, want an empty history", loaded, err)
+	}
+}
+
+func TestDestroyKeyShared(t *testing.T) {
+	// Setup: two instances sharing the storage, each with its own cache of the ciphers.
+	ctx := context.Background()
+	baseDir, keyDir := t.TempDir(), t.TempDir()
+	key := bytes.Repeat([]byte{1}, 32)
+	first := FS{BaseDir: baseDir, Encryption: must(NewEncryption(key, keyDir))}
+	second := FS{BaseDir: baseDir, Encryption: must(NewEncryption(key, keyDir))}
+
+	if err := first.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "UTC"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	// Execute: the second instance erases the user the first one has cached the cipher of.
+	if err := second.DestroyKey(ctx, 1); err != nil {
+		t.Fatalf("DestroyKey failed: %s", err)
+	}
+
+	id := chat.ID{User: 1, Chat: 1, Model: "gpt-4"}
+	if err := first.SaveHistory(ctx, &chat.History{ID: id, Log: []chat.Message{{User: "Hi"}}}); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// Assert: the files written by the first instance are readable by the second one.
+	if loaded, err := first.LoadProfile(ctx, 1); err != nil || loaded.Timezone != "UTC" {
+		t.Errorf("LoadProfile of the first instance returned %+v, %v", loaded, err)
+	}
+	if loaded, err := second.LoadHistory(ctx, id); err != nil || len(loaded.Log) != 1 {
+		t.Errorf("LoadHistory of the second instance returned %+v, %v", loaded, err)
+	}
+}
+
+func must[T any](result T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func TestDeleteHistoryAndStatistics(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	if err := fs.SaveHistory(ctx, &chat.History{ID: id, Log: []chat.Message{{User: "Hi"}}}); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	if err := fs.SaveStatistics(ctx, &chat.Statistics{ID: id, Total: 1}); err != nil {
+		t.Fatalf("SaveStatistics failed: %s", err)
+	}
+
+	// Execute DeleteHistory and DeleteStatistics, twice to check the missing files.
+	for i := 0; i < 2; i++ {
+		if err := fs.DeleteHistory(ctx, id); err != nil {
+			t.Fatalf("DeleteHistory failed: %s", err)
+		}
+		if err := fs.DeleteStatistics(ctx, id); err != nil {
+			t.Fatalf("DeleteStatistics failed: %s", err)
+		}
+	}
+
+	// Assert.
+	listed, err := fs.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("Listed %+v after the deletion", listed)
+	}
+}
+
+func TestLedger(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+	now := chat.Now()
+	id := chat.ID{User: 1, Chat: -100, Model: "gpt-4"}
+
+	stats := &chat.Statistics{ID: id}
+	stats.AddCost(now, id.Model, 2)
+	stats.AddTokens(now, chat.Tokens{Input: 10, Output: 5})
+
+	// Execute SaveStatistics, delete the statistics and save new ones, as after an erasure.
+	if err := fs.SaveStatistics(ctx, stats); err != nil {
+		t.Fatalf("SaveStatistics failed: %s", err)
+	}
+	if err := fs.DeleteStatistics(ctx, id); err != nil {
+		t.Fatalf("DeleteStatistics failed: %s", err)
+	}
+
+	stats = &chat.Statistics{ID: id}
+	stats.AddCost(now, id.Model, 3)
+	stats.AddTokens(now, chat.Tokens{Input: 1, Output: 1})
+	if err := fs.SaveStatistics(ctx, stats); err != nil {
+		t.Fatalf("SaveStatistics failed: %s", err)
+	}
+
+	// Assert both the user's and the group chat's ledgers kept the whole spending.
+	for _, owner := range []int64{id.User, id.Chat} {
+		ledger, err := fs.LoadLedger(ctx, owner)
+		if err != nil {
+			t.Fatalf("LoadLedger failed: %s", err)
+		}
+
+		if ledger.Total != 5 || ledger.ThisMonth(now) != 5 || ledger.Today(now) != 5 {
+			t.Errorf("Ledger of %d holds %+v, want 5 spent", owner, ledger)
+		}
+		if ledger.Usage.Messages != 2 || ledger.Usage.Tokens.Input != 11 {
+			t.Errorf("Ledger of %d holds the usage %+v, want 2 messages", owner, ledger.Usage)
+		}
+	}
+
+	owners, err := fs.ListLedgers(ctx)
+	if err != nil {
+		t.Fatalf("ListLedgers failed: %s", err)
+	}
+	if len(owners) != 2 {
+		t.Errorf("ListLedgers returned %v, want the user and the chat", owners)
+	}
+}
+
+func TestLedgerSavedBeforeStatistics(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+	now := chat.Now()
+	id := chat.ID{User: 1, Chat: 1, Model: "gpt-4"}
+
+	stats := &chat.Statistics{ID: id}
+	stats.AddCost(now, id.Model, 2)
+
+	// Execute recordSpending with a failing save of the statistics.
+	failed := errors.New("disk full")
+	if err := recordSpending(ctx, &fs, stats, func() error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("recordSpending returned %v, want the error of the save", err)
+	}
+
+	// Assert the spending reached the ledger even though the statistics were not saved.
+	ledger, err := fs.LoadLedger(ctx, id.User)
+	if err != nil {
+		t.Fatalf("LoadLedger failed: %s", err)
+	}
+	if ledger.Total != 2 {
+		t.Errorf("Ledger holds %v after the failed save, want 2", ledger.Total)
+	}
+}
+
+func TestFillLedgers(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	fs := FS{BaseDir: t.TempDir()}
+	now := chat.Now()
+	ids := []chat.ID{
+		{User: 1, Chat: 1, Model: "gpt-4"},
+		{User: 1, Chat: -100, Model: "gpt-4"},
+		{User: 2, Chat: -100, Model: "gpt-4"},
+	}
+
+	for _, id := range ids {
+		stats := &chat.Statistics{ID: id}
+		stats.AddCost(now, id.Model, 1)
+		if err := fs.SaveStatistics(ctx, stats); err != nil {
+			t.Fatalf("SaveStatistics failed: %s", err)
+		}
+	}
+
+	// Remove the ledgers, as if the statistics were saved by an older version.
+	for _, owner := range []int64{1, 2, -100} {
+		if err := os.Remove(fs.userPath(owner, fmt.Sprintf("ledger-%d.json", owner))); err != nil {
+			t.Fatalf("Failed to remove the ledger: %s", err)
+		}
+	}
+
+	// Execute FillLedgers twice, the second call must not add the spending again.
+	filled, err := FillLedgers(ctx, &fs)
+	if err != nil {
+		t.Fatalf("FillLedgers failed: %s", err)
+	}
+	if filled != 3 {
+		t.Errorf("FillLedgers filled %d ledgers, want 3", filled)
+	}
+
+	if filled, err = FillLedgers(ctx, &fs); err != nil || filled != 0 {
+		t.Errorf("FillLedgers filled %d ledgers again, %v", filled, err)
+	}
+
+	// Assert.
+	for owner, want := range map[int64]chat.Cost{1: 2, 2: 1, -100: 2} {
+		ledger, err := fs.LoadLedger(ctx, owner)
+		if err != nil {
+			t.Fatalf("LoadLedger failed: %s", err)
+		}
+		if ledger.Total != want || ledger.ThisMonth(now) != want {
+			t.Errorf("Ledger of %d holds %+v, want %v spent", owner, ledger, want)
+		}
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir := t.TempDir()
+	fs := FS{BaseDir: baseDir}
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	// A history stored before the versioning.
+	legacy := `{"ID": {"User": 1, "Chat": 2, "Model": "gpt-4"}, "Log": [{"User": "Hi"}]}`
+	if err := os.WriteFile(filepath.Join(baseDir, "history-1-2-gpt-4.json"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+
+	if _, err := fs.MigrateLayout(ctx); err != nil {
+		t.Fatalf("MigrateLayout failed: %s", err)
+	}
+
+	// Execute LoadHistory.
+	history, err := fs.LoadHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+
+	// Assert.
+	if history.Version != chat.HistoryVersion || len(history.Log) != 1 {
+		t.Errorf("Loaded %+v, want the upgraded history", history)
+	}
+
+	// A statistics stored by a newer version is not loaded with the unknown fields lost.
+	future := fmt.Sprintf(`{"ID": {"User": 1, "Chat": 2, "Model": "gpt-4"}, "Version": %d}`, chat.StatisticsVersion+1)
+	if err := os.WriteFile(filepath.Join(baseDir, "statistics-1-2-gpt-4.json"), []byte(future), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+
+	if _, err := fs.MigrateLayout(ctx); err != nil {
+		t.Fatalf("MigrateLayout failed: %s", err)
+	}
+
+	if _, err := fs.LoadStatistics(ctx, id); err == nil {
+		t.Error("LoadStatistics loaded the statistics of a newer version")
+	}
+}
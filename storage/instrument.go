@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that Instrumented implements the chat.Storage interface
+var _ chat.Storage = (*Instrumented)(nil)
+
+// slowOperation is the duration of a storage operation which is logged as slow.
+const slowOperation = time.Second
+
+// OperationStats holds the metrics of an operation of the storage.
+type OperationStats struct {
+	Operation string        // Operation is the name of the method, e.g. "LoadHistory".
+	Calls     uint64        // Calls is the number of the calls.
+	Errors    uint64        // Errors is the number of the failed calls.
+	Duration  time.Duration // Duration is the total duration of the calls.
+	Bytes     uint64        // Bytes is the total number of the bytes the backend wrote or read, as stored.
+}
+
+// Instrumented is a chat.Storage which records the number, the latency, the errors and
+// the sizes of the objects of the operations of any storage, and logs the slow ones.
+type Instrumented struct {
+	chat.Wrapper // Wrapper wraps the instrumented storage, keeping its optional interfaces.
+
+	mu  sync.Mutex
+	ops map[string]*OperationStats
+}
+
+// NewInstrumented wraps the storage with the instrumentation.
+//
+// backend: The storage to instrument.
+func NewInstrumented(backend chat.Storage) *Instrumented {
+	return &Instrumented{
+		Wrapper: chat.Wrapper{Storage: backend},
+		ops:     make(map[string]*OperationStats),
+	}
+}
+
+// Stats returns the metrics of the operations called so far, sorted by their names.
+func (s *Instrumented) Stats() []OperationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]OperationStats, 0, len(s.ops))
+	for _, op := range s.ops {
+		stats = append(stats, *op)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Operation < stats[j].Operation })
+
+	return stats
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format.
+//
+// w: The writer of the metrics, e.g. the response of the metrics endpoint.
+func (s *Instrumented) WriteMetrics(w io.Writer) error {
+	stats := s.Stats()
+
+	metrics := []struct {
+		name, kind, help string
+		value            func(OperationStats) string
+	}{
+		{"tgpt_storage_operations_total", "counter", "The number of the storage operations.",
+			func(op OperationStats) string { return fmt.Sprint(op.Calls) }},
+		{"tgpt_storage_errors_total", "counter", "The number of the failed storage operations.",
+			func(op OperationStats) string { return fmt.Sprint(op.Errors) }},
+		{"tgpt_storage_duration_seconds_total", "counter", "The total duration of the storage operations.",
+			func(op OperationStats) string { return fmt.Sprint(op.Duration.Seconds()) }},
+		{"tgpt_storage_bytes_total", "counter", "The total number of the bytes the storage wrote and read.",
+			func(op OperationStats) string { return fmt.Sprint(op.Bytes) }},
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+
+		for _, op := range stats {
+			if _, err := fmt.Fprintf(w, "%s{operation=%q} %s\n", metric.name, op.Operation, metric.value(op)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// observe records the call of the operation.
+//
+// op: The name of the operation.
+// start: The time the call started.
+// size: The size of the saved or loaded object, zero if none.
+// err: The error of the call.
+func (s *Instrumented) observe(op string, start time.Time, size int, err error) {
+	elapsed := time.Since(start)
+
+	s.mu.Lock()
+	stats, ok := s.ops[op]
+	if !ok {
+		stats = &OperationStats{Operation: op}
+		s.ops[op] = stats
+	}
+	stats.Calls++
+	stats.Duration += elapsed
+	stats.Bytes += uint64(size)
+	if err != nil {
+		stats.Errors++
+	}
+	s.mu.Unlock()
+
+	if elapsed >= slowOperation {
+		slog.Warn("slow storage operation", slog.String("operation", op), slog.Duration("duration", elapsed))
+	}
+}
+
+// instrumentSave records the call of the operation saving an object with the number of
+// the bytes the backend has written.
+func instrumentSave(ctx context.Context, s *Instrumented, op string, save func(context.Context) error) error {
+	start := time.Now()
+	ctx, size := withSizeCounter(ctx)
+	err := save(ctx)
+	s.observe(op, start, int(size.Load()), err)
+	return err
+}
+
+// instrumentLoad records the call of the operation loading an object with the number of
+// the bytes the backend has read.
+func instrumentLoad[T any](ctx context.Context, s *Instrumented, op string, load func(context.Context) (T, error)) (T, error) {
+	start := time.Now()
+	ctx, size := withSizeCounter(ctx)
+	obj, err := load(ctx)
+	s.observe(op, start, int(size.Load()), err)
+	return obj, err
+}
+
+// instrumentCall records the call of the operation without an object.
+func instrumentCall[T any](s *Instrumented, op string, call func() (T, error)) (T, error) {
+	start := time.Now()
+	result, err := call()
+	s.observe(op, start, 0, err)
+	return result, err
+}
+
+// SaveHistory saves the history to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// history: The History object to be saved.
+func (s *Instrumented) SaveHistory(ctx context.Context, history *chat.History) error {
+	return instrumentSave(ctx, s, "SaveHistory", func(ctx context.Context) error { return s.Storage.SaveHistory(ctx, history) })
+}
+
+// LoadHistory loads the history from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat.
+func (s *Instrumented) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	return instrumentLoad(ctx, s, "LoadHistory", func(ctx context.Context) (*chat.History, error) { return s.Storage.LoadHistory(ctx, id) })
+}
+
+// DeleteHistory removes the history with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat history to be removed.
+func (s *Instrumented) DeleteHistory(ctx context.Context, id chat.ID) error {
+	_, err := instrumentCall(s, "DeleteHistory", func() (any, error) { return nil, s.Storage.DeleteHistory(ctx, id) })
+	return err
+}
+
+// SaveStatistics saves the statistics to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// statistics: The chat statistics to be saved.
+func (s *Instrumented) SaveStatistics(ctx context.Context, statistics *chat.Statistics) error {
+	return instrumentSave(ctx, s, "SaveStatistics", func(ctx context.Context) error { return s.Storage.SaveStatistics(ctx, statistics) })
+}
+
+// LoadStatistics loads the statistics from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat.
+func (s *Instrumented) LoadStatistics(ctx context.Context, id chat.ID) (*chat.Statistics, error) {
+	return instrumentLoad(ctx, s, "LoadStatistics", func(ctx context.Context) (*chat.Statistics, error) { return s.Storage.LoadStatistics(ctx, id) })
+}
+
+// DeleteStatistics removes the statistics with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// id: The ID of the chat statistics to be removed.
+func (s *Instrumented) DeleteStatistics(ctx context.Context, id chat.ID) error {
+	_, err := instrumentCall(s, "DeleteStatistics", func() (any, error) { return nil, s.Storage.DeleteStatistics(ctx, id) })
+	return err
+}
+
+// List lists the IDs of the stored chats with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) List(ctx context.Context) ([]chat.ID, error) {
+	return instrumentCall(s, "List", func() ([]chat.ID, error) { return s.Storage.List(ctx) })
+}
+
+// EraseUser erases the data of the user with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user or the group chat.
+func (s *Instrumented) EraseUser(ctx context.Context, user int64) error {
+	_, err := instrumentCall(s, "EraseUser", func() (any, error) { return nil, s.Storage.EraseUser(ctx, user) })
+	return err
+}
+
+// SaveRollup saves the rollup to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// rollup: The rollup to be saved.
+func (s *Instrumented) SaveRollup(ctx context.Context, rollup *chat.Rollup) error {
+	return instrumentSave(ctx, s, "SaveRollup", func(ctx context.Context) error { return s.Wrapper.SaveRollup(ctx, rollup) })
+}
+
+// LoadRollup loads the rollup from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) LoadRollup(ctx context.Context) (*chat.Rollup, error) {
+	return instrumentLoad(ctx, s, "LoadRollup", func(ctx context.Context) (*chat.Rollup, error) { return s.Wrapper.LoadRollup(ctx) })
+}
+
+// SaveProfile saves the profile to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// profile: The profile to be saved.
+func (s *Instrumented) SaveProfile(ctx context.Context, profile *chat.Profile) error {
+	return instrumentSave(ctx, s, "SaveProfile", func(ctx context.Context) error { return s.Wrapper.SaveProfile(ctx, profile) })
+}
+
+// LoadProfile loads the profile from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (s *Instrumented) LoadProfile(ctx context.Context, user int64) (*chat.Profile, error) {
+	return instrumentLoad(ctx, s, "LoadProfile", func(ctx context.Context) (*chat.Profile, error) { return s.Wrapper.LoadProfile(ctx, user) })
+}
+
+// SaveBudget saves the budget to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// budget: The budget to be saved.
+func (s *Instrumented) SaveBudget(ctx context.Context, budget *chat.Budget) error {
+	return instrumentSave(ctx, s, "SaveBudget", func(ctx context.Context) error { return s.Wrapper.SaveBudget(ctx, budget) })
+}
+
+// LoadBudget loads the budget from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// owner: The ID of the user or the group chat.
+func (s *Instrumented) LoadBudget(ctx context.Context, owner int64) (*chat.Budget, error) {
+	return instrumentLoad(ctx, s, "LoadBudget", func(ctx context.Context) (*chat.Budget, error) { return s.Wrapper.LoadBudget(ctx, owner) })
+}
+
+// SaveLedger saves the ledger to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// ledger: The ledger to be saved.
+func (s *Instrumented) SaveLedger(ctx context.Context, ledger *chat.Ledger) error {
+	return instrumentSave(ctx, s, "SaveLedger", func(ctx context.Context) error { return s.Wrapper.SaveLedger(ctx, ledger) })
+}
+
+// LoadLedger loads the ledger from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// owner: The ID of the user or the group chat.
+func (s *Instrumented) LoadLedger(ctx context.Context, owner int64) (*chat.Ledger, error) {
+	return instrumentLoad(ctx, s, "LoadLedger", func(ctx context.Context) (*chat.Ledger, error) { return s.Wrapper.LoadLedger(ctx, owner) })
+}
+
+// SaveInvite saves the invite to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// invite: The invite to be saved.
+func (s *Instrumented) SaveInvite(ctx context.Context, invite *chat.Invite) error {
+	return instrumentSave(ctx, s, "SaveInvite", func(ctx context.Context) error { return s.Wrapper.SaveInvite(ctx, invite) })
+}
+
+// LoadInvite loads the invite from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// code: The code of the invite.
+func (s *Instrumented) LoadInvite(ctx context.Context, code string) (*chat.Invite, error) {
+	return instrumentLoad(ctx, s, "LoadInvite", func(ctx context.Context) (*chat.Invite, error) { return s.Wrapper.LoadInvite(ctx, code) })
+}
+
+// SaveUpdates saves the progress of the updates to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// updates: The progress to be saved.
+func (s *Instrumented) SaveUpdates(ctx context.Context, updates *chat.Updates) error {
+	return instrumentSave(ctx, s, "SaveUpdates", func(ctx context.Context) error { return s.Wrapper.SaveUpdates(ctx, updates) })
+}
+
+// LoadUpdates loads the progress of the updates from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) LoadUpdates(ctx context.Context) (*chat.Updates, error) {
+	return instrumentLoad(ctx, s, "LoadUpdates", func(ctx context.Context) (*chat.Updates, error) { return s.Wrapper.LoadUpdates(ctx) })
+}
+
+// SaveSettings saves the settings to the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+// settings: The settings to be saved.
+func (s *Instrumented) SaveSettings(ctx context.Context, settings *chat.Settings) error {
+	return instrumentSave(ctx, s, "SaveSettings", func(ctx context.Context) error { return s.Wrapper.SaveSettings(ctx, settings) })
+}
+
+// LoadSettings loads the settings from the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) LoadSettings(ctx context.Context) (*chat.Settings, error) {
+	return instrumentLoad(ctx, s, "LoadSettings", func(ctx context.Context) (*chat.Settings, error) { return s.Wrapper.LoadSettings(ctx) })
+}
+
+// ListProfiles lists the IDs of the users with a profile with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) ListProfiles(ctx context.Context) ([]int64, error) {
+	return instrumentCall(s, "ListProfiles", func() ([]int64, error) { return s.Wrapper.ListProfiles(ctx) })
+}
+
+// ListBudgets lists the owners of the budgets with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) ListBudgets(ctx context.Context) ([]int64, error) {
+	return instrumentCall(s, "ListBudgets", func() ([]int64, error) { return s.Wrapper.ListBudgets(ctx) })
+}
+
+// ListLedgers lists the owners of the ledgers with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) ListLedgers(ctx context.Context) ([]int64, error) {
+	return instrumentCall(s, "ListLedgers", func() ([]int64, error) { return s.Wrapper.ListLedgers(ctx) })
+}
+
+// ListInvites lists the codes of the invites with the instrumented storage and records the call.
+//
+// ctx: The context for the storage operation.
+func (s *Instrumented) ListInvites(ctx context.Context) ([]string, error) {
+	return instrumentCall(s, "ListInvites", func() ([]string, error) { return s.Wrapper.ListInvites(ctx) })
+}
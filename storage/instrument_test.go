@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestInstrumented(t *testing.T) {
+	ctx := context.Background()
+	store := NewInstrumented(failingStorage{NewMemory()})
+
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+	history := &chat.History{ID: id}
+	history.Add(chat.Message{User: "Hello", Assistant: "Hi"})
+
+	if err := store.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+	if _, err := store.LoadHistory(ctx, id); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if _, err := store.LoadHistory(ctx, chat.ID{User: 3}); !errors.Is(err, errFailing) {
+		t.Fatalf("LoadHistory missing: %v", err)
+	}
+
+	stats := store.Stats()
+	if len(stats) != 2 || stats[0].Operation != "LoadHistory" || stats[1].Operation != "SaveHistory" {
+		t.Fatalf("unexpected operations: %+v", stats)
+	}
+	if stats[0].Calls != 2 || stats[0].Errors != 1 || stats[0].Bytes == 0 {
+		t.Errorf("unexpected LoadHistory stats: %+v", stats[0])
+	}
+	if stats[1].Calls != 1 || stats[1].Errors != 0 || stats[1].Bytes != stats[0].Bytes {
+		t.Errorf("unexpected SaveHistory stats: %+v", stats[1])
+	}
+
+	var metrics strings.Builder
+	if err := store.WriteMetrics(&metrics); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	if !strings.Contains(metrics.String(), `tgpt_storage_errors_total{operation="LoadHistory"} 1`) {
+		t.Errorf("unexpected metrics:\n%s", metrics.String())
+	}
+}
+
+// errFailing is returned by failingStorage.
+var errFailing = errors.New("failing")
+
+// failingStorage fails to load the histories of the user 3.
+type failingStorage struct {
+	*Memory
+}
+
+func (s failingStorage) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	if id.User == 3 {
+		return nil, errFailing
+	}
+
+	return s.Memory.LoadHistory(ctx, id)
+}
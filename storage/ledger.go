@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ledgerLocker is implemented by the backends keeping the ledgers, which lock them
+// while the spending is added.
+type ledgerLocker interface {
+	// lockLedgers keeps the other saves of the statistics and of the ledgers of the
+	// owners out until the returned function is called.
+	lockLedgers(ctx context.Context, owners []int64) (func(), error)
+}
+
+// ledgerStorage is a storage keeping the statistics and the ledgers.
+type ledgerStorage interface {
+	chat.Storage
+	chat.Ledgers
+}
+
+// recordSpending adds the growth of the statistics since the stored version to the
+// ledgers of their owners (see chat.LedgerOwners) and then saves the statistics. The
+// caller must keep the other saves of the statistics and of the ledgers out meanwhile.
+// The ledgers are saved first, so if a save fails, the next save of the statistics
+// counts the growth again: the spending may be counted twice, but it is never lost.
+//
+// ctx: The context for the storage operations.
+// storage: The storage of the statistics and the ledgers.
+// statistics: The statistics being saved.
+// save: The function saving the statistics.
+//
+// Returns:
+// error: An error if the statistics or a ledger could not be loaded or saved.
+func recordSpending(ctx context.Context, storage ledgerStorage, statistics *chat.Statistics, save func() error) error {
+	before, err := storage.LoadStatistics(ctx, statistics.ID)
+	if err != nil {
+		return fmt.Errorf("error loading the stored statistics: %w", err)
+	}
+
+	for _, owner := range chat.LedgerOwners(statistics.ID) {
+		ledger, err := storage.LoadLedger(ctx, owner)
+		if err != nil {
+			return fmt.Errorf("error loading the ledger: %w", err)
+		}
+
+		ledger.Add(before, statistics)
+		if err := storage.SaveLedger(ctx, ledger); err != nil {
+			return fmt.Errorf("error saving the ledger: %w", err)
+		}
+	}
+
+	return save()
+}
+
+// FillLedgers adds the spending recorded in the statistics before the ledgers were
+// introduced to the ledgers of their owners. It is called once at startup, before the
+// storage is used: the settings record that the ledgers have been filled, and the
+// ledgers already updated since are left as they are.
+//
+// ctx: The context for the storage operations.
+// storage: The storage of the statistics and the ledgers.
+//
+// Returns:
+// int: The number of the filled ledgers.
+// error: An error if the storage does not keep ledgers and settings, or the statistics,
+// the ledgers or the settings could not be loaded or saved.
+func FillLedgers(ctx context.Context, storage chat.Storage) (int, error) {
+	ledgers, ok := storage.(ledgerStorage)
+	if !ok {
+		return 0, errors.New("the storage does not keep ledgers")
+	}
+
+	locker, ok := storage.(ledgerLocker)
+	if !ok {
+		return 0, errors.New("the storage does not lock ledgers")
+	}
+
+	configuration, ok := storage.(chat.Configuration)
+	if !ok {
+		return 0, errors.New("the storage does not keep settings")
+	}
+
+	settings, err := configuration.LoadSettings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error loading the settings: %w", err)
+	}
+	if settings.LedgersFilled {
+		return 0, nil
+	}
+
+	ids, err := storage.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error listing the sessions: %w", err)
+	}
+
+	filled := make(map[int64]*chat.Ledger)
+	for _, id := range ids {
+		stats, err := storage.LoadStatistics(ctx, id)
+		if err != nil {
+			return 0, fmt.Errorf("error loading the statistics: %w", err)
+		}
+
+		for _, owner := range chat.LedgerOwners(id) {
+			if filled[owner] == nil {
+				filled[owner] = &chat.Ledger{Owner: owner}
+			}
+			filled[owner].Add(nil, stats)
+		}
+	}
+
+	owners := make([]int64, 0, len(filled))
+	for owner := range filled {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+
+	count := 0
+	for _, owner := range owners {
+		saved, err := fillLedger(ctx, ledgers, locker, filled[owner])
+		if err != nil {
+			return count, err
+		}
+		if saved {
+			count++
+		}
+	}
+
+	// Load the settings again, they may have been changed by another instance meanwhile.
+	if settings, err = configuration.LoadSettings(ctx); err != nil {
+		return count, fmt.Errorf("error loading the settings: %w", err)
+	}
+
+	settings.LedgersFilled = true
+	if err := configuration.SaveSettings(ctx, settings); err != nil {
+		return count, fmt.Errorf("error saving the settings: %w", err)
+	}
+
+	return count, nil
+}
+
+// fillLedger saves the filled ledger unless the stored ledger of the owner has been
+// updated already, e.g. by a previous, interrupted fill.
+//
+// ctx: The context for the storage operations.
+// storage: The storage of the ledgers.
+// locker: The locker of the ledgers of the storage.
+// ledger: The ledger filled with the stored statistics.
+//
+// Returns:
+// bool: True if the ledger was saved.
+// error: An error if the ledger could not be locked, loaded or saved.
+func fillLedger(ctx context.Context, storage chat.Ledgers, locker ledgerLocker, ledger *chat.Ledger) (bool, error) {
+	unlock, err := locker.lockLedgers(ctx, []int64{ledger.Owner})
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	stored, err := storage.LoadLedger(ctx, ledger.Owner)
+	if err != nil {
+		return false, fmt.Errorf("error loading the ledger: %w", err)
+	}
+	if !stored.Updated.IsZero() {
+		return false, nil
+	}
+
+	if err := storage.SaveLedger(ctx, ledger); err != nil {
+		return false, fmt.Errorf("error saving the ledger: %w", err)
+	}
+
+	return true, nil
+}
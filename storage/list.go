@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	iofs "io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// List enumerates the identifiers of all chat sessions with a history or statistics
+// file in the BaseDir, including the shard directories of the users. Files that do not
+// follow the storage naming scheme are ignored. If the BaseDir does not exist, an empty
+// list is returned.
+//
+// Returns:
+// []chat.ID: The identifiers of the stored chat sessions.
+// error: An error if encountered while reading the directories.
+func (fs *FS) List(ctx context.Context) ([]chat.ID, error) {
+	seen := make(map[chat.ID]struct{})
+	ids := make([]chat.ID, 0)
+
+	err := filepath.WalkDir(fs.BaseDir, func(_ string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		id, ok := parseFilename(entry.Name())
+		if !ok {
+			return nil
+		}
+
+		if _, exists := seen[id]; exists {
+			return nil
+		}
+
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []chat.ID{}, nil
+		}
+		return nil, fmt.Errorf("could not read the directory: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ListProfiles enumerates the IDs of all users with a profile file in the storage.
+//
+// Returns:
+// []int64: The IDs of the users.
+// error: An error if encountered while reading the directories.
+func (fs *FS) ListProfiles(ctx context.Context) ([]int64, error) {
+	return fs.listOwners(ctx, "profile-")
+}
+
+// ListBudgets enumerates the IDs of all users and group chats with a budget file in
+// the storage.
+//
+// Returns:
+// []int64: The IDs of the owners of the budgets.
+// error: An error if encountered while reading the directories.
+func (fs *FS) ListBudgets(ctx context.Context) ([]int64, error) {
+	return fs.listOwners(ctx, "budget-")
+}
+
+// ListLedgers enumerates the IDs of all users and group chats with a ledger file in
+// the storage.
+//
+// Returns:
+// []int64: The IDs of the owners of the ledgers.
+// error: An error if encountered while reading the directories.
+func (fs *FS) ListLedgers(ctx context.Context) ([]int64, error) {
+	return fs.listOwners(ctx, "ledger-")
+}
+
+// ListInvites enumerates the codes of all invite files in the BaseDir.
+//
+// Returns:
+// []string: The codes of the invites.
+// error: An error if encountered while reading the directory.
+func (fs *FS) ListInvites(ctx context.Context) ([]string, error) {
+	codes := make([]string, 0)
+	err := fs.walk(ctx, func(name string) {
+		if code, ok := strings.CutPrefix(name, "invite-"); ok && validInviteCode(code) {
+			codes = append(codes, code)
+		}
+	})
+
+	return codes, err
+}
+
+// listOwners enumerates the IDs in the names of the files with the prefix, such as
+// "profile-123.json".
+//
+// ctx: The context of the listing.
+// prefix: The prefix of the names of the files.
+func (fs *FS) listOwners(ctx context.Context, prefix string) ([]int64, error) {
+	owners := make([]int64, 0)
+	err := fs.walk(ctx, func(name string) {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			return
+		}
+
+		if owner, err := strconv.ParseInt(rest, 10, 64); err == nil {
+			owners = append(owners, owner)
+		}
+	})
+
+	return owners, err
+}
+
+// walk passes the names of all data files in the BaseDir and the shard directories
+// to fn, without the ".json" and ".gz" extensions. A file saved both compressed and
+// uncompressed is passed once. If the BaseDir does not exist, fn is never called.
+//
+// ctx: The context of the listing.
+// fn: The callback invoked for every file.
+//
+// Returns:
+// error: An error if encountered while reading the directories.
+func (fs *FS) walk(ctx context.Context, fn func(name string)) error {
+	seen := make(map[string]struct{})
+
+	err := filepath.WalkDir(fs.BaseDir, func(_ string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		name, ok := strings.CutSuffix(strings.TrimSuffix(entry.Name(), gzipExt), ".json")
+		if !ok {
+			return nil
+		}
+
+		if _, exists := seen[name]; exists {
+			return nil
+		}
+
+		seen[name] = struct{}{}
+		fn(name)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read the directory: %w", err)
+	}
+
+	return nil
+}
+
+// parseFilename extracts the chat ID from a history or statistics file name
+// (e.g., "history-123--456-gpt-4.json").
+//
+// name: The base name of the file.
+//
+// Returns:
+// chat.ID: The parsed chat ID.
+// bool: False if the name does not follow the storage naming scheme.
+func parseFilename(name string) (chat.ID, bool) {
+	rest, ok := strings.CutSuffix(strings.TrimSuffix(name, gzipExt), ".json")
+	if !ok {
+		return chat.ID{}, false
+	}
+
+	if r, ok := strings.CutPrefix(rest, "history-"); ok {
+		rest = r
+	} else if r, ok := strings.CutPrefix(rest, "statistics-"); ok {
+		rest = r
+	} else {
+		return chat.ID{}, false
+	}
+
+	var id chat.ID
+	if n, err := fmt.Sscanf(rest, "%d-%d-%s", &id.User, &id.Chat, &id.Model); err != nil || n != 3 {
+		return chat.ID{}, false
+	}
+
+	// Sscanf stops at white space, make sure the whole model name was consumed.
+	if !strings.HasSuffix(rest, "-"+id.Model) {
+		return chat.ID{}, false
+	}
+
+	if model, err := url.PathUnescape(id.Model); err == nil {
+		id.Model = model
+	}
+
+	return id, true
+}
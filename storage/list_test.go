@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestList(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	baseDir, err := os.MkdirTemp("", "test_list")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir) // Clean up.
+
+	fs := FS{BaseDir: baseDir}
+	ids := []chat.ID{
+		{User: 1, Chat: 1, Model: "gpt-4"},
+		{User: 2, Chat: -100123, Model: "gpt-4-1106-preview"},
+		{User: 3, Chat: 3, Model: "openai/gpt-4o"},
+	}
+
+	for _, id := range ids {
+		if err := fs.SaveHistory(ctx, &chat.History{ID: id}); err != nil {
+			t.Fatalf("SaveHistory failed: %s", err)
+		}
+		if err := fs.SaveStatistics(ctx, &chat.Statistics{ID: id}); err != nil {
+			t.Fatalf("SaveStatistics failed: %s", err)
+		}
+	}
+
+	// Unrelated files must be ignored.
+	if err := os.WriteFile(filepath.Join(baseDir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create unrelated file: %s", err)
+	}
+
+	// Execute List.
+	listed, err := fs.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+
+	// Assert.
+	sort.Slice(listed, func(i, j int) bool { return listed[i].User < listed[j].User })
+	if !reflect.DeepEqual(ids, listed) {
+		t.Errorf("Listed IDs %+v do not match saved IDs %+v", listed, ids)
+	}
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that the FileLocker implements the chat.Locker interface
+var _ chat.Locker = (*FileLocker)(nil)
+
+// lockPollInterval is how often a held lock is checked while waiting for it.
+const lockPollInterval = 100 * time.Millisecond
+
+// FileLocker locks the chat sessions with lock files in a directory shared by the
+// instances of the bot, usually the BaseDir of the FS storage. A lock file is created
+// exclusively, so only one instance holds it at a time. The modification time of the
+// lock file is refreshed while the lock is held.
+type FileLocker struct {
+	BaseDir string // BaseDir is the directory of the lock files.
+
+	// TTL is the age after which a lock which has not been refreshed is considered
+	// abandoned by a crashed instance and is taken over; zero means the locks never expire.
+	TTL time.Duration
+}
+
+// Lock waits until the lock file of the session can be created.
+//
+// ctx: The context to stop waiting for the lock.
+// id: The ID of the session to lock.
+//
+// Returns:
+// func(): The function removing the lock file and ending its refresh.
+// error: An error if the context is done first or the lock file could not be created.
+func (l *FileLocker) Lock(ctx context.Context, id chat.ID) (func(), error) {
+	filename := fmt.Sprintf("lock-%d-%d-%s.lock", id.User, id.Chat, escapeModel(id.Model))
+	path := filepath.Join(l.BaseDir, filename)
+
+	// The token tells this lock from the one of another instance which took it over.
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = file.WriteString(token)
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("could not write the lock file: %w", err)
+			}
+
+			stop := l.refresh(path, token)
+			return func() {
+				stop()
+				l.unlock(path, token)
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("could not create the lock file: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && l.TTL > 0 && time.Since(info.ModTime()) > l.TTL {
+			// The instance holding the lock has crashed.
+			l.takeOver(path, info, token)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not lock the session: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// takeOver removes the abandoned lock file, so it can be created again. Several instances
+// may find it abandoned at the same time, and one of them may have already taken it over
+// and created a new lock file, which must not be removed. So the lock file is first
+// renamed, which only one instance manages, and a lock file other than the abandoned one
+// is put back.
+//
+// path: The path of the lock file.
+// stale: The information of the abandoned lock file.
+// token: The token of the lock being taken, which names the renamed file apart.
+func (l *FileLocker) takeOver(path string, stale os.FileInfo, token string) {
+	taken := path + "." + token
+	if err := os.Rename(path, taken); err != nil {
+		return
+	}
+
+	if info, err := os.Stat(taken); err == nil && !os.SameFile(info, stale) {
+		os.Link(taken, path)
+	}
+
+	os.Remove(taken)
+}
+
+// refresh keeps updating the modification time of the lock file while it holds the
+// token, so the lock is not taken over however long it is held.
+//
+// path: The path of the lock file.
+// token: The token written to the lock file when it was created.
+//
+// Returns the function ending the refresh.
+func (l *FileLocker) refresh(path, token string) func() {
+	if l.TTL <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(l.TTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+
+			case <-ticker.C:
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != token {
+				// The lock has been taken over.
+				return
+			}
+
+			now := time.Now()
+			os.Chtimes(path, now, now)
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// unlock removes the lock file unless another instance has taken the lock over. Reading
+// the token and removing the file are not atomic, so the lock file is first renamed to
+// a name of its own, which keeps a lock file created meanwhile by another instance from
+// being removed, and it is put back if it holds another token.
+//
+// path: The path of the lock file.
+// token: The token written to the lock file when it was created.
+func (l *FileLocker) unlock(path, token string) {
+	released := path + "." + token + ".released"
+	if err := os.Rename(path, released); err != nil {
+		return
+	}
+
+	if data, err := os.ReadFile(released); err != nil || string(data) != token {
+		// Put back the lock of the other instance, unless yet another one has been created.
+		os.Link(released, path)
+	}
+
+	os.Remove(released)
+}
+
+// lockToken returns a random token identifying a lock.
+func lockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("could not generate the lock token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
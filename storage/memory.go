@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that Memory implements the chat.Storage interface
+var _ chat.Storage = (*Memory)(nil)
+
+// Memory is a chat.Storage keeping everything in memory, for the tests, the demos and
+// the ephemeral deployments. The objects are kept serialized, so the callers never
+// share them with the storage. The content can be saved to a snapshot file, e.g. on
+// shutdown, and loaded back with LoadMemory.
+type Memory struct {
+	*Objects
+
+	store *memoryStore
+}
+
+// memoryStore is an ObjectStore keeping the objects in a map.
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte // objects holds the serialized objects by their names.
+}
+
+// NewMemory creates an empty Memory storage.
+func NewMemory() *Memory {
+	store := &memoryStore{objects: make(map[string][]byte)}
+	return &Memory{Objects: NewObjects(store), store: store}
+}
+
+// LoadMemory creates a Memory storage with the content of the snapshot file. If the
+// file does not exist or the path is empty, the storage is empty.
+//
+// path: The path of the snapshot file.
+//
+// Returns:
+// *Memory: The storage with the content of the snapshot.
+// error: An error if the snapshot exists but could not be read.
+func LoadMemory(path string) (*Memory, error) {
+	m := NewMemory()
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the snapshot: %w", err)
+	}
+
+	var objects map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("error decoding the snapshot: %w", err)
+	}
+
+	for name, object := range objects {
+		m.store.objects[name] = object
+	}
+
+	return m, nil
+}
+
+// Snapshot saves all the content of the storage to the file, replacing it atomically.
+//
+// path: The path of the snapshot file.
+//
+// Returns:
+// error: An error if the snapshot could not be written.
+func (m *Memory) Snapshot(path string) error {
+	m.store.mu.RLock()
+	objects := make(map[string]json.RawMessage, len(m.store.objects))
+	for name, object := range m.store.objects {
+		objects[name] = object
+	}
+	m.store.mu.RUnlock()
+
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return fmt.Errorf("error encoding the snapshot: %w", err)
+	}
+
+	// The snapshot holds the whole storage, so only the owner can read it.
+	err = replaceFile(context.Background(), path, 0600, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not write the snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Ping always succeeds, the memory is always available.
+func (m *Memory) Ping(_ context.Context) error {
+	return nil
+}
+
+// Get returns the object from the map.
+func (s *memoryStore) Get(_ context.Context, name string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.objects[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+// Put keeps the object in the map.
+func (s *memoryStore) Put(_ context.Context, name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[name] = data
+	return nil
+}
+
+// Delete removes the object from the map.
+func (s *memoryStore) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, name)
+	return nil
+}
+
+// List returns the sorted names of the objects starting with the prefix.
+func (s *memoryStore) List(_ context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0)
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestMemorySnapshot(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	memory := NewMemory()
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	history := &chat.History{ID: id, Log: []chat.Message{{User: "Hello", Assistant: "Hi"}}}
+	if err := memory.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+	if err := memory.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "Europe/Moscow"}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+	if err := memory.SaveInvite(ctx, &chat.Invite{Code: "welcome"}); err != nil {
+		t.Fatalf("SaveInvite failed: %s", err)
+	}
+
+	// The saved objects are copies.
+	history.Log[0].User = "Changed"
+
+	// Execute Snapshot and LoadMemory.
+	if err := memory.Snapshot(path); err != nil {
+		t.Fatalf("Snapshot failed: %s", err)
+	}
+	restored, err := LoadMemory(path)
+	if err != nil {
+		t.Fatalf("LoadMemory failed: %s", err)
+	}
+
+	// Assert.
+	loaded, err := restored.LoadHistory(ctx, id)
+	if err != nil || len(loaded.Log) != 1 || loaded.Log[0].User != "Hello" {
+		t.Errorf("LoadHistory returned %+v, %v, want the saved history", loaded, err)
+	}
+
+	profile, err := restored.LoadProfile(ctx, 1)
+	if err != nil || profile.Timezone != "Europe/Moscow" {
+		t.Errorf("LoadProfile returned %+v, %v, want the saved profile", profile, err)
+	}
+
+	ids, _ := restored.List(ctx)
+	if !reflect.DeepEqual(ids, []chat.ID{id}) {
+		t.Errorf("List returned %+v, want %+v", ids, []chat.ID{id})
+	}
+
+	codes, _ := restored.ListInvites(ctx)
+	if !reflect.DeepEqual(codes, []string{"welcome"}) {
+		t.Errorf("ListInvites returned %v, want [welcome]", codes)
+	}
+
+	// A missing snapshot gives an empty storage.
+	empty, err := LoadMemory(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadMemory failed: %s", err)
+	}
+	if ids, _ := empty.List(ctx); len(ids) != 0 {
+		t.Errorf("List returned %+v, want no sessions", ids)
+	}
+}
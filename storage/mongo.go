@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ensure that Mongo implements the ObjectStore interface
+var _ ObjectStore = (*Mongo)(nil)
+
+// mongoObject is the document of an object. The object is kept as an embedded
+// document, so its fields can be queried; the content which is not a JSON object, and
+// the objects written by the earlier versions, are kept as a string. The objects of the
+// chat sessions carry their IDs, which are indexed for the queries of the operators.
+type mongoObject struct {
+	Name    string    `bson:"_id"`
+	User    int64     `bson:"user,omitempty"`
+	Chat    int64     `bson:"chat,omitempty"`
+	Model   string    `bson:"model,omitempty"`
+	Doc     bson.Raw  `bson:"doc,omitempty"`
+	Data    string    `bson:"data,omitempty"`
+	Updated time.Time `bson:"updated"`
+}
+
+// newMongoObject creates the document of the object.
+//
+// name: The name of the object.
+// data: The content of the object, usually in JSON.
+func newMongoObject(name string, data []byte) mongoObject {
+	obj := mongoObject{Name: name, Updated: time.Now()}
+	if id, ok := parseFilename(name); ok {
+		obj.User, obj.Chat, obj.Model = id.User, id.Chat, id.Model
+	}
+
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err == nil {
+		if obj.Doc, err = bson.Marshal(doc); err == nil {
+			return obj
+		}
+	}
+
+	obj.Data = string(data)
+	return obj
+}
+
+// content returns the content of the object.
+func (obj *mongoObject) content() ([]byte, error) {
+	if obj.Doc == nil {
+		return []byte(obj.Data), nil
+	}
+
+	data, err := bson.MarshalExtJSON(obj.Doc, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("error converting the document of %s: %w", obj.Name, err)
+	}
+
+	return data, nil
+}
+
+// Mongo is an ObjectStore keeping the objects in a MongoDB database, a collection per
+// kind of the objects.
+type Mongo struct {
+	client   *mongo.Client
+	database *mongo.Database
+}
+
+// NewMongo connects to the MongoDB server and creates the indexes of the collections.
+//
+// ctx: The context for the connection.
+// uri: The connection string, e.g. "mongodb://localhost:27017".
+// database: The name of the database.
+//
+// Returns:
+// *Mongo: The store of the objects in the database.
+// error: An error if the server is unreachable or the indexes could not be created.
+func NewMongo(ctx context.Context, uri, database string) (*Mongo, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to mongo: %w", err)
+	}
+
+	m := &Mongo{client: client, database: client.Database(database)}
+
+	if err := m.Ping(ctx); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	for _, kind := range objectKinds {
+		if !kind.session {
+			continue
+		}
+
+		_, err := m.database.Collection(kind.name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "user", Value: 1}, {Key: "chat", Value: 1}, {Key: "model", Value: 1}},
+		})
+		if err != nil {
+			client.Disconnect(ctx)
+			return nil, fmt.Errorf("error creating the index of %s: %w", kind.name, err)
+		}
+	}
+
+	return m, nil
+}
+
+// Get returns the content of the object.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+//
+// Returns the content of the object, or ErrNotFound if it does not exist.
+func (m *Mongo) Get(ctx context.Context, name string) ([]byte, error) {
+	var obj mongoObject
+	err := m.collection(name).FindOne(ctx, bson.D{{Key: "_id", Value: name}}).Decode(&obj)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.content()
+}
+
+// Put creates or replaces the object.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+// data: The content of the object.
+func (m *Mongo) Put(ctx context.Context, name string, data []byte) error {
+	obj := newMongoObject(name, data)
+
+	_, err := m.collection(name).ReplaceOne(
+		ctx,
+		bson.D{{Key: "_id", Value: name}},
+		obj,
+		options.Replace().SetUpsert(true),
+	)
+
+	return err
+}
+
+// Delete removes the object; removing a missing object is not an error.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+func (m *Mongo) Delete(ctx context.Context, name string) error {
+	_, err := m.collection(name).DeleteOne(ctx, bson.D{{Key: "_id", Value: name}})
+	return err
+}
+
+// List returns the names of the objects starting with the prefix.
+//
+// ctx: The context for the query.
+// prefix: The prefix of the names.
+func (m *Mongo) List(ctx context.Context, prefix string) ([]string, error) {
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}}}}
+	cursor, err := m.collection(prefix).Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	names := make([]string, 0)
+	for cursor.Next(ctx) {
+		var obj mongoObject
+		if err := cursor.Decode(&obj); err != nil {
+			return nil, err
+		}
+		names = append(names, obj.Name)
+	}
+
+	return names, cursor.Err()
+}
+
+// Ping checks that the server is reachable.
+//
+// ctx: The context for the check.
+func (m *Mongo) Ping(ctx context.Context) error {
+	if err := m.client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("error pinging mongo: %w", err)
+	}
+
+	return nil
+}
+
+// Close disconnects from the server.
+//
+// ctx: The context for the disconnection.
+func (m *Mongo) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+// collection returns the collection keeping the objects with the name or the prefix.
+func (m *Mongo) collection(name string) *mongo.Collection {
+	return m.database.Collection(kindOf(name))
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMongoObject(t *testing.T) {
+	// Setup.
+	id := chat.ID{User: 1234567890123, Chat: -100, Model: "gpt-3.5-turbo"}
+	now := time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC)
+	statistics := &chat.Statistics{ID: id, Version: chat.StatisticsVersion}
+	statistics.AddCost(now, id.Model, 0.000125)
+	statistics.AddCost(now, "openai/gpt-4o", 2)
+	statistics.AddTokens(now, chat.Tokens{Input: 10, Output: 20})
+
+	var data bytes.Buffer
+	if err := statistics.Write(&data); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+
+	// Execute: the document goes through the server as BSON.
+	name := fmt.Sprintf("statistics-%d-%d-%s.json", id.User, id.Chat, escapeModel(id.Model))
+	raw, err := bson.Marshal(newMongoObject(name, data.Bytes()))
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	var obj mongoObject
+	if err := bson.Unmarshal(raw, &obj); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+
+	// Verify: the fields of the object are stored as the fields of the document.
+	if obj.Data != "" {
+		t.Errorf("The object is stored as a string: %q", obj.Data)
+	}
+	if user, ok := obj.Doc.Lookup("User").AsInt64OK(); !ok || user != id.User {
+		t.Errorf("The User field of the document is %v, want %d", obj.Doc.Lookup("User"), id.User)
+	}
+	if obj.User != id.User || obj.Chat != id.Chat || obj.Model != id.Model {
+		t.Errorf("The document has the ID %d, %d, %q, want %+v", obj.User, obj.Chat, obj.Model, id)
+	}
+
+	content, err := obj.content()
+	if err != nil {
+		t.Fatalf("content failed: %s", err)
+	}
+	restored := new(chat.Statistics)
+	if err := restored.Read(bytes.NewReader(content)); err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	if !reflect.DeepEqual(restored, statistics) {
+		t.Errorf("Restored %+v, want %+v", restored, statistics)
+	}
+
+	// The content which is not a JSON object is kept as it is.
+	plain := newMongoObject("meta-note", []byte("not json"))
+	if content, err := plain.content(); err != nil || string(content) != "not json" {
+		t.Errorf("content returned %q, %v, want the plain content", content, err)
+	}
+}
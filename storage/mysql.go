@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql" // Register the MySQL driver.
+)
+
+// ensure that MySQL implements the ObjectStore interface
+var _ ObjectStore = (*MySQL)(nil)
+
+// mysqlSchema creates the table of the objects. The objects of the chat sessions carry
+// their IDs, which are indexed for the queries of the operators.
+const mysqlSchema = `CREATE TABLE IF NOT EXISTS tgpt_objects (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	kind VARCHAR(16) NOT NULL,
+	user BIGINT NULL,
+	chat BIGINT NULL,
+	model VARCHAR(255) NULL,
+	data LONGBLOB NOT NULL,
+	updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX session (kind, user, chat, model)
+) CHARACTER SET utf8mb4`
+
+// MySQL is an ObjectStore keeping the objects in a table of a MySQL or MariaDB
+// database. The queries are prepared once, and the objects are saved with upserts.
+type MySQL struct {
+	db *sql.DB
+
+	get, put, delete, list *sql.Stmt
+}
+
+// OpenMySQL connects to the database, creates the table of the objects if it does not
+// exist and prepares the queries.
+//
+// ctx: The context for the connection.
+// dsn: The data source name, e.g. "user:password@tcp(localhost:3306)/tgpt".
+//
+// Returns:
+// *MySQL: The store of the objects in the database.
+// error: An error if the database is unreachable or the queries could not be prepared.
+func OpenMySQL(ctx context.Context, dsn string) (_ *MySQL, err error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening mysql: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
+
+	if _, err := db.ExecContext(ctx, mysqlSchema); err != nil {
+		return nil, fmt.Errorf("error creating the table: %w", err)
+	}
+
+	m := &MySQL{db: db}
+	for stmt, query := range map[**sql.Stmt]string{
+		&m.get:    "SELECT data FROM tgpt_objects WHERE name = ?",
+		&m.delete: "DELETE FROM tgpt_objects WHERE name = ?",
+		&m.list:   `SELECT name FROM tgpt_objects WHERE name LIKE ? ESCAPE '\\' ORDER BY name`,
+		&m.put: "INSERT INTO tgpt_objects (name, kind, user, chat, model, data) VALUES (?, ?, ?, ?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE data = VALUES(data)",
+	} {
+		if *stmt, err = db.PrepareContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("error preparing the query: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Get returns the content of the object.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+//
+// Returns the content of the object, or ErrNotFound if it does not exist.
+func (m *MySQL) Get(ctx context.Context, name string) ([]byte, error) {
+	var data []byte
+	err := m.get.QueryRowContext(ctx, name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+// Put creates or replaces the object.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+// data: The content of the object.
+func (m *MySQL) Put(ctx context.Context, name string, data []byte) error {
+	var user, chat sql.NullInt64
+	var model sql.NullString
+	if id, ok := parseFilename(name); ok {
+		user = sql.NullInt64{Int64: id.User, Valid: true}
+		chat = sql.NullInt64{Int64: id.Chat, Valid: true}
+		model = sql.NullString{String: id.Model, Valid: true}
+	}
+
+	_, err := m.put.ExecContext(ctx, name, kindOf(name), user, chat, model, data)
+	return err
+}
+
+// Delete removes the object; removing a missing object is not an error.
+//
+// ctx: The context for the query.
+// name: The name of the object.
+func (m *MySQL) Delete(ctx context.Context, name string) error {
+	_, err := m.delete.ExecContext(ctx, name)
+	return err
+}
+
+// List returns the names of the objects starting with the prefix, in the order of
+// the names.
+//
+// ctx: The context for the query.
+// prefix: The prefix of the names.
+func (m *MySQL) List(ctx context.Context, prefix string) ([]string, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+
+	rows, err := m.list.QueryContext(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// Ping checks that the database is reachable.
+//
+// ctx: The context for the check.
+func (m *MySQL) Ping(ctx context.Context) error {
+	if err := m.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("error pinging mysql: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the prepared queries and the connections.
+func (m *MySQL) Close() error {
+	for _, stmt := range []*sql.Stmt{m.get, m.put, m.delete, m.list} {
+		stmt.Close()
+	}
+
+	return m.db.Close()
+}
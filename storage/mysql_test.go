@@ -0,0 +1,78 @@
+//go:build integration
+
+package storage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// TestMySQLStorage runs against the database of TGPT_TEST_MYSQL_DSN, e.g.
+// "root:secret@tcp(localhost:3306)/tgpt_test":
+//
+//	go test -tags integration ./storage -run MySQL
+func TestMySQLStorage(t *testing.T) {
+	dsn := os.Getenv("TGPT_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TGPT_TEST_MYSQL_DSN is not set")
+	}
+
+	// Setup.
+	ctx := context.Background()
+	id := chat.ID{User: 1, Chat: 2, Model: "gpt-4"}
+
+	db, err := OpenMySQL(ctx, dsn)
+	if err != nil {
+		t.Fatalf("OpenMySQL failed: %s", err)
+	}
+	defer db.Close()
+
+	names := []string{"invite-a_b", "invite-axb"}
+	t.Cleanup(func() {
+		for _, name := range append(names, "history-1-2-gpt-4.json") {
+			db.Delete(ctx, name)
+		}
+	})
+
+	store := NewObjects(db)
+	history := &chat.History{ID: id, Version: chat.HistoryVersion, Log: []chat.Message{{User: "Hello", Assistant: "Hi"}}}
+	if err := store.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// Execute: the save replaces the stored history.
+	history.Log = append(history.Log, chat.Message{User: "Bye", Assistant: "Bye"})
+	if err := store.SaveHistory(ctx, history); err != nil {
+		t.Fatalf("SaveHistory failed: %s", err)
+	}
+
+	// Verify.
+	loaded, err := store.LoadHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Log, history.Log) {
+		t.Errorf("Loaded %+v, want %+v", loaded.Log, history.Log)
+	}
+
+	// The wildcards of LIKE in the prefix are matched literally.
+	for _, name := range names {
+		if err := db.Put(ctx, name, []byte("{}")); err != nil {
+			t.Fatalf("Put failed: %s", err)
+		}
+	}
+	if listed, err := db.List(ctx, "invite-a_"); err != nil || !reflect.DeepEqual(listed, names[:1]) {
+		t.Errorf("List returned %v, %v, want %v", listed, err, names[:1])
+	}
+
+	if err := store.DeleteHistory(ctx, id); err != nil {
+		t.Fatalf("DeleteHistory failed: %s", err)
+	}
+	if _, err := db.Get(ctx, "history-1-2-gpt-4.json"); err != ErrNotFound {
+		t.Errorf("Get returned %v after the delete, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// ensure that Objects implements the chat.Storage interface and all its optional interfaces
+var (
+	_ chat.Storage       = (*Objects)(nil)
+	_ chat.Rollups       = (*Objects)(nil)
+	_ chat.Profiles      = (*Objects)(nil)
+	_ chat.Budgets       = (*Objects)(nil)
+	_ chat.Ledgers       = (*Objects)(nil)
+	_ chat.Invites       = (*Objects)(nil)
+	_ chat.Progress      = (*Objects)(nil)
+	_ chat.Configuration = (*Objects)(nil)
+)
+
+// ErrNotFound is returned by an ObjectStore when the object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStore keeps the serialized objects by their names, e.g. in a bucket or a table.
+// The names are those of the files of FS, such as "history-1-2-gpt-4.json".
+type ObjectStore interface {
+	// Get returns the content of the object, or ErrNotFound if it does not exist.
+	Get(ctx context.Context, name string) ([]byte, error)
+
+	// Put creates or replaces the object.
+	Put(ctx context.Context, name string, data []byte) error
+
+	// Delete removes the object; removing a missing object is not an error.
+	Delete(ctx context.Context, name string) error
+
+	// List returns the names of the objects starting with the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectKinds groups the objects by the prefixes of their names, for the stores keeping
+// each kind apart, such as the collections of MongoDB. The other objects, such as the
+// rollup, are of the "meta" kind.
+var objectKinds = []struct {
+	prefix  string
+	name    string
+	session bool // session reports whether the objects belong to a chat session.
+}{
+	{prefix: "history-", name: "histories", session: true},
+	{prefix: "statistics-", name: "statistics", session: true},
+	{prefix: "profile-", name: "profiles"},
+	{prefix: "budget-", name: "budgets"},
+	{prefix: "ledger-", name: "ledgers"},
+	{prefix: "invite-", name: "invites"},
+}
+
+// kindOf returns the kind of the objects with the name or the prefix.
+func kindOf(name string) string {
+	for _, kind := range objectKinds {
+		if strings.HasPrefix(name, kind.prefix) {
+			return kind.name
+		}
+	}
+
+	return "meta"
+}
+
+// ledgerLockModel is the model of the pseudo session locked while the ledger of its
+// user or group chat is updated.
+const ledgerLockModel = "ledger"
+
+// Objects is a chat.Storage keeping every object serialized in JSON in an ObjectStore,
+// which makes a storage backend of any key-value store.
+type Objects struct {
+	store ObjectStore
+
+	// ledgerMu serializes the saves of the statistics, which update the ledgers.
+	ledgerMu sync.Mutex
+
+	// locker locks the ledgers in the store shared with other instances; nil if not shared.
+	locker chat.Locker
+}
+
+// NewObjects creates a chat.Storage over the object store.
+//
+// store: The store of the serialized objects.
+func NewObjects(store ObjectStore) *Objects {
+	return &Objects{store: store}
+}
+
+// SetLocker shares the store with the other instances of the bot: the ledgers are
+// locked with the locker while they are updated, since the mutex of an instance does
+// not keep out the others. It should be called before the storage is used.
+//
+// locker: The Locker of the instances sharing the store.
+func (o *Objects) SetLocker(locker chat.Locker) {
+	o.locker = locker
+}
+
+// SaveHistory serializes the history into its object.
+//
+// ctx: The context for the store operation.
+// history: The History object to be saved.
+func (o *Objects) SaveHistory(ctx context.Context, history *chat.History) error {
+	return o.save(ctx, historyName(history.ID), history.Write)
+}
+
+// LoadHistory deserializes the history with the provided ID, or returns a new History
+// instance if there is none.
+//
+// ctx: The context for the store operation.
+// id: The ID of the chat history to be loaded.
+func (o *Objects) LoadHistory(ctx context.Context, id chat.ID) (*chat.History, error) {
+	return load(ctx, o, historyName(id), func() *chat.History {
+		return &chat.History{ID: id, Log: []chat.Message{}}
+	})
+}
+
+// DeleteHistory removes the history with the provided ID.
+//
+// ctx: The context for the store operation.
+// id: The ID of the chat history to be removed.
+func (o *Objects) DeleteHistory(ctx context.Context, id chat.ID) error {
+	return o.delete(ctx, historyName(id))
+}
+
+// SaveStatistics serializes the statistics into their object and adds the growth of
+// the costs to the ledgers of the user and the group chat, which are locked meanwhile.
+//
+// ctx: The context for the store operation.
+// statistics: The chat statistics to be saved.
+func (o *Objects) SaveStatistics(ctx context.Context, statistics *chat.Statistics) error {
+	unlock, err := o.lockLedgers(ctx, chat.LedgerOwners(statistics.ID))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return recordSpending(ctx, o, statistics, func() error {
+		return o.save(ctx, statisticsName(statistics.ID), statistics.Write)
+	})
+}
+
+// lockLedgers keeps the other saves of the statistics out of the ledgers of the owners:
+// the saves of this instance with the mutex and those of the other instances sharing
+// the store with the locker.
+//
+// ctx: The context for the wait.
+// owners: The IDs of the users and the group chats whose ledgers are locked.
+//
+// Returns:
+// func(): The function releasing the locks.
+// error: An error if a ledger could not be locked.
+func (o *Objects) lockLedgers(ctx context.Context, owners []int64) (func(), error) {
+	o.ledgerMu.Lock()
+	unlocks := []func(){o.ledgerMu.Unlock}
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+
+	if o.locker == nil {
+		return unlock, nil
+	}
+
+	for _, owner := range owners {
+		release, err := o.locker.Lock(ctx, chat.ID{User: owner, Model: ledgerLockModel})
+		if err != nil {
+			unlock()
+			return nil, fmt.Errorf("error locking the ledger: %w", err)
+		}
+		unlocks = append(unlocks, release)
+	}
+
+	return unlock, nil
+}
+
+// LoadStatistics deserializes the statistics with the provided ID, or returns a new
+// Statistics instance if there are none.
+//
+// ctx: The context for the store operation.
+// id: The ID of the chat statistics to be loaded.
+func (o *Objects) LoadStatistics(ctx context.Context, id chat.ID) (*chat.Statistics, error) {
+	return load(ctx, o, statisticsName(id), func() *chat.Statistics {
+		return &chat.Statistics{
+			ID:     id,
+			Days:   map[string]chat.Cost{},
+			Months: map[string]chat.Cost{},
+		}
+	})
+}
+
+// DeleteStatistics removes the statistics with the provided ID.
+//
+// ctx: The context for the store operation.
+// id: The ID of the chat statistics to be removed.
+func (o *Objects) DeleteStatistics(ctx context.Context, id chat.ID) error {
+	return o.delete(ctx, statisticsName(id))
+}
+
+// EraseUser does nothing, as the objects are never quarantined.
+func (o *Objects) EraseUser(context.Context, int64) error {
+	return nil
+}
+
+// SaveRollup serializes the aggregated statistics into their object.
+//
+// ctx: The context for the store operation.
+// rollup: The rollup to be saved.
+func (o *Objects) SaveRollup(ctx context.Context, rollup *chat.Rollup) error {
+	return o.save(ctx, "rollup.json", rollup.Write)
+}
+
+// LoadRollup deserializes the aggregated statistics, or returns an empty Rollup if
+// there are none.
+//
+// ctx: The context for the store operation.
+func (o *Objects) LoadRollup(ctx context.Context) (*chat.Rollup, error) {
+	return load(ctx, o, "rollup.json", func() *chat.Rollup {
+		return &chat.Rollup{
+			PerUser:  map[int64]chat.Totals{},
+			PerModel: map[string]chat.Totals{},
+		}
+	})
+}
+
+// SaveProfile serializes the profile into its object.
+//
+// ctx: The context for the store operation.
+// profile: The profile to be saved.
+func (o *Objects) SaveProfile(ctx context.Context, profile *chat.Profile) error {
+	return o.save(ctx, fmt.Sprintf("profile-%d.json", profile.User), profile.Write)
+}
+
+// LoadProfile deserializes the profile of the user, or returns an empty Profile if
+// there is none.
+//
+// ctx: The context for the store operation.
+// user: The ID of the user.
+func (o *Objects) LoadProfile(ctx context.Context, user int64) (*chat.Profile, error) {
+	return load(ctx, o, fmt.Sprintf("profile-%d.json", user), func() *chat.Profile {
+		return &chat.Profile{User: user}
+	})
+}
+
+// SaveBudget serializes the budget into its object.
+//
+// ctx: The context for the store operation.
+// budget: The budget to be saved.
+func (o *Objects) SaveBudget(ctx context.Context, budget *chat.Budget) error {
+	return o.save(ctx, fmt.Sprintf("budget-%d.json", budget.Owner), budget.Write)
+}
+
+// LoadBudget deserializes the budget of the owner, or returns an empty Budget if there
+// is none.
+//
+// ctx: The context for the store operation.
+// owner: The ID of the user or the group chat.
+func (o *Objects) LoadBudget(ctx context.Context, owner int64) (*chat.Budget, error) {
+	return load(ctx, o, fmt.Sprintf("budget-%d.json", owner), func() *chat.Budget {
+		return &chat.Budget{Owner: owner}
+	})
+}
+
+// SaveLedger serializes the ledger into its object.
+//
+// ctx: The context for the store operation.
+// ledger: The ledger to be saved.
+func (o *Objects) SaveLedger(ctx context.Context, ledger *chat.Ledger) error {
+	return o.save(ctx, fmt.Sprintf("ledger-%d.json", ledger.Owner), ledger.Write)
+}
+
+// LoadLedger deserializes the ledger of the owner, or returns an empty Ledger if there
+// is none.
+//
+// ctx: The context for the store operation.
+// owner: The ID of the user or the group chat.
+func (o *Objects) LoadLedger(ctx context.Context, owner int64) (*chat.Ledger, error) {
+	return load(ctx, o, fmt.Sprintf("ledger-%d.json", owner), func() *chat.Ledger {
+		return &chat.Ledger{
+			Owner:  owner,
+			Days:   map[string]chat.Cost{},
+			Months: map[string]chat.Cost{},
+		}
+	})
+}
+
+// SaveInvite serializes the invite into its object.
+//
+// ctx: The context for the store operation.
+// invite: The invite to be saved.
+//
+// Returns:
+// error: An error if the code of the invite is invalid or the object could not be saved.
+func (o *Objects) SaveInvite(ctx context.Context, invite *chat.Invite) error {
+	if !validInviteCode(invite.Code) {
+		return fmt.Errorf("invalid invite code: %q", invite.Code)
+	}
+
+	return o.save(ctx, fmt.Sprintf("invite-%s.json", invite.Code), invite.Write)
+}
+
+// LoadInvite deserializes the invite with the code, or returns an empty Invite if
+// there is none.
+//
+// ctx: The context for the store operation.
+// code: The code of the invite.
+func (o *Objects) LoadInvite(ctx context.Context, code string) (*chat.Invite, error) {
+	// The code comes from users, never let it form an arbitrary name.
+	if !validInviteCode(code) {
+		return &chat.Invite{Code: code}, nil
+	}
+
+	return load(ctx, o, fmt.Sprintf("invite-%s.json", code), func() *chat.Invite {
+		return &chat.Invite{Code: code}
+	})
+}
+
+// SaveUpdates serializes the progress through the Telegram updates into its object.
+//
+// ctx: The context for the store operation.
+// updates: The progress to be saved.
+func (o *Objects) SaveUpdates(ctx context.Context, updates *chat.Updates) error {
+	return o.save(ctx, "updates.json", updates.Write)
+}
+
+// LoadUpdates deserializes the progress through the Telegram updates, or returns
+// empty Updates if there are none.
+//
+// ctx: The context for the store operation.
+func (o *Objects) LoadUpdates(ctx context.Context) (*chat.Updates, error) {
+	return load(ctx, o, "updates.json", func() *chat.Updates {
+		return &chat.Updates{}
+	})
+}
+
+// SaveSettings serializes the settings of the bot into their object.
+//
+// ctx: The context for the store operation.
+// settings: The settings to be saved.
+func (o *Objects) SaveSettings(ctx context.Context, settings *chat.Settings) error {
+	return o.save(ctx, "settings.json", settings.Write)
+}
+
+// LoadSettings deserializes the settings of the bot, or returns the defaults if there
+// are none.
+//
+// ctx: The context for the store operation.
+func (o *Objects) LoadSettings(ctx context.Context) (*chat.Settings, error) {
+	return load(ctx, o, "settings.json", func() *chat.Settings {
+		return &chat.Settings{}
+	})
+}
+
+// List enumerates the IDs of all sessions with a history or statistics.
+//
+// ctx: The context for the store operation.
+func (o *Objects) List(ctx context.Context) ([]chat.ID, error) {
+	seen := make(map[chat.ID]struct{})
+	ids := make([]chat.ID, 0)
+
+	for _, prefix := range []string{"history-", "statistics-"} {
+		names, err := o.store.List(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the objects: %w", err)
+		}
+
+		for _, name := range names {
+			if id, ok := parseFilename(name); ok {
+				if _, dup := seen[id]; !dup {
+					seen[id] = struct{}{}
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// ListProfiles enumerates the IDs of all users with a profile.
+//
+// ctx: The context for the store operation.
+func (o *Objects) ListProfiles(ctx context.Context) ([]int64, error) {
+	return o.listOwners(ctx, "profile-")
+}
+
+// ListBudgets enumerates the IDs of all users and group chats with a budget.
+//
+// ctx: The context for the store operation.
+func (o *Objects) ListBudgets(ctx context.Context) ([]int64, error) {
+	return o.listOwners(ctx, "budget-")
+}
+
+// ListLedgers enumerates the IDs of all users and group chats with a ledger.
+//
+// ctx: The context for the store operation.
+func (o *Objects) ListLedgers(ctx context.Context) ([]int64, error) {
+	return o.listOwners(ctx, "ledger-")
+}
+
+// ListInvites enumerates the codes of all invites.
+//
+// ctx: The context for the store operation.
+func (o *Objects) ListInvites(ctx context.Context) ([]string, error) {
+	names, err := o.store.List(ctx, "invite-")
+	if err != nil {
+		return nil, fmt.Errorf("error listing the objects: %w", err)
+	}
+
+	codes := make([]string, 0, len(names))
+	for _, name := range names {
+		code := strings.TrimSuffix(strings.TrimPrefix(name, "invite-"), ".json")
+		if validInviteCode(code) {
+			codes = append(codes, code)
+		}
+	}
+
+	return codes, nil
+}
+
+// listOwners enumerates the IDs in the names of the objects with the prefix, such as
+// "profile-123.json".
+func (o *Objects) listOwners(ctx context.Context, prefix string) ([]int64, error) {
+	names, err := o.store.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the objects: %w", err)
+	}
+
+	owners := make([]int64, 0, len(names))
+	for _, name := range names {
+		rest := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".json")
+		if owner, err := strconv.ParseInt(rest, 10, 64); err == nil {
+			owners = append(owners, owner)
+		}
+	}
+
+	return owners, nil
+}
+
+// save serializes the object and puts it into the store.
+func (o *Objects) save(ctx context.Context, name string, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return fmt.Errorf("error serializing %s: %w", name, err)
+	}
+
+	if err := o.store.Put(ctx, name, buf.Bytes()); err != nil {
+		return fmt.Errorf("error saving %s: %w", name, err)
+	}
+	countSize(ctx, buf.Len())
+
+	return nil
+}
+
+// delete removes the object from the store.
+func (o *Objects) delete(ctx context.Context, name string) error {
+	if err := o.store.Delete(ctx, name); err != nil {
+		return fmt.Errorf("error removing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// load deserializes the object from the store, or returns a new one if there is none.
+//
+// ctx: The context for the store operation.
+// o: The storage.
+// name: The name of the object.
+// fresh: The function creating a new object.
+func load[T any, PT interface {
+	*T
+	reader
+}](ctx context.Context, o *Objects, name string, fresh func() PT) (PT, error) {
+	data, err := o.store.Get(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return fresh(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", name, err)
+	}
+	countSize(ctx, len(data))
+
+	obj := PT(new(T))
+	if err := obj.Read(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("error deserializing %s: %w", name, err)
+	}
+
+	return obj, nil
+}
+
+// historyName returns the name of the history with the ID.
+func historyName(id chat.ID) string {
+	return fmt.Sprintf("history-%d-%d-%s.json", id.User, id.Chat, id.Model)
+}
+
+// statisticsName returns the name of the statistics with the ID.
+func statisticsName(id chat.ID) string {
+	return fmt.Sprintf("statistics-%d-%d-%s.json", id.User, id.Chat, id.Model)
+}
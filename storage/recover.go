@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// corruptExt is the extension added to the names of the quarantined corrupted files.
+// The quarantined files do not end with ".json", so List ignores them.
+const corruptExt = ".corrupt"
+
+// reader is an object read from a file.
+type reader interface {
+	Read(r io.Reader) error
+}
+
+// readRecovering reads the object from the file. A corrupted file is quarantined and
+// as much of the object as possible is recovered from it, so a broken file does not
+// make the chat unusable; the files which cannot be decrypted are not considered
+// corrupted, as the key may be wrong.
+//
+// ctx: The context of the read.
+// path: The path of the uncompressed file.
+// fresh: The function creating a new object, returned if the file does not exist.
+//
+// Returns:
+// T: The object read from the file, recovered from the corrupted file, or a new one.
+// error: An error if the file could not be read for a reason other than corruption.
+func readRecovering[T reader](ctx context.Context, fs *FS, path string, fresh func() T) (T, error) {
+	file, err := fs.openLocked(ctx, path)
+	if os.IsNotExist(err) {
+		return fresh(), nil
+	}
+
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(file)
+		file.Close()
+	}
+
+	if err == nil {
+		obj := fresh()
+		if err = obj.Read(bytes.NewReader(data)); err == nil {
+			return obj, nil
+		}
+	}
+
+	if !isCorrupted(err) {
+		var zero T
+		return zero, err
+	}
+
+	quarantined, qerr := fs.quarantine(ctx, path)
+	if qerr != nil {
+		var zero T
+		return zero, fmt.Errorf("%w (could not quarantine the file: %s)", err, qerr)
+	}
+
+	obj, recovered := fresh(), false
+	if doc, ok := recoverDocument(data); ok {
+		partial := fresh()
+		if partial.Read(bytes.NewReader(doc)) == nil {
+			obj, recovered = partial, true
+		}
+	}
+
+	slog.Error(
+		"storage quarantined a corrupted file",
+		slog.String("path", quarantined),
+		slog.Bool("recovered", recovered),
+		slog.String("error", err.Error()),
+	)
+
+	return obj, nil
+}
+
+// isCorrupted reports whether the error means the content of the file is broken:
+// invalid or truncated JSON or gzip data.
+func isCorrupted(err error) bool {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	return errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, gzip.ErrHeader) ||
+		errors.Is(err, gzip.ErrChecksum)
+}
+
+// quarantine renames the file, whichever variant is read first, adding the time and
+// ".corrupt" to its name, so the file is kept for an inspection and a new one is
+// written in its place.
+//
+// ctx: The context of the rename.
+// path: The path of the uncompressed file.
+//
+// Returns the new path of the file and an error if the file could not be renamed.
+func (fs *FS) quarantine(ctx context.Context, path string) (string, error) {
+	unlock, err := fs.lockFilesIn(ctx, filepath.Dir(path), true)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	suffix := "." + time.Now().UTC().Format("20060102T150405") + corruptExt
+
+	preferred, other := fs.variants(path)
+	for _, variant := range []string{preferred, other} {
+		err := os.Rename(variant, variant+suffix)
+		if err == nil {
+			return variant + suffix, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	// Another process has quarantined or replaced the file.
+	return "", nil
+}
+
+// recoverDocument recovers the complete top-level fields of a broken JSON object. The
+// field broken by a truncation keeps its complete elements if it is an array, such as
+// the log of a history.
+//
+// data: The content of the broken file.
+//
+// Returns the JSON object of the recovered fields, and false if nothing was recovered.
+func recoverDocument(data []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+
+	doc := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		key, ok := tok.(string)
+		if !ok {
+			break
+		}
+
+		start := dec.InputOffset()
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			if items, ok := recoverArray(data[start:]); ok {
+				doc[key] = items
+			}
+			break
+		}
+
+		doc[key] = value
+	}
+
+	if len(doc) == 0 {
+		return nil, false
+	}
+
+	recovered, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+
+	return recovered, true
+}
+
+// recoverArray recovers the complete elements of a broken JSON array.
+//
+// data: The content following the key of the array.
+func recoverArray(data []byte) (json.RawMessage, bool) {
+	data = bytes.TrimLeft(data, " \t\r\n:")
+	if !bytes.HasPrefix(data, []byte("[")) {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, false
+	}
+
+	items := []json.RawMessage{}
+	for dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			break
+		}
+		items = append(items, item)
+	}
+
+	recovered, err := json.Marshal(items)
+	if err != nil {
+		return nil, false
+	}
+
+	return recovered, true
+}
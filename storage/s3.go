@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ensure that S3 implements the ObjectStore interface
+var _ ObjectStore = (*S3)(nil)
+
+// S3 is an ObjectStore keeping the objects in a bucket of an S3-compatible object
+// storage, such as Amazon S3, Google Cloud Storage with HMAC keys, or MinIO. The
+// requests are signed with AWS Signature Version 4 and address the bucket in the
+// path, which all these services support.
+type S3 struct {
+	Endpoint string // Endpoint is the URL of the service, e.g. "https://s3.eu-central-1.amazonaws.com".
+	Region   string // Region is the region of the bucket, e.g. "eu-central-1"; "auto" for GCS.
+	Bucket   string // Bucket is the name of the bucket.
+	Prefix   string // Prefix is prepended to the names of the objects, e.g. "tgpt/".
+
+	AccessKey    string // AccessKey is the ID of the access key.
+	SecretKey    string // SecretKey is the secret of the access key.
+	SessionToken string // SessionToken is the token of the temporary credentials, if any.
+
+	Client *http.Client // Client sends the requests; nil uses a client with s3Timeout.
+}
+
+// s3Timeout is the timeout of the requests sent without a configured Client.
+const s3Timeout = 30 * time.Second
+
+// defaultS3Client sends the requests without a configured Client.
+var defaultS3Client = &http.Client{Timeout: s3Timeout}
+
+// Get downloads the object.
+//
+// ctx: The context for the request.
+// name: The name of the object.
+//
+// Returns the content of the object, or ErrNotFound if it does not exist.
+func (s *S3) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.Prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the object: %w", err)
+	}
+
+	return data, nil
+}
+
+// Put uploads the object, replacing the existing one.
+//
+// ctx: The context for the request.
+// name: The name of the object.
+// data: The content of the object.
+func (s *S3) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.Prefix+name, nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+
+	return nil
+}
+
+// Delete removes the object; removing a missing object is not an error.
+//
+// ctx: The context for the request.
+// name: The name of the object.
+func (s *S3) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.Prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+
+	return nil
+}
+
+// List returns the names of the objects starting with the prefix, without the Prefix
+// of the store, fetching all the pages of the listing.
+//
+// ctx: The context for the requests.
+// prefix: The prefix of the names.
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	names := make([]string, 0)
+
+	var token string
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding the listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			names = append(names, strings.TrimPrefix(object.Key, s.Prefix))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Ping checks that the bucket is reachable with the credentials.
+//
+// ctx: The context for the request.
+func (s *S3) Ping(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// do sends a signed request for the object, or for the bucket if the key is empty.
+//
+// ctx: The context for the request.
+// method: The HTTP method.
+// key: The key of the object.
+// query: The query parameters.
+// body: The content to upload.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	path := endpoint.Path + "/" + s.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+
+	u := *endpoint
+	u.Path = path
+	u.RawPath = s3Escape(path, false)
+	u.RawQuery = s3Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	s.sign(req, body, time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = defaultS3Client
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request error: %w", err)
+	}
+
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 of the request, covering all its headers.
+//
+// req: The request to sign.
+// body: The content of the request.
+// now: The time of the signature.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	payload := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	// The canonical headers, sorted by their lowercase names.
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape escapes the string as required by the signature: everything but the
+// unreserved characters is percent-encoded, and the slashes as well unless they
+// separate the segments of a path.
+//
+// s: The string to escape.
+// slash: Whether to escape the slashes.
+func s3Escape(s string, slash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// s3Query encodes the query parameters sorted by their names, as required by the signature.
+func s3Query(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// s3Error returns the error of the failed request with the code and the message of
+// the service, if present.
+func s3Error(resp *http.Response) error {
+	var body struct {
+		Code    string
+		Message string
+	}
+	if xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body) == nil && body.Code != "" {
+		return fmt.Errorf("s3: %s: %s: %s", resp.Status, body.Code, body.Message)
+	}
+
+	return fmt.Errorf("s3: %s", resp.Status)
+}
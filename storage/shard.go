@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// userPath returns the path of a file of the user in the sharded layout: the files are
+// kept in a directory of the user, inside a shard directory named after the first two
+// digits of the user ID, e.g. "12/12345/profile-12345.json". A flat directory with
+// many users is slow to list and to back up. The files left in the BaseDir by an older
+// version are moved by MigrateLayout.
+//
+// user: The ID of the user or the group chat owning the file.
+// filename: The name of the file.
+func (fs *FS) userPath(user int64, filename string) string {
+	return filepath.Join(fs.userDir(user), filename)
+}
+
+// userDir returns the directory of the files of the user in the sharded layout.
+//
+// user: The ID of the user or the group chat.
+func (fs *FS) userDir(user int64) string {
+	return shardDir(fs.BaseDir, user)
+}
+
+// shardDir returns the directory of the user in the sharded layout under the base
+// directory.
+//
+// base: The base directory.
+// user: The ID of the user or the group chat.
+func shardDir(base string, user int64) string {
+	id := strconv.FormatInt(user, 10)
+	shard := strings.TrimPrefix(id, "-")
+	shard = shard[:min(2, len(shard))]
+
+	return filepath.Join(base, shard, id)
+}
+
+// ownerOf returns the ID of the user whose directory holds the file.
+//
+// path: The path of the file.
+//
+// Returns false if the file is not in the directory of a user, like the rollup.
+func (fs *FS) ownerOf(path string) (int64, bool) {
+	rel, err := filepath.Rel(fs.BaseDir, path)
+	if err != nil {
+		return 0, false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	user, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return user, true
+}
+
+// MigrateLayout moves the files of the users left in the BaseDir by an older version to
+// the sharded layout (see userPath). It is called once at startup, before the storage
+// is used; the files already in the shard directories are not touched.
+//
+// ctx: The context of the move.
+//
+// Returns:
+// int: The number of the moved files.
+// error: An error if the BaseDir could not be read or a file could not be moved.
+func (fs *FS) MigrateLayout(ctx context.Context) (int, error) {
+	unlock, err := lockDir(ctx, fs.BaseDir, true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(fs.BaseDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not read the directory: %w", err)
+	}
+
+	moved := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return moved, err
+		}
+
+		owner, ok := flatOwner(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		dir := fs.userDir(owner)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return moved, fmt.Errorf("could not create the directory: %w", err)
+		}
+
+		if err := os.Rename(filepath.Join(fs.BaseDir, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+			return moved, fmt.Errorf("could not move the file to the sharded layout: %w", err)
+		}
+		moved++
+	}
+
+	return moved, nil
+}
+
+// flatOwner returns the ID of the user owning the data file of the flat layout.
+//
+// name: The name of the file in the BaseDir.
+//
+// Returns false if the file is not a file of a user, like the rollup.
+func flatOwner(name string) (int64, bool) {
+	if id, ok := parseFilename(name); ok {
+		return id.User, true
+	}
+
+	rest, ok := strings.CutSuffix(strings.TrimSuffix(name, gzipExt), ".json")
+	if !ok {
+		return 0, false
+	}
+
+	for _, prefix := range []string{"profile-", "budget-", "ledger-"} {
+		if id, ok := strings.CutPrefix(rest, prefix); ok {
+			owner, err := strconv.ParseInt(id, 10, 64)
+			return owner, err == nil
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,231 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// Access expiry schedule: the grants are checked every accessCheckInterval and the
+// users are warned accessWarning before their access expires.
+const (
+	accessCheckInterval = time.Hour
+	accessWarning       = 24 * time.Hour
+)
+
+// isPersistedAllowed reports whether the user was granted access at runtime (e.g., by
+// redeeming an invite or with the /allow command). Grants without an expiry are cached
+// in the allowlist; expiring grants are checked on every call.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user to check.
+func (b *Bot) isPersistedAllowed(ctx context.Context, user int64) bool {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"isPersistedAllowed LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+
+	if !profile.HasAccess(chat.Now()) {
+		return false
+	}
+
+	if profile.AccessExpires.IsZero() {
+		b.accessMu.Lock()
+		b.allowedUsers[user] = struct{}{}
+		b.accessMu.Unlock()
+	}
+
+	return true
+}
+
+// allowUser grants the user access and persists the grant in the user's profile.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user to allow.
+// expires: The time the access expires; zero grants access without an expiry.
+//
+// Returns an error if the profile could not be loaded or saved.
+func (b *Bot) allowUser(ctx context.Context, user int64, expires time.Time) error {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		return fmt.Errorf("error loading profile: %w", err)
+	}
+
+	profile.Allowed = true
+	profile.AccessExpires = expires
+	profile.ExpiryWarned = false
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		return fmt.Errorf("error saving profile: %w", err)
+	}
+
+	b.accessMu.Lock()
+	if expires.IsZero() {
+		b.allowedUsers[user] = struct{}{}
+	} else {
+		// The configured allowlist is not affected by runtime grants.
+		delete(b.allowedUsers, user)
+		b.restoreConfiguredAccess(user)
+	}
+	b.accessMu.Unlock()
+
+	return nil
+}
+
+// restoreConfiguredAccess puts the user back into the cached allowlist if the user is
+// an administrator or listed in the configuration. The caller must hold accessMu.
+func (b *Bot) restoreConfiguredAccess(user int64) {
+	if _, configured := b.configuredUsers[user]; configured {
+		b.allowedUsers[user] = struct{}{}
+	}
+}
+
+// handleAllow grants a user access, optionally for a limited time given as a number of
+// days (30d), weeks (2w) or a Go duration (12h): /allow <user> [duration].
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /allow command.
+func (b *Bot) handleAllow(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 1 || len(args) > 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAllowUsage))
+		return
+	}
+
+	user, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAllowUsage))
+		return
+	}
+
+	var expires time.Time
+	if len(args) == 2 {
+		duration, err := parseDuration(args[1])
+		if err != nil || duration <= 0 {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgAllowUsage))
+			return
+		}
+		expires = chat.Now().Add(duration)
+	}
+
+	if err := b.allowUser(ctx, user, expires); err != nil {
+		b.handleError(ctx, msg, "handleAllow allowUser", err)
+		return
+	}
+
+	if expires.IsZero() {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAllowed, user))
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgAllowedUntil, user, expires.Format(time.RFC3339)))
+}
+
+// RunAccessExpiry periodically checks the expiring access grants until the context is
+// cancelled. Users are warned shortly before their access expires, and the access is
+// revoked once it has expired.
+//
+// ctx: The context controlling the lifecycle of the checks.
+func (b *Bot) RunAccessExpiry(ctx context.Context) {
+	ticker := time.NewTicker(accessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.checkAccessExpiry(ctx); err != nil {
+			slog.Error("RunAccessExpiry checkAccessExpiry error", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAccessExpiry warns the users whose access expires soon and revokes the access
+// of the users whose access has expired. The access is granted in the profiles, so all
+// of them are checked, including those of the users who have not chatted yet.
+//
+// ctx: The context for the storage operations.
+//
+// Returns an error if the profiles could not be listed or a profile could not be
+// loaded or saved.
+func (b *Bot) checkAccessExpiry(ctx context.Context) error {
+	users, err := b.storage.ListProfiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing profiles: %w", err)
+	}
+
+	now := chat.Now()
+	for _, user := range users {
+		profile, err := b.storage.LoadProfile(ctx, user)
+		if err != nil {
+			return fmt.Errorf("error loading profile: %w", err)
+		}
+
+		if !profile.Allowed || profile.AccessExpires.IsZero() {
+			continue
+		}
+
+		switch {
+		case !profile.HasAccess(now):
+			profile.Allowed = false
+			profile.AccessExpires = time.Time{}
+			profile.ExpiryWarned = false
+
+			b.accessMu.Lock()
+			delete(b.allowedUsers, user)
+			b.restoreConfiguredAccess(user)
+			b.accessMu.Unlock()
+
+			b.Send(user, b.printer.Sprintf(lang.MsgAccessExpired, b.adminContact))
+
+		case !profile.ExpiryWarned && profile.AccessExpires.Sub(now) <= accessWarning:
+			profile.ExpiryWarned = true
+
+			b.Send(user, b.printer.Sprintf(
+				lang.MsgAccessExpiring,
+				profile.AccessExpires.In(profile.Location()).Format("2006-01-02 15:04 MST"),
+				b.adminContact,
+			))
+
+		default:
+			continue
+		}
+
+		if err := b.storage.SaveProfile(ctx, profile); err != nil {
+			return fmt.Errorf("error saving profile: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseDuration parses a duration given as a number of days (30d), weeks (2w) or
+// in the format accepted by time.ParseDuration (12h).
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
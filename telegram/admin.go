@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// adminCommands returns the list of commands available only to administrators.
+// The descriptions are localized with the bot's printer.
+func (b *Bot) adminCommands() []tgbotapi.BotCommand {
+	return []tgbotapi.BotCommand{
+		{Command: "status", Description: b.printer.Sprintf(lang.MsgCommandStatus)},
+		{Command: "sessions", Description: b.printer.Sprintf(lang.MsgCommandSessions)},
+		{Command: "evict", Description: b.printer.Sprintf(lang.MsgCommandEvict)},
+		{Command: "broadcast", Description: b.printer.Sprintf(lang.MsgCommandBroadcast)},
+		{Command: "maintenance", Description: b.printer.Sprintf(lang.MsgCommandMaintenance)},
+		{Command: "usage", Description: b.printer.Sprintf(lang.MsgCommandUsage)},
+		{Command: "top", Description: b.printer.Sprintf(lang.MsgCommandTop)},
+		{Command: "exportstats", Description: b.printer.Sprintf(lang.MsgCommandExportStats)},
+		{Command: "budget", Description: b.printer.Sprintf(lang.MsgCommandBudget)},
+		{Command: "credit", Description: b.printer.Sprintf(lang.MsgCommandCredit)},
+		{Command: "setrate", Description: b.printer.Sprintf(lang.MsgCommandSetRate)},
+		{Command: "invite", Description: b.printer.Sprintf(lang.MsgCommandInvite)},
+		{Command: "allow", Description: b.printer.Sprintf(lang.MsgCommandAllow)},
+		{Command: "setrole", Description: b.printer.Sprintf(lang.MsgCommandSetRole)},
+		{Command: "grantadmin", Description: b.printer.Sprintf(lang.MsgCommandGrantAdmin)},
+		{Command: "revokeadmin", Description: b.printer.Sprintf(lang.MsgCommandRevokeAdmin)},
+		{Command: "setmodel", Description: b.printer.Sprintf(lang.MsgCommandSetModel)},
+		{Command: "ban", Description: b.printer.Sprintf(lang.MsgCommandBan)},
+		{Command: "unban", Description: b.printer.Sprintf(lang.MsgCommandUnban)},
+		{Command: "audit", Description: b.printer.Sprintf(lang.MsgCommandAudit)},
+		{Command: "userstats", Description: b.printer.Sprintf(lang.MsgCommandUserStats)},
+	}
+}
+
+// handleAdminCommand processes a command that is available only to administrators.
+// The caller is responsible for checking that the sender of the message is an admin.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the command to process.
+//
+// Returns:
+// - true if the command was recognized and handled, false otherwise.
+func (b *Bot) handleAdminCommand(ctx context.Context, msg *tgbotapi.Message) bool {
+	switch msg.Command() {
+	case "status":
+		b.handleStatus(ctx, msg)
+
+	case "sessions":
+		b.handleSessions(ctx, msg)
+
+	case "evict":
+		b.handleEvict(ctx, msg)
+
+	case "broadcast":
+		b.handleBroadcast(ctx, msg)
+
+	case "maintenance":
+		b.handleMaintenance(ctx, msg)
+
+	case "usage":
+		b.handleUsage(ctx, msg)
+
+	case "top":
+		b.handleTop(ctx, msg)
+
+	case "exportstats":
+		b.handleExportStats(ctx, msg)
+
+	case "budget":
+		b.handleBudget(ctx, msg)
+
+	case "credit":
+		b.handleCredit(ctx, msg)
+
+	case "setrate":
+		b.handleSetRate(ctx, msg)
+
+	case "invite":
+		b.handleInvite(ctx, msg)
+
+	case "allow":
+		b.handleAllow(ctx, msg)
+
+	case "setrole":
+		b.handleSetRole(ctx, msg)
+
+	case "grantadmin":
+		b.handleGrantAdmin(ctx, msg)
+
+	case "revokeadmin":
+		b.handleRevokeAdmin(ctx, msg)
+
+	case "setmodel":
+		b.handleSetModel(ctx, msg)
+
+	case "ban":
+		b.handleBan(ctx, msg)
+
+	case "unban":
+		b.handleUnban(ctx, msg)
+
+	case "audit":
+		b.handleAudit(ctx, msg)
+
+	case "userstats":
+		b.handleUserStats(ctx, msg)
+
+	default:
+		return false
+	}
+
+	b.auditAdmin(ctx, msg)
+	return true
+}
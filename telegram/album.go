@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// albumWait is how long the messages of an album are collected after the last one arrived.
+const albumWait = time.Second
+
+// maxPhotoSize limits the size of a downloaded photo.
+const maxPhotoSize = 20 << 20
+
+// handleAlbum collects the messages of an album (a media group) and asks about all of
+// its photos in a single request, with the caption of the album as the question.
+// Telegram delivers every message of an album as a separate update; the album is
+// processed by the handler of the message which completes it.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message of the album.
+func (b *Bot) handleAlbum(ctx context.Context, msg *tgbotapi.Message) {
+	b.albums.add(msg.MediaGroupID, msg, albumWait)
+
+	album, ok := b.albums.collect(ctx, msg.MediaGroupID)
+	if !ok {
+		return
+	}
+
+	// Reply to the message with the caption, which Telegram shows as the album caption.
+	first, caption := album[0], ""
+	for _, m := range album {
+		if m.Caption != "" {
+			first, caption = m, m.Caption
+			break
+		}
+	}
+
+	var photos []tgbotapi.PhotoSize
+	for _, m := range album {
+		if len(m.Photo) > 0 {
+			// The last size is the largest one.
+			photos = append(photos, m.Photo[len(m.Photo)-1])
+		}
+	}
+
+	if len(photos) == 0 {
+		b.Reply(first, b.printer.Sprintf(lang.MsgNotSupported))
+		return
+	}
+
+	model, permitted := b.sessionModels(ctx, first.From.ID)
+	ctx = chat.WithPermittedModels(ctx, permitted)
+	if !b.checkVision(first, model) || !b.checkSpending(ctx, first) || !b.checkInputSize(first, caption) {
+		return
+	}
+
+	session, err := b.session.ProvideSession(ctx, chat.ID{
+		User:  first.From.ID,
+		Chat:  first.Chat.ID,
+		Model: model,
+	})
+	if err != nil {
+		b.handleError(ctx, first, "handleAlbum ProvideSession", err)
+		return
+	}
+
+	if prompt := b.sessionPrompt(ctx, first.From.ID); prompt != "" {
+		if err := session.SetPrompt(ctx, prompt); err != nil {
+			b.handleError(ctx, first, "handleAlbum SetPrompt", err)
+			return
+		}
+	}
+
+	progress := b.indicateProcessing(ctx, first)
+	defer progress.stop()
+
+	ctx, meter := chat.WithCostMeter(ctx)
+
+	images := make([][]byte, 0, len(photos))
+	for _, photo := range photos {
+		image, err := b.downloadFile(ctx, photo.FileID, maxPhotoSize)
+		if err != nil {
+			b.handleError(ctx, first, "handleAlbum downloadFile", err)
+			return
+		}
+		images = append(images, image)
+	}
+
+	reply, fallback, err := session.See(ctx, caption, images)
+	if err != nil {
+		b.handleError(ctx, first, "handleAlbum See", err)
+		return
+	}
+
+	slog.Info(
+		"handleAlbum finished",
+		slog.Int64("chatID", first.Chat.ID),
+		slog.String("mediaGroupID", first.MediaGroupID),
+		slog.Int("photos", len(images)),
+	)
+
+	progress.reply(ctx, b.withFallbackNote(reply, fallback))
+	b.auditQuestion(ctx, first, session, caption, fallback)
+	b.chargeBalance(ctx, first.From.ID, meter)
+	b.checkAlerts(ctx, first.From.ID)
+}
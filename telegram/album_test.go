@@ -0,0 +1,234 @@
+package telegram
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chatgpt"
+	"github.com/muzykantov/tgpt/storage"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
+)
+
+// seeingClient is a chatgpt.Client which records the images of the chat completion
+// requests and answers them with a fixed reply.
+type seeingClient struct {
+	answeringClient
+
+	mu       sync.Mutex
+	requests [][]string // requests holds the images of the last message of every request.
+}
+
+func (c *seeingClient) CreateChatCompletion(
+	ctx context.Context,
+	req openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	var images []string
+	for _, part := range req.Messages[len(req.Messages)-1].MultiContent {
+		if part.Type != openai.ChatMessagePartTypeImageURL {
+			continue
+		}
+
+		_, data, _ := strings.Cut(part.ImageURL.URL, ";base64,")
+		image, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return openai.ChatCompletionResponse{}, err
+		}
+		images = append(images, string(image))
+	}
+	sort.Strings(images)
+
+	c.mu.Lock()
+	c.requests = append(c.requests, images)
+	c.mu.Unlock()
+
+	return c.answeringClient.CreateChatCompletion(ctx, req)
+}
+
+// newAlbumBot returns a bot like newTestBot which asks the seeing client.
+func newAlbumBot(t *testing.T) (*Bot, *telegramtest.Sender, *seeingClient) {
+	t.Helper()
+
+	client := &seeingClient{answeringClient: answeringClient{reply: "Cats."}}
+	fs := &storage.FS{BaseDir: t.TempDir()}
+	provider := chatgpt.NewSessionProvider(client, fs, chatgpt.DefaultRequestParams, time.Hour, time.Hour)
+
+	sender := telegramtest.NewSender()
+	bot := NewBot(
+		"TGPT", sender, provider, fs, openai.GPT4oMini,
+		[]int64{1}, nil, language.English, "@admin", "$", 1, "",
+	)
+	bot.sender = sender
+
+	return bot, sender, client
+}
+
+// albumPhoto returns the message of the album with a photo, whose content is stored in
+// a local file of the self-hosted Bot API server. The content is the ID of the file.
+func albumPhoto(t *testing.T, sender *telegramtest.Sender, album, fileID string) *tgbotapi.Message {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), fileID+".jpg")
+	if err := os.WriteFile(path, []byte(fileID), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	sender.Files[fileID] = localFileURL(path)
+
+	msg := telegramtest.NewMessage(1, "")
+	msg.MediaGroupID = album
+	msg.Photo = []tgbotapi.PhotoSize{
+		{FileID: fileID + "-small", Width: 90},
+		{FileID: fileID, Width: 1280},
+	}
+
+	return msg
+}
+
+// handleUpdates handles the messages concurrently, as the bot handles the updates, and
+// waits until all of them are handled.
+func handleUpdates(bot *Bot, messages ...*tgbotapi.Message) {
+	var wg sync.WaitGroup
+	for _, msg := range messages {
+		wg.Add(1)
+		go func(msg *tgbotapi.Message) {
+			defer wg.Done()
+			bot.handleMessage(context.Background(), msg)
+		}(msg)
+	}
+	wg.Wait()
+}
+
+func TestAlbumCollected(t *testing.T) {
+	// Setup: two albums arrive interleaved, the caption is on the second photo.
+	bot, sender, client := newAlbumBot(t)
+
+	cats := []*tgbotapi.Message{
+		albumPhoto(t, sender, "cats", "cat1"),
+		albumPhoto(t, sender, "cats", "cat2"),
+		albumPhoto(t, sender, "cats", "cat3"),
+	}
+	cats[1].Caption = "Who is the fattest?"
+	dog := albumPhoto(t, sender, "dog", "dog1")
+
+	// Execute.
+	handleUpdates(bot, cats[0], dog, cats[1], cats[2])
+
+	// Assert: one request per album with the largest size of all its photos.
+	requests := client.requests
+	sort.Slice(requests, func(i, j int) bool { return len(requests[i]) > len(requests[j]) })
+	want := [][]string{{"cat1", "cat2", "cat3"}, {"dog1"}}
+	if len(requests) != len(want) {
+		t.Fatalf("Sent %d requests with the images %q, want %q", len(requests), requests, want)
+	}
+	for i := range want {
+		if strings.Join(requests[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("Sent the images %q, want %q", requests, want)
+		}
+	}
+
+	// The answer about the cats replies to the caption.
+	replies := make(map[int]string)
+	for _, msg := range sender.Messages() {
+		replies[msg.ReplyToMessageID] = msg.Text
+	}
+	if len(replies) != 2 || replies[cats[1].MessageID] != "Cats." || replies[dog.MessageID] != "Cats." {
+		t.Errorf("Sent the replies %v, want the answers to the messages %d and %d",
+			replies, cats[1].MessageID, dog.MessageID)
+	}
+}
+
+func TestAlbumDownloadFailure(t *testing.T) {
+	// Setup: the second photo of the album cannot be downloaded.
+	bot, sender, client := newAlbumBot(t)
+
+	first := albumPhoto(t, sender, "cats", "cat1")
+	second := albumPhoto(t, sender, "cats", "cat2")
+	delete(sender.Files, "cat2")
+
+	// Execute.
+	handleUpdates(bot, first, second)
+
+	// Assert: the model is not asked about a part of the album.
+	if len(client.requests) != 0 {
+		t.Errorf("Sent the images %q, want no requests", client.requests)
+	}
+	if messages := sender.Messages(); len(messages) != 1 {
+		t.Errorf("Sent %q, want one error", sender.Texts())
+	}
+}
+
+func TestBatchBufferCollect(t *testing.T) {
+	// Setup: the second item arrives before the batch of the first one is complete.
+	const wait = 50 * time.Millisecond
+	var buffer batchBuffer[string, int]
+	ctx := context.Background()
+
+	buffer.add("album", 1, wait)
+	start := time.Now()
+
+	type result struct {
+		items []int
+		ok    bool
+		at    time.Duration
+	}
+	results := make(chan result, 2)
+	collect := func() {
+		items, ok := buffer.collect(ctx, "album")
+		results <- result{items, ok, time.Since(start)}
+	}
+	go collect()
+
+	time.Sleep(wait / 2)
+	buffer.add("album", 2, wait)
+	go collect()
+
+	// Assert: only one collector takes the batch, once the wait passed after the last item.
+	var taken []result
+	for i := 0; i < 2; i++ {
+		if r := <-results; r.ok {
+			taken = append(taken, r)
+		}
+	}
+
+	if len(taken) != 1 {
+		t.Fatalf("The batch was taken %d times, want once", len(taken))
+	}
+	if got := taken[0].items; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Collected %v, want [1 2]", got)
+	}
+	if taken[0].at < wait/2+wait {
+		t.Errorf("The batch was taken after %v, want at least %v", taken[0].at, wait/2+wait)
+	}
+	if items := buffer.take("album"); items != nil {
+		t.Errorf("The buffer kept %v after the batch was taken", items)
+	}
+}
+
+func TestBatchBufferCollectCanceled(t *testing.T) {
+	// Setup.
+	var buffer batchBuffer[string, int]
+	buffer.add("album", 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Execute.
+	items, ok := buffer.collect(ctx, "album")
+
+	// Assert: the canceled collector takes nothing and leaves the batch.
+	if ok || items != nil {
+		t.Errorf("Collected %v, %t after the cancellation, want nothing", items, ok)
+	}
+	if items := buffer.take("album"); len(items) != 1 {
+		t.Errorf("The buffer kept %v, want [1]", items)
+	}
+}
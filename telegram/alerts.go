@@ -0,0 +1,189 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// AlertThresholds defines the spending limits that trigger a notification to the
+// administrators. The amounts are expressed in the cost units of the statistics,
+// before the conversion with the bot's rate. A zero amount disables the threshold.
+type AlertThresholds struct {
+	UserDaily    chat.Cost // UserDaily is the maximum cost of a single user per day.
+	TotalMonthly chat.Cost // TotalMonthly is the maximum cost of all users per month.
+	Throttle     bool      // Throttle rejects messages of users who crossed UserDaily until the next day.
+}
+
+// alerts keeps the spending thresholds and remembers the periods for which the
+// administrators were already notified, so every threshold is reported once per period.
+type alerts struct {
+	thresholds AlertThresholds
+
+	mu           sync.Mutex
+	userAlerted  map[int64]string // userAlerted maps a user to the last day (see chat.DayLayout) reported.
+	totalAlerted string           // totalAlerted is the last month (see chat.MonthLayout) reported.
+}
+
+// SetAlertThresholds configures the spending thresholds. When a threshold is crossed,
+// the administrators are notified immediately, once per day for the per-user threshold
+// and once per month for the total threshold.
+//
+// thresholds: The spending thresholds to apply.
+func (b *Bot) SetAlertThresholds(thresholds AlertThresholds) {
+	b.alerts.mu.Lock()
+	defer b.alerts.mu.Unlock()
+
+	b.alerts.thresholds = thresholds
+	b.alerts.userAlerted = make(map[int64]string)
+	b.alerts.totalAlerted = ""
+}
+
+// isThrottled reports whether the user crossed the daily threshold and their messages
+// must be rejected until the next day. Administrators are never throttled.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user to check.
+func (b *Bot) isThrottled(ctx context.Context, user int64) bool {
+	b.alerts.mu.Lock()
+	thresholds := b.alerts.thresholds
+	b.alerts.mu.Unlock()
+
+	if !thresholds.Throttle || thresholds.UserDaily <= 0 || b.IsUserAdmin(user) {
+		return false
+	}
+
+	cost, err := b.userCostToday(ctx, user)
+	if err != nil {
+		slog.Error(
+			"isThrottled userCostToday error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+
+	return cost >= thresholds.UserDaily
+}
+
+// checkAlerts compares the spending of the user and of all users with the configured
+// thresholds and notifies the administrators about every newly crossed threshold.
+// It is called after every billed request.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user who made the request.
+func (b *Bot) checkAlerts(ctx context.Context, user int64) {
+	b.alerts.mu.Lock()
+	thresholds := b.alerts.thresholds
+	b.alerts.mu.Unlock()
+
+	if thresholds.UserDaily > 0 {
+		if err := b.checkUserAlert(ctx, user, thresholds.UserDaily); err != nil {
+			slog.Error(
+				"checkAlerts checkUserAlert error",
+				slog.Int64("userID", user),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	if thresholds.TotalMonthly > 0 {
+		if err := b.checkTotalAlert(ctx, thresholds.TotalMonthly); err != nil {
+			slog.Error("checkAlerts checkTotalAlert error", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// checkUserAlert notifies the administrators if the user's cost for today crossed
+// the threshold and no notification was sent for this user today.
+func (b *Bot) checkUserAlert(ctx context.Context, user int64, threshold chat.Cost) error {
+	cost, err := b.userCostToday(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	if cost < threshold {
+		return nil
+	}
+
+	day := chat.Now().In(b.userLocation(ctx, user)).Format(chat.DayLayout)
+
+	b.alerts.mu.Lock()
+	alerted := b.alerts.userAlerted[user] == day
+	b.alerts.userAlerted[user] = day
+	b.alerts.mu.Unlock()
+
+	if alerted {
+		return nil
+	}
+
+	b.sendAdmins(b.printer.Sprintf(
+		lang.MsgAlertUserDaily,
+		user,
+		b.currency, b.amount(cost),
+		b.currency, b.amount(threshold),
+	))
+
+	return nil
+}
+
+// checkTotalAlert notifies the administrators if the cost of all users for this month
+// crossed the threshold and no notification was sent this month.
+func (b *Bot) checkTotalAlert(ctx context.Context, threshold chat.Cost) error {
+	rollup, err := b.rollup(ctx)
+	if err != nil {
+		return fmt.Errorf("error aggregating statistics: %w", err)
+	}
+
+	if rollup.Global.ThisMonth < threshold {
+		return nil
+	}
+
+	month := rollup.Updated.In(chat.DefaultLocation).Format(chat.MonthLayout)
+
+	b.alerts.mu.Lock()
+	alerted := b.alerts.totalAlerted == month
+	b.alerts.totalAlerted = month
+	b.alerts.mu.Unlock()
+
+	if alerted {
+		return nil
+	}
+
+	b.sendAdmins(b.printer.Sprintf(
+		lang.MsgAlertTotalMonthly,
+		b.currency, b.amount(rollup.Global.ThisMonth),
+		b.currency, b.amount(threshold),
+	))
+
+	return nil
+}
+
+// userCostToday returns the cost of all chat sessions of the user for the current
+// day in the user's time zone.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+//
+// Returns the cost and an error if the ledger could not be loaded.
+func (b *Bot) userCostToday(ctx context.Context, user int64) (chat.Cost, error) {
+	ledger, err := b.storage.LoadLedger(ctx, user)
+	if err != nil {
+		return 0, fmt.Errorf("error loading ledger: %w", err)
+	}
+
+	return ledger.Today(chat.Now().In(b.userLocation(ctx, user))), nil
+}
+
+// sendAdmins sends a message to the private chats of all administrators.
+//
+// text: The text content of the message.
+func (b *Bot) sendAdmins(text string) {
+	for _, adminID := range b.admins() {
+		b.Send(adminID, text)
+	}
+}
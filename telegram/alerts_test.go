@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/storage"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
+)
+
+// adminID is the administrator of the bots of newSpendingBot, notified by the alerts.
+const adminID = 100
+
+// setClock stops the clock of the statistics and the ledgers at now until the test ends.
+func setClock(t *testing.T, now time.Time) {
+	t.Helper()
+
+	clock := chat.Now
+	chat.Now = func() time.Time { return now }
+	t.Cleanup(func() { chat.Now = clock })
+}
+
+// newSpendingBot returns a bot over an empty storage which allows the users 1 and 2,
+// with the clock stopped at now. The bot answers nothing: the spending is recorded with
+// spend.
+func newSpendingBot(t *testing.T, now time.Time) (*Bot, *telegramtest.Sender) {
+	t.Helper()
+
+	setClock(t, now)
+
+	sender := telegramtest.NewSender()
+	bot := NewBot(
+		"TGPT", sender, nil, &storage.FS{BaseDir: t.TempDir()}, openai.GPT4oMini,
+		[]int64{1, 2}, []int64{adminID}, language.English, "@admin", "$", 1, "",
+	)
+	bot.sender = sender
+
+	return bot, sender
+}
+
+// spend records the cost of a request in the chat session at the time, in the time zone
+// of the user, and adds it to the ledgers of the user and of the group chat.
+func spend(t *testing.T, bot *Bot, id chat.ID, at time.Time, cost chat.Cost) {
+	t.Helper()
+
+	ctx := context.Background()
+	stats, err := bot.storage.LoadStatistics(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadStatistics failed: %s", err)
+	}
+
+	stats.AddCost(at.In(bot.userLocation(ctx, id.User)), id.Model, cost)
+	if err := bot.storage.SaveStatistics(ctx, stats); err != nil {
+		t.Fatalf("SaveStatistics failed: %s", err)
+	}
+}
+
+// setTimezone sets the time zone of the user.
+func setTimezone(t *testing.T, bot *Bot, user int64, timezone string) {
+	t.Helper()
+
+	ctx := context.Background()
+	profile, err := bot.storage.LoadProfile(ctx, user)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %s", err)
+	}
+
+	profile.Timezone = timezone
+	if err := bot.storage.SaveProfile(ctx, profile); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+}
+
+// alertsSent returns the texts sent to the administrator containing the text.
+func alertsSent(sender *telegramtest.Sender, text string) []string {
+	var alerts []string
+	for _, msg := range sender.Messages() {
+		if msg.ChatID == adminID && strings.Contains(msg.Text, text) {
+			alerts = append(alerts, msg.Text)
+		}
+	}
+
+	return alerts
+}
+
+func TestUserDailyAlertDayBoundary(t *testing.T) {
+	// The clock is an hour past midnight in Tokyo, still the previous day in UTC.
+	now := time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timezone  string
+		spentAt   time.Time
+		wantAlert bool
+	}{
+		{
+			name:      "spent today",
+			spentAt:   now.Add(-time.Hour),
+			wantAlert: true,
+		},
+		{
+			name:    "spent yesterday",
+			spentAt: time.Date(2024, 3, 9, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "spent before midnight of the user",
+			timezone: "Asia/Tokyo",
+			spentAt:  now.Add(-2 * time.Hour),
+		},
+		{
+			name:      "spent after midnight of the user",
+			timezone:  "Asia/Tokyo",
+			spentAt:   now.Add(-30 * time.Minute),
+			wantAlert: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup.
+			ctx := context.Background()
+			bot, sender := newSpendingBot(t, now)
+			bot.SetAlertThresholds(AlertThresholds{UserDaily: 5, Throttle: true})
+			setTimezone(t, bot, 1, tt.timezone)
+			spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, tt.spentAt, 5)
+
+			// Execute.
+			bot.checkAlerts(ctx, 1)
+
+			// Assert.
+			if alerts := alertsSent(sender, "user 1 spent"); (len(alerts) == 1) != tt.wantAlert {
+				t.Errorf("Sent the alerts %q, want an alert %v", alerts, tt.wantAlert)
+			}
+			if throttled := bot.isThrottled(ctx, 1); throttled != tt.wantAlert {
+				t.Errorf("isThrottled() = %v, want %v", throttled, tt.wantAlert)
+			}
+		})
+	}
+}
+
+func TestUserDailyAlertOncePerDay(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	bot, sender := newSpendingBot(t, now)
+	bot.SetAlertThresholds(AlertThresholds{UserDaily: 5})
+	id := chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}
+
+	// Execute: the threshold is crossed and the spending goes on.
+	spend(t, bot, id, now, 5)
+	bot.checkAlerts(ctx, 1)
+	spend(t, bot, id, now, 1)
+	bot.checkAlerts(ctx, 1)
+
+	if alerts := alertsSent(sender, "user 1 spent"); len(alerts) != 1 {
+		t.Fatalf("Sent the alerts %q on the same day, want one", alerts)
+	}
+
+	// Assert: the threshold crossed again on the next day is reported again.
+	now = now.Add(24 * time.Hour)
+	setClock(t, now)
+	spend(t, bot, id, now, 5)
+	bot.checkAlerts(ctx, 1)
+
+	if alerts := alertsSent(sender, "user 1 spent"); len(alerts) != 2 {
+		t.Errorf("Sent the alerts %q over two days, want two", alerts)
+	}
+}
+
+func TestUserDailyAlertGroupChat(t *testing.T) {
+	// Setup: the user spends in a group chat and in private.
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	bot, sender := newSpendingBot(t, now)
+	bot.SetAlertThresholds(AlertThresholds{UserDaily: 5})
+
+	spend(t, bot, chat.ID{User: 1, Chat: -100, Model: openai.GPT4oMini}, now, 3)
+	spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, now, 2)
+
+	// Execute.
+	bot.checkAlerts(ctx, 1)
+	bot.checkAlerts(ctx, 2)
+
+	// Assert: the spending in the group chat counts towards the user who made it.
+	if alerts := alertsSent(sender, "spent"); len(alerts) != 1 || !strings.Contains(alerts[0], "user 1 spent $5.00") {
+		t.Errorf("Sent the alerts %q, want the alert about the user 1", alerts)
+	}
+}
+
+func TestTotalMonthlyAlert(t *testing.T) {
+	// Setup: the users spend over two months.
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	bot, sender := newSpendingBot(t, now)
+	bot.SetAlertThresholds(AlertThresholds{TotalMonthly: 10})
+
+	spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, now.AddDate(0, 0, -1), 8)
+	spend(t, bot, chat.ID{User: 2, Chat: -100, Model: openai.GPT4oMini}, now, 6)
+
+	// Execute: the spending of the last month does not count.
+	bot.checkAlerts(ctx, 2)
+	if alerts := alertsSent(sender, "all users spent"); len(alerts) != 0 {
+		t.Fatalf("Sent the alerts %q below the threshold", alerts)
+	}
+
+	spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, now, 4)
+	bot.checkAlerts(ctx, 1)
+	bot.checkAlerts(ctx, 2)
+
+	// Assert: the threshold is reported once this month.
+	if alerts := alertsSent(sender, "all users spent"); len(alerts) != 1 || !strings.Contains(alerts[0], "$10.00") {
+		t.Errorf("Sent the alerts %q, want one about $10.00", alerts)
+	}
+}
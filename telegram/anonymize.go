@@ -0,0 +1,33 @@
+package telegram
+
+import (
+	"github.com/muzykantov/tgpt/privacy"
+)
+
+// SetPseudonymizer enables the anonymized mode: the names of the authors of the forwarded
+// messages are not sent to the model, and the reports sent to the external error reporter
+// carry the pseudonyms of the users and the chats instead of their identifiers, without
+// the usernames and the texts of the messages. The administrators still get the full
+// reports. The storage and the logs are pseudonymized separately.
+//
+// p: The Pseudonymizer; nil disables the anonymized mode.
+func (b *Bot) SetPseudonymizer(p *privacy.Pseudonymizer) {
+	b.pseudonymizer = p
+}
+
+// anonymizeReport returns the report without the identities of the user and the chat
+// in the anonymized mode.
+//
+// report: The report of the incident.
+func (b *Bot) anonymizeReport(report ErrorReport) ErrorReport {
+	if b.pseudonymizer == nil {
+		return report
+	}
+
+	report.UserID = b.pseudonymizer.ID(report.UserID)
+	report.ChatID = b.pseudonymizer.ID(report.ChatID)
+	report.Username = ""
+	report.MessageText = ""
+
+	return report
+}
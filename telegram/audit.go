@@ -0,0 +1,232 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/audit"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// defaultAuditEntries is the number of the entries shown by /audit when no limit is given.
+const defaultAuditEntries = 10
+
+// maxAuditEntries limits the number of the entries shown by /audit.
+const maxAuditEntries = 30
+
+// maxAuditText limits the length of the questions shown by /audit, in characters.
+const maxAuditText = 80
+
+// AuditLog defines an interface for the append-only audit trail of the questions of the
+// users and the commands of the administrators.
+type AuditLog interface {
+	// Append records the entry at the end of the audit trail.
+	Append(ctx context.Context, entry audit.Entry) error
+
+	// Query returns the entries selected by the filter in the order they were recorded.
+	Query(ctx context.Context, filter audit.Filter) ([]audit.Entry, error)
+
+	// Erase removes the entries of the user or the pseudonym.
+	Erase(ctx context.Context, user int64) error
+
+	// Prune removes the entries recorded before the time.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// SetAuditLog configures the audit trail recording who asked what, with which model and
+// at what cost, and the commands of the administrators. In the anonymized mode the
+// entries are recorded with the pseudonyms of the users and the chats, the questions
+// without their texts and the commands without their arguments. Passing nil disables
+// the audit trail.
+//
+// log: The AuditLog implementation to use.
+func (b *Bot) SetAuditLog(log AuditLog) {
+	b.auditLog = log
+}
+
+// auditQuestion records the question answered in the session. Errors are logged.
+//
+// ctx: The context for the audit trail operation.
+// msg: The message with the question.
+// session: The session which answered the question.
+// question: The question sent to the model.
+// fallback: The model which answered instead of the model of the session, empty if none.
+func (b *Bot) auditQuestion(ctx context.Context, msg *tgbotapi.Message, session chat.Session, question, fallback string) {
+	if b.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Time: time.Now().UTC(),
+		Kind: audit.KindQuestion,
+		User: msg.From.ID,
+		Chat: msg.Chat.ID,
+		Text: question,
+	}
+
+	// The model which answered and the cost of the answer are known from the statistics.
+	if stats, err := session.Statistics(ctx); err == nil {
+		entry.Model, entry.Cost = stats.Model, stats.LastMessage
+	}
+	if fallback != "" {
+		entry.Model = fallback
+	}
+
+	if b.pseudonymizer != nil {
+		entry.User = b.pseudonymizer.ID(entry.User)
+		entry.Chat = b.pseudonymizer.ID(entry.Chat)
+		entry.Text = ""
+	}
+
+	b.appendAudit(ctx, entry)
+}
+
+// auditAdmin records the command of an administrator. Errors are logged.
+//
+// ctx: The context for the audit trail operation.
+// msg: The message with the command.
+func (b *Bot) auditAdmin(ctx context.Context, msg *tgbotapi.Message) {
+	if b.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Time: time.Now().UTC(),
+		Kind: audit.KindAdmin,
+		User: msg.From.ID,
+		Chat: msg.Chat.ID,
+		Text: msg.Text,
+	}
+
+	// The arguments of the commands name the users, e.g. /ban <user>.
+	if b.pseudonymizer != nil {
+		entry.User = b.pseudonymizer.ID(entry.User)
+		entry.Chat = b.pseudonymizer.ID(entry.Chat)
+		entry.Text = "/" + msg.Command()
+	}
+
+	b.appendAudit(ctx, entry)
+}
+
+// appendAudit records the entry in the audit trail, logging the errors.
+func (b *Bot) appendAudit(ctx context.Context, entry audit.Entry) {
+	if err := b.auditLog.Append(ctx, entry); err != nil {
+		slog.Error(
+			"appendAudit error",
+			slog.Int64("userID", entry.User),
+			slog.String("kind", string(entry.Kind)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// handleAudit replies with the latest entries of the audit trail, of all users or of
+// the given user: /audit [user] [N].
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /audit command.
+func (b *Bot) handleAudit(ctx context.Context, msg *tgbotapi.Message) {
+	if b.auditLog == nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAuditDisabled))
+		return
+	}
+
+	filter := audit.Filter{Limit: defaultAuditEntries}
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) > 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAuditUsage, maxAuditEntries))
+		return
+	}
+	if len(args) > 0 {
+		user, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgAuditUsage, maxAuditEntries))
+			return
+		}
+		filter.User = user
+	}
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 || n > maxAuditEntries {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgAuditUsage, maxAuditEntries))
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := b.auditLog.Query(ctx, filter)
+	if err != nil {
+		b.handleError(ctx, msg, "handleAudit Query", err)
+		return
+	}
+
+	// The questions of the user are recorded under the pseudonym in the anonymized mode.
+	if b.pseudonymizer != nil && filter.User != 0 {
+		filter.User = b.pseudonymizer.ID(filter.User)
+		questions, err := b.auditLog.Query(ctx, filter)
+		if err != nil {
+			b.handleError(ctx, msg, "handleAudit Query", err)
+			return
+		}
+
+		entries = append(entries, questions...)
+		sortAuditEntries(entries)
+		if len(entries) > filter.Limit {
+			entries = entries[len(entries)-filter.Limit:]
+		}
+	}
+
+	if len(entries) == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgAuditEmpty))
+		return
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = b.formatAuditEntry(entry)
+	}
+
+	text := strings.Join(lines, "\n\n")
+	if len(text) > maxReportLength {
+		text = strings.ToValidUTF8(text[:maxReportLength], "")
+	}
+
+	// The questions are sent as plain text, as they routinely break Markdown parsing.
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyToMessageID = msg.MessageID
+	if _, err := b.sender.Send(reply); err != nil {
+		b.handleError(ctx, msg, "handleAudit Send", err)
+	}
+}
+
+// formatAuditEntry formats the entry of the audit trail as a line of /audit.
+func (b *Bot) formatAuditEntry(entry audit.Entry) string {
+	at := entry.Time.Format("2006-01-02 15:04:05 MST")
+
+	if entry.Kind == audit.KindAdmin {
+		return b.printer.Sprintf(lang.MsgAuditAdmin, at, entry.User, entry.Chat, entry.Text)
+	}
+
+	text := []rune(entry.Text)
+	if len(text) > maxAuditText {
+		text = append(text[:maxAuditText], '…')
+	}
+
+	return b.printer.Sprintf(
+		lang.MsgAuditQuestion,
+		at, entry.User, entry.Chat, entry.Model, b.currency, b.amount(entry.Cost), string(text),
+	)
+}
+
+// sortAuditEntries sorts the entries by their time.
+func sortAuditEntries(entries []audit.Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+}
@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// isBanned reports whether the user was banned by an administrator. A ban overrides
+// every other kind of access, except that the administrators from the configuration
+// cannot be banned.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user to check.
+func (b *Bot) isBanned(ctx context.Context, user int64) bool {
+	b.accessMu.RLock()
+	_, configuredAdmin := b.configuredAdmins[user]
+	b.accessMu.RUnlock()
+
+	if configuredAdmin {
+		return false
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"isBanned LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+
+	return profile.Banned
+}
+
+// setBanned bans or unbans the user and persists the ban in the user's profile.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+// banned: Whether the user is banned.
+//
+// Returns an error if the profile could not be loaded or saved.
+func (b *Bot) setBanned(ctx context.Context, user int64, banned bool) error {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		return fmt.Errorf("error loading profile: %w", err)
+	}
+
+	profile.Banned = banned
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		return fmt.Errorf("error saving profile: %w", err)
+	}
+
+	return nil
+}
+
+// handleBan bans a user: /ban <user>. Banned users are refused regardless of the
+// allowlist, invites, roles and the public mode.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /ban command.
+func (b *Bot) handleBan(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBanUsage))
+		return
+	}
+
+	b.accessMu.RLock()
+	_, configuredAdmin := b.configuredAdmins[user]
+	b.accessMu.RUnlock()
+
+	if configuredAdmin {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBanAdmin))
+		return
+	}
+
+	if err := b.setBanned(ctx, user, true); err != nil {
+		b.handleError(ctx, msg, "handleBan setBanned", err)
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgUserBanned, user))
+}
+
+// handleUnban lifts the ban of a user: /unban <user>.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /unban command.
+func (b *Bot) handleUnban(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgUnbanUsage))
+		return
+	}
+
+	if err := b.setBanned(ctx, user, false); err != nil {
+		b.handleError(ctx, msg, "handleUnban setBanned", err)
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgUserUnbanned, user))
+}
@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batch holds the items collected under a key.
+type batch[V any] struct {
+	items    []V
+	deadline time.Time // deadline is the time the batch is complete if no more items arrive.
+}
+
+// batchBuffer collects items which Telegram delivers as separate updates but which
+// belong together, e.g., the messages of an album or a batch of forwarded messages.
+// The zero value is ready to use.
+type batchBuffer[K comparable, V any] struct {
+	mu      sync.Mutex
+	batches map[K]*batch[V]
+}
+
+// add appends the item to the batch of the key and postpones the batch deadline.
+//
+// key: The key of the batch.
+// item: The item to add.
+// wait: How long to wait for more items.
+func (f *batchBuffer[K, V]) add(key K, item V, wait time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.batches == nil {
+		f.batches = make(map[K]*batch[V])
+	}
+
+	b, ok := f.batches[key]
+	if !ok {
+		b = &batch[V]{}
+		f.batches[key] = b
+	}
+
+	b.items = append(b.items, item)
+	b.deadline = time.Now().Add(wait)
+}
+
+// take removes the batch of the key and returns its items.
+//
+// key: The key of the batch.
+func (f *batchBuffer[K, V]) take(key K) []V {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.batches[key]
+	if !ok {
+		return nil
+	}
+
+	delete(f.batches, key)
+	return b.items
+}
+
+// collect waits until the batch of the key is complete and takes it. Every goroutine
+// which added an item calls collect; only the one which finds the batch complete gets
+// the items, the others get nothing.
+//
+// ctx: The context for cancelling the wait.
+// key: The key of the batch.
+//
+// Returns the items of the batch, or false if the batch was taken by another caller.
+func (f *batchBuffer[K, V]) collect(ctx context.Context, key K) ([]V, bool) {
+	for {
+		f.mu.Lock()
+		b, ok := f.batches[key]
+		var wait time.Duration
+		if ok {
+			wait = time.Until(b.deadline)
+		}
+		f.mu.Unlock()
+
+		if !ok {
+			return nil, false
+		}
+
+		if wait <= 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+
+		case <-time.After(wait):
+		}
+	}
+
+	items := f.take(key)
+	return items, len(items) > 0
+}
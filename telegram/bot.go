@@ -2,14 +2,21 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/muzykantov/tgpt/chat"
 	"github.com/muzykantov/tgpt/lang"
+	"github.com/muzykantov/tgpt/privacy"
+	"github.com/muzykantov/tgpt/render"
+	"github.com/muzykantov/tgpt/version"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 )
@@ -23,13 +30,19 @@ type Bot struct {
 	// name of the chat bot.
 	name string
 
-	// sender is the mechanism for sending messages.
+	// sender is the mechanism for sending messages. It is wrapped with the flood
+	// control which keeps the outgoing messages within Telegram's limits.
 	sender Sender
 
 	// session handles the session state for different chats.
 	// It is a provider that manages chat sessions.
 	session chat.SessionProvider
 
+	// storage provides access to the persisted chat data, e.g., to enumerate known users.
+	// The data of the optional interfaces the storage does not implement, such as the
+	// profiles, is read as if nothing had been saved, see chat.Wrapper.
+	storage chat.Wrapper
+
 	// model is the name of the model for sessions.
 	model string
 
@@ -37,11 +50,64 @@ type Bot struct {
 	// It maps user IDs to empty structs, acting as a set to efficiently check user access.
 	allowedUsers map[int64]struct{}
 
+	// configuredUsers specifies the users allowed by the configuration, as opposed
+	// to the users granted access at runtime and cached in allowedUsers.
+	configuredUsers map[int64]struct{}
+
+	// allowedUsernames specifies the normalized usernames of the users permitted to
+	// interact with the bot whose IDs are not known yet.
+	allowedUsernames map[string]struct{}
+
+	// accessMu protects allowedUsers, configuredUsers, allowedUsernames, adminUsers
+	// and configuredAdmins.
+	accessMu sync.RWMutex
+
+	// inviteMu serializes the updates of the invite codes.
+	inviteMu sync.Mutex
+
+	// balanceMu serializes the updates of the prepaid balances.
+	balanceMu sync.Mutex
+
+	// locker serializes the updates of the prepaid balances across the instances of
+	// the bot sharing the storage. It is optional and may be nil.
+	locker chat.Locker
+
+	// username is the Telegram username of the bot, used in deep links.
+	username string
+
 	// adminUsers specifies which users have administrative privileges.
 	// It maps user IDs to empty structs, similar to allowedUsers,
 	// to efficiently check for administrative access.
+	// Administrators assigned at runtime are cached here as well, see syncRole.
 	adminUsers map[int64]struct{}
 
+	// configuredAdmins specifies the administrators from the configuration, who
+	// cannot be demoted at runtime.
+	configuredAdmins map[int64]struct{}
+
+	// roleBudgets holds the default monthly budgets per role.
+	roleBudgets map[chat.Role]chat.Cost
+
+	// roleModels holds the models permitted per role.
+	roleModels map[chat.Role][]string
+
+	// knownModel reports whether a model may be assigned with /setmodel. It is
+	// optional and may be nil.
+	knownModel func(model string) bool
+
+	// visionModel reports whether a model accepts images. It is optional and may be
+	// nil, then all models are assumed to accept them.
+	visionModel func(model string) bool
+
+	// forwards collects the forwarded messages waiting for a question.
+	forwards batchBuffer[forwardKey, string]
+
+	// albums collects the messages of the albums being received.
+	albums batchBuffer[string, *tgbotapi.Message]
+
+	// maxInputTokens limits the estimated size of a user message; 0 means no limit.
+	maxInputTokens int
+
 	// printer is used for localizing messages based on the provided language tag.
 	// It facilitates internationalization by printing messages in the user's language.
 	printer *message.Printer
@@ -60,6 +126,112 @@ type Bot struct {
 
 	// prompt for new sessions.
 	prompt string
+
+	// reporter receives handler errors and panics for external error tracking.
+	// It is optional and may be nil.
+	reporter ErrorReporter
+
+	// rollups provides the aggregated statistics for administrative reports.
+	// It is optional and may be nil.
+	rollups RollupProvider
+
+	// statusChecks are the self-diagnostic probes reported by the /status command.
+	statusChecks []statusCheck
+
+	// maintenance caches whether the maintenance mode is enabled, read from the storage
+	// at maintenanceRead, in Unix nanoseconds.
+	maintenance     atomic.Bool
+	maintenanceRead atomic.Int64
+
+	// channel restricts the bot to the subscribers of a channel, nil if disabled.
+	channel *channelGate
+
+	// joinGate enables the verification of the new members of the group chats.
+	joinGate bool
+
+	// public bypasses the allowlist.
+	public bool
+
+	// limiter limits the number of messages per user, nil if disabled.
+	limiter *rateLimiter
+
+	// defaultBudget is the monthly budget of users without a budget of their own.
+	defaultBudget chat.Cost
+
+	// trial is the free usage granted to users who are not in the allowlist.
+	trial TrialQuota
+
+	// terms is the text of the terms of use the users accept before the bot serves
+	// them; empty disables the acceptance.
+	terms string
+
+	// termsVersion identifies the text of the terms of use.
+	termsVersion string
+
+	// auditLog records the audit trail, nil if disabled.
+	auditLog AuditLog
+
+	// pseudonymizer hides the identities of the users from the model and the error
+	// reporter in the anonymized mode, nil if disabled.
+	pseudonymizer *privacy.Pseudonymizer
+
+	// publisher publishes the answers longer than publishThreshold characters.
+	publisher        Publisher
+	publishThreshold int
+
+	// renderers render the blocks of the answers Telegram cannot display.
+	renderers map[render.Kind]render.Renderer
+
+	// personas holds the system prompts selected with the /start deep links, by name.
+	personas map[string]string
+
+	// httpClient downloads the files uploaded to Telegram.
+	httpClient *http.Client
+
+	// scheduler limits the number of messages processed at once, nil if unlimited.
+	scheduler *scheduler
+
+	// queue receives the updates to be processed by the workers; nil if this instance
+	// processes the updates itself.
+	queue UpdateQueue
+
+	// worker is the queue of a receiver the updates come from, nil if they come from Telegram.
+	worker UpdateAcker
+
+	// handlers tracks the updates being processed, see Wait.
+	handlers sync.WaitGroup
+
+	// updatesMu protects updates, updatesSaved and updatesDirty.
+	updatesMu sync.Mutex
+
+	// updates is the progress through the Telegram updates, loaded on the first use.
+	updates *chat.Updates
+
+	// updatesSaved is the time the progress was last saved, and updatesDirty reports
+	// whether it has changed since.
+	updatesSaved time.Time
+	updatesDirty bool
+
+	// placeholder enables the instant placeholder reply edited into the answer.
+	placeholder bool
+
+	// reactions enables the processing indicator with a reaction instead of typing.
+	reactions bool
+
+	// voiceReplies enables synthesized voice replies to voice messages.
+	voiceReplies bool
+
+	// rates optionally provides the live exchange rate which replaces rate.
+	rates RateProvider
+
+	// alerts holds the spending thresholds and the state of the sent alerts.
+	alerts alerts
+
+	// errors counts the unexpected errors since the last weekly digest.
+	errors atomic.Int64
+
+	// started records the time the bot was created, used to report uptime.
+	started time.Time
 }
 
 // NewBot creates and initializes a new instance of Bot with the necessary dependencies.
@@ -71,6 +243,7 @@ type Bot struct {
 //   - name: Name of the chat bot.
 //   - sender: The message sending mechanism complying with the Sender interface.
 //   - sessionProvider: The provider for managing chat session states.
+//   - storage: The storage holding the persisted chat histories and statistics.
 //   - model: The name of the model used for sessions.
 //   - allowedUsers: An optional slice of user IDs permitted to interact with the bot.
 //   - adminUsers: An optional slice of user IDs granted administrative privileges.
@@ -86,6 +259,7 @@ func NewBot(
 	name string,
 	sender Sender,
 	sessionProvider chat.SessionProvider,
+	storage chat.Storage,
 	model string,
 	allowedUsers, adminUsers []int64,
 	language language.Tag,
@@ -96,34 +270,46 @@ func NewBot(
 ) *Bot {
 	bot := &Bot{
 		name:         name,
-		sender:       sender,
+		sender:       newFloodControl(sender),
 		session:      sessionProvider,
+		storage:      chat.Wrapper{Storage: storage},
 		model:        model,
 		allowedUsers: make(map[int64]struct{}),
-		adminUsers:   make(map[int64]struct{}),
-		printer:      message.NewPrinter(language),
-		adminContact: adminContact,
-		currency:     currency,
-		rate:         rate,
-		prompt:       prompt,
+
+		configuredUsers:  make(map[int64]struct{}),
+		allowedUsernames: make(map[string]struct{}),
+		adminUsers:       make(map[int64]struct{}),
+		configuredAdmins: make(map[int64]struct{}),
+		printer:          message.NewPrinter(language),
+		adminContact:     adminContact,
+		currency:         currency,
+		rate:             rate,
+		prompt:           prompt,
+		httpClient:       http.DefaultClient,
+		started:          time.Now(),
 	}
 
 	// Populate the allowedUsers map
 	for _, userID := range allowedUsers {
 		bot.allowedUsers[userID] = struct{}{}
+		bot.configuredUsers[userID] = struct{}{}
 	}
 
 	// Populate the adminUsers map
 	for _, userID := range adminUsers {
 		bot.allowedUsers[userID] = struct{}{}
+		bot.configuredUsers[userID] = struct{}{}
 		bot.adminUsers[userID] = struct{}{}
+		bot.configuredAdmins[userID] = struct{}{}
 	}
 
 	return bot
 }
 
 // ProcessUpdates listens for incoming updates from the Telegram bot API
-// and processes each message update asynchronously.
+// and processes each message update asynchronously. The progress through the updates
+// is saved to the storage once their processing finishes, see Offset, and the updates
+// processed already are skipped if Telegram delivers them again.
 //
 // ctx: The context to control the lifecycle of the update processing. If the context
 // is canceled, the method will stop processing updates and return.
@@ -136,18 +322,70 @@ func (b *Bot) ProcessUpdates(ctx context.Context, updates tgbotapi.UpdatesChanne
 	for {
 		select {
 		case <-ctx.Done():
+			b.flushUpdates(context.WithoutCancel(ctx))
 			return ctx.Err()
 
 		case update := <-updates:
-			if update.Message == nil { // Ignore any non-Message updates.
+			if b.worker == nil && !b.markProcessed(ctx, update.UpdateID) {
+				slog.Warn("duplicate update skipped", slog.Int("updateID", update.UpdateID))
 				continue
 			}
 
-			go b.handleMessage(ctx, update.Message)
+			if b.queue != nil {
+				b.publishUpdate(ctx, update)
+				continue
+			}
+
+			b.dispatchUpdate(ctx, update)
 		}
 	}
 }
 
+// dispatchUpdate starts the processing of the update in a separate goroutine.
+//
+// ctx: The context for controlling the processing lifecycle.
+// update: The update received from Telegram.
+func (b *Bot) dispatchUpdate(ctx context.Context, update tgbotapi.Update) {
+	if update.CallbackQuery == nil && update.Message == nil { // Ignore any other non-Message updates.
+		b.finishUpdate(ctx, update.UpdateID)
+		return
+	}
+
+	b.handlers.Add(1)
+	go func() {
+		defer b.handlers.Done()
+		defer b.finishUpdate(ctx, update.UpdateID)
+
+		if update.CallbackQuery != nil {
+			b.handleCallback(ctx, update.CallbackQuery)
+			return
+		}
+
+		b.scheduleMessage(ctx, update.Message)
+	}()
+}
+
+// Wait waits for the updates being processed to finish, e.g. on shutdown once
+// ProcessUpdates has returned.
+//
+// timeout: The maximum time to wait.
+//
+// Returns false if the updates did not finish in time.
+func (b *Bot) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		b.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // Reply sends a textual reply to a specific message within a Telegram chat.
 // It constructs a message configuration targeting the original message
 // and uses the Bot's sender to dispatch the reply. If an error occurs
@@ -277,12 +515,20 @@ func (b *Bot) Typing(ctx context.Context, chatID int64) {
 }
 
 // IsUserAllowed checks if the user with the given ID is allowed to interact with the bot.
+// In public mode every user is allowed.
 //
 // userID: The Telegram user ID to check for permission.
 //
 // Returns:
 // - true if the user is allowed, false otherwise.
 func (b *Bot) IsUserAllowed(userID int64) bool {
+	if b.public {
+		return true
+	}
+
+	b.accessMu.RLock()
+	defer b.accessMu.RUnlock()
+
 	_, allowed := b.allowedUsers[userID]
 	return allowed
 }
@@ -294,6 +540,9 @@ func (b *Bot) IsUserAllowed(userID int64) bool {
 // Returns:
 // - true if the user is an admin, false otherwise.
 func (b *Bot) IsUserAdmin(userID int64) bool {
+	b.accessMu.RLock()
+	defer b.accessMu.RUnlock()
+
 	_, admin := b.adminUsers[userID]
 	return admin
 }
@@ -329,15 +578,59 @@ func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 		)
 	}()
 
+	defer b.recoverPanic(ctx, msg)
+
+	// New members of the group chats are verified before the bot responds to them.
+	if !b.checkJoinGate(ctx, msg) {
+		return
+	}
+
+	// Banned users are refused before anything else.
+	if b.isBanned(ctx, msg.From.ID) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBanned, b.adminContact))
+		return
+	}
+
 	// First, check if the user or admin is allowed to interact with the bot.
-	if !b.IsUserAllowed(msg.From.ID) {
+	b.resolveUsername(msg.From)
+	b.syncRole(ctx, msg.From.ID)
+	if !b.isUserPermitted(ctx, msg.From.ID) && !b.redeemInvite(ctx, msg) {
 		b.Reply(msg, b.printer.Sprintf(lang.MsgNotAllowed, msg.From.ID, b.adminContact))
 		return
 	}
 
+	// In the channel-membership mode only subscribers are served.
+	if !b.isSubscribed(msg.From.ID) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSubscribeRequired, b.channel.channel))
+		return
+	}
+
+	// During maintenance only administrators are served.
+	if !b.IsUserAdmin(msg.From.ID) && b.IsMaintenance(ctx) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgMaintenance, b.adminContact))
+		return
+	}
+
+	// Protect the bot from floods of messages.
+	if !b.IsUserAdmin(msg.From.ID) && !b.limiter.Allow(msg.From.ID) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgRateLimited))
+		return
+	}
+
+	// New users accept the terms of use before anything is sent to the model.
+	if !b.checkTerms(ctx, msg) {
+		return
+	}
+
 	if msg.IsCommand() {
 		// Handle the command.
 		b.handleCommand(ctx, msg)
+	} else if isImport(msg) {
+		// Handle a history file captioned with /import.
+		b.handleImport(ctx, msg)
+	} else if msg.MediaGroupID != "" {
+		// Handle an album as a whole.
+		b.handleAlbum(ctx, msg)
 	} else {
 		// Handle a regular message.
 		b.handleRegularMessage(ctx, msg)
@@ -353,45 +646,66 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 	session, err := b.session.ProvideSession(ctx, chat.ID{
 		User:  msg.From.ID,
 		Chat:  msg.Chat.ID,
-		Model: b.model,
+		Model: b.sessionModel(ctx, msg.From.ID),
 	})
 	if err != nil {
-		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-		slog.Error(
-			"handleCommand ProvideSession error",
-			slog.Int64("chatID", msg.Chat.ID),
-			slog.Int("messageID", msg.MessageID),
-			slog.String("messageText", msg.Text),
-			slog.String("error", err.Error()),
-		)
+		b.handleError(ctx, msg, "handleCommand ProvideSession", err)
 		return
 	}
 
 	commands := []tgbotapi.BotCommand{
 		{Command: "help", Description: b.printer.Sprintf(lang.MsgCommandHelp)},
+		{Command: "image", Description: b.printer.Sprintf(lang.MsgCommandImage)},
 		{Command: "stats", Description: b.printer.Sprintf(lang.MsgCommandStats)},
+		{Command: "balance", Description: b.printer.Sprintf(lang.MsgCommandBalance)},
+		{Command: "resetstats", Description: b.printer.Sprintf(lang.MsgCommandResetStats)},
+		{Command: "timezone", Description: b.printer.Sprintf(lang.MsgCommandTimezone)},
+		{Command: "export", Description: b.printer.Sprintf(lang.MsgCommandExport)},
+		{Command: "import", Description: b.printer.Sprintf(lang.MsgCommandImport)},
 		{Command: "restart", Description: b.printer.Sprintf(lang.MsgCommandRestart)},
+		{Command: "version", Description: b.printer.Sprintf(lang.MsgCommandVersion)},
+		{Command: "deletemydata", Description: b.printer.Sprintf(lang.MsgCommandDeleteMyData)},
+	}
+
+	// Users see the commands of their role, administrators also see the admin-only commands.
+	role := b.userRole(ctx, msg.From.ID)
+	isAdmin := b.IsUserAdmin(msg.From.ID)
+	visible := make([]tgbotapi.BotCommand, 0, len(commands))
+	for _, cmd := range commands {
+		if required, gated := commandRoles[cmd.Command]; !gated || role.AtLeast(required) {
+			visible = append(visible, cmd)
+		}
+	}
+	if isAdmin {
+		visible = append(visible, b.adminCommands()...)
+	}
+
+	if required, gated := commandRoles[msg.Command()]; gated && !role.AtLeast(required) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgCommandNotAllowed, b.adminContact))
+		return
 	}
 
 	switch msg.Command() {
 	case "start":
 		if _, err := b.sender.Request(tgbotapi.NewSetMyCommands(commands...)); err != nil {
-			b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-			slog.Error(
-				"handleCommand Request error",
-				slog.Int64("chatID", msg.Chat.ID),
-				slog.Int("messageID", msg.MessageID),
-				slog.String("messageText", msg.Text),
-				slog.String("error", err.Error()),
-			)
+			b.handleError(ctx, msg, "handleCommand Request", err)
 		}
 
+		if isAdmin {
+			scope := tgbotapi.NewBotCommandScopeChat(msg.Chat.ID)
+			if _, err := b.sender.Request(tgbotapi.NewSetMyCommandsWithScope(scope, visible...)); err != nil {
+				b.handleError(ctx, msg, "handleCommand Request", err)
+			}
+		}
+
+		b.startPersona(ctx, msg, session)
+
 		fallthrough
 
 	case "help":
 		sb := &strings.Builder{}
 		sb.WriteString(b.printer.Sprintf(lang.MsgGreeting, b.name))
-		for _, cmd := range commands {
+		for _, cmd := range visible {
 			sb.WriteString(
 				fmt.Sprintf("/%s — %s\n\n", cmd.Command, cmd.Description),
 			)
@@ -401,27 +715,20 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 
 	case "restart":
 		if err := session.Reset(ctx); err != nil {
-			b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-			slog.Error(
-				"handleCommand Reset error",
-				slog.Int64("chatID", msg.Chat.ID),
-				slog.Int("messageID", msg.MessageID),
-				slog.String("messageText", msg.Text),
-				slog.String("error", err.Error()),
-			)
+			b.handleError(ctx, msg, "handleCommand Reset", err)
+		}
+
+		// A new conversation returns the user from the persona to the configured prompt.
+		if len(b.personas) > 0 {
+			if err := b.setPersona(ctx, msg.From.ID, ""); err != nil {
+				b.handleError(ctx, msg, "handleCommand setPersona", err)
+			}
 		}
 
 		args := msg.CommandArguments()
 		if args != "" {
 			if err := session.SetPrompt(ctx, args); err != nil {
-				b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-				slog.Error(
-					"handleCommand SetPrompt error",
-					slog.Int64("chatID", msg.Chat.ID),
-					slog.Int("messageID", msg.MessageID),
-					slog.String("messageText", msg.Text),
-					slog.String("error", err.Error()),
-				)
+				b.handleError(ctx, msg, "handleCommand SetPrompt", err)
 			}
 		}
 
@@ -430,27 +737,59 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 	case "stats":
 		stats, err := session.Statistics(ctx)
 		if err != nil {
-			b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-			slog.Error(
-				"handleCommand ProvideSession error",
-				slog.Int64("chatID", msg.Chat.ID),
-				slog.Int("messageID", msg.MessageID),
-				slog.String("messageText", msg.Text),
-				slog.String("error", err.Error()),
-			)
+			b.handleError(ctx, msg, "handleCommand Statistics", err)
 			return
 		}
 
-		now := chat.Now()
+		now := chat.Now().In(b.userLocation(ctx, msg.From.ID))
+		p := b.userPricing(ctx, msg.From.ID)
 		b.Send(msg.Chat.ID, b.printer.Sprintf(
 			lang.MsgStats,
-			b.currency, b.rate*float64(stats.LastMessage),
-			b.currency, b.rate*float64(stats.Daily),
-			b.currency, b.rate*float64(stats.Monthly[now.Month()]),
-			b.currency, b.rate*float64(stats.Total),
+			p.currency, p.amount(stats.LastMessage),
+			p.currency, p.amount(stats.Today(now)),
+			p.currency, p.amount(stats.Yesterday(now)),
+			p.currency, p.amount(stats.ThisMonth(now)),
+			p.currency, p.amount(stats.Total),
+		)+b.formatModelCosts(stats, p)+b.printer.Sprintf(
+			lang.MsgStatsTokens,
+			stats.LastTokens.Input, stats.LastTokens.Output,
+			stats.TotalTokens.Input, stats.TotalTokens.Output,
+		))
+		b.sendStatsChart(msg, stats, now, p)
+
+	case "balance":
+		b.handleBalance(ctx, msg)
+
+	case "resetstats":
+		b.handleResetStats(msg)
+
+	case "deletemydata":
+		b.handleDeleteMyData(msg)
+
+	case "timezone":
+		b.handleTimezone(ctx, msg)
+
+	case "image":
+		b.handleImage(ctx, msg, session)
+
+	case "export":
+		b.handleExport(ctx, msg, session)
+
+	case "import":
+		b.handleImport(ctx, msg)
+
+	case "version":
+		info := version.Get()
+		b.Send(msg.Chat.ID, b.printer.Sprintf(
+			lang.MsgVersion,
+			b.name, info.Version, info.Revision(), info.Date, info.GoVersion,
 		))
 
 	default:
+		if isAdmin && b.handleAdminCommand(ctx, msg) {
+			return
+		}
+
 		b.Reply(msg, b.printer.Sprintf(lang.MsgCommandNotSupported))
 	}
 }
@@ -484,59 +823,101 @@ func (b *Bot) handleRegularMessage(ctx context.Context, msg *tgbotapi.Message) {
 		)
 	}()
 
-	if msg.Text == "" {
+	// Captioned photos and documents are asked about with their caption.
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	if text == "" && msg.Voice == nil && !hasMedia(msg) {
 		b.Reply(msg, b.printer.Sprintf(lang.MsgNotSupported))
 		return
 	}
 
+	model, permitted := b.sessionModels(ctx, msg.From.ID)
+	ctx = chat.WithPermittedModels(ctx, permitted)
+	if hasImage(msg) && !b.checkVision(msg, model) {
+		return
+	}
+
+	if !b.checkSpending(ctx, msg) {
+		return
+	}
+
 	session, err := b.session.ProvideSession(ctx, chat.ID{
 		User:  msg.From.ID,
 		Chat:  msg.Chat.ID,
-		Model: b.model,
+		Model: model,
 	})
 	if err != nil {
-		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-		slog.Error(
-			"handleRegularMessage ProvideSession error",
-			slog.Int64("chatID", msg.Chat.ID),
-			slog.Int("messageID", msg.MessageID),
-			slog.String("messageText", msg.Text),
-			slog.String("error", err.Error()),
-		)
+		b.handleError(ctx, msg, "handleRegularMessage ProvideSession", err)
 		return
 	}
 
-	if b.prompt != "" {
-		if err := session.SetPrompt(ctx, b.prompt); err != nil {
-			b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-			slog.Error(
-				"handleRegularMessage SetPrompt error",
-				slog.Int64("chatID", msg.Chat.ID),
-				slog.Int("messageID", msg.MessageID),
-				slog.String("messageText", msg.Text),
-				slog.String("error", err.Error()),
-			)
+	if prompt := b.sessionPrompt(ctx, msg.From.ID); prompt != "" {
+		if err := session.SetPrompt(ctx, prompt); err != nil {
+			b.handleError(ctx, msg, "handleRegularMessage SetPrompt", err)
+			return
+		}
+	}
+
+	progress := b.indicateProcessing(ctx, msg)
+	defer progress.stop()
+
+	// The whole request is charged, including the transcription and the spoken reply.
+	ctx, meter := chat.WithCostMeter(ctx)
+	defer b.chargeBalance(ctx, msg.From.ID, meter)
+
+	if msg.Voice != nil {
+		if text, err = b.transcribeVoice(ctx, msg.Voice, session); err != nil {
+			b.handleError(ctx, msg, "handleRegularMessage transcribeVoice", err)
 			return
 		}
 	}
 
-	typingCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	go b.Typing(typingCtx, msg.Chat.ID)
+	text, images, err := b.readMedia(ctx, msg, text)
+	if errors.Is(err, errUnsupportedMedia) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgNotSupported))
+		return
+	}
+	if err != nil {
+		b.handleError(ctx, msg, "handleRegularMessage readMedia", err)
+		return
+	}
+
+	// Forwarded messages are included as quoted context.
+	text, ok := b.withForwarded(ctx, msg, text)
+	if !ok {
+		return
+	}
+
+	// A reply to an earlier answer continues from that exchange.
+	text = b.withQuoted(ctx, msg, session, text)
+
+	if !b.checkInputSize(msg, text) {
+		return
+	}
 
-	reply, err := session.Ask(ctx, msg.Text, false)
+	var reply, fallback string
+	if len(images) > 0 {
+		reply, fallback, err = session.See(ctx, text, images)
+	} else {
+		reply, fallback, err = session.Ask(ctx, text, false)
+	}
 	if err != nil {
-		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
-		slog.Error(
-			"handleRegularMessage Ask error",
-			slog.Int64("chatID", msg.Chat.ID),
-			slog.Int("messageID", msg.MessageID),
-			slog.String("messageText", msg.Text),
-			slog.String("error", err.Error()),
-		)
+		b.handleError(ctx, msg, "handleRegularMessage Ask", err)
 		return
 	}
 
-	b.Reply(msg, reply)
+	progress.reply(ctx, b.withFallbackNote(reply, fallback))
 	replyText = reply
+	b.auditQuestion(ctx, msg, session, text, fallback)
+
+	if msg.Voice != nil && b.voiceReplies {
+		if err := b.sendVoiceReply(ctx, msg, session, reply); err != nil {
+			b.handleError(ctx, msg, "handleRegularMessage sendVoiceReply", err)
+		}
+	}
+
+	b.checkAlerts(ctx, msg.From.ID)
 }
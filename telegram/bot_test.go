@@ -0,0 +1,525 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/audit"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/chatgpt"
+	"github.com/muzykantov/tgpt/lang"
+	"github.com/muzykantov/tgpt/storage"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
+)
+
+// ensure that the fake sender implements the Sender interface
+var _ Sender = (*telegramtest.Sender)(nil)
+
+// answeringClient is a chatgpt.Client which answers every chat completion with a fixed reply.
+type answeringClient struct {
+	chatgpt.Client // Client is nil: the tests call only CreateChatCompletion.
+
+	reply string
+}
+
+func (c *answeringClient) CreateChatCompletion(
+	context.Context,
+	openai.ChatCompletionRequest,
+) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: c.reply}},
+		},
+		Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 5},
+	}, nil
+}
+
+// newTestBot returns a bot which allows the user 1 and sends everything to the fake sender.
+func newTestBot(t *testing.T, reply string) (*Bot, *telegramtest.Sender) {
+	t.Helper()
+
+	fs := &storage.FS{BaseDir: t.TempDir()}
+	provider := chatgpt.NewSessionProvider(
+		&answeringClient{reply: reply}, fs, chatgpt.DefaultRequestParams, time.Hour, time.Hour,
+	)
+
+	sender := telegramtest.NewSender()
+	bot := NewBot(
+		"TGPT", sender, provider, fs, openai.GPT4oMini,
+		[]int64{1}, nil, language.English, "@admin", "$", 1, "",
+	)
+
+	// Skip the flood control, which paces the messages to the real Telegram.
+	bot.sender = sender
+
+	return bot, sender
+}
+
+func TestHandleMessageAnswers(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+
+	msg := telegramtest.NewMessage(1, "Hello!")
+	bot.handleMessage(context.Background(), msg)
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Sent %d messages, want 1: %q", len(messages), sender.Texts())
+	}
+	if messages[0].Text != "Hello, User!" || messages[0].ReplyToMessageID != msg.MessageID {
+		t.Errorf("Sent %q in reply to %d, want the answer in reply to %d",
+			messages[0].Text, messages[0].ReplyToMessageID, msg.MessageID)
+	}
+}
+
+func TestHandleMessageNotAllowed(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+
+	bot.handleMessage(context.Background(), telegramtest.NewMessage(2, "Hello!"))
+
+	texts := sender.Texts()
+	if len(texts) != 1 || strings.Contains(texts[0], "Hello, User!") {
+		t.Fatalf("Sent %q, want only the refusal", texts)
+	}
+}
+
+func TestReplyFallsBackToPlainText(t *testing.T) {
+	bot, sender := newTestBot(t, "")
+	sender.Fail(errors.New("Bad Request: can't parse entities"))
+
+	bot.Reply(telegramtest.NewMessage(1, "Hello!"), "*unbalanced")
+
+	messages := sender.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("Sent %d messages, want 2", len(messages))
+	}
+	if messages[0].ParseMode == "" || messages[1].ParseMode != "" {
+		t.Errorf("Sent with the parse modes %q and %q, want markdown and then plain text",
+			messages[0].ParseMode, messages[1].ParseMode)
+	}
+}
+
+func TestMarkProcessedSkipsDuplicates(t *testing.T) {
+	bot, _ := newTestBot(t, "")
+	ctx := context.Background()
+
+	if !bot.markProcessed(ctx, 42) {
+		t.Fatal("The first delivery of the update was skipped")
+	}
+	if bot.markProcessed(ctx, 42) {
+		t.Error("The second delivery of the update was not skipped")
+	}
+	if !bot.markProcessed(ctx, 41) {
+		t.Error("The update received out of order was skipped")
+	}
+	if bot.markProcessed(ctx, 42-dedupWindow) {
+		t.Error("The update below the window was not skipped")
+	}
+
+	// The polling resumes from the first update interrupted by a restart, which Telegram
+	// delivers again unless it has been confirmed by polling for the next ones.
+	restart := func() *Bot {
+		return NewBot(
+			"TGPT", bot.sender, bot.session, bot.storage, openai.GPT4oMini,
+			[]int64{1}, nil, language.English, "@admin", "$", 1, "",
+		)
+	}
+	bot.flushUpdates(ctx)
+	if offset := restart().Offset(ctx); offset != 41 {
+		t.Errorf("Offset() = %d after an interrupted restart, want 41", offset)
+	}
+
+	// The progress of the finished updates survives a restart.
+	bot.finishUpdate(ctx, 41)
+	bot.finishUpdate(ctx, 42)
+	bot.flushUpdates(ctx)
+
+	restarted := restart()
+	if offset := restarted.Offset(ctx); offset != 43 {
+		t.Errorf("Offset() = %d after a restart, want 43", offset)
+	}
+	if restarted.markProcessed(ctx, 42) {
+		t.Error("The update delivered again after a restart was not skipped")
+	}
+}
+
+func TestDeleteUserData(t *testing.T) {
+	bot, _ := newTestBot(t, "Hello, User!")
+	bot.SetAuditLog(&audit.File{Dir: t.TempDir()})
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+	if err := bot.storage.SaveProfile(ctx, &chat.Profile{User: 1, Timezone: "Europe/Berlin", Allowed: true}); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	sessions, err := bot.deleteUserData(ctx, 1)
+	if err != nil {
+		t.Fatalf("deleteUserData failed: %s", err)
+	}
+	if sessions != 1 {
+		t.Errorf("Deleted %d sessions, want 1", sessions)
+	}
+
+	if ids, err := bot.storage.List(ctx); err != nil || len(ids) != 0 {
+		t.Errorf("List() = %v, %v after the deletion, want no sessions", ids, err)
+	}
+	if profile, err := bot.storage.LoadProfile(ctx, 1); err != nil || profile.Timezone != "" || !profile.Allowed {
+		t.Errorf("LoadProfile() = %+v, %v after the deletion, want the access without the time zone", profile, err)
+	}
+	if entries, err := bot.auditLog.Query(ctx, audit.Filter{User: 1}); err != nil || len(entries) != 0 {
+		t.Errorf("Query() = %+v, %v after the deletion, want no entries", entries, err)
+	}
+
+	// The spending is kept, so the deletion does not renew the trial or the budget.
+	if usage, _, err := bot.userUsage(ctx, 1); err != nil || usage.Messages != 1 {
+		t.Errorf("userUsage() = %+v, %v after the deletion, want 1 message", usage, err)
+	}
+}
+
+func TestRetentionWithoutStatistics(t *testing.T) {
+	bot, _ := newTestBot(t, "Hello, User!")
+	ctx := context.Background()
+	now := chat.Now()
+
+	// The statistics of the old session were reset, and the legacy history has no time.
+	old := chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}
+	legacy := chat.ID{User: 2, Chat: 2, Model: openai.GPT4oMini}
+	for _, history := range []*chat.History{
+		{ID: old, Log: []chat.Message{{User: "Hello!"}}, Updated: now.AddDate(0, 0, -40)},
+		{ID: legacy, Log: []chat.Message{{User: "Hello!"}}},
+	} {
+		if err := bot.storage.SaveHistory(ctx, history); err != nil {
+			t.Fatalf("SaveHistory failed: %s", err)
+		}
+		if err := bot.storage.SaveStatistics(ctx, &chat.Statistics{ID: history.ID}); err != nil {
+			t.Fatalf("SaveStatistics failed: %s", err)
+		}
+	}
+
+	policy := RetentionPolicy{History: 30 * 24 * time.Hour, Statistics: 60 * 24 * time.Hour}
+	if err := bot.applyRetention(ctx, policy); err != nil {
+		t.Fatalf("applyRetention failed: %s", err)
+	}
+
+	if history, err := bot.storage.LoadHistory(ctx, old); err != nil || len(history.Log) != 0 {
+		t.Errorf("LoadHistory() = %+v, %v, want the expired history deleted", history, err)
+	}
+
+	// The legacy history starts aging instead of being kept forever.
+	history, err := bot.storage.LoadHistory(ctx, legacy)
+	if err != nil || len(history.Log) != 1 || history.Updated.IsZero() {
+		t.Errorf("LoadHistory() = %+v, %v, want the legacy history kept and timed", history, err)
+	}
+}
+
+func TestTermsAcceptance(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.SetTerms("Be nice.")
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+
+	messages := sender.Messages()
+	if len(messages) != 1 || !strings.Contains(messages[0].Text, "Be nice.") || messages[0].ReplyMarkup == nil {
+		t.Fatalf("Sent %q, want only the terms with the Accept button", sender.Texts())
+	}
+
+	data := *messages[0].ReplyMarkup.(tgbotapi.InlineKeyboardMarkup).InlineKeyboard[0][0].CallbackData
+	bot.handleCallback(ctx, &tgbotapi.CallbackQuery{
+		ID:      "1",
+		From:    &tgbotapi.User{ID: 1},
+		Message: &tgbotapi.Message{MessageID: messages[0].ReplyToMessageID, Chat: &tgbotapi.Chat{ID: 1}},
+		Data:    data,
+	})
+
+	profile, err := bot.storage.LoadProfile(ctx, 1)
+	if err != nil || profile.TermsAccepted.IsZero() {
+		t.Fatalf("LoadProfile() = %+v, %v, want the accepted terms", profile, err)
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+	if texts := sender.Texts(); len(texts) != 1 || texts[0] != "Hello, User!" {
+		t.Errorf("Sent %q after the terms were accepted, want the answer", texts)
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.SetAuditLog(&audit.File{Dir: t.TempDir()})
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/top"))
+
+	entries, err := bot.auditLog.Query(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if len(entries) != 2 ||
+		entries[0].Kind != audit.KindQuestion || entries[0].Text != "Hello!" || entries[0].Cost == 0 ||
+		entries[1].Kind != audit.KindAdmin || entries[1].Text != "/top" {
+		t.Fatalf("Recorded %+v, want the question and the admin command", entries)
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/audit 1"))
+	if texts := sender.Texts(); len(texts) != 1 || !strings.Contains(texts[0], "Hello!") {
+		t.Errorf("Sent %q, want the audit trail", texts)
+	}
+}
+
+func TestUserStats(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/userstats 1"))
+	texts := sender.Texts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "Stored sessions: 1") || !strings.Contains(texts[0], "Active sessions: 1") {
+		t.Fatalf("Sent %q, want the statistics of the user", texts)
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/userstats"))
+	if texts := sender.Texts(); len(texts) != 1 || !strings.Contains(texts[0], "Usage") {
+		t.Errorf("Sent %q, want the usage", texts)
+	}
+}
+
+func TestCredit(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/credit 1 100"))
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+
+	budget, err := bot.storage.LoadBudget(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadBudget failed: %s", err)
+	}
+	if !budget.Prepaid || budget.Balance <= 0 || budget.Balance >= 100 {
+		t.Fatalf("Budget is %+v, want the balance charged for the answer", budget)
+	}
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/credit 1 -100"))
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello again!"))
+	if texts := sender.Texts(); len(texts) != 1 || !strings.Contains(texts[0], "balance is used up") {
+		t.Errorf("Sent %q, want the exhausted balance notice", texts)
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setrate 1 2 EUR"))
+	if p := bot.userPricing(ctx, 1); p.currency != "EUR" || p.rate != 2*bot.amount(1) {
+		t.Fatalf("Pricing is %+v, want EUR at twice the bot's rate", p)
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/stats"))
+	if texts := sender.Texts(); len(texts) == 0 || !strings.Contains(texts[0], "EUR") {
+		t.Errorf("Sent %q, want the statistics in EUR", texts)
+	}
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setrate 1 off"))
+	if p := bot.userPricing(ctx, 1); p.currency != bot.currency || p.rate != bot.amount(1) {
+		t.Errorf("Pricing is %+v, want the bot's pricing", p)
+	}
+}
+
+func TestSetModel(t *testing.T) {
+	bot, _ := newTestBot(t, "Hello, User!")
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	bot.SetKnownModels(func(model string) bool { return model == "gpt-4o" })
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setmodel 1 gpt-unknown"))
+	if model := bot.sessionModel(ctx, 1); model != bot.model {
+		t.Fatalf("Model is %q after an unknown model, want %q", model, bot.model)
+	}
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setmodel 1 gpt-4o"))
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "Hello!"))
+
+	ids, err := bot.storage.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+	if len(ids) != 1 || ids[0].Model != "gpt-4o" {
+		t.Fatalf("Stored %+v, want a session with the pinned model", ids)
+	}
+
+	// The restriction keeps the bot's model if it is listed, and replaces it otherwise.
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setmodel 1 gpt-4o,"+bot.model))
+	if model := bot.sessionModel(ctx, 1); model != bot.model {
+		t.Errorf("Model is %q, want %q", model, bot.model)
+	}
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/setmodel 1 reset"))
+	if model := bot.sessionModel(ctx, 1); model != bot.model {
+		t.Errorf("Model is %q, want %q", model, bot.model)
+	}
+}
+
+func TestVisionUnsupported(t *testing.T) {
+	bot, sender := newTestBot(t, "A cat.")
+	bot.SetVisionModels(func(model string) bool { return model == "gpt-4o" })
+
+	// The photo is not downloaded for the model which cannot see it.
+	msg := telegramtest.NewMessage(1, "")
+	msg.Caption = "What is this?"
+	msg.Photo = []tgbotapi.PhotoSize{{FileID: "photo"}}
+	bot.handleMessage(context.Background(), msg)
+
+	want := bot.printer.Sprintf(lang.MsgVisionUnsupported, bot.model)
+	if texts := sender.Texts(); len(texts) != 1 || texts[0] != want {
+		t.Errorf("Sent %q, want %q", texts, want)
+	}
+}
+
+func TestGrantAdmin(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.adminUsers[1] = struct{}{}
+	bot.configuredAdmins[1] = struct{}{}
+	ctx := context.Background()
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/grantadmin 2"))
+	if !bot.IsUserAdmin(2) {
+		t.Fatal("User 2 is not an admin after /grantadmin")
+	}
+
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/revokeadmin 2"))
+	if bot.IsUserAdmin(2) {
+		t.Fatal("User 2 is an admin after /revokeadmin")
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, telegramtest.NewMessage(1, "/revokeadmin 1"))
+	if !bot.IsUserAdmin(1) {
+		t.Fatal("The configured admin was demoted")
+	}
+	if texts := sender.Texts(); len(texts) != 1 || !strings.Contains(texts[0], "cannot be demoted") {
+		t.Errorf("Sent %q, want the refusal", texts)
+	}
+}
+
+func TestAccessExpiryWithoutSessions(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	ctx := context.Background()
+
+	// The access of the user who has never chatted has expired.
+	profile := &chat.Profile{User: 2, Allowed: true, AccessExpires: chat.Now().Add(-time.Hour)}
+	if err := bot.storage.SaveProfile(ctx, profile); err != nil {
+		t.Fatalf("SaveProfile failed: %s", err)
+	}
+
+	if err := bot.checkAccessExpiry(ctx); err != nil {
+		t.Fatalf("checkAccessExpiry failed: %s", err)
+	}
+
+	if profile, err := bot.storage.LoadProfile(ctx, 2); err != nil || profile.Allowed {
+		t.Errorf("LoadProfile() = %+v, %v, want the access revoked", profile, err)
+	}
+	if texts := sender.Texts(); len(texts) != 1 {
+		t.Errorf("Sent %q, want the notice of the expiry", texts)
+	}
+}
+
+func TestJoinGate(t *testing.T) {
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.SetJoinGate(true)
+	ctx := context.Background()
+
+	group := &tgbotapi.Chat{ID: -100, Type: "group"}
+	join := telegramtest.NewMessage(1, "")
+	join.Chat, join.NewChatMembers = group, []tgbotapi.User{{ID: 1, FirstName: "New"}}
+	bot.handleMessage(ctx, join)
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Sent %d messages, want the challenge", len(messages))
+	}
+
+	sender.Reset()
+	question := telegramtest.NewMessage(1, "Hello!")
+	question.Chat = group
+	bot.handleMessage(ctx, question)
+	if texts := sender.Texts(); len(texts) != 0 {
+		t.Fatalf("Sent %q to an unverified member", texts)
+	}
+
+	answer := func(user int64, data string) {
+		bot.handleCallback(ctx, &tgbotapi.CallbackQuery{
+			ID:      "1",
+			From:    &tgbotapi.User{ID: user},
+			Message: &tgbotapi.Message{MessageID: messages[0].ReplyToMessageID, Chat: group},
+			Data:    data,
+		})
+	}
+
+	pending := func() (chat.Challenge, bool) {
+		profile, err := bot.storage.LoadProfile(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		challenge, ok := profile.Challenges[group.ID]
+		return challenge, ok
+	}
+
+	challenge, _ := pending()
+	answer(2, fmt.Sprintf("join:1:%d", challenge.Answer))
+	answer(1, fmt.Sprintf("join:1:%d", challenge.Answer+1))
+	answer(1, fmt.Sprintf("join:1:%d", challenge.Answer))
+	if challenge, ok := pending(); !ok || !challenge.Failed {
+		t.Fatal("The member passed the gate after a wrong answer")
+	}
+
+	var kicked bool
+	for _, request := range sender.Requested() {
+		if ban, ok := request.(tgbotapi.BanChatMemberConfig); ok && ban.UserID == 1 {
+			kicked = true
+		}
+	}
+	if !kicked {
+		t.Error("The member who failed the challenge was not removed")
+	}
+
+	// The member joining again is challenged anew.
+	sender.Reset()
+	bot.handleMessage(ctx, join)
+	messages = sender.Messages()
+	challenge, _ = pending()
+	answer(1, fmt.Sprintf("join:1:%d", challenge.Answer))
+	if _, ok := pending(); ok {
+		t.Fatal("The member did not pass the gate with the right answer")
+	}
+
+	sender.Reset()
+	bot.handleMessage(ctx, question)
+	if texts := sender.Texts(); len(texts) != 1 || texts[0] != "Hello, User!" {
+		t.Errorf("Sent %q, want the answer", texts)
+	}
+}
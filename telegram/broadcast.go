@@ -0,0 +1,113 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// broadcastInterval is the delay between two consecutive broadcast messages.
+// Telegram allows about 30 messages per second across all chats, the interval
+// keeps the broadcast below this limit to leave room for regular replies.
+const broadcastInterval = time.Second / 20
+
+// maxReportedFailures limits the number of failed recipients listed in the
+// broadcast delivery report.
+const maxReportedFailures = 20
+
+// handleBroadcast sends the command arguments as an announcement to all users known
+// from the storage. Messages are delivered sequentially with a rate limit, and a
+// delivery report is sent back to the administrator when the broadcast is finished.
+//
+// ctx: The context for controlling the processing lifecycle. Cancelling it stops
+// the broadcast; the report then covers the messages processed so far.
+// msg: The message containing the /broadcast command.
+func (b *Bot) handleBroadcast(ctx context.Context, msg *tgbotapi.Message) {
+	text := msg.CommandArguments()
+	if text == "" {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBroadcastUsage))
+		return
+	}
+
+	users, err := chat.ListUsers(ctx, b.storage)
+	if err != nil {
+		b.handleError(ctx, msg, "handleBroadcast ListUsers", err)
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgBroadcastStarted, len(users)))
+
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	var (
+		delivered int
+		failed    []int64
+	)
+
+loop:
+	for _, user := range users {
+		select {
+		case <-ctx.Done():
+			break loop
+
+		case <-ticker.C:
+		}
+
+		if err := b.deliver(user, text); err != nil {
+			slog.Error(
+				"handleBroadcast deliver error",
+				slog.Int64("userID", user),
+				slog.String("error", err.Error()),
+			)
+			failed = append(failed, user)
+			continue
+		}
+
+		delivered++
+	}
+
+	report := b.printer.Sprintf(lang.MsgBroadcastReport, delivered, len(users), len(failed))
+	if len(failed) > 0 {
+		ids := make([]string, 0, maxReportedFailures)
+		for i, user := range failed {
+			if i == maxReportedFailures {
+				ids = append(ids, "...")
+				break
+			}
+			ids = append(ids, fmt.Sprint(user))
+		}
+		report += "\n" + strings.Join(ids, ", ")
+	}
+
+	b.Reply(msg, report)
+}
+
+// deliver sends a message to the given chat, falling back to plain text if the
+// Markdown formatting is rejected. Unlike Send, it returns the delivery error
+// instead of notifying the chat about it.
+//
+// Parameters:
+//
+//	chatID - The chat ID to which the message should be sent.
+//	text   - The text content of the message.
+//
+// Returns an error if the message could not be delivered.
+func (b *Bot) deliver(chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "markdown"
+
+	if _, err := b.sender.Send(msg); err == nil {
+		return nil
+	}
+
+	msg.ParseMode = ""
+	_, err := b.sender.Send(msg)
+	return err
+}
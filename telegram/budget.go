@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// checkSpending verifies that the sender of the message may spend more: the sender
+// must not be throttled by the spending alerts and neither the monthly budget of the
+// sender nor the pooled budget of the group chat may be exhausted, and a sender paying
+// from a prepaid balance must have some balance left. If the spending is
+// not allowed, the sender is notified.
+//
+// ctx: The context for the storage operations.
+// msg: The message that triggers a billed request.
+//
+// Returns true if the billed request may proceed.
+func (b *Bot) checkSpending(ctx context.Context, msg *tgbotapi.Message) bool {
+	if b.isThrottled(ctx, msg.From.ID) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSpendingLimit, b.adminContact))
+		return false
+	}
+
+	exhausted, err := b.balanceExhausted(ctx, msg.From.ID)
+	if err != nil {
+		b.handleError(ctx, msg, "checkSpending balanceExhausted", err)
+		return false
+	}
+
+	if exhausted {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBalanceExhausted, b.adminContact))
+		return false
+	}
+
+	exceeded, err := b.budgetExceeded(ctx, msg.From.ID, b.userBudget(ctx, msg.From.ID), b.userLocation(ctx, msg.From.ID))
+	if err != nil {
+		b.handleError(ctx, msg, "checkSpending budgetExceeded", err)
+		return false
+	}
+
+	if exceeded {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBudgetExceeded, b.adminContact))
+		return false
+	}
+
+	if msg.Chat.IsPrivate() {
+		return true
+	}
+
+	exceeded, err = b.budgetExceeded(ctx, msg.Chat.ID, 0, chat.DefaultLocation)
+	if err != nil {
+		b.handleError(ctx, msg, "checkSpending budgetExceeded", err)
+		return false
+	}
+
+	if exceeded {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgGroupBudgetExceeded, b.adminContact))
+		return false
+	}
+
+	return true
+}
+
+// userBudget returns the default monthly budget of the user, which depends on the
+// user's role. Administrators have no default budget.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) userBudget(ctx context.Context, user int64) chat.Cost {
+	role := b.userRole(ctx, user)
+	if role == chat.RoleAdmin {
+		return 0
+	}
+
+	return b.roleBudget(role)
+}
+
+// budgetExceeded reports whether the spending of the owner for the current month
+// reached the owner's budget.
+//
+// ctx: The context for the storage operations.
+// owner: The ID of the user or the group chat.
+// fallback: The budget applied if the owner has no budget of their own.
+// loc: The time zone defining the month boundaries.
+//
+// Returns true if the budget is exhausted and an error if the budget or the
+// ledger could not be loaded.
+func (b *Bot) budgetExceeded(ctx context.Context, owner int64, fallback chat.Cost, loc *time.Location) (bool, error) {
+	budget, err := b.storage.LoadBudget(ctx, owner)
+	if err != nil {
+		return false, fmt.Errorf("error loading budget: %w", err)
+	}
+
+	if budget.Monthly <= 0 {
+		budget.Monthly = fallback
+	}
+
+	if budget.Monthly <= 0 {
+		return false, nil
+	}
+
+	spent, err := b.monthSpending(ctx, owner, chat.Now().In(loc))
+	if err != nil {
+		return false, err
+	}
+
+	return spent >= budget.Monthly, nil
+}
+
+// monthSpending returns the spending of the user or the group chat for the month of
+// the given time, kept in the owner's ledger.
+//
+// ctx: The context for the storage operations.
+// owner: The ID of the user or the group chat.
+// now: Any time within the month, in the time zone defining the month boundaries.
+//
+// Returns the cost and an error if the ledger could not be loaded.
+func (b *Bot) monthSpending(ctx context.Context, owner int64, now time.Time) (chat.Cost, error) {
+	ledger, err := b.storage.LoadLedger(ctx, owner)
+	if err != nil {
+		return 0, fmt.Errorf("error loading ledger: %w", err)
+	}
+
+	return ledger.ThisMonth(now), nil
+}
+
+// handleBudget shows or sets the monthly budget of a user or a group chat. Group
+// chat IDs are negative. The amount is given in the cost units of the statistics,
+// before the conversion with the bot's rate: /budget <id> [amount|off].
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /budget command.
+func (b *Bot) handleBudget(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 1 || len(args) > 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBudgetUsage))
+		return
+	}
+
+	owner, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBudgetUsage))
+		return
+	}
+
+	budget, err := b.storage.LoadBudget(ctx, owner)
+	if err != nil {
+		b.handleError(ctx, msg, "handleBudget LoadBudget", err)
+		return
+	}
+
+	if len(args) == 2 {
+		monthly := 0.0
+		if args[1] != "off" {
+			if monthly, err = strconv.ParseFloat(args[1], 64); err != nil || monthly <= 0 {
+				b.Reply(msg, b.printer.Sprintf(lang.MsgBudgetUsage))
+				return
+			}
+		}
+
+		budget.Monthly = chat.Cost(monthly)
+		if err := b.storage.SaveBudget(ctx, budget); err != nil {
+			b.handleError(ctx, msg, "handleBudget SaveBudget", err)
+			return
+		}
+	}
+
+	// Users spend in their own time zone, group chats in the bot-wide one.
+	now := chat.Now().In(chat.DefaultLocation)
+	if owner > 0 {
+		now = chat.Now().In(b.userLocation(ctx, owner))
+
+		if budget.Monthly <= 0 {
+			budget.Monthly = b.userBudget(ctx, owner)
+		}
+	}
+
+	spent, err := b.monthSpending(ctx, owner, now)
+	if err != nil {
+		b.handleError(ctx, msg, "handleBudget monthSpending", err)
+		return
+	}
+
+	if budget.Monthly <= 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBudgetNone, owner, b.currency, b.amount(spent)))
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(
+		lang.MsgBudget,
+		owner,
+		b.currency, b.amount(spent),
+		b.currency, b.amount(budget.Monthly),
+	))
+}
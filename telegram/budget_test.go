@@ -0,0 +1,176 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+	"github.com/sashabaranov/go-openai"
+)
+
+// setBudget sets the monthly budget of the user or the group chat.
+func setBudget(t *testing.T, bot *Bot, owner int64, monthly chat.Cost) {
+	t.Helper()
+
+	ctx := context.Background()
+	budget, err := bot.storage.LoadBudget(ctx, owner)
+	if err != nil {
+		t.Fatalf("LoadBudget failed: %s", err)
+	}
+
+	budget.Monthly = monthly
+	if err := bot.storage.SaveBudget(ctx, budget); err != nil {
+		t.Fatalf("SaveBudget failed: %s", err)
+	}
+}
+
+// groupMessage returns a text message of the user in the group chat.
+func groupMessage(user, chatID int64) *tgbotapi.Message {
+	msg := telegramtest.NewMessage(user, "Hello!")
+	msg.Chat = &tgbotapi.Chat{ID: chatID, Type: "group"}
+
+	return msg
+}
+
+// spendingRefusal returns the reply refusing the billed request, empty if it was allowed.
+func spendingRefusal(t *testing.T, bot *Bot, sender *telegramtest.Sender, msg *tgbotapi.Message) string {
+	t.Helper()
+
+	sender.Reset()
+	allowed := bot.checkSpending(context.Background(), msg)
+
+	texts := sender.Texts()
+	if allowed != (len(texts) == 0) {
+		t.Fatalf("checkSpending() = %v and sent %q", allowed, texts)
+	}
+	if allowed {
+		return ""
+	}
+
+	return texts[0]
+}
+
+func TestBudgetMonthBoundary(t *testing.T) {
+	// The clock is five hours into April in Tokyo, still March in UTC.
+	now := time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		timezone     string
+		spentAt      time.Time
+		wantExceeded bool
+	}{
+		{
+			name:         "spent this month",
+			spentAt:      now.Add(-time.Hour),
+			wantExceeded: true,
+		},
+		{
+			name:    "spent last month",
+			spentAt: time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "spent before the month of the user",
+			timezone: "Asia/Tokyo",
+			spentAt:  now.Add(-6 * time.Hour),
+		},
+		{
+			name:         "spent in the month of the user",
+			timezone:     "Asia/Tokyo",
+			spentAt:      now.Add(-time.Hour),
+			wantExceeded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup.
+			bot, sender := newSpendingBot(t, now)
+			setTimezone(t, bot, 1, tt.timezone)
+			setBudget(t, bot, 1, 10)
+			spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, tt.spentAt, 10)
+
+			// Execute.
+			refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(1, "Hello!"))
+
+			// Assert.
+			want := ""
+			if tt.wantExceeded {
+				want = fmt.Sprintf(lang.MsgBudgetExceeded, "@admin")
+			}
+			if refusal != want {
+				t.Errorf("Replied %q, want %q", refusal, want)
+			}
+		})
+	}
+}
+
+func TestBudgetDefault(t *testing.T) {
+	// Setup: the users without a budget of their own get the default one, except the
+	// administrators.
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	bot, sender := newSpendingBot(t, now)
+	bot.SetDefaultBudget(5)
+
+	spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, now, 5)
+	spend(t, bot, chat.ID{User: adminID, Chat: adminID, Model: openai.GPT4oMini}, now, 50)
+
+	// Execute & Assert.
+	if refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(1, "Hello!")); refusal == "" {
+		t.Error("The request over the default budget was allowed")
+	}
+	if refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(adminID, "Hello!")); refusal != "" {
+		t.Errorf("The request of the administrator was refused: %q", refusal)
+	}
+
+	// A budget of the user's own takes precedence over the default one.
+	setBudget(t, bot, 1, 20)
+	if refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(1, "Hello!")); refusal != "" {
+		t.Errorf("The request within the budget of the user was refused: %q", refusal)
+	}
+}
+
+func TestBudgetGroupChat(t *testing.T) {
+	// Setup: the user 1 spends in the group chat, which is charged to both of them.
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	bot, sender := newSpendingBot(t, now)
+	setBudget(t, bot, 1, 8)
+	setBudget(t, bot, -100, 5)
+
+	spend(t, bot, chat.ID{User: 1, Chat: -100, Model: openai.GPT4oMini}, now, 6)
+
+	for owner, want := range map[int64]chat.Cost{1: 6, -100: 6, 2: 0} {
+		spent, err := bot.monthSpending(ctx, owner, now)
+		if err != nil {
+			t.Fatalf("monthSpending failed: %s", err)
+		}
+		if spent != want {
+			t.Errorf("The ledger of %d holds %.2f this month, want %.2f", owner, spent, want)
+		}
+	}
+
+	// Execute & Assert: the pooled budget of the chat is exhausted for all its members.
+	groupExceeded := fmt.Sprintf(lang.MsgGroupBudgetExceeded, "@admin")
+	if refusal := spendingRefusal(t, bot, sender, groupMessage(2, -100)); refusal != groupExceeded {
+		t.Errorf("Replied %q in the group chat, want %q", refusal, groupExceeded)
+	}
+
+	// The user still has a budget of their own in private.
+	if refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(1, "Hello!")); refusal != "" {
+		t.Errorf("Replied %q in private, want the request allowed", refusal)
+	}
+
+	// The spending in the group chat counts towards the budget of the user as well.
+	spend(t, bot, chat.ID{User: 1, Chat: 1, Model: openai.GPT4oMini}, now, 2)
+
+	userExceeded := fmt.Sprintf(lang.MsgBudgetExceeded, "@admin")
+	if refusal := spendingRefusal(t, bot, sender, telegramtest.NewMessage(1, "Hello!")); refusal != userExceeded {
+		t.Errorf("Replied %q in private, want %q", refusal, userExceeded)
+	}
+}
@@ -0,0 +1,124 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// callbackCancel is the callback data of the buttons cancelling a pending action.
+const callbackCancel = "cancel"
+
+// handleCallback processes a callback query triggered by an inline keyboard button.
+// The callback data has the form "<action>:<arguments>", the action selects the handler.
+//
+// ctx: The context for controlling the processing lifecycle.
+// cq: The callback query to process.
+func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	slog.Info(
+		"handleCallback started",
+		slog.Int64("userID", cq.From.ID),
+		slog.String("data", cq.Data),
+	)
+
+	// Buttons are attached to messages; without one there is nothing to update.
+	if cq.Message == nil {
+		b.answerCallback(cq, "")
+		return
+	}
+
+	if b.isBanned(ctx, cq.From.ID) {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgBanned, b.adminContact))
+		return
+	}
+
+	// New group members answer their challenge regardless of their access.
+	action, args, _ := strings.Cut(cq.Data, ":")
+	if action == callbackJoin {
+		b.handleJoinCallback(ctx, cq, args)
+		return
+	}
+
+	b.resolveUsername(cq.From)
+	b.syncRole(ctx, cq.From.ID)
+	if !b.isUserPermitted(ctx, cq.From.ID) {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgNotAllowed, cq.From.ID, b.adminContact))
+		return
+	}
+
+	if !b.isSubscribed(cq.From.ID) {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgSubscribeRequired, b.channel.channel))
+		return
+	}
+
+	switch action {
+	case callbackCancel:
+		b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgCancelled))
+		b.answerCallback(cq, "")
+
+	case callbackResetStats:
+		b.handleResetStatsCallback(ctx, cq, args)
+
+	case callbackDeleteMyData:
+		b.handleDeleteMyDataCallback(ctx, cq, args)
+
+	case callbackAcceptTerms:
+		b.handleAcceptTermsCallback(ctx, cq, args)
+
+	default:
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgCommandNotSupported))
+	}
+}
+
+// answerCallback acknowledges a callback query, optionally showing a notification
+// with the given text to the user. Telegram clients display a progress indicator
+// until the query is answered.
+//
+// Parameters:
+//
+//	cq   - The callback query to answer.
+//	text - The notification text; may be empty.
+func (b *Bot) answerCallback(cq *tgbotapi.CallbackQuery, text string) {
+	if _, err := b.sender.Request(tgbotapi.NewCallback(cq.ID, text)); err != nil {
+		slog.Error(
+			"answerCallback error",
+			slog.Int64("userID", cq.From.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// editCallbackMessage replaces the text of the message the callback button belongs to,
+// removing the inline keyboard.
+//
+// Parameters:
+//
+//	cq   - The callback query whose message should be edited.
+//	text - The new text of the message.
+func (b *Bot) editCallbackMessage(cq *tgbotapi.CallbackQuery, text string) {
+	edit := tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, text)
+	if _, err := b.sender.Send(edit); err != nil {
+		slog.Error(
+			"editCallbackMessage error",
+			slog.Int64("chatID", cq.Message.Chat.ID),
+			slog.Int("messageID", cq.Message.MessageID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// confirmationKeyboard returns an inline keyboard with localized Confirm and Cancel
+// buttons, where the Confirm button carries the given callback data.
+//
+// data: The callback data of the Confirm button.
+func (b *Bot) confirmationKeyboard(data string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.printer.Sprintf(lang.MsgConfirm), data),
+			tgbotapi.NewInlineKeyboardButtonData(b.printer.Sprintf(lang.MsgCancel), callbackCancel),
+		),
+	)
+}
@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// notMemberTTL is how long a negative membership check is cached, kept short so
+// users are served soon after they subscribe.
+const notMemberTTL = time.Minute
+
+// channelGate serves only the subscribers of a channel. The membership of every
+// user is cached and re-checked periodically.
+type channelGate struct {
+	// channel is the @username or the numeric ID of the channel.
+	channel string
+
+	// ttl is how long a positive membership check is cached.
+	ttl time.Duration
+
+	mu      sync.Mutex
+	members map[int64]membership
+}
+
+// membership is a cached result of a membership check.
+type membership struct {
+	member  bool
+	checked time.Time
+}
+
+// SetRequiredChannel enables the channel-membership access mode: only the subscribers
+// of the channel are served. The bot must be an administrator of the channel to check
+// its members. Administrators of the bot are always served.
+//
+// channel: The @username or the numeric ID of the channel; empty disables the mode.
+// recheck: How often the membership of a subscribed user is checked again.
+func (b *Bot) SetRequiredChannel(channel string, recheck time.Duration) {
+	if channel == "" {
+		b.channel = nil
+		return
+	}
+
+	b.channel = &channelGate{
+		channel: channel,
+		ttl:     recheck,
+		members: make(map[int64]membership),
+	}
+}
+
+// isSubscribed reports whether the user is subscribed to the required channel. If no
+// channel is required, every user is considered subscribed. If the membership cannot
+// be checked, the user is served to avoid locking everyone out on API failures.
+//
+// user: The ID of the user to check.
+func (b *Bot) isSubscribed(user int64) bool {
+	gate := b.channel
+	if gate == nil || b.IsUserAdmin(user) {
+		return true
+	}
+
+	gate.mu.Lock()
+	cached, exists := gate.members[user]
+	gate.mu.Unlock()
+
+	ttl := gate.ttl
+	if !cached.member {
+		ttl = notMemberTTL
+	}
+
+	if exists && time.Since(cached.checked) < ttl {
+		return cached.member
+	}
+
+	member, err := b.checkMembership(gate.channel, user)
+	if err != nil {
+		slog.Error(
+			"isSubscribed checkMembership error",
+			slog.Int64("userID", user),
+			slog.String("channel", gate.channel),
+			slog.String("error", err.Error()),
+		)
+		return true
+	}
+
+	gate.mu.Lock()
+	gate.members[user] = membership{member: member, checked: time.Now()}
+	gate.mu.Unlock()
+
+	return member
+}
+
+// checkMembership asks Telegram whether the user is a member of the channel.
+//
+// channel: The @username or the numeric ID of the channel.
+// user: The ID of the user to check.
+//
+// Returns true if the user is a member and an error if the request failed.
+func (b *Bot) checkMembership(channel string, user int64) (bool, error) {
+	config := tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{UserID: user},
+	}
+	if id, err := strconv.ParseInt(channel, 10, 64); err == nil {
+		config.ChatID = id
+	} else {
+		config.SuperGroupUsername = "@" + strings.TrimPrefix(channel, "@")
+	}
+
+	resp, err := b.sender.Request(config)
+	if err != nil {
+		return false, fmt.Errorf("error requesting chat member: %w", err)
+	}
+
+	var member tgbotapi.ChatMember
+	if err := json.Unmarshal(resp.Result, &member); err != nil {
+		return false, fmt.Errorf("error decoding chat member: %w", err)
+	}
+
+	switch {
+	case member.IsCreator(), member.IsAdministrator(), member.Status == "member":
+		return true, nil
+
+	case member.Status == "restricted":
+		return member.IsMember, nil
+
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// maxMessageLength is the maximum length of a Telegram text message.
+const maxMessageLength = 4096
+
+// codeExtensions maps the languages of the code blocks to the file extensions.
+var codeExtensions = map[string]string{
+	"go":         "go",
+	"golang":     "go",
+	"python":     "py",
+	"py":         "py",
+	"javascript": "js",
+	"js":         "js",
+	"typescript": "ts",
+	"ts":         "ts",
+	"java":       "java",
+	"kotlin":     "kt",
+	"swift":      "swift",
+	"c":          "c",
+	"cpp":        "cpp",
+	"c++":        "cpp",
+	"csharp":     "cs",
+	"cs":         "cs",
+	"rust":       "rs",
+	"ruby":       "rb",
+	"php":        "php",
+	"bash":       "sh",
+	"sh":         "sh",
+	"shell":      "sh",
+	"sql":        "sql",
+	"html":       "html",
+	"css":        "css",
+	"json":       "json",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"xml":        "xml",
+	"markdown":   "md",
+}
+
+// codeBlock is a fenced code block of an answer.
+type codeBlock struct {
+	start, end int    // start and end are the offsets of the block including its fences.
+	language   string // language is the language of the fence, lowercased.
+	code       string // code is the content of the block.
+}
+
+// attachCode sends the code block which dominates an answer too long for a single
+// message as a document, with the extension inferred from the language of the block.
+// The block is replaced in the answer with a note about the attachment.
+//
+// ctx: The context for controlling the processing lifecycle.
+// p: The processing of the message being answered.
+// text: The answer.
+//
+// Returns the answer without the attached code block.
+func (b *Bot) attachCode(ctx context.Context, p *processing, text string) string {
+	if len(text) <= maxMessageLength {
+		return text
+	}
+
+	block, ok := largestCodeBlock(text)
+
+	// The block dominates the answer if it takes more than half of it.
+	if !ok || len(block.code)*2 < len(text) {
+		return text
+	}
+
+	ext, ok := codeExtensions[block.language]
+	if !ok {
+		ext = "txt"
+	}
+
+	name := "code." + ext
+	b.sendDocument(ctx, p.msg, name, []byte(block.code+"\n"))
+
+	return strings.TrimSpace(text[:block.start] + b.printer.Sprintf(lang.MsgCodeAttached, name) + text[block.end:])
+}
+
+// largestCodeBlock returns the largest fenced code block of the text, or false if the
+// text has no complete code blocks.
+//
+// text: The text to search.
+func largestCodeBlock(text string) (codeBlock, bool) {
+	var (
+		largest codeBlock
+		found   bool
+		current *codeBlock
+		lines   []string
+	)
+
+	for offset := 0; offset < len(text); {
+		line, _, _ := strings.Cut(text[offset:], "\n")
+		next := offset + len(line) + 1
+
+		if fence := strings.TrimSpace(line); strings.HasPrefix(fence, "```") {
+			if current == nil {
+				current = &codeBlock{start: offset, language: strings.ToLower(strings.TrimPrefix(fence, "```"))}
+				lines = nil
+			} else {
+				current.end = min(next, len(text))
+				current.code = strings.Join(lines, "\n")
+				if !found || len(current.code) > len(largest.code) {
+					largest, found = *current, true
+				}
+				current = nil
+			}
+		} else if current != nil {
+			lines = append(lines, line)
+		}
+
+		offset = next
+	}
+
+	return largest, found
+}
@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// SetLocker configures the locker which serializes the updates of the prepaid balances
+// across the instances of the bot sharing the storage.
+//
+// locker: The locker shared by the instances.
+func (b *Bot) SetLocker(locker chat.Locker) {
+	b.locker = locker
+}
+
+// balanceExhausted reports whether the user pays from a prepaid balance which is used up.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+//
+// Returns true if the balance is exhausted and an error if the budget could not be loaded.
+func (b *Bot) balanceExhausted(ctx context.Context, user int64) (bool, error) {
+	budget, err := b.storage.LoadBudget(ctx, user)
+	if err != nil {
+		return false, fmt.Errorf("error loading budget: %w", err)
+	}
+
+	return budget.Prepaid && budget.Balance <= 0, nil
+}
+
+// balanceLockModel is the model of the pseudo session locked while the prepaid balance
+// of a user is updated.
+const balanceLockModel = "balance"
+
+// chargeBalance deducts the cost of the request from the prepaid balance of the user,
+// if the user pays from one: all costs metered for the request, so the voice
+// transcription and the spoken reply are charged along with the answer, and the other
+// requests of the session running meanwhile are not. Errors are logged.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+// meter: The meter of the request, see chat.WithCostMeter.
+func (b *Bot) chargeBalance(ctx context.Context, user int64, meter *chat.CostMeter) {
+	cost := meter.Total()
+	if cost <= 0 {
+		return
+	}
+
+	if _, err := b.updateBalance(ctx, user, -cost, false); err != nil {
+		slog.Error(
+			"chargeBalance error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// updateBalance adds the amount to the prepaid balance of the user.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+// amount: The amount to add, negative to deduct.
+// open: Whether to start paying from the prepaid balance if the user does not yet.
+//
+// Returns the updated budget and an error if it could not be locked, loaded or saved.
+// The budget is left unchanged if the user does not pay from a prepaid balance and open
+// is false.
+func (b *Bot) updateBalance(ctx context.Context, user int64, amount chat.Cost, open bool) (*chat.Budget, error) {
+	b.balanceMu.Lock()
+	defer b.balanceMu.Unlock()
+
+	// The other instances sharing the storage are kept out by the locker.
+	if b.locker != nil {
+		unlock, err := b.locker.Lock(ctx, chat.ID{User: user, Model: balanceLockModel})
+		if err != nil {
+			return nil, fmt.Errorf("error locking balance: %w", err)
+		}
+		defer unlock()
+	}
+
+	budget, err := b.storage.LoadBudget(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("error loading budget: %w", err)
+	}
+
+	if !budget.Prepaid && !open {
+		return budget, nil
+	}
+
+	budget.Prepaid = true
+	budget.Balance += amount
+	if err := b.storage.SaveBudget(ctx, budget); err != nil {
+		return nil, fmt.Errorf("error saving budget: %w", err)
+	}
+
+	return budget, nil
+}
+
+// handleCredit tops up or deducts the prepaid balance of a user. Once credited, the
+// user pays from the balance and is stopped when it is used up. The amount is given
+// in the cost units of the statistics, before the conversion with the bot's rate:
+// /credit <user> <amount>.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /credit command.
+func (b *Bot) handleCredit(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgCreditUsage))
+		return
+	}
+
+	user, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || user <= 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgCreditUsage))
+		return
+	}
+
+	amount, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || amount == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgCreditUsage))
+		return
+	}
+
+	budget, err := b.updateBalance(ctx, user, chat.Cost(amount), true)
+	if err != nil {
+		b.handleError(ctx, msg, "handleCredit updateBalance", err)
+		return
+	}
+
+	slog.Info(
+		"handleCredit finished",
+		slog.Int64("adminID", msg.From.ID),
+		slog.Int64("userID", user),
+		slog.Float64("amount", amount),
+	)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgCredit, user, b.currency, b.amount(budget.Balance)))
+}
+
+// handleBalance replies with the prepaid balance of the sender.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /balance command.
+func (b *Bot) handleBalance(ctx context.Context, msg *tgbotapi.Message) {
+	budget, err := b.storage.LoadBudget(ctx, msg.From.ID)
+	if err != nil {
+		b.handleError(ctx, msg, "handleBalance LoadBudget", err)
+		return
+	}
+
+	if !budget.Prepaid {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgBalanceNone))
+		return
+	}
+
+	p := b.userPricing(ctx, msg.From.ID)
+	b.Reply(msg, b.printer.Sprintf(lang.MsgBalance, p.currency, p.amount(budget.Balance)))
+}
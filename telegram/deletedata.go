@@ -0,0 +1,206 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// callbackDeleteMyData is the callback action confirming the deletion of the data of
+// a user. Its argument is the ID of the user.
+const callbackDeleteMyData = "deletemydata"
+
+// handleDeleteMyData asks the user for a confirmation to delete all of the user's data.
+//
+// msg: The message containing the /deletemydata command.
+func (b *Bot) handleDeleteMyData(msg *tgbotapi.Message) {
+	reply := tgbotapi.NewMessage(msg.Chat.ID, b.printer.Sprintf(lang.MsgDeleteMyDataConfirm))
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = b.confirmationKeyboard(fmt.Sprintf("%s:%d", callbackDeleteMyData, msg.From.ID))
+	if _, err := b.sender.Send(reply); err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+	}
+}
+
+// handleDeleteMyDataCallback deletes the data of the user confirmed with the inline
+// button and replaces the confirmation with a deletion receipt. Only the user the data
+// belongs to may confirm the deletion.
+//
+// ctx: The context for controlling the processing lifecycle.
+// cq: The callback query of the Confirm button.
+// args: The callback arguments, the ID of the user.
+func (b *Bot) handleDeleteMyDataCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, args string) {
+	user, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgCommandNotSupported))
+		return
+	}
+
+	if cq.From.ID != user {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgNotPermitted))
+		return
+	}
+
+	sessions, err := b.deleteUserData(ctx, user)
+	if err != nil {
+		slog.Error(
+			"handleDeleteMyDataCallback deleteUserData error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+		b.answerCallback(cq, "")
+		return
+	}
+
+	receipt, err := newReceiptID()
+	if err != nil {
+		receipt = "-"
+	}
+	deleted := time.Now().UTC()
+
+	// The receipt is logged, so the administrators can confirm the deletion on request.
+	slog.Info(
+		"handleDeleteMyDataCallback finished",
+		slog.Int64("userID", user),
+		slog.String("receipt", receipt),
+		slog.Int("sessions", sessions),
+	)
+
+	b.editCallbackMessage(cq, b.printer.Sprintf(
+		lang.MsgDeleteMyDataReceipt,
+		receipt, deleted.Format("2006-01-02 15:04:05 MST"), sessions,
+	))
+	b.answerCallback(cq, "")
+}
+
+// deleteUserData deletes the histories and the statistics of all chat sessions of the
+// user, the other data the storage keeps of the user and the user's entries of the
+// audit trail, and resets the user's preferences. The access rights and the budget of
+// the user are kept, as they are managed by the administrators, and so is the ledger of
+// the user's spending, so the deletion does not renew the trial or the budget. The data
+// of the chat sessions is deleted through the sessions, which wait for their running
+// requests and are closed afterwards, so the data is not saved again.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+//
+// Returns the number of the deleted chat sessions and an error if the data could not
+// be deleted.
+func (b *Bot) deleteUserData(ctx context.Context, user int64) (int, error) {
+	ids, err := b.storage.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error listing sessions: %w", err)
+	}
+
+	// The cached sessions may not have saved anything yet, but they would.
+	cached, err := b.session.Sessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error listing cached sessions: %w", err)
+	}
+
+	stored := make(map[chat.ID]bool)
+	for _, id := range ids {
+		if id.User == user {
+			stored[id] = true
+		}
+	}
+
+	for _, info := range cached {
+		if info.User == user && !stored[info.ID] {
+			if err := b.deleteSession(ctx, info.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	sessions := 0
+	for id := range stored {
+		if err := b.deleteSession(ctx, id); err != nil {
+			return sessions, err
+		}
+		sessions++
+	}
+
+	if err := b.storage.EraseUser(ctx, user); err != nil {
+		return sessions, fmt.Errorf("error erasing data: %w", err)
+	}
+
+	if err := b.eraseAudit(ctx, user); err != nil {
+		return sessions, fmt.Errorf("error erasing audit trail: %w", err)
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		return sessions, fmt.Errorf("error loading profile: %w", err)
+	}
+
+	if profile.Timezone != "" || profile.Persona != "" {
+		profile.Timezone, profile.Persona = "", ""
+		if err := b.storage.SaveProfile(ctx, profile); err != nil {
+			return sessions, fmt.Errorf("error saving profile: %w", err)
+		}
+	}
+
+	// The closed sessions are dropped, and the others are recreated with the reset profile.
+	b.evictUserSessions(ctx, user)
+
+	return sessions, nil
+}
+
+// deleteSession deletes the history and the statistics of the chat session through the
+// session, so they are not saved again by a request running meanwhile.
+//
+// ctx: The context for the session operations.
+// id: The ID of the chat session.
+func (b *Bot) deleteSession(ctx context.Context, id chat.ID) error {
+	session, err := b.session.ProvideSession(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error providing session: %w", err)
+	}
+
+	if err := session.Delete(ctx); err != nil {
+		return fmt.Errorf("error deleting session: %w", err)
+	}
+
+	return nil
+}
+
+// eraseAudit removes the entries of the user from the audit trail, including those
+// recorded under the user's pseudonym in the anonymized mode.
+//
+// ctx: The context for the audit trail operations.
+// user: The ID of the user.
+func (b *Bot) eraseAudit(ctx context.Context, user int64) error {
+	if b.auditLog == nil {
+		return nil
+	}
+
+	if err := b.auditLog.Erase(ctx, user); err != nil {
+		return err
+	}
+
+	if b.pseudonymizer != nil {
+		return b.auditLog.Erase(ctx, b.pseudonymizer.ID(user))
+	}
+
+	return nil
+}
+
+// newReceiptID generates a random identifier of a deletion receipt.
+func newReceiptID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,113 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// Weekly digest schedule: every Monday at digestHour in chat.DefaultLocation.
+const (
+	digestWeekday = time.Monday
+	digestHour    = 9
+)
+
+// maxDigestModels limits the number of models listed in the weekly digest.
+const maxDigestModels = 5
+
+// RunWeeklyDigest sends a summary of the past week to all administrators every Monday
+// morning until the context is cancelled. The summary contains the total spend, the
+// number of active users, the top models and the number of errors since the previous
+// digest.
+//
+// ctx: The context controlling the lifecycle of the scheduler.
+func (b *Bot) RunWeeklyDigest(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextDigest(chat.Now().In(chat.DefaultLocation))))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+
+		case <-timer.C:
+		}
+
+		if err := b.sendWeeklyDigest(ctx); err != nil {
+			slog.Error("RunWeeklyDigest sendWeeklyDigest error", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// nextDigest returns the time of the next weekly digest after the given time.
+func nextDigest(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), digestHour, 0, 0, 0, now.Location())
+	for next.Weekday() != digestWeekday || !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
+
+// sendWeeklyDigest aggregates the statistics of the past week and sends the digest
+// to all administrators.
+//
+// ctx: The context for the storage operations.
+//
+// Returns an error if the statistics could not be aggregated.
+func (b *Bot) sendWeeklyDigest(ctx context.Context) error {
+	rollup, err := chat.Aggregate(ctx, b.storage, chat.Now().In(chat.DefaultLocation))
+	if err != nil {
+		return fmt.Errorf("error aggregating statistics: %w", err)
+	}
+
+	active := 0
+	for _, totals := range rollup.PerUser {
+		if totals.LastWeek > 0 {
+			active++
+		}
+	}
+
+	models := make([]string, 0, len(rollup.PerModel))
+	for model, totals := range rollup.PerModel {
+		if totals.LastWeek > 0 {
+			models = append(models, model)
+		}
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return rollup.PerModel[models[i]].LastWeek > rollup.PerModel[models[j]].LastWeek
+	})
+	if len(models) > maxDigestModels {
+		models = models[:maxDigestModels]
+	}
+
+	modelLines := &strings.Builder{}
+	for _, model := range models {
+		modelLines.WriteString(fmt.Sprintf(
+			"%s: %s%.2f\n",
+			model,
+			b.currency, b.amount(rollup.PerModel[model].LastWeek),
+		))
+	}
+	if len(models) == 0 {
+		modelLines.WriteString("-\n")
+	}
+
+	digest := b.printer.Sprintf(
+		lang.MsgWeeklyDigest,
+		b.currency, b.amount(rollup.Global.LastWeek),
+		active,
+		b.errors.Swap(0),
+		modelLines.String(),
+	)
+
+	b.sendAdmins(digest)
+
+	return nil
+}
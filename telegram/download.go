@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// downloadFile downloads a file uploaded to Telegram.
+//
+// ctx: The context for the download.
+// fileID: The identifier of the file.
+// limit: The maximum size of the file in bytes.
+//
+// Returns the content of the file and an error if the file could not be downloaded
+// or is larger than the limit.
+func (b *Bot) downloadFile(ctx context.Context, fileID string, limit int64) ([]byte, error) {
+	file, err := b.openFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("error reading file: larger than %d bytes", limit)
+	}
+
+	return data, nil
+}
+
+// openFile opens a file uploaded to Telegram for reading. The files of a self-hosted
+// Bot API server running in the --local mode are opened on the disk.
+//
+// ctx: The context for the download.
+// fileID: The identifier of the file.
+//
+// Returns the content of the file, which the caller must close, and an error if the
+// file could not be opened.
+func (b *Bot) openFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	fileURL, err := b.sender.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting file: %w", err)
+	}
+
+	if strings.HasPrefix(fileURL, "file://") {
+		// The path of the file URL is escaped, e.g. its spaces are "%20".
+		parsed, err := url.Parse(fileURL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing file URL: %w", err)
+		}
+
+		file, err := os.Open(filepath.FromSlash(parsed.Path))
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %w", err)
+		}
+
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error downloading file: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenLocalFile(t *testing.T) {
+	// Setup: the self-hosted Bot API server stored the file under a name which is
+	// escaped in its URL.
+	bot, sender := newTestBot(t, "")
+
+	path := filepath.Join(t.TempDir(), "photo 100% #1?.jpg")
+	if err := os.WriteFile(path, []byte("content"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	sender.Files["photo"] = localFileURL(path)
+
+	// Execute.
+	file, err := bot.openFile(context.Background(), "photo")
+	if err != nil {
+		t.Fatalf("openFile failed: %s", err)
+	}
+	defer file.Close()
+
+	// Assert.
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %s", err)
+	}
+	if string(content) != "content" {
+		t.Errorf("Read %q, want %q", content, "content")
+	}
+}
@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// selfHosted is a Sender of a self-hosted Telegram Bot API server. The library builds
+// the download URLs of the files with the address of the cloud Bot API, so they are
+// built with the address of the server instead.
+type selfHosted struct {
+	*tgbotapi.BotAPI
+
+	server string // server is the base URL of the Bot API server.
+}
+
+// NewSelfHostedSender returns a Sender which downloads the files from the self-hosted
+// Bot API server the client is connected to. A server running in the --local mode
+// returns the absolute paths of the files on its disk, which are opened directly, so
+// the bot must share the working directory of the server.
+//
+// client: The client created with tgbotapi.NewBotAPIWithAPIEndpoint.
+// server: The base URL of the Bot API server, e.g. "http://localhost:8081".
+func NewSelfHostedSender(client *tgbotapi.BotAPI, server string) Sender {
+	return &selfHosted{
+		BotAPI: client,
+		server: strings.TrimSuffix(server, "/"),
+	}
+}
+
+// APIEndpoint returns the endpoint of the self-hosted Bot API server at the base URL
+// for tgbotapi.NewBotAPIWithAPIEndpoint.
+//
+// server: The base URL of the Bot API server, e.g. "http://localhost:8081".
+func APIEndpoint(server string) string {
+	return strings.TrimSuffix(server, "/") + "/bot%s/%s"
+}
+
+// GetFileDirectURL returns the URL to download the file from the server, or a file://
+// URL of the file on the disk if the server runs in the --local mode.
+//
+// fileID: The identifier of the file.
+func (s *selfHosted) GetFileDirectURL(fileID string) (string, error) {
+	file, err := s.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(file.FilePath) {
+		return localFileURL(file.FilePath), nil
+	}
+
+	return fmt.Sprintf("%s/file/bot%s/%s", s.server, s.Token, file.FilePath), nil
+}
+
+// localFileURL returns the file:// URL of the file on the disk, which openFile opens.
+//
+// path: The absolute path of the file.
+func localFileURL(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// SetHTTPClient configures the HTTP client which downloads the files uploaded to
+// Telegram, e.g. to download them through a proxy.
+//
+// client: The HTTP client.
+func (b *Bot) SetHTTPClient(client *http.Client) {
+	b.httpClient = client
+}
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// statisticsCSVHeader is the header row of the statistics CSV export.
+var statisticsCSVHeader = []string{
+	"user", "chat", "model",
+	"last_message", "today", "yesterday", "this_month", "total",
+	"input_tokens", "output_tokens",
+	"last_update",
+}
+
+// handleExportStats sends the statistics of all chat sessions persisted in the storage
+// as a CSV document, suitable for spreadsheets or billing systems. Costs are exported
+// in the raw currency of the chat service, without the display rate applied.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /exportstats command.
+func (b *Bot) handleExportStats(ctx context.Context, msg *tgbotapi.Message) {
+	var (
+		now = chat.Now().In(chat.DefaultLocation)
+		buf = &bytes.Buffer{}
+		w   = csv.NewWriter(buf)
+	)
+
+	if err := w.Write(statisticsCSVHeader); err != nil {
+		b.handleError(ctx, msg, "handleExportStats Write", err)
+		return
+	}
+
+	err := chat.EachStatistics(ctx, b.storage, func(stats *chat.Statistics) error {
+		lastUpdate := ""
+		if !stats.LastUpdate.IsZero() {
+			lastUpdate = stats.LastUpdate.Format(time.RFC3339)
+		}
+
+		return w.Write([]string{
+			strconv.FormatInt(stats.User, 10),
+			strconv.FormatInt(stats.Chat, 10),
+			stats.Model,
+			formatCSVCost(stats.LastMessage),
+			formatCSVCost(stats.Today(now)),
+			formatCSVCost(stats.Yesterday(now)),
+			formatCSVCost(stats.ThisMonth(now)),
+			formatCSVCost(stats.Total),
+			strconv.Itoa(stats.TotalTokens.Input),
+			strconv.Itoa(stats.TotalTokens.Output),
+			lastUpdate,
+		})
+	})
+	if err != nil {
+		b.handleError(ctx, msg, "handleExportStats EachStatistics", err)
+		return
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		b.handleError(ctx, msg, "handleExportStats Flush", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("statistics-%s.csv", now.Format("2006-01-02")),
+		Bytes: buf.Bytes(),
+	})
+	doc.ReplyToMessageID = msg.MessageID
+	doc.Caption = b.printer.Sprintf(lang.MsgExportStatsCaption, now.Format("2006-01-02 15:04:05 MST"))
+
+	if _, err := b.sender.Send(doc); err != nil {
+		b.handleError(ctx, msg, "handleExportStats Send", err)
+	}
+}
+
+// formatCSVCost formats a cost for the CSV export with a fixed precision.
+func formatCSVCost(cost chat.Cost) string {
+	return strconv.FormatFloat(float64(cost), 'f', 6, 64)
+}
@@ -0,0 +1,16 @@
+package telegram
+
+import "github.com/muzykantov/tgpt/lang"
+
+// withFallbackNote adds a footer to the answer if it was given by the fallback model
+// because the model of the session was rate limited or out of quota.
+//
+// reply: The answer.
+// fallback: The model which answered instead of the model of the session, empty if none.
+func (b *Bot) withFallbackNote(reply, fallback string) string {
+	if fallback == "" {
+		return reply
+	}
+
+	return reply + "\n\n_" + b.printer.Sprintf(lang.MsgFallbackModel, fallback) + "_"
+}
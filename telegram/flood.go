@@ -0,0 +1,204 @@
+package telegram
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram limits the bots to about 30 messages per second overall, one message per
+// second in a private chat and 20 messages per minute in a group.
+const (
+	globalSendInterval  = time.Second / 30
+	privateSendInterval = time.Second
+	groupSendInterval   = time.Minute / 20
+)
+
+// maxSendRetries limits the number of retries of a request rejected with 429 Too Many Requests.
+const maxSendRetries = 3
+
+// floodControl is a Sender which paces the outgoing messages to stay within
+// Telegram's limits and retries the requests rejected with 429 Too Many Requests
+// after the delay requested by Telegram.
+type floodControl struct {
+	Sender
+
+	mu    sync.Mutex
+	next  time.Time           // next is the earliest time of the next message to any chat.
+	chats map[int64]time.Time // chats holds the earliest time of the next message per chat.
+
+	now   func() time.Time    // now returns the current time, replaced in the tests.
+	sleep func(time.Duration) // sleep pauses the sending, replaced in the tests.
+}
+
+// newFloodControl wraps the sender with the outbound rate limiting.
+//
+// sender: The Sender to deliver the requests.
+func newFloodControl(sender Sender) *floodControl {
+	return &floodControl{
+		Sender: sender,
+		chats:  make(map[int64]time.Time),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// Send waits for a free slot in the limits of the chat and sends the content,
+// retrying if Telegram asks to slow down. Chat actions are not rate limited.
+//
+// c: The content to send.
+func (f *floodControl) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if _, action := c.(tgbotapi.ChatActionConfig); action {
+		return f.Sender.Send(c)
+	}
+
+	f.wait(chatOf(c))
+
+	for retry := 0; ; retry++ {
+		msg, err := f.Sender.Send(c)
+		if delay, ok := retryAfter(err); ok && retry < maxSendRetries {
+			f.backoff(chatOf(c), delay, err)
+			continue
+		}
+
+		return msg, err
+	}
+}
+
+// Request sends the request, retrying if Telegram asks to slow down.
+//
+// c: The request to send.
+func (f *floodControl) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	for retry := 0; ; retry++ {
+		resp, err := f.Sender.Request(c)
+		if delay, ok := retryAfter(err); ok && retry < maxSendRetries {
+			f.backoff(chatOf(c), delay, err)
+			continue
+		}
+
+		return resp, err
+	}
+}
+
+// MakeRequest calls the Bot API method, retrying if Telegram asks to slow down.
+//
+// endpoint: The name of the Bot API method.
+// params: The parameters of the method.
+func (f *floodControl) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	for retry := 0; ; retry++ {
+		resp, err := f.Sender.MakeRequest(endpoint, params)
+		if delay, ok := retryAfter(err); ok && retry < maxSendRetries {
+			f.backoff(0, delay, err)
+			continue
+		}
+
+		return resp, err
+	}
+}
+
+// wait blocks until the next message may be sent to the chat and reserves the slot.
+//
+// chatID: The ID of the chat, or 0 if unknown.
+func (f *floodControl) wait(chatID int64) {
+	f.mu.Lock()
+
+	now := f.now()
+	slot := maxTime(now, f.next)
+	if chatID != 0 {
+		slot = maxTime(slot, f.chats[chatID])
+	}
+
+	f.next = slot.Add(globalSendInterval)
+	if chatID != 0 {
+		interval := privateSendInterval
+		if chatID < 0 {
+			interval = groupSendInterval
+		}
+		f.chats[chatID] = slot.Add(interval)
+	}
+
+	// Forget the chats which are free again, so the map does not grow indefinitely.
+	for id, next := range f.chats {
+		if next.Before(now) {
+			delete(f.chats, id)
+		}
+	}
+
+	f.mu.Unlock()
+
+	if delay := slot.Sub(now); delay > 0 {
+		f.sleep(delay)
+	}
+}
+
+// backoff postpones the messages to the chat and waits for the delay requested by Telegram.
+//
+// chatID: The ID of the chat, or 0 if unknown.
+// delay: The delay requested by Telegram.
+// err: The error returned by Telegram.
+func (f *floodControl) backoff(chatID int64, delay time.Duration, err error) {
+	slog.Warn(
+		"telegram flood control",
+		slog.Int64("chatID", chatID),
+		slog.Duration("retryAfter", delay),
+		slog.String("error", err.Error()),
+	)
+
+	if chatID != 0 {
+		f.mu.Lock()
+		f.chats[chatID] = maxTime(f.chats[chatID], f.now().Add(delay))
+		f.mu.Unlock()
+	}
+
+	f.sleep(delay)
+}
+
+// retryAfter returns the delay requested by Telegram if the error is a 429 Too Many
+// Requests response.
+//
+// err: The error returned by Telegram.
+func retryAfter(err error) (time.Duration, bool) {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	return time.Duration(max(tgErr.RetryAfter, 1)) * time.Second, true
+}
+
+// chatOf returns the ID of the chat the content is sent to, or 0 if unknown.
+//
+// c: The content to send.
+func chatOf(c tgbotapi.Chattable) int64 {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID
+	case tgbotapi.PhotoConfig:
+		return c.ChatID
+	case tgbotapi.DocumentConfig:
+		return c.ChatID
+	case tgbotapi.VoiceConfig:
+		return c.ChatID
+	case tgbotapi.AudioConfig:
+		return c.ChatID
+	case tgbotapi.MediaGroupConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID
+	default:
+		return 0
+	}
+}
+
+// maxTime returns the later of the two times.
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
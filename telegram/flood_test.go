@@ -0,0 +1,216 @@
+package telegram
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+)
+
+// fakeClock is a clock which advances only when the flood control sleeps.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration // slept is the total time slept.
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+// timedSender is a fake Sender recording the time each message is sent at.
+type timedSender struct {
+	*telegramtest.Sender
+
+	clock *fakeClock
+	start time.Time
+	times []time.Duration // times holds the times of the Send calls since the start.
+}
+
+func (s *timedSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.times = append(s.times, s.clock.now.Sub(s.start))
+	return s.Sender.Send(c)
+}
+
+// newTestFloodControl returns the flood control over a fake sender and a fake clock.
+func newTestFloodControl() (*floodControl, *timedSender, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sender := &timedSender{Sender: telegramtest.NewSender(), clock: clock, start: clock.now}
+
+	flood := newFloodControl(sender)
+	flood.now, flood.sleep = clock.Now, clock.Sleep
+
+	return flood, sender, clock
+}
+
+// tooManyRequests returns the 429 error asking to retry after the seconds.
+func tooManyRequests(seconds int) error {
+	return &tgbotapi.Error{
+		Code:               http.StatusTooManyRequests,
+		Message:            "Too Many Requests",
+		ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: seconds},
+	}
+}
+
+func TestFloodControlPacing(t *testing.T) {
+	// A message to each of 31 chats: the last one is sent a second after the first.
+	var (
+		manyChats []int64
+		manyTimes []time.Duration
+	)
+	for i := 0; i <= 30; i++ {
+		manyChats = append(manyChats, int64(i+1))
+		manyTimes = append(manyTimes, time.Duration(i)*globalSendInterval)
+	}
+
+	tests := []struct {
+		name  string
+		chats []int64
+		want  []time.Duration
+	}{
+		{
+			name:  "private chat once per second",
+			chats: []int64{1, 1, 1},
+			want:  []time.Duration{0, time.Second, 2 * time.Second},
+		},
+		{
+			name:  "group chat once per three seconds",
+			chats: []int64{-100, -100},
+			want:  []time.Duration{0, 3 * time.Second},
+		},
+		{
+			name:  "different chats within the global cap",
+			chats: []int64{1, 2, 3},
+			want:  []time.Duration{0, globalSendInterval, 2 * globalSendInterval},
+		},
+		{
+			name:  "chat waits for its own slot",
+			chats: []int64{1, 2, 1},
+			want:  []time.Duration{0, globalSendInterval, time.Second},
+		},
+		{
+			name:  "global cap of 30 messages per second",
+			chats: manyChats,
+			want:  manyTimes,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flood, sender, _ := newTestFloodControl()
+
+			for _, chatID := range tt.chats {
+				if _, err := flood.Send(tgbotapi.NewMessage(chatID, "hi")); err != nil {
+					t.Fatalf("Send failed: %s", err)
+				}
+			}
+
+			for i, want := range tt.want {
+				if got := sender.times[i]; got != want {
+					t.Errorf("Message %d was sent at %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFloodControlChatActionNotPaced(t *testing.T) {
+	flood, sender, clock := newTestFloodControl()
+
+	for i := 0; i < 3; i++ {
+		if _, err := flood.Send(tgbotapi.NewChatAction(1, tgbotapi.ChatTyping)); err != nil {
+			t.Fatalf("Send failed: %s", err)
+		}
+	}
+
+	if clock.slept != 0 || len(sender.times) != 3 {
+		t.Errorf("Sent %d chat actions after sleeping %v, want 3 at once", len(sender.times), clock.slept)
+	}
+}
+
+func TestFloodControlRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []error
+		wantCalls int
+		wantSlept time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "retried after the requested delay",
+			failures:  []error{tooManyRequests(2)},
+			wantCalls: 2,
+			wantSlept: 2 * time.Second,
+		},
+		{
+			name:      "missing delay waits a second",
+			failures:  []error{tooManyRequests(0)},
+			wantCalls: 2,
+			wantSlept: time.Second,
+		},
+		{
+			name:      "gives up after the retries",
+			failures:  []error{tooManyRequests(1), tooManyRequests(1), tooManyRequests(1), tooManyRequests(1)},
+			wantCalls: maxSendRetries + 1,
+			wantSlept: maxSendRetries * time.Second,
+			wantErr:   true,
+		},
+		{
+			name:      "other errors are not retried",
+			failures:  []error{&tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request"}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flood, sender, clock := newTestFloodControl()
+			sender.Fail(tt.failures...)
+
+			_, err := flood.Send(tgbotapi.NewMessage(1, "hi"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send returned %v, want error %v", err, tt.wantErr)
+			}
+			if calls := len(sender.times); calls != tt.wantCalls {
+				t.Errorf("Send called the sender %d times, want %d", calls, tt.wantCalls)
+			}
+			if clock.slept != tt.wantSlept {
+				t.Errorf("Send slept %v, want %v", clock.slept, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func TestFloodControlBackoffPostponesChat(t *testing.T) {
+	flood, sender, _ := newTestFloodControl()
+	sender.Fail(tooManyRequests(5))
+
+	// The first message is retried after 5 seconds.
+	if _, err := flood.Send(tgbotapi.NewMessage(1, "hi")); err != nil {
+		t.Fatalf("Send failed: %s", err)
+	}
+
+	// The next message to the chat waits until the requested delay has passed.
+	if _, err := flood.Send(tgbotapi.NewMessage(1, "again")); err != nil {
+		t.Fatalf("Send failed: %s", err)
+	}
+
+	if got := sender.times[len(sender.times)-1]; got < 5*time.Second {
+		t.Errorf("The next message to the chat was sent at %v, want after the requested delay", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if _, ok := retryAfter(errors.New("network error")); ok {
+		t.Error("retryAfter accepted an error other than 429")
+	}
+	if delay, ok := retryAfter(tooManyRequests(3)); !ok || delay != 3*time.Second {
+		t.Errorf("retryAfter returned %v, %v, want 3s", delay, ok)
+	}
+}
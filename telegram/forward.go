@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// forwardWait is how long the forwarded messages are collected before they are
+// answered on their own. Telegram delivers a batch of forwarded messages as
+// separate updates, and a question sent right after them joins the batch.
+const forwardWait = 2 * time.Second
+
+// forwardKey identifies the forwarded messages of a user in a chat.
+type forwardKey struct {
+	user int64
+	chat int64
+}
+
+// withForwarded includes the forwarded messages as quoted context of the text. A regular
+// message takes the forwarded messages received right before it as its context. A forwarded
+// message joins the batch and waits for a question; the last message of a batch which got
+// no question asks about the forwarded messages on their own.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message being processed.
+// text: The text of the message.
+//
+// Returns the text to send to the model, and false if there is nothing to send because the
+// message has been included in another one.
+func (b *Bot) withForwarded(ctx context.Context, msg *tgbotapi.Message, text string) (string, bool) {
+	key := forwardKey{user: msg.From.ID, chat: msg.Chat.ID}
+
+	if msg.ForwardDate == 0 {
+		if quotes := b.forwards.take(key); len(quotes) > 0 {
+			return strings.Join(quotes, "\n\n") + "\n\n" + text, true
+		}
+
+		return text, true
+	}
+
+	b.forwards.add(key, b.quoteForwarded(msg, text), forwardWait)
+
+	quotes, ok := b.forwards.collect(ctx, key)
+	if !ok {
+		// A question or another forwarded message took the batch.
+		return "", false
+	}
+
+	return strings.Join(quotes, "\n\n"), true
+}
+
+// quoteForwarded formats the forwarded message as a quote with its origin. The origin
+// is left out in the anonymized mode.
+//
+// msg: The forwarded message.
+// text: The text of the message.
+func (b *Bot) quoteForwarded(msg *tgbotapi.Message, text string) string {
+	var from string
+	switch {
+	case b.pseudonymizer != nil:
+	case msg.ForwardFrom != nil:
+		from = strings.TrimSpace(msg.ForwardFrom.FirstName + " " + msg.ForwardFrom.LastName)
+	case msg.ForwardFromChat != nil:
+		from = msg.ForwardFromChat.Title
+	default:
+		from = msg.ForwardSenderName
+	}
+
+	if from == "" {
+		return fmt.Sprintf("Forwarded message:\n%s", quote(text))
+	}
+
+	return fmt.Sprintf("Forwarded message from %s:\n%s", from, quote(text))
+}
@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// handleImage generates an image from the command arguments and replies with it.
+// The cost of the image is accounted in the statistics of the session.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /image command.
+// session: The chat session of the user.
+func (b *Bot) handleImage(ctx context.Context, msg *tgbotapi.Message, session chat.Session) {
+	prompt := msg.CommandArguments()
+	if prompt == "" {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgImageUsage))
+		return
+	}
+
+	if !b.checkSpending(ctx, msg) {
+		return
+	}
+
+	typingCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go b.Typing(typingCtx, msg.Chat.ID)
+
+	ctx, meter := chat.WithCostMeter(ctx)
+	image, err := session.Draw(ctx, prompt)
+	if err != nil {
+		b.handleError(ctx, msg, "handleImage Draw", err)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileBytes{Name: "image.png", Bytes: image})
+	photo.ReplyToMessageID = msg.MessageID
+	if _, err := b.sender.Send(photo); err != nil {
+		b.handleError(ctx, msg, "handleImage Send", err)
+		return
+	}
+
+	b.auditQuestion(ctx, msg, session, "/image "+prompt, "")
+	b.chargeBalance(ctx, msg.From.ID, meter)
+	b.checkAlerts(ctx, msg.From.ID)
+}
@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// SetMaxInputTokens limits the size of a single user message. Longer messages are
+// rejected with an explanation instead of being sent to the model, where they would
+// fail with an API error or consume a large part of the budget.
+//
+// tokens: The maximum estimated number of tokens in a message; 0 disables the limit.
+func (b *Bot) SetMaxInputTokens(tokens int) {
+	b.maxInputTokens = tokens
+}
+
+// checkInputSize replies with an explanation if the text exceeds the input limit.
+//
+// msg: The message being processed.
+// text: The text of the message, or the transcription of a voice message.
+//
+// Returns true if the text may be sent to the model.
+func (b *Bot) checkInputSize(msg *tgbotapi.Message, text string) bool {
+	if b.maxInputTokens <= 0 {
+		return true
+	}
+
+	if tokens := chat.EstimateTokens(text); tokens > b.maxInputTokens {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgInputTooLong, tokens, b.maxInputTokens))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// inviteCodeBytes is the number of random bytes in an invite code.
+const inviteCodeBytes = 8
+
+// SetUsername configures the Telegram username of the bot, used to build the deep
+// links of the invite codes.
+//
+// username: The username of the bot without the leading "@".
+func (b *Bot) SetUsername(username string) {
+	b.username = username
+}
+
+// redeemInvite grants access to the sender of a /start <code> message if the code
+// is a valid invite, and counts the use of the invite.
+//
+// ctx: The context for the storage operations.
+// msg: The /start message carrying the invite code.
+//
+// Returns true if the invite was redeemed.
+func (b *Bot) redeemInvite(ctx context.Context, msg *tgbotapi.Message) bool {
+	code := strings.TrimSpace(msg.CommandArguments())
+	if msg.Command() != "start" || code == "" {
+		return false
+	}
+
+	b.inviteMu.Lock()
+	defer b.inviteMu.Unlock()
+
+	invite, err := b.storage.LoadInvite(ctx, code)
+	if err != nil {
+		b.handleError(ctx, msg, "redeemInvite LoadInvite", err)
+		return false
+	}
+
+	if !invite.Valid() {
+		return false
+	}
+
+	invite.Used++
+	if err := b.storage.SaveInvite(ctx, invite); err != nil {
+		b.handleError(ctx, msg, "redeemInvite SaveInvite", err)
+		return false
+	}
+
+	if err := b.allowUser(ctx, msg.From.ID, time.Time{}); err != nil {
+		b.handleError(ctx, msg, "redeemInvite allowUser", err)
+		return false
+	}
+
+	slog.Info(
+		"redeemInvite redeemed",
+		slog.Int64("userID", msg.From.ID),
+		slog.String("code", invite.Code),
+		slog.Int("used", invite.Used),
+	)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgInviteRedeemed))
+	return true
+}
+
+// handleInvite creates or revokes invite codes: /invite new [uses] creates a code
+// which can be redeemed the given number of times (once by default), /invite revoke
+// <code> revokes a code.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /invite command.
+func (b *Bot) handleInvite(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgInviteUsage))
+		return
+	}
+
+	switch {
+	case args[0] == "new" && len(args) <= 2:
+		uses := 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				b.Reply(msg, b.printer.Sprintf(lang.MsgInviteUsage))
+				return
+			}
+			uses = n
+		}
+
+		code, err := newInviteCode()
+		if err != nil {
+			b.handleError(ctx, msg, "handleInvite newInviteCode", err)
+			return
+		}
+
+		invite := &chat.Invite{
+			Code:      code,
+			MaxUses:   uses,
+			CreatedBy: msg.From.ID,
+			Created:   chat.Now(),
+		}
+		if err := b.storage.SaveInvite(ctx, invite); err != nil {
+			b.handleError(ctx, msg, "handleInvite SaveInvite", err)
+			return
+		}
+
+		link := "/start " + code
+		if b.username != "" {
+			link = fmt.Sprintf("https://t.me/%s?start=%s", b.username, code)
+		}
+
+		b.Reply(msg, b.printer.Sprintf(lang.MsgInviteCreated, code, uses, link))
+
+	case args[0] == "revoke" && len(args) == 2:
+		b.inviteMu.Lock()
+		defer b.inviteMu.Unlock()
+
+		invite, err := b.storage.LoadInvite(ctx, args[1])
+		if err != nil {
+			b.handleError(ctx, msg, "handleInvite LoadInvite", err)
+			return
+		}
+
+		if invite.Created.IsZero() {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgInviteNotFound))
+			return
+		}
+
+		invite.Revoked = true
+		if err := b.storage.SaveInvite(ctx, invite); err != nil {
+			b.handleError(ctx, msg, "handleInvite SaveInvite", err)
+			return
+		}
+
+		b.Reply(msg, b.printer.Sprintf(lang.MsgDone))
+
+	default:
+		b.Reply(msg, b.printer.Sprintf(lang.MsgInviteUsage))
+	}
+}
+
+// newInviteCode generates a random invite code usable in a deep link.
+func newInviteCode() (string, error) {
+	buf := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,301 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// callbackJoin is the callback action answering the challenge of a new group member.
+// Its arguments are the ID of the member and the chosen answer.
+const callbackJoin = "join"
+
+// joinChoices is the number of the answers offered by a join challenge.
+const joinChoices = 4
+
+// joinTimeout is the time a new member has to answer the challenge.
+const joinTimeout = 5 * time.Minute
+
+// memberKey identifies a member of a group chat.
+type memberKey struct {
+	chat int64
+	user int64
+}
+
+// newChallenge returns a challenge: the two numbers to add, the shuffled answers to
+// choose from, one of which is their sum, and the challenge to persist.
+func newChallenge() (int, int, []int, chat.Challenge) {
+	x, y := rand.Intn(9)+1, rand.Intn(9)+1
+
+	choices := []int{x + y}
+	for len(choices) < joinChoices {
+		choice := rand.Intn(17) + 2
+		if !slices.Contains(choices, choice) {
+			choices = append(choices, choice)
+		}
+	}
+	rand.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+
+	return x, y, choices, chat.Challenge{Answer: x + y, Expires: chat.Now().Add(joinTimeout)}
+}
+
+// updateChallenge changes the challenge of the member in the member's profile, so the
+// pending challenges survive a restart and are shared by the instances of the bot.
+//
+// ctx: The context for the storage operations.
+// key: The member.
+// update: The function changing the challenge; it returns nil to drop the challenge.
+//
+// Returns an error if the profile could not be loaded or saved.
+func (b *Bot) updateChallenge(ctx context.Context, key memberKey, update func(*chat.Challenge) *chat.Challenge) error {
+	profile, err := b.storage.LoadProfile(ctx, key.user)
+	if err != nil {
+		return fmt.Errorf("error loading profile: %w", err)
+	}
+
+	var current *chat.Challenge
+	if challenge, ok := profile.Challenges[key.chat]; ok {
+		current = &challenge
+	}
+
+	updated := update(current)
+	if updated == nil && current == nil {
+		return nil
+	}
+
+	if updated == nil {
+		delete(profile.Challenges, key.chat)
+	} else {
+		if profile.Challenges == nil {
+			profile.Challenges = make(map[int64]chat.Challenge)
+		}
+		profile.Challenges[key.chat] = *updated
+	}
+
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		return fmt.Errorf("error saving profile: %w", err)
+	}
+
+	return nil
+}
+
+// pendingChallenge returns the challenge the member has not passed yet, if any. The
+// member is held back if the profile could not be loaded.
+//
+// ctx: The context for the storage operation.
+// key: The member.
+func (b *Bot) pendingChallenge(ctx context.Context, key memberKey) (chat.Challenge, bool) {
+	profile, err := b.storage.LoadProfile(ctx, key.user)
+	if err != nil {
+		slog.Error(
+			"pendingChallenge LoadProfile error",
+			slog.Int64("chatID", key.chat),
+			slog.Int64("userID", key.user),
+			slog.String("error", err.Error()),
+		)
+		return chat.Challenge{Failed: true}, true
+	}
+
+	challenge, ok := profile.Challenges[key.chat]
+	return challenge, ok
+}
+
+// kickMember removes the member who failed the challenge from the group chat. The
+// member is unbanned right away, so they may join again and get a new challenge.
+// Errors, e.g. when the bot is not an administrator of the chat, are logged; the
+// member's messages are held back anyway.
+//
+// key: The member.
+func (b *Bot) kickMember(key memberKey) {
+	member := tgbotapi.ChatMemberConfig{ChatID: key.chat, UserID: key.user}
+
+	_, err := b.sender.Request(tgbotapi.BanChatMemberConfig{ChatMemberConfig: member})
+	if err == nil {
+		_, err = b.sender.Request(tgbotapi.UnbanChatMemberConfig{ChatMemberConfig: member, OnlyIfBanned: true})
+	}
+
+	if err != nil {
+		slog.Error(
+			"kickMember error",
+			slog.Int64("chatID", key.chat),
+			slog.Int64("userID", key.user),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// SetJoinGate enables or disables the verification of the new members of the group
+// chats. A new member has to solve a simple sum with the inline buttons within
+// joinTimeout before the bot responds to them, which stops the spam bots joining the
+// groups from using it. A member who answers wrong or too late is removed from the chat.
+//
+// enabled: True to verify the new members.
+func (b *Bot) SetJoinGate(enabled bool) {
+	b.joinGate = enabled
+}
+
+// checkJoinGate challenges the members joining a group chat and holds back the
+// messages of the members who have not passed their challenge. The members whose
+// challenge has expired are removed from the chat.
+//
+// ctx: The context for the storage operations.
+// msg: The message being processed.
+//
+// Returns false if the message must not be processed.
+func (b *Bot) checkJoinGate(ctx context.Context, msg *tgbotapi.Message) bool {
+	if !b.joinGate || msg.Chat.IsPrivate() {
+		return true
+	}
+
+	if msg.LeftChatMember != nil {
+		key := memberKey{chat: msg.Chat.ID, user: msg.LeftChatMember.ID}
+		if err := b.updateChallenge(ctx, key, func(*chat.Challenge) *chat.Challenge { return nil }); err != nil {
+			slog.Error(
+				"checkJoinGate updateChallenge error",
+				slog.Int64("chatID", key.chat),
+				slog.Int64("userID", key.user),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	if len(msg.NewChatMembers) > 0 {
+		for i := range msg.NewChatMembers {
+			b.challengeMember(ctx, msg, &msg.NewChatMembers[i])
+		}
+		return false
+	}
+
+	key := memberKey{chat: msg.Chat.ID, user: msg.From.ID}
+	challenge, ok := b.pendingChallenge(ctx, key)
+	if ok && !challenge.Failed && !challenge.Passable(chat.Now()) {
+		b.kickMember(key)
+	}
+
+	return !ok
+}
+
+// challengeMember sends the challenge to a new member of the group chat. Bots and the
+// administrators are not challenged.
+//
+// ctx: The context for the storage operations.
+// msg: The message announcing the new members.
+// member: The new member.
+func (b *Bot) challengeMember(ctx context.Context, msg *tgbotapi.Message, member *tgbotapi.User) {
+	if member.IsBot || b.IsUserAdmin(member.ID) {
+		return
+	}
+
+	x, y, choices, pending := newChallenge()
+	key := memberKey{chat: msg.Chat.ID, user: member.ID}
+	if err := b.updateChallenge(ctx, key, func(*chat.Challenge) *chat.Challenge { return &pending }); err != nil {
+		slog.Error(
+			"challengeMember updateChallenge error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int64("userID", member.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	row := make([]tgbotapi.InlineKeyboardButton, len(choices))
+	for i, choice := range choices {
+		row[i] = tgbotapi.NewInlineKeyboardButtonData(
+			strconv.Itoa(choice),
+			fmt.Sprintf("%s:%d:%d", callbackJoin, member.ID, choice),
+		)
+	}
+
+	challenge := tgbotapi.NewMessage(msg.Chat.ID, b.printer.Sprintf(lang.MsgJoinChallenge, member.FirstName, x, y))
+	challenge.ReplyToMessageID = msg.MessageID
+	challenge.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+	if _, err := b.sender.Send(challenge); err != nil {
+		slog.Error(
+			"challengeMember Send error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int64("userID", member.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// handleJoinCallback checks the answer of a new member to the challenge. Only the
+// challenged member may answer, and only once: a wrong or late answer fails the
+// challenge and removes the member from the chat.
+//
+// ctx: The context for the storage operations.
+// cq: The callback query of the answer button.
+// args: The ID of the challenged member and the chosen answer.
+func (b *Bot) handleJoinCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, args string) {
+	member, choice, _ := strings.Cut(args, ":")
+
+	user, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || user != cq.From.ID {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgJoinNotYours))
+		return
+	}
+
+	answer, err := strconv.Atoi(choice)
+	if err != nil || !b.joinGate {
+		b.answerCallback(cq, "")
+		return
+	}
+
+	// The member without a challenge, e.g. verified already, is through.
+	passed := true
+	key := memberKey{chat: cq.Message.Chat.ID, user: user}
+	err = b.updateChallenge(ctx, key, func(challenge *chat.Challenge) *chat.Challenge {
+		if challenge == nil {
+			return nil
+		}
+
+		if !challenge.Passable(chat.Now()) || challenge.Answer != answer {
+			passed = false
+			challenge.Failed = true
+			return challenge
+		}
+
+		return nil
+	})
+	if err != nil {
+		slog.Error(
+			"handleJoinCallback updateChallenge error",
+			slog.Int64("chatID", key.chat),
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+		return
+	}
+
+	if !passed {
+		slog.Info(
+			"handleJoinCallback failed",
+			slog.Int64("chatID", key.chat),
+			slog.Int64("userID", user),
+		)
+
+		b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgJoinFailed, cq.From.FirstName))
+		b.answerCallback(cq, "")
+		b.kickMember(key)
+		return
+	}
+
+	slog.Info(
+		"handleJoinCallback verified",
+		slog.Int64("chatID", cq.Message.Chat.ID),
+		slog.Int64("userID", user),
+	)
+
+	b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgJoinVerified, cq.From.FirstName))
+	b.answerCallback(cq, "")
+}
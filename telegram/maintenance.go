@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// maintenanceTTL is how long the maintenance mode read from the storage is trusted, so
+// the instances sharing the storage follow its changes within seconds without reading
+// the storage for every message.
+const maintenanceTTL = 5 * time.Second
+
+// SetMaintenance enables or disables the maintenance mode. While the maintenance
+// mode is enabled, messages from non-admin users are answered with a localized
+// "temporarily unavailable" reply and are not forwarded to the chat service. The mode
+// is kept in the settings of the storage, so it applies to all instances of the bot.
+//
+// ctx: The context for the storage operations.
+// enabled: Whether the maintenance mode should be enabled.
+//
+// Returns an error if the settings could not be loaded or saved.
+func (b *Bot) SetMaintenance(ctx context.Context, enabled bool) error {
+	settings, err := b.storage.LoadSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings.Maintenance = enabled
+	settings.Updated = chat.Now()
+	if err := b.storage.SaveSettings(ctx, settings); err != nil {
+		return err
+	}
+
+	b.maintenance.Store(enabled)
+	b.maintenanceRead.Store(time.Now().UnixNano())
+	return nil
+}
+
+// IsMaintenance reports whether the maintenance mode is enabled. The mode is read from
+// the storage at most once per maintenanceTTL; if the storage fails, the mode read
+// before is kept.
+//
+// ctx: The context for the storage operations.
+func (b *Bot) IsMaintenance(ctx context.Context) bool {
+	if time.Since(time.Unix(0, b.maintenanceRead.Load())) < maintenanceTTL {
+		return b.maintenance.Load()
+	}
+
+	settings, err := b.storage.LoadSettings(ctx)
+	if err != nil {
+		slog.Error("IsMaintenance LoadSettings error", slog.String("error", err.Error()))
+		return b.maintenance.Load()
+	}
+
+	b.maintenance.Store(settings.Maintenance)
+	b.maintenanceRead.Store(time.Now().UnixNano())
+	return settings.Maintenance
+}
+
+// handleMaintenance toggles the maintenance mode: /maintenance on|off.
+// Without arguments, it replies with the current state.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /maintenance command.
+func (b *Bot) handleMaintenance(ctx context.Context, msg *tgbotapi.Message) {
+	var err error
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "on":
+		err = b.SetMaintenance(ctx, true)
+	case "off":
+		err = b.SetMaintenance(ctx, false)
+	case "":
+	default:
+		b.Reply(msg, b.printer.Sprintf(lang.MsgMaintenanceUsage))
+		return
+	}
+
+	if err != nil {
+		b.handleError(ctx, msg, "handleMaintenance SetMaintenance", err)
+		return
+	}
+
+	if b.IsMaintenance(ctx) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgMaintenanceOn))
+	} else {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgMaintenanceOff))
+	}
+}
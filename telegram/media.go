@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxDocumentSize limits the size of a text document included in a question.
+const maxDocumentSize = 1 << 20
+
+// errUnsupportedMedia is returned for the attachments the bot cannot pass to the model.
+var errUnsupportedMedia = errors.New("unsupported media")
+
+// hasMedia reports whether the message has an attachment which may be passed to the model.
+func hasMedia(msg *tgbotapi.Message) bool {
+	return len(msg.Photo) > 0 || msg.Document != nil
+}
+
+// hasImage reports whether the attachment of the message is an image for a vision query.
+func hasImage(msg *tgbotapi.Message) bool {
+	return len(msg.Photo) > 0 || msg.Document != nil && strings.HasPrefix(msg.Document.MimeType, "image/")
+}
+
+// readMedia downloads the attachment of the message. Photos and image documents are
+// returned as images for a vision query; text documents are included in the text.
+//
+// ctx: The context for the download.
+// msg: The message with the attachment.
+// text: The text of the message, i.e., its caption.
+//
+// Returns the text with the included documents, the images, and an error if the
+// attachment could not be downloaded; errUnsupportedMedia if it is neither an image
+// nor a text document.
+func (b *Bot) readMedia(ctx context.Context, msg *tgbotapi.Message, text string) (string, [][]byte, error) {
+	if len(msg.Photo) > 0 {
+		// The last size is the largest one.
+		image, err := b.downloadFile(ctx, msg.Photo[len(msg.Photo)-1].FileID, maxPhotoSize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return text, [][]byte{image}, nil
+	}
+
+	doc := msg.Document
+	if doc == nil {
+		return text, nil, nil
+	}
+
+	switch {
+	case strings.HasPrefix(doc.MimeType, "image/"):
+		image, err := b.downloadFile(ctx, doc.FileID, maxPhotoSize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return text, [][]byte{image}, nil
+
+	case isTextDocument(doc):
+		if doc.FileSize > maxDocumentSize {
+			return "", nil, errUnsupportedMedia
+		}
+
+		content, err := b.downloadFile(ctx, doc.FileID, maxDocumentSize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !utf8.Valid(content) {
+			return "", nil, errUnsupportedMedia
+		}
+
+		return strings.TrimSpace(fmt.Sprintf("File %s:\n```\n%s\n```\n\n%s", doc.FileName, content, text)), nil, nil
+
+	default:
+		return "", nil, errUnsupportedMedia
+	}
+}
+
+// isTextDocument reports whether the document contains text, judging by its MIME type.
+func isTextDocument(doc *tgbotapi.Document) bool {
+	switch {
+	case strings.HasPrefix(doc.MimeType, "text/"):
+		return true
+
+	case strings.HasSuffix(doc.MimeType, "json"),
+		strings.HasSuffix(doc.MimeType, "xml"),
+		strings.HasSuffix(doc.MimeType, "yaml"),
+		strings.HasSuffix(doc.MimeType, "javascript"),
+		doc.MimeType == "application/x-sh":
+		return true
+
+	default:
+		return false
+	}
+}
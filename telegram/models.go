@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// SetRoleModels restricts the models available to the users of each role. A user
+// whose role does not permit the bot's model gets sessions with the first model
+// permitted for the role. Roles without a restriction may use any model.
+//
+// models: The permitted models per role, the first one being the role's default.
+func (b *Bot) SetRoleModels(models map[chat.Role][]string) {
+	b.roleModels = models
+}
+
+// SetKnownModels configures the check of the model names given to /setmodel, e.g. the
+// models with a known price. Without it, only the bot's model and the models of the
+// roles may be assigned.
+//
+// known: The function reporting whether the model is supported.
+func (b *Bot) SetKnownModels(known func(model string) bool) {
+	b.knownModel = known
+}
+
+// SetVisionModels configures the check of the models asked about images, so the user
+// sending a photo to a text-only model gets an explanation instead of an error of the
+// chat service.
+//
+// vision: The function reporting whether the model accepts images.
+func (b *Bot) SetVisionModels(vision func(model string) bool) {
+	b.visionModel = vision
+}
+
+// checkVision reports whether the model accepts the images of the message. If not, the
+// user is told so.
+//
+// msg: The message with the images.
+// model: The model of the session.
+func (b *Bot) checkVision(msg *tgbotapi.Message, model string) bool {
+	if b.visionModel == nil || b.visionModel(model) {
+		return true
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgVisionUnsupported, model))
+	return false
+}
+
+// sessionModel resolves the model of the user's sessions: the model pinned for the
+// user by an administrator, the bot's model if the models the user is restricted to,
+// or else the models of the user's role, permit it, otherwise the first permitted model.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) sessionModel(ctx context.Context, user int64) string {
+	model, _ := b.sessionModels(ctx, user)
+	return model
+}
+
+// sessionModels resolves the model of the user's sessions like sessionModel, along with
+// the models permitted to the user, so the sessions do not switch to the others, e.g.
+// when falling back (see chat.WithPermittedModels).
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+//
+// Returns the model of the sessions and the permitted models, empty if any model is.
+func (b *Bot) sessionModels(ctx context.Context, user int64) (model string, permitted []string) {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"sessionModels LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		permitted = b.roleModels[chat.RoleGuest]
+		return b.permittedModel(permitted, b.model), permitted
+	}
+
+	switch {
+	case profile.Model != "":
+		return profile.Model, []string{profile.Model}
+	case len(profile.Models) > 0:
+		return b.permittedModel(profile.Models, b.model), profile.Models
+	case len(b.roleModels) == 0:
+		return b.model, nil
+	}
+
+	permitted = b.roleModels[b.profileRole(user, profile)]
+	return b.permittedModel(permitted, b.model), permitted
+}
+
+// permittedModel returns the model if it is permitted, otherwise the first permitted
+// model.
+//
+// permitted: The permitted models; empty means any model.
+// model: The requested model.
+func (b *Bot) permittedModel(permitted []string, model string) string {
+	if len(permitted) == 0 || slices.Contains(permitted, model) {
+		return model
+	}
+
+	return permitted[0]
+}
+
+// isKnownModel reports whether the model may be assigned to a user: the bot's model,
+// a model of a role or a model accepted by the configured check.
+//
+// model: The name of the model.
+func (b *Bot) isKnownModel(model string) bool {
+	if model == b.model {
+		return true
+	}
+
+	for _, models := range b.roleModels {
+		if slices.Contains(models, model) {
+			return true
+		}
+	}
+
+	return b.knownModel != nil && b.knownModel(model)
+}
+
+// handleSetModel assigns the models of a user's sessions: /setmodel <user> <model>
+// pins the model, /setmodel <user> <model>,<model>... restricts the user to the listed
+// models instead of the models of the user's role, and "reset" removes both. The
+// cached sessions of the user are evicted, so the next message is answered by the
+// new model.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /setmodel command.
+func (b *Bot) handleSetModel(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetModelUsage))
+		return
+	}
+
+	user, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetModelUsage))
+		return
+	}
+
+	var models []string
+	if args[1] != "reset" {
+		models = strings.Split(args[1], ",")
+		for _, model := range models {
+			if !b.isKnownModel(model) {
+				b.Reply(msg, b.printer.Sprintf(lang.MsgSetModelUnknown, model))
+				return
+			}
+		}
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		b.handleError(ctx, msg, "handleSetModel LoadProfile", err)
+		return
+	}
+
+	profile.Model, profile.Models = "", nil
+	if len(models) == 1 {
+		profile.Model = models[0]
+	} else {
+		profile.Models = models
+	}
+
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		b.handleError(ctx, msg, "handleSetModel SaveProfile", err)
+		return
+	}
+
+	b.evictUserSessions(ctx, user)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgSetModel, user, b.sessionModel(ctx, user)))
+}
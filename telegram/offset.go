@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// dedupWindow is the number of the latest update IDs the processed updates are
+// remembered for, to skip the updates Telegram delivers again out of order after a
+// network failure or a restart. The older updates are skipped anyway.
+const dedupWindow = 1000
+
+// updatesSaveInterval is how often the progress through the updates is saved at
+// most. The updates processed since the last save may be delivered again after a
+// crash only if Telegram has not been told they were received, i.e. they came in the
+// last batch.
+const updatesSaveInterval = 5 * time.Second
+
+// Offset returns the ID of the first update the bot has not processed yet, to resume
+// polling after a restart from where the bot stopped. Telegram keeps the updates for
+// 24 hours, so the messages sent while the bot was down are processed once. The
+// polling confirms the updates received as soon as it asks for the next ones, and
+// Telegram drops the confirmed updates, so an update whose processing was interrupted
+// by the restart is processed again only if it came in the last batch received;
+// otherwise it is lost.
+//
+// ctx: The context for loading the progress from the storage.
+//
+// Returns zero, which means all the pending updates, if no update has been processed
+// yet or the progress could not be loaded.
+func (b *Bot) Offset(ctx context.Context) int {
+	b.updatesMu.Lock()
+	defer b.updatesMu.Unlock()
+
+	updates, err := b.loadUpdates(ctx)
+	if err != nil {
+		return 0
+	}
+
+	return updates.Offset()
+}
+
+// markProcessed records the update as received and pending until finishUpdate is
+// called, and saves the progress to the storage at most every updatesSaveInterval.
+//
+// ctx: The context for saving the progress to the storage.
+// updateID: The ID of the update.
+//
+// Returns false if the update has already been processed. The update is processed,
+// but not recorded, if the progress could not be loaded.
+func (b *Bot) markProcessed(ctx context.Context, updateID int) bool {
+	b.updatesMu.Lock()
+	defer b.updatesMu.Unlock()
+
+	updates, err := b.loadUpdates(ctx)
+	if err != nil {
+		return true
+	}
+
+	if updates.Seen(updateID, dedupWindow) {
+		return false
+	}
+
+	updates.Add(updateID, time.Now(), dedupWindow)
+	b.updatesDirty = true
+
+	if time.Since(b.updatesSaved) >= updatesSaveInterval {
+		b.saveUpdates(ctx)
+	}
+
+	return true
+}
+
+// finishUpdate records that the processing of the update has finished, so the polling
+// does not resume before it after a restart. The receiver of a queue finishes the update once it
+// is published, and the workers acknowledge it to the queue instead, which delivers it
+// again if the worker stops before.
+//
+// ctx: The context for saving the progress to the storage.
+// updateID: The ID of the update.
+func (b *Bot) finishUpdate(ctx context.Context, updateID int) {
+	if b.worker != nil {
+		// The handlers finishing while the bot stops still acknowledge their updates.
+		if err := b.worker.Ack(context.WithoutCancel(ctx), updateID); err != nil {
+			slog.Error(
+				"finishUpdate Ack error",
+				slog.Int("updateID", updateID),
+				slog.String("error", err.Error()),
+			)
+		}
+		return
+	}
+
+	b.updatesMu.Lock()
+	defer b.updatesMu.Unlock()
+
+	if b.updates == nil {
+		return
+	}
+
+	b.updates.Done(updateID, time.Now())
+	b.updatesDirty = true
+
+	// The handlers finishing while the bot stops save their progress right away.
+	if ctx.Err() != nil || time.Since(b.updatesSaved) >= updatesSaveInterval {
+		b.saveUpdates(context.WithoutCancel(ctx))
+	}
+}
+
+// saveUpdates saves the progress through the updates to the storage if it has changed
+// since the last save. The caller must hold updatesMu.
+//
+// ctx: The context for saving the progress to the storage.
+func (b *Bot) saveUpdates(ctx context.Context) {
+	if !b.updatesDirty {
+		return
+	}
+
+	if err := b.storage.SaveUpdates(ctx, b.updates); err != nil {
+		slog.Error(
+			"saveUpdates SaveUpdates error",
+			slog.Int("lastID", b.updates.LastID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	b.updatesSaved, b.updatesDirty = time.Now(), false
+}
+
+// flushUpdates saves the progress through the updates not saved yet, e.g. when the
+// bot stops.
+//
+// ctx: The context for saving the progress to the storage.
+func (b *Bot) flushUpdates(ctx context.Context) {
+	b.updatesMu.Lock()
+	defer b.updatesMu.Unlock()
+
+	b.saveUpdates(ctx)
+}
+
+// loadUpdates returns the progress through the updates, loading it from the storage on
+// the first use. The caller must hold updatesMu.
+//
+// ctx: The context for loading the progress from the storage.
+//
+// Returns an error if the progress could not be loaded; it is loaded again on the next
+// call, and nothing is saved meanwhile, so the stored progress is not overwritten.
+func (b *Bot) loadUpdates(ctx context.Context) (*chat.Updates, error) {
+	if b.updates != nil {
+		return b.updates, nil
+	}
+
+	updates, err := b.storage.LoadUpdates(ctx)
+	if err != nil {
+		slog.Error("loadUpdates LoadUpdates error", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	updates.Resume()
+	b.updates = updates
+	return b.updates, nil
+}
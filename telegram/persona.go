@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// SetPersonas configures the named system prompts which the users select with the
+// /start <name> deep links, e.g. t.me/bot?start=translator. Telegram allows only
+// letters, digits, "_" and "-" in the payloads of the deep links.
+//
+// personas: The system prompts by the name of the persona.
+func (b *Bot) SetPersonas(personas map[string]string) {
+	b.personas = make(map[string]string, len(personas))
+	for name, prompt := range personas {
+		b.personas[strings.ToLower(name)] = prompt
+	}
+}
+
+// sessionPrompt returns the system prompt of the user's sessions: the prompt of the
+// persona selected by the user, or the configured prompt.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) sessionPrompt(ctx context.Context, user int64) string {
+	if len(b.personas) == 0 {
+		return b.prompt
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"sessionPrompt LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return b.prompt
+	}
+
+	if prompt, ok := b.personas[profile.Persona]; ok {
+		return prompt
+	}
+
+	return b.prompt
+}
+
+// startPersona selects the persona named by the payload of a /start deep link and
+// starts a new conversation with its prompt.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The /start message.
+// session: The session of the chat.
+//
+// Returns true if the payload named a persona.
+func (b *Bot) startPersona(ctx context.Context, msg *tgbotapi.Message, session chat.Session) bool {
+	name := strings.ToLower(strings.TrimSpace(msg.CommandArguments()))
+	prompt, ok := b.personas[name]
+	if name == "" || !ok {
+		return false
+	}
+
+	if err := b.setPersona(ctx, msg.From.ID, name); err != nil {
+		b.handleError(ctx, msg, "startPersona setPersona", err)
+		return false
+	}
+
+	if err := session.Reset(ctx); err != nil {
+		b.handleError(ctx, msg, "startPersona Reset", err)
+	}
+
+	if err := session.SetPrompt(ctx, prompt); err != nil {
+		b.handleError(ctx, msg, "startPersona SetPrompt", err)
+	}
+
+	slog.Info(
+		"startPersona selected",
+		slog.Int64("userID", msg.From.ID),
+		slog.String("persona", name),
+	)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgPersona, name))
+	return true
+}
+
+// setPersona persists the persona selected by the user; an empty name returns the
+// user to the configured prompt.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+// name: The name of the persona.
+//
+// Returns an error if the profile could not be loaded or saved.
+func (b *Bot) setPersona(ctx context.Context, user int64, name string) error {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		return fmt.Errorf("error loading profile: %w", err)
+	}
+
+	if profile.Persona == name {
+		return nil
+	}
+
+	profile.Persona = name
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		return fmt.Errorf("error saving profile: %w", err)
+	}
+
+	return nil
+}
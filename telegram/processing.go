@@ -0,0 +1,178 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// SetPlaceholder configures whether the bot acknowledges a message instantly with a
+// placeholder reply which is then edited into the answer, instead of showing the
+// typing status. The answer keeps the position of the placeholder in the chat.
+//
+// enabled: True to send placeholder replies.
+func (b *Bot) SetPlaceholder(enabled bool) {
+	b.placeholder = enabled
+}
+
+// processing shows the user that a message is being processed and delivers the reply.
+type processing struct {
+	bot *Bot
+	msg *tgbotapi.Message
+
+	placeholder *tgbotapi.Message // placeholder is the reply to edit into the answer, if any.
+	stopper     func()            // stopper stops the typing status or removes the reaction.
+}
+
+// indicateProcessing shows the user that the message is being processed: with a
+// placeholder reply, a reaction on the message, or the typing status, depending on
+// the configuration. If the placeholder or the reaction cannot be sent, the typing
+// status is shown instead.
+//
+// ctx: The context of the message processing.
+// msg: The message being processed.
+//
+// Returns the processing indication; its stop method must be called when the
+// processing is finished.
+func (b *Bot) indicateProcessing(ctx context.Context, msg *tgbotapi.Message) *processing {
+	p := &processing{bot: b, msg: msg, stopper: func() {}}
+
+	if b.placeholder {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, b.printer.Sprintf(lang.MsgThinking))
+		reply.ReplyToMessageID = msg.MessageID
+
+		sent, err := b.sender.Send(reply)
+		if err == nil {
+			p.placeholder = &sent
+			return p
+		}
+
+		slog.Error(
+			"indicateProcessing send placeholder error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int("messageID", msg.MessageID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if b.reactions {
+		err := b.react(msg, processingReaction)
+		if err == nil {
+			p.stopper = func() {
+				if err := b.react(msg, ""); err != nil {
+					slog.Error(
+						"indicateProcessing remove reaction error",
+						slog.Int64("chatID", msg.Chat.ID),
+						slog.Int("messageID", msg.MessageID),
+						slog.String("error", err.Error()),
+					)
+				}
+			}
+			return p
+		}
+
+		slog.Error(
+			"indicateProcessing set reaction error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int("messageID", msg.MessageID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	typingCtx, cancel := context.WithCancel(ctx)
+	go b.Typing(typingCtx, msg.Chat.ID)
+	p.stopper = cancel
+
+	return p
+}
+
+// reply delivers the answer: the placeholder is edited into the answer, otherwise the
+// answer is sent as a reply to the message. A huge code block of a long answer is
+// sent as a file. A long answer is published on the web if the publishing is
+// configured, and only its excerpt is sent. The blocks which
+// Telegram cannot display (e.g., formulas) are sent as images between the parts of
+// the text if their rendering is configured.
+//
+// ctx: The context for the publishing and the rendering.
+// text: The answer.
+func (p *processing) reply(ctx context.Context, text string) {
+	text = p.bot.attachCode(ctx, p, text)
+	text = p.bot.publishLong(ctx, text)
+
+	parts := p.bot.splitAnswer(text)
+	if len(parts) == 0 {
+		p.replyText(text)
+		return
+	}
+
+	for i, part := range parts {
+		switch {
+		case part.Kind != "":
+			p.bot.sendRendered(ctx, p.msg, part)
+
+		case i == 0:
+			p.replyText(part.Source)
+
+		default:
+			p.bot.Send(p.msg.Chat.ID, part.Source)
+		}
+	}
+}
+
+// replyText edits the placeholder into the text, or sends the text as a reply to the
+// message if there is no placeholder. If the placeholder cannot be edited (e.g., the
+// text is too long for a single message), it is deleted and the text is sent as a
+// regular reply.
+//
+// text: The text to send.
+func (p *processing) replyText(text string) {
+	if p.placeholder == nil {
+		p.bot.Reply(p.msg, text)
+		return
+	}
+
+	placeholder := p.placeholder
+	p.placeholder = nil
+
+	edit := tgbotapi.NewEditMessageText(placeholder.Chat.ID, placeholder.MessageID, text)
+	edit.ParseMode = "markdown"
+	if _, err := p.bot.sender.Send(edit); err == nil {
+		return
+	}
+
+	edit.ParseMode = ""
+	if _, err := p.bot.sender.Send(edit); err == nil {
+		return
+	}
+
+	p.bot.deletePlaceholder(placeholder)
+	p.bot.Reply(p.msg, text)
+}
+
+// stop finishes the indication. A placeholder which was not edited into an answer
+// (e.g., because the processing failed) is deleted.
+func (p *processing) stop() {
+	p.stopper()
+
+	if p.placeholder != nil {
+		p.bot.deletePlaceholder(p.placeholder)
+		p.placeholder = nil
+	}
+}
+
+// deletePlaceholder deletes the placeholder reply.
+//
+// placeholder: The placeholder message.
+func (b *Bot) deletePlaceholder(placeholder *tgbotapi.Message) {
+	del := tgbotapi.NewDeleteMessage(placeholder.Chat.ID, placeholder.MessageID)
+	if _, err := b.sender.Request(del); err != nil {
+		slog.Error(
+			"deletePlaceholder error",
+			slog.Int64("chatID", placeholder.Chat.ID),
+			slog.Int("messageID", placeholder.MessageID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
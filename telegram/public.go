@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// rateLimitWindow is the period over which the messages of a user are counted
+// by the rate limiter.
+const rateLimitWindow = time.Minute
+
+// SetPublic enables or disables the public mode. In public mode the allowlist is
+// bypassed and anyone can use the bot; the rate limiter and the default user budget
+// keep the spending of unknown users under control.
+//
+// enabled: True to serve everyone.
+func (b *Bot) SetPublic(enabled bool) {
+	b.public = enabled
+}
+
+// SetRateLimit limits the number of messages a non-admin user may send per minute.
+// Zero disables the rate limiter.
+//
+// perMinute: The maximum number of messages per user per minute.
+func (b *Bot) SetRateLimit(perMinute int) {
+	b.limiter = newRateLimiter(perMinute, rateLimitWindow)
+}
+
+// SetDefaultBudget configures the monthly budget of users who have no budget of
+// their own set with the /budget command. Zero means no limit.
+//
+// monthly: The default monthly budget, before the conversion with the bot's rate.
+func (b *Bot) SetDefaultBudget(monthly chat.Cost) {
+	b.defaultBudget = monthly
+}
+
+// rateLimiter counts the messages of every user in fixed windows of time.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[int64]*rateWindow
+}
+
+// rateWindow is the message count of a user in the window starting at start.
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter creates a rate limiter allowing limit messages per window.
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[int64]*rateWindow),
+	}
+}
+
+// Allow records a message of the user and reports whether it is within the limit.
+// A nil or disabled limiter allows all messages.
+//
+// user: The ID of the user who sent the message.
+func (l *rateLimiter) Allow(user int64) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop the expired windows to keep the map bounded by the active users.
+	for id, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, id)
+		}
+	}
+
+	w, exists := l.windows[user]
+	if !exists {
+		w = &rateWindow{start: now}
+		l.windows[user] = w
+	}
+
+	w.count++
+	return w.count <= l.limit
+}
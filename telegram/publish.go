@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// excerptLength is the approximate number of characters of a published answer which
+// is sent to the chat along with the link.
+const excerptLength = 600
+
+// Publisher publishes long texts on the web, e.g., on Telegraph.
+type Publisher interface {
+	// Publish creates a page with the given Markdown text.
+	//
+	// ctx: The context for the request.
+	// title: The title of the page.
+	// markdown: The content of the page.
+	//
+	// Returns the URL of the page and an error if it could not be created.
+	Publish(ctx context.Context, title, markdown string) (string, error)
+}
+
+// SetPublisher configures the publishing of long answers. An answer longer than the
+// threshold is published with the publisher, and only its beginning is sent to the
+// chat along with the link to the full answer.
+//
+// publisher: The Publisher to use; nil disables the publishing.
+// threshold: The length of an answer in characters above which it is published.
+func (b *Bot) SetPublisher(publisher Publisher, threshold int) {
+	b.publisher = publisher
+	b.publishThreshold = threshold
+}
+
+// publishLong publishes the answer if it exceeds the threshold and returns its excerpt
+// with the link to the full answer. If the answer is short or the publishing fails,
+// the answer is returned unchanged.
+//
+// ctx: The context for the publishing.
+// answer: The answer to publish.
+func (b *Bot) publishLong(ctx context.Context, answer string) string {
+	if b.publisher == nil || b.publishThreshold <= 0 || utf8.RuneCountInString(answer) <= b.publishThreshold {
+		return answer
+	}
+
+	url, err := b.publisher.Publish(ctx, b.name, answer)
+	if err != nil {
+		slog.Error(
+			"publishLong Publish error",
+			slog.Int("length", len(answer)),
+			slog.String("error", err.Error()),
+		)
+		return answer
+	}
+
+	return excerpt(answer, excerptLength) + "\n\n" + b.printer.Sprintf(lang.MsgFullAnswer, url)
+}
+
+// excerpt returns the beginning of the text of about the given number of characters,
+// cut at a paragraph or word boundary. The cut never splits a code block, which would
+// break the Markdown formatting.
+//
+// text: The text to shorten.
+// length: The maximum number of characters.
+func excerpt(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	cut := string(runes[:length])
+	if i := strings.LastIndex(cut, "\n\n"); i > length/2 {
+		cut = cut[:i]
+	} else if i := strings.LastIndexAny(cut, " \n"); i > 0 {
+		cut = cut[:i]
+	}
+
+	// Drop an unterminated code block.
+	if strings.Count(cut, "```")%2 == 1 {
+		cut = cut[:strings.LastIndex(cut, "```")]
+	}
+
+	return strings.TrimSpace(cut) + " …"
+}
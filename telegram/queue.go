@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// UpdateQueue distributes the updates received from Telegram to the worker instances
+// of the bot, which process them with ProcessUpdates.
+type UpdateQueue interface {
+	// Publish adds the update to the queue.
+	//
+	// ctx: The context for the request to the queue.
+	// update: The update received from Telegram.
+	//
+	// Returns an error if the update could not be published.
+	Publish(ctx context.Context, update tgbotapi.Update) error
+}
+
+// SetUpdateQueue makes the bot a receiver: the updates are published to the queue for
+// the workers instead of being processed by this instance.
+//
+// queue: The queue of the updates.
+func (b *Bot) SetUpdateQueue(queue UpdateQueue) {
+	b.queue = queue
+}
+
+// UpdateAcker acknowledges the updates a worker consumed from the queue once they are
+// processed; the queue delivers the updates not acknowledged again, e.g. after a crash
+// of the worker.
+type UpdateAcker interface {
+	// Ack acknowledges that the processing of the update has finished.
+	//
+	// ctx: The context for the request to the queue.
+	// updateID: The ID of the processed update.
+	//
+	// Returns an error if the update could not be acknowledged.
+	Ack(ctx context.Context, updateID int) error
+}
+
+// SetWorker makes the bot a worker processing the updates consumed from the queue. The
+// receiver keeps track of the updates received from Telegram, so the worker does not;
+// it acknowledges the processed updates to the queue instead.
+//
+// queue: The queue the updates are consumed from; nil if the bot is not a worker.
+func (b *Bot) SetWorker(queue UpdateAcker) {
+	b.worker = queue
+}
+
+// publishUpdate publishes the update to the queue; if the queue fails, the update is
+// processed by this instance rather than lost.
+//
+// ctx: The context for controlling the processing lifecycle.
+// update: The update received from Telegram.
+func (b *Bot) publishUpdate(ctx context.Context, update tgbotapi.Update) {
+	if err := b.queue.Publish(ctx, update); err != nil {
+		slog.Error(
+			"publishUpdate Publish error",
+			slog.Int("updateID", update.UpdateID),
+			slog.String("error", err.Error()),
+		)
+		b.dispatchUpdate(ctx, update)
+		return
+	}
+
+	b.finishUpdate(ctx, update.UpdateID)
+}
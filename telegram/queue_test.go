@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/telegram/telegramtest"
+)
+
+// fakeQueue is an UpdateQueue and an UpdateAcker recording the published and the
+// acknowledged updates.
+type fakeQueue struct {
+	mu        sync.Mutex
+	err       error // err is returned by Publish.
+	published []int
+	acked     []int
+
+	onAck func(updateID int) // onAck is called by Ack, if set.
+}
+
+func (q *fakeQueue) Publish(_ context.Context, update tgbotapi.Update) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err != nil {
+		return q.err
+	}
+
+	q.published = append(q.published, update.UpdateID)
+	return nil
+}
+
+func (q *fakeQueue) Ack(_ context.Context, updateID int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.onAck != nil {
+		q.onAck(updateID)
+	}
+	q.acked = append(q.acked, updateID)
+	return nil
+}
+
+func TestReceiverFinishesPublishedUpdates(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	bot, sender := newTestBot(t, "Hello, User!")
+	queue := &fakeQueue{}
+	bot.SetUpdateQueue(queue)
+
+	// Execute: the published update is finished, the queue delivers it to a worker.
+	update := tgbotapi.Update{UpdateID: 7, Message: telegramtest.NewMessage(1, "Hello!")}
+	if !bot.markProcessed(ctx, update.UpdateID) {
+		t.Fatal("The update was skipped")
+	}
+	bot.publishUpdate(ctx, update)
+
+	// Assert.
+	if len(queue.published) != 1 || len(sender.Messages()) != 0 {
+		t.Fatalf("Published %v and sent %q, want the update published only", queue.published, sender.Texts())
+	}
+	if len(bot.updates.Pending) != 0 || bot.Offset(ctx) != 8 {
+		t.Errorf("The updates %v are pending and the offset is %d, want the update finished",
+			bot.updates.Pending, bot.Offset(ctx))
+	}
+}
+
+func TestReceiverProcessesUnpublishedUpdates(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	bot, sender := newTestBot(t, "Hello, User!")
+	bot.SetUpdateQueue(&fakeQueue{err: errors.New("connection refused")})
+
+	// Execute: the update the queue failed to take is processed by the receiver.
+	update := tgbotapi.Update{UpdateID: 7, Message: telegramtest.NewMessage(1, "Hello!")}
+	bot.markProcessed(ctx, update.UpdateID)
+	bot.publishUpdate(ctx, update)
+	bot.handlers.Wait()
+
+	// Assert.
+	if texts := sender.Texts(); len(texts) != 1 || texts[0] != "Hello, User!" {
+		t.Fatalf("Sent %q, want the answer", texts)
+	}
+	if len(bot.updates.Pending) != 0 {
+		t.Errorf("The updates %v are pending after the processing", bot.updates.Pending)
+	}
+}
+
+func TestWorkerAcksProcessedUpdates(t *testing.T) {
+	// Setup.
+	ctx := context.Background()
+	bot, sender := newTestBot(t, "Hello, User!")
+
+	sentBeforeAck := make(map[int]int)
+	queue := &fakeQueue{onAck: func(updateID int) { sentBeforeAck[updateID] = len(sender.Messages()) }}
+	bot.SetWorker(queue)
+
+	// Execute.
+	bot.dispatchUpdate(ctx, tgbotapi.Update{UpdateID: 7, Message: telegramtest.NewMessage(1, "Hello!")})
+	bot.dispatchUpdate(ctx, tgbotapi.Update{UpdateID: 8})
+	bot.handlers.Wait()
+
+	// Assert: the update is acknowledged once it is processed, the ignored one at once.
+	if len(queue.acked) != 2 {
+		t.Fatalf("Acknowledged %v, want both updates", queue.acked)
+	}
+	if sentBeforeAck[7] != 1 {
+		t.Errorf("The update was acknowledged before the answer was sent")
+	}
+
+	// The receiver keeps the progress through the updates, the worker does not.
+	if bot.updates != nil {
+		t.Errorf("The worker recorded the progress %+v", bot.updates)
+	}
+}
+
+func TestWorkerAcksOnShutdown(t *testing.T) {
+	// Setup: the handler finishes after the worker has been stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	bot, _ := newTestBot(t, "Hello, User!")
+	queue := &fakeQueue{}
+	bot.SetWorker(queue)
+	cancel()
+
+	// Execute.
+	bot.finishUpdate(ctx, 7)
+
+	// Assert.
+	if len(queue.acked) != 1 || queue.acked[0] != 7 {
+		t.Errorf("Acknowledged %v on shutdown, want the update 7", queue.acked)
+	}
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// markdownReplacer removes the Markdown markup, which Telegram strips from the text
+// of the sent messages, to compare the messages with the stored history.
+var markdownReplacer = strings.NewReplacer("*", "", "_", "", "`", "", "[", "", "]", "")
+
+// withQuoted includes the earlier exchange the user replies to as quoted context of
+// the text, which lets the user continue the conversation from an older answer. The
+// question of the exchange is looked up in the session history; if it is not found,
+// only the quoted answer is included.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message being processed.
+// session: The session of the chat.
+// text: The text of the message.
+func (b *Bot) withQuoted(ctx context.Context, msg *tgbotapi.Message, session chat.Session, text string) string {
+	quoted := msg.ReplyToMessage
+	if quoted == nil || quoted.From == nil || quoted.Text == "" || !b.isSelf(quoted.From) {
+		return text
+	}
+
+	history, err := session.History(ctx)
+	if err != nil {
+		slog.Error(
+			"withQuoted History error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int("messageID", msg.MessageID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	question, latest := "", false
+	if history != nil {
+		question, latest = findQuestion(history, quoted.Text)
+	}
+
+	// The latest exchange is the context of the conversation anyway.
+	if latest {
+		return text
+	}
+
+	if question == "" {
+		return fmt.Sprintf("The user replies to your earlier answer:\n%s\n\n%s", quote(quoted.Text), text)
+	}
+
+	return fmt.Sprintf(
+		"The user replies to this earlier exchange:\nQuestion:\n%s\nAnswer:\n%s\n\n%s",
+		quote(question), quote(quoted.Text), text,
+	)
+}
+
+// isSelf reports whether the user is this bot.
+func (b *Bot) isSelf(user *tgbotapi.User) bool {
+	if b.username == "" {
+		return user.IsBot
+	}
+
+	return strings.EqualFold(user.UserName, b.username)
+}
+
+// findQuestion returns the question of the latest exchange in the history whose answer
+// contains the text, or an empty string if there is none. Long answers are sent in
+// several messages, so the text may be a part of the answer.
+//
+// history: The history of the session.
+// answer: The text of the answer as displayed by Telegram.
+//
+// Returns the question and whether the exchange is the last one in the history.
+func findQuestion(history *chat.History, answer string) (string, bool) {
+	answer = strings.Join(strings.Fields(markdownReplacer.Replace(answer)), " ")
+
+	for i := len(history.Log) - 1; i >= 0; i-- {
+		logged := strings.Join(strings.Fields(markdownReplacer.Replace(history.Log[i].Assistant)), " ")
+		if strings.Contains(logged, answer) {
+			return history.Log[i].User, i == len(history.Log)-1
+		}
+	}
+
+	return "", false
+}
+
+// quote formats the text as a Markdown quote.
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// RateProvider defines an interface for obtaining the current exchange rate used
+// to convert the costs into the bot's currency.
+type RateProvider interface {
+	// Rate returns the current exchange rate.
+	Rate() float64
+}
+
+// SetRateProvider configures a source of the exchange rate which replaces the static
+// rate passed to NewBot, e.g. to display the costs with live exchange rates. Passing
+// nil restores the static rate.
+//
+// provider: The RateProvider implementation to use.
+func (b *Bot) SetRateProvider(provider RateProvider) {
+	b.rates = provider
+}
+
+// amount converts a cost into the bot's currency using the current exchange rate.
+//
+// cost: The cost to convert.
+//
+// Returns the amount in the bot's currency.
+func (b *Bot) amount(cost chat.Cost) float64 {
+	if b.rates != nil {
+		return b.rates.Rate() * float64(cost)
+	}
+
+	return b.rate * float64(cost)
+}
+
+// pricing holds the currency and the rate the costs are shown to a user in.
+type pricing struct {
+	currency string
+	rate     float64
+}
+
+// amount converts a cost into the currency of the pricing.
+//
+// cost: The cost to convert.
+func (p pricing) amount(cost chat.Cost) float64 {
+	return p.rate * float64(cost)
+}
+
+// userPricing returns the currency assigned to the user and the bot's rate multiplied
+// by the rate assigned to the user, or the bot's ones if none are assigned or the
+// profile could not be loaded.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) userPricing(ctx context.Context, user int64) pricing {
+	p := pricing{currency: b.currency, rate: b.amount(1)}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"userPricing LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return p
+	}
+
+	if profile.Rate > 0 {
+		p.rate = b.amount(1) * profile.Rate
+	}
+	if profile.Currency != "" {
+		p.currency = profile.Currency
+	}
+
+	return p
+}
+
+// handleSetRate assigns the display currency and the rate multiplier to a user, e.g. to
+// show the costs with a reseller markup: /setrate <user> <rate> [currency]. The bot's
+// rate, live or static, is multiplied by the rate. The currency defaults to the bot's
+// one; "off" instead of the rate restores the bot's rate and currency.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /setrate command.
+func (b *Bot) handleSetRate(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 2 || len(args) > 3 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRateUsage))
+		return
+	}
+
+	user, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRateUsage))
+		return
+	}
+
+	var rate float64
+	if args[1] != "off" {
+		if rate, err = strconv.ParseFloat(args[1], 64); err != nil || rate <= 0 {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgSetRateUsage))
+			return
+		}
+	} else if len(args) == 3 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRateUsage))
+		return
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		b.handleError(ctx, msg, "handleSetRate LoadProfile", err)
+		return
+	}
+
+	profile.Rate, profile.Currency = rate, ""
+	if len(args) == 3 {
+		profile.Currency = args[2]
+	}
+
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		b.handleError(ctx, msg, "handleSetRate SaveProfile", err)
+		return
+	}
+
+	if rate == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRateOff, user))
+		return
+	}
+
+	p := b.userPricing(ctx, user)
+	b.Reply(msg, b.printer.Sprintf(lang.MsgSetRate, user, p.currency, p.rate))
+}
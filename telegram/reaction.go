@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// processingReaction is the reaction set on a message while it is being processed.
+const processingReaction = "👀"
+
+// SetReactions configures whether the bot marks the messages being processed with
+// a reaction instead of showing the typing status. The reaction is removed when the
+// reply has been sent.
+//
+// enabled: True to use reactions.
+func (b *Bot) SetReactions(enabled bool) {
+	b.reactions = enabled
+}
+
+// react sets the emoji reaction of the bot on the message, or removes it if the
+// emoji is empty.
+//
+// msg: The message to react to.
+// emoji: The reaction emoji.
+func (b *Bot) react(msg *tgbotapi.Message, emoji string) error {
+	reaction := []map[string]string{}
+	if emoji != "" {
+		reaction = append(reaction, map[string]string{"type": "emoji", "emoji": emoji})
+	}
+
+	params := tgbotapi.Params{
+		"chat_id":    strconv.FormatInt(msg.Chat.ID, 10),
+		"message_id": strconv.Itoa(msg.MessageID),
+	}
+	if err := params.AddInterface("reaction", reaction); err != nil {
+		return err
+	}
+
+	_, err := b.sender.MakeRequest("setMessageReaction", params)
+	return err
+}
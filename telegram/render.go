@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/render"
+)
+
+// SetRenderer configures the rendering of the blocks of the given kind in the answers.
+// The blocks are sent as images between the parts of the text. Passing nil disables
+// the rendering of the kind.
+//
+// kind: The kind of the blocks (e.g., render.KindLaTeX).
+// renderer: The Renderer which renders the blocks into images.
+func (b *Bot) SetRenderer(kind render.Kind, renderer render.Renderer) {
+	if renderer == nil {
+		delete(b.renderers, kind)
+		return
+	}
+
+	if b.renderers == nil {
+		b.renderers = make(map[render.Kind]render.Renderer)
+	}
+
+	b.renderers[kind] = renderer
+}
+
+// splitAnswer splits the answer into the text parts and the blocks to render.
+//
+// text: The answer.
+func (b *Bot) splitAnswer(text string) []render.Part {
+	kinds := make([]render.Kind, 0, len(b.renderers))
+	for kind := range b.renderers {
+		kinds = append(kinds, kind)
+	}
+
+	return render.Split(text, kinds...)
+}
+
+// sendRendered renders the block and sends it as a photo to the chat of the message.
+// If a formula cannot be rendered, its source is sent as a code block instead.
+//
+// ctx: The context for the rendering.
+// msg: The message being answered.
+// part: The block to render.
+func (b *Bot) sendRendered(ctx context.Context, msg *tgbotapi.Message, part render.Part) {
+	image, err := b.renderers[part.Kind].Render(ctx, part.Source)
+	if err == nil {
+		photo := tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileBytes{Name: string(part.Kind) + ".png", Bytes: image})
+		if _, err = b.sender.Send(photo); err == nil {
+			return
+		}
+	}
+
+	slog.Error(
+		"sendRendered error",
+		slog.Int64("chatID", msg.Chat.ID),
+		slog.String("kind", string(part.Kind)),
+		slog.String("error", err.Error()),
+	)
+
+	// The diagrams are preceded by their code blocks, the formulas are sent as code.
+	if part.Kind == render.KindLaTeX {
+		b.Send(msg.Chat.ID, "```\n"+part.Source+"\n```")
+	}
+}
@@ -0,0 +1,185 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// maxReportLength limits the size of an error report sent to administrators.
+// Telegram rejects text messages longer than 4096 characters.
+const maxReportLength = 4096
+
+// SetErrorReporter configures the external error tracking system which receives
+// handler errors and panics. Passing nil disables external reporting.
+//
+// reporter: The ErrorReporter implementation to use.
+func (b *Bot) SetErrorReporter(reporter ErrorReporter) {
+	b.reporter = reporter
+}
+
+// handleError deals with an unexpected error that occurred while processing a message.
+// It replies to the user with the generic localized error message, logs the failure
+// and forwards a detailed report to all administrators and the error reporter.
+// While the chat service is unavailable, the user is told so and nothing is reported.
+//
+// Parameters:
+//
+//	ctx - The context of the message processing.
+//	msg - The message that was being processed when the error occurred.
+//	op  - A short description of the failed operation (e.g., "handleCommand Reset").
+//	err - The error that occurred.
+func (b *Bot) handleError(ctx context.Context, msg *tgbotapi.Message, op string, err error) {
+	// The outage of the chat service is already known, so it is not reported again.
+	if errors.Is(err, chat.ErrUnavailable) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgServiceDegraded))
+		slog.Warn(
+			op+" unavailable",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int("messageID", msg.MessageID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	// The session was closed by a concurrent change of the user's data or settings, so
+	// the message is just to be sent again.
+	if errors.Is(err, chat.ErrSessionClosed) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSessionClosed))
+		slog.Warn(
+			op+" session closed",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int("messageID", msg.MessageID),
+		)
+		return
+	}
+
+	// A request which timed out is worth retrying, unlike the other errors.
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgRequestTimeout))
+	} else {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+	}
+
+	slog.Error(
+		op+" error",
+		slog.Int64("chatID", msg.Chat.ID),
+		slog.Int("messageID", msg.MessageID),
+		slog.String("messageText", msg.Text),
+		slog.String("error", err.Error()),
+	)
+
+	b.report(ctx, newErrorReport(msg, op, err, nil))
+}
+
+// recoverPanic recovers from a panic raised while processing a message. It replies
+// to the user with the generic localized error message, logs the panic and reports
+// it like any other unexpected error. It must be called directly via defer.
+//
+// Parameters:
+//
+//	ctx - The context of the message processing.
+//	msg - The message that was being processed when the panic occurred.
+func (b *Bot) recoverPanic(ctx context.Context, msg *tgbotapi.Message) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic: %v", recovered)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+
+	slog.Error(
+		"handleMessage panic",
+		slog.Int64("chatID", msg.Chat.ID),
+		slog.Int("messageID", msg.MessageID),
+		slog.String("messageText", msg.Text),
+		slog.String("error", err.Error()),
+	)
+
+	b.report(ctx, newErrorReport(msg, "handleMessage", err, recovered))
+}
+
+// report forwards the incident to all administrators and, if configured,
+// to the external error reporter.
+//
+// Parameters:
+//
+//	ctx    - The context of the message processing.
+//	report - The details of the incident.
+func (b *Bot) report(ctx context.Context, report ErrorReport) {
+	b.errors.Add(1)
+
+	b.notifyAdmins(report)
+
+	if b.reporter != nil {
+		b.reporter.Report(ctx, b.anonymizeReport(report))
+	}
+}
+
+// notifyAdmins sends a detailed error report to the private chats of all administrators.
+// The report contains the user and chat that triggered the error, the failed operation,
+// the error message and the stack trace. The report is sent as plain text because
+// stack traces routinely break Markdown parsing.
+//
+// Parameters:
+//
+//	report - The details of the incident.
+func (b *Bot) notifyAdmins(report ErrorReport) {
+	text := b.printer.Sprintf(
+		lang.MsgAdminErrorReport,
+		report.UserID, report.Username,
+		report.ChatID,
+		report.Operation,
+		report.Err.Error(),
+		report.Stack,
+	)
+
+	if len(text) > maxReportLength {
+		text = strings.ToValidUTF8(text[:maxReportLength], "")
+	}
+
+	for _, adminID := range b.admins() {
+		if _, err := b.sender.Send(tgbotapi.NewMessage(adminID, text)); err != nil {
+			slog.Error(
+				"notifyAdmins send error",
+				slog.Int64("adminID", adminID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// newErrorReport builds an ErrorReport for the given message and failure,
+// capturing the stack trace of the current goroutine.
+//
+// Parameters:
+//
+//	msg       - The message that was being processed.
+//	op        - A short description of the failed operation.
+//	err       - The error that occurred.
+//	recovered - The value recovered from a panic, or nil for regular errors.
+//
+// Returns:
+//   - The populated ErrorReport.
+func newErrorReport(msg *tgbotapi.Message, op string, err error, recovered any) ErrorReport {
+	return ErrorReport{
+		UserID:      msg.From.ID,
+		Username:    msg.From.UserName,
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.MessageID,
+		MessageText: msg.Text,
+		Operation:   op,
+		Err:         err,
+		Panic:       recovered,
+		Stack:       debug.Stack(),
+	}
+}
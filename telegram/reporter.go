@@ -0,0 +1,31 @@
+package telegram
+
+import "context"
+
+// ErrorReport describes an incident that occurred while the bot was processing
+// an update. It carries enough context to identify the affected user, chat and
+// message, as well as the failure itself.
+type ErrorReport struct {
+	UserID      int64  // UserID is the Telegram ID of the user who sent the message.
+	Username    string // Username is the Telegram username of the user, if any.
+	ChatID      int64  // ChatID is the ID of the chat where the message was sent.
+	MessageID   int    // MessageID is the ID of the message being processed.
+	MessageText string // MessageText is the text of the message being processed.
+	Operation   string // Operation is a short description of the failed operation.
+	Err         error  // Err is the error returned by the failed operation. Nil for panics.
+	Panic       any    // Panic is the value recovered from a panic. Nil for regular errors.
+	Stack       []byte // Stack is the stack trace captured at the moment of the failure.
+}
+
+// ErrorReporter defines an interface for delivering incidents to an external
+// error tracking system, so production failures are captured centrally.
+type ErrorReporter interface {
+	// Report delivers the given incident to the error tracking system.
+	// Implementations must be safe for concurrent use and should not block
+	// message processing for a long time.
+	//
+	// Parameters:
+	//   - ctx: The context of the message processing that failed.
+	//   - report: The details of the incident.
+	Report(ctx context.Context, report ErrorReport)
+}
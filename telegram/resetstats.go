@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// callbackResetStats is the callback action confirming a statistics reset.
+// Its arguments are "<user>:<chat>" to reset a single session, or "<user>"
+// to reset all sessions of the user.
+const callbackResetStats = "resetstats"
+
+// handleResetStats asks for a confirmation to zero out statistics. Users reset the
+// statistics of their current session, administrators may pass a user ID to reset
+// all statistics of that user: /resetstats [user].
+//
+// msg: The message containing the /resetstats command.
+func (b *Bot) handleResetStats(msg *tgbotapi.Message) {
+	data := fmt.Sprintf("%s:%d:%d", callbackResetStats, msg.From.ID, msg.Chat.ID)
+	text := b.printer.Sprintf(lang.MsgResetStatsConfirm)
+
+	if args := strings.TrimSpace(msg.CommandArguments()); args != "" {
+		if !b.IsUserAdmin(msg.From.ID) {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgResetStatsUsage))
+			return
+		}
+
+		user, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgResetStatsUsage))
+			return
+		}
+
+		data = fmt.Sprintf("%s:%d", callbackResetStats, user)
+		text = b.printer.Sprintf(lang.MsgResetStatsUserConfirm, user)
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = b.confirmationKeyboard(data)
+	if _, err := b.sender.Send(reply); err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+	}
+}
+
+// handleResetStatsCallback performs the statistics reset confirmed with the inline button.
+// Only the user the statistics belong to or an administrator may confirm the reset.
+//
+// ctx: The context for controlling the processing lifecycle.
+// cq: The callback query of the Confirm button.
+// args: The callback arguments, "<user>:<chat>" or "<user>".
+func (b *Bot) handleResetStatsCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, args string) {
+	userArg, chatArg, single := strings.Cut(args, ":")
+
+	user, err := strconv.ParseInt(userArg, 10, 64)
+	if err != nil {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgCommandNotSupported))
+		return
+	}
+
+	isAdmin := b.IsUserAdmin(cq.From.ID)
+	if (single && cq.From.ID != user) || (!single && !isAdmin) {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgNotPermitted))
+		return
+	}
+
+	var ids []chat.ID
+	if single {
+		chatID, err := strconv.ParseInt(chatArg, 10, 64)
+		if err != nil {
+			b.answerCallback(cq, b.printer.Sprintf(lang.MsgCommandNotSupported))
+			return
+		}
+		ids = append(ids, chat.ID{User: user, Chat: chatID, Model: b.sessionModel(ctx, user)})
+	} else {
+		stored, err := b.storage.List(ctx)
+		if err != nil {
+			b.answerCallback(cq, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+			return
+		}
+		for _, id := range stored {
+			if id.User == user {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	// Reset through the sessions, so the cached statistics stay consistent.
+	for _, id := range ids {
+		session, err := b.session.ProvideSession(ctx, id)
+		if err == nil {
+			err = session.ResetStatistics(ctx)
+		}
+		if err != nil {
+			b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+			b.answerCallback(cq, "")
+			return
+		}
+	}
+
+	b.editCallbackMessage(cq, b.printer.Sprintf(lang.MsgResetStatsDone))
+	b.answerCallback(cq, "")
+}
@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// retentionInterval is how often the stored data is checked for the expired sessions.
+const retentionInterval = 6 * time.Hour
+
+// RetentionPolicy defines how long the data of the inactive chat sessions and the
+// entries of the audit trail are stored. The age of the history is the time since it
+// was last changed, and the age of the statistics is the time since the last message.
+type RetentionPolicy struct {
+	History    time.Duration // History is the age after which the history is deleted; zero keeps it forever.
+	Statistics time.Duration // Statistics is the age after which the statistics are deleted; zero keeps them forever.
+	Audit      time.Duration // Audit is the age after which the audit entries are deleted; zero keeps them forever.
+}
+
+// RunRetention periodically deletes the data of the chat sessions older than the policy
+// allows until the context is cancelled, for the disk hygiene and the data minimization.
+//
+// ctx: The context controlling the lifecycle of the job.
+// policy: The retention policy.
+func (b *Bot) RunRetention(ctx context.Context, policy RetentionPolicy) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.applyRetention(ctx, policy); err != nil {
+			slog.Error("RunRetention applyRetention error", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyRetention deletes the histories and the statistics of the chat sessions and the
+// entries of the audit trail older than the policy allows. The sessions are evicted from
+// the cache first, so their data is not saved again.
+//
+// ctx: The context for the storage operations.
+// policy: The retention policy.
+//
+// Returns an error if the sessions could not be listed or their data could not be
+// loaded or deleted, or the audit trail could not be pruned.
+func (b *Bot) applyRetention(ctx context.Context, policy RetentionPolicy) error {
+	if policy.Audit > 0 && b.auditLog != nil {
+		if err := b.auditLog.Prune(ctx, chat.Now().Add(-policy.Audit)); err != nil {
+			return fmt.Errorf("error pruning audit trail: %w", err)
+		}
+	}
+
+	if policy.History <= 0 && policy.Statistics <= 0 {
+		return nil
+	}
+
+	ids, err := b.storage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing sessions: %w", err)
+	}
+
+	now := chat.Now()
+	histories, statistics := 0, 0
+	for _, id := range ids {
+		history, err := b.storage.LoadHistory(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error loading history: %w", err)
+		}
+
+		stats, err := b.storage.LoadStatistics(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error loading statistics: %w", err)
+		}
+
+		// The histories saved before their changes were timed are as old as the last
+		// message, if the statistics still tell it, and the others start aging now.
+		hasHistory := len(history.Log) > 0 || history.Prompt != ""
+		historyUpdated := history.Updated
+		if historyUpdated.IsZero() {
+			historyUpdated = stats.LastUpdate
+		}
+		if hasHistory && historyUpdated.IsZero() && policy.History > 0 {
+			history.Updated = now
+			if err := b.storage.SaveHistory(ctx, history); err != nil {
+				return fmt.Errorf("error saving history: %w", err)
+			}
+			historyUpdated = now
+		}
+
+		// The statistics without a message, e.g. reset, hold nothing worth keeping once
+		// the history is gone.
+		expiredHistory := policy.History > 0 && hasHistory && now.Sub(historyUpdated) > policy.History
+		expiredStatistics := policy.Statistics > 0 && (stats.LastUpdate.IsZero() && !hasHistory ||
+			!stats.LastUpdate.IsZero() && now.Sub(stats.LastUpdate) > policy.Statistics)
+		if !expiredHistory && !expiredStatistics {
+			continue
+		}
+
+		if _, err = b.session.Evict(ctx, id); err != nil {
+			return fmt.Errorf("error evicting session: %w", err)
+		}
+
+		if expiredHistory {
+			if err := b.storage.DeleteHistory(ctx, id); err != nil {
+				return fmt.Errorf("error deleting history: %w", err)
+			}
+			histories++
+		}
+
+		if expiredStatistics {
+			if err := b.storage.DeleteStatistics(ctx, id); err != nil {
+				return fmt.Errorf("error deleting statistics: %w", err)
+			}
+			statistics++
+		}
+	}
+
+	slog.Info(
+		"applyRetention finished",
+		slog.Int("sessions", len(ids)),
+		slog.Int("histories", histories),
+		slog.Int("statistics", statistics),
+	)
+
+	return nil
+}
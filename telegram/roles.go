@@ -0,0 +1,249 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// commandRoles lists the user commands that require more than the guest role.
+// Admin commands always require the admin role.
+var commandRoles = map[string]chat.Role{
+	"image":      chat.RoleUser,
+	"resetstats": chat.RoleUser,
+	"restart":    chat.RoleUser,
+}
+
+// SetRoleBudgets configures the default monthly budgets per role. A role budget
+// applies to the users of the role who have no budget of their own set with the
+// /budget command, and takes precedence over the default user budget.
+//
+// budgets: The monthly budgets per role, before the conversion with the bot's rate.
+func (b *Bot) SetRoleBudgets(budgets map[chat.Role]chat.Cost) {
+	b.roleBudgets = budgets
+}
+
+// userRole returns the effective role of the user. The role assigned with /setrole
+// takes precedence, except that the administrators from the configuration are always
+// admins. Without an assigned role, users with access have the user role and
+// everyone else is a guest.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) userRole(ctx context.Context, user int64) chat.Role {
+	b.accessMu.RLock()
+	_, configuredAdmin := b.configuredAdmins[user]
+	b.accessMu.RUnlock()
+
+	if configuredAdmin {
+		return chat.RoleAdmin
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"userRole LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return chat.RoleGuest
+	}
+
+	return b.profileRole(user, profile)
+}
+
+// profileRole returns the effective role of the user with the loaded profile, see
+// userRole.
+//
+// user: The ID of the user.
+// profile: The profile of the user.
+func (b *Bot) profileRole(user int64, profile *chat.Profile) chat.Role {
+	b.accessMu.RLock()
+	_, configuredAdmin := b.configuredAdmins[user]
+	b.accessMu.RUnlock()
+
+	if configuredAdmin {
+		return chat.RoleAdmin
+	}
+
+	if profile.Role != "" {
+		return profile.Role
+	}
+
+	if b.IsUserAllowed(user) || profile.HasAccess(chat.Now()) {
+		return chat.RoleUser
+	}
+
+	return chat.RoleGuest
+}
+
+// syncRole updates the cached administrators with the role of the user persisted
+// in the storage, so administrators assigned with /setrole get their privileges.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) syncRole(ctx context.Context, user int64) {
+	admin := b.userRole(ctx, user) == chat.RoleAdmin
+
+	b.accessMu.Lock()
+	defer b.accessMu.Unlock()
+
+	if admin {
+		b.adminUsers[user] = struct{}{}
+	} else {
+		delete(b.adminUsers, user)
+	}
+}
+
+// admins returns the IDs of the known administrators.
+func (b *Bot) admins() []int64 {
+	b.accessMu.RLock()
+	defer b.accessMu.RUnlock()
+
+	admins := make([]int64, 0, len(b.adminUsers))
+	for adminID := range b.adminUsers {
+		admins = append(admins, adminID)
+	}
+
+	return admins
+}
+
+// roleBudget returns the default monthly budget of the user's role, or the default
+// user budget if the role has none.
+//
+// role: The role of the user.
+func (b *Bot) roleBudget(role chat.Role) chat.Cost {
+	if budget := b.roleBudgets[role]; budget > 0 {
+		return budget
+	}
+
+	return b.defaultBudget
+}
+
+// handleSetRole assigns a role to a user: /setrole <user> <role>. The assigned role
+// takes precedence over the access derived from the allowlist; "reset" removes the
+// assigned role. The administrators from the configuration cannot be demoted.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /setrole command.
+func (b *Bot) handleSetRole(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRoleUsage, rolesList()))
+		return
+	}
+
+	user, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSetRoleUsage, rolesList()))
+		return
+	}
+
+	var role chat.Role
+	if args[1] != "reset" {
+		if role, err = chat.ParseRole(args[1]); err != nil {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgSetRoleUsage, rolesList()))
+			return
+		}
+	}
+
+	b.setRole(ctx, msg, user, role)
+}
+
+// handleGrantAdmin promotes a user to an administrator: /grantadmin <user>, the same
+// as /setrole <user> admin.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /grantadmin command.
+func (b *Bot) handleGrantAdmin(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgGrantAdminUsage))
+		return
+	}
+
+	b.setRole(ctx, msg, user, chat.RoleAdmin)
+}
+
+// handleRevokeAdmin demotes an administrator: /revokeadmin <user>, the same as
+// /setrole <user> reset, except that the role of a user who is not an administrator
+// is left as it is.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /revokeadmin command.
+func (b *Bot) handleRevokeAdmin(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgRevokeAdminUsage))
+		return
+	}
+
+	if b.userRole(ctx, user) != chat.RoleAdmin {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgRevokeAdminNotAdmin, user))
+		return
+	}
+
+	b.setRole(ctx, msg, user, "")
+}
+
+// setRole persists the role of the user, applies it to the cached access and replies
+// with the effective role. The administrators from the configuration cannot be demoted.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the command.
+// user: The ID of the user.
+// role: The role to assign; empty means the role follows from the access.
+func (b *Bot) setRole(ctx context.Context, msg *tgbotapi.Message, user int64, role chat.Role) {
+	b.accessMu.RLock()
+	_, configuredAdmin := b.configuredAdmins[user]
+	b.accessMu.RUnlock()
+
+	if configuredAdmin && role != chat.RoleAdmin {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgRevokeAdminConfigured, user))
+		return
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		b.handleError(ctx, msg, "setRole LoadProfile", err)
+		return
+	}
+
+	profile.Role = role
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		b.handleError(ctx, msg, "setRole SaveProfile", err)
+		return
+	}
+
+	// Drop the cached runtime grant, the access follows from the role from now on.
+	b.accessMu.Lock()
+	delete(b.allowedUsers, user)
+	b.restoreConfiguredAccess(user)
+	b.accessMu.Unlock()
+
+	b.syncRole(ctx, user)
+
+	slog.Info(
+		"setRole finished",
+		slog.Int64("adminID", msg.From.ID),
+		slog.Int64("userID", user),
+		slog.String("role", string(role)),
+	)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgRole, user, b.userRole(ctx, user)))
+}
+
+// rolesList returns the names of all roles separated by commas.
+func rolesList() string {
+	names := make([]string, len(chat.Roles))
+	for i, role := range chat.Roles {
+		names[i] = string(role)
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// scheduler limits the number of messages processed at once. When all slots are busy,
+// the messages wait in two queues, and a freed slot goes to the first waiting message
+// of an administrator before the messages of the other users, so the operators can
+// still control the bot during an overload.
+type scheduler struct {
+	mu     sync.Mutex
+	free   int             // free is the number of free slots.
+	admins []chan struct{} // admins holds the waiting messages of the administrators.
+	users  []chan struct{} // users holds the waiting messages of the other users.
+}
+
+// newScheduler creates a scheduler processing at most slots messages at once.
+//
+// slots: The maximum number of messages processed at once.
+func newScheduler(slots int) *scheduler {
+	return &scheduler{free: slots}
+}
+
+// SetMaxConcurrentMessages limits the number of messages processed at once; the other
+// messages wait, the messages of the administrators first. Zero removes the limit.
+//
+// limit: The maximum number of messages processed at once.
+func (b *Bot) SetMaxConcurrentMessages(limit int) {
+	if limit <= 0 {
+		b.scheduler = nil
+		return
+	}
+
+	b.scheduler = newScheduler(limit)
+}
+
+// acquire waits for a free slot.
+//
+// ctx: The context; waiting stops when it is done.
+// admin: Whether the message is from an administrator.
+//
+// Returns false if the context was done before a slot became free.
+func (s *scheduler) acquire(ctx context.Context, admin bool) bool {
+	s.mu.Lock()
+	if s.free > 0 {
+		s.free--
+		s.mu.Unlock()
+		return true
+	}
+
+	ready := make(chan struct{})
+	if admin {
+		s.admins = append(s.admins, ready)
+	} else {
+		s.users = append(s.users, ready)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return true
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		select {
+		case <-ready:
+			// The slot was handed over meanwhile, so it is passed on.
+			s.handOver()
+		default:
+			s.admins = remove(s.admins, ready)
+			s.users = remove(s.users, ready)
+		}
+		return false
+	}
+}
+
+// release frees the slot, handing it over to the next waiting message.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handOver()
+}
+
+// handOver gives a free slot to the next waiting message, the messages of the
+// administrators first. The caller must hold the lock.
+func (s *scheduler) handOver() {
+	switch {
+	case len(s.admins) > 0:
+		close(s.admins[0])
+		s.admins = s.admins[1:]
+	case len(s.users) > 0:
+		close(s.users[0])
+		s.users = s.users[1:]
+	default:
+		s.free++
+	}
+}
+
+// remove removes the waiting message from the queue.
+func remove(queue []chan struct{}, ready chan struct{}) []chan struct{} {
+	for i, c := range queue {
+		if c == ready {
+			return append(queue[:i], queue[i+1:]...)
+		}
+	}
+
+	return queue
+}
+
+// scheduleMessage processes the message once the scheduler has a free slot for it.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message to process.
+func (b *Bot) scheduleMessage(ctx context.Context, msg *tgbotapi.Message) {
+	if b.scheduler != nil && msg.From != nil {
+		if !b.scheduler.acquire(ctx, b.IsUserAdmin(msg.From.ID)) {
+			return
+		}
+		defer b.scheduler.release()
+	}
+
+	b.handleMessage(ctx, msg)
+}
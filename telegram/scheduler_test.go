@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waiting returns the number of the waiting messages of the scheduler.
+func (s *scheduler) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.admins) + len(s.users)
+}
+
+// queueJob waits for a slot of the scheduler in the background until the number of the
+// waiting messages is want, and returns the channel receiving the result of the wait.
+func queueJob(t *testing.T, ctx context.Context, s *scheduler, admin bool, want int) <-chan bool {
+	t.Helper()
+
+	acquired := make(chan bool, 1)
+	go func() { acquired <- s.acquire(ctx, admin) }()
+
+	deadline := time.Now().Add(time.Second)
+	for s.waiting() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("The job was not queued, %d messages are waiting", s.waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return acquired
+}
+
+func TestSchedulerAdminFirst(t *testing.T) {
+	// Setup: the only slot is busy, two user jobs and then an admin job are queued.
+	ctx := context.Background()
+	s := newScheduler(1)
+	if !s.acquire(ctx, false) {
+		t.Fatal("The free slot was not acquired")
+	}
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	run := func(name string, acquired <-chan bool) {
+		defer wg.Done()
+		if <-acquired {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			s.release()
+		}
+	}
+
+	wg.Add(3)
+	go run("user 1", queueJob(t, ctx, s, false, 1))
+	go run("user 2", queueJob(t, ctx, s, false, 2))
+	go run("admin", queueJob(t, ctx, s, true, 3))
+
+	// Execute.
+	s.release()
+	wg.Wait()
+
+	// Assert.
+	want := []string{"admin", "user 1", "user 2"}
+	if len(order) != len(want) {
+		t.Fatalf("The jobs ran in the order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("The jobs ran in the order %v, want %v", order, want)
+		}
+	}
+
+	if s.free != 1 {
+		t.Errorf("The scheduler has %d free slots after the jobs, want 1", s.free)
+	}
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	// Setup: the only slot is busy and jobs are waiting for it.
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newScheduler(1)
+	if !s.acquire(ctx, false) {
+		t.Fatal("The free slot was not acquired")
+	}
+
+	user := queueJob(t, ctx, s, false, 1)
+	admin := queueJob(t, ctx, s, true, 2)
+
+	// Execute: the shutdown cancels the waiting jobs.
+	cancel()
+
+	// Assert.
+	for name, acquired := range map[string]<-chan bool{"user": user, "admin": admin} {
+		select {
+		case ok := <-acquired:
+			if ok {
+				t.Errorf("The %s job acquired a slot after the shutdown", name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("The %s job is still waiting after the shutdown", name)
+		}
+	}
+
+	if n := s.waiting(); n != 0 {
+		t.Errorf("%d jobs are left in the queue after the shutdown", n)
+	}
+
+	// The running job finishes and its slot is free again.
+	s.release()
+	if s.free != 1 {
+		t.Errorf("The scheduler has %d free slots after the shutdown, want 1", s.free)
+	}
+}
@@ -36,4 +36,26 @@ type Sender interface {
 	//   - error: An error encountered while making the request to Telegram's API. If the
 	//            request was successful, this will be nil.
 	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+
+	// MakeRequest calls a Bot API method by its name. It is used for the methods which
+	// have no Chattable configuration in the library (e.g., setMessageReaction).
+	//
+	// Parameters:
+	//   - endpoint: The name of the Bot API method.
+	//   - params: The parameters of the method.
+	//
+	// Returns:
+	//   - APIResponse: The response from Telegram.
+	//   - error: An error encountered while making the request.
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+
+	// GetFileDirectURL returns the URL to download a file uploaded to Telegram.
+	//
+	// Parameters:
+	//   - fileID: The identifier of the file.
+	//
+	// Returns:
+	//   - string: The download URL of the file.
+	//   - error: An error encountered while requesting the file information.
+	GetFileDirectURL(fileID string) (string, error)
 }
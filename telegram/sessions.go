@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// maxListedSessions limits the number of sessions listed by the /sessions command
+// to keep the reply within Telegram's message size limit.
+const maxListedSessions = 50
+
+// handleSessions replies with the list of sessions currently cached by the session
+// provider, including the last access time and the history length of each one.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /sessions command.
+func (b *Bot) handleSessions(ctx context.Context, msg *tgbotapi.Message) {
+	sessions, err := b.session.Sessions(ctx)
+	if err != nil {
+		b.handleError(ctx, msg, "handleSessions Sessions", err)
+		return
+	}
+
+	if len(sessions) == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSessionsEmpty))
+		return
+	}
+
+	now := chat.Now()
+	sb := &strings.Builder{}
+	for i, s := range sessions {
+		if i == maxListedSessions {
+			sb.WriteString(b.printer.Sprintf(lang.MsgSessionsMore, len(sessions)-maxListedSessions))
+			break
+		}
+
+		history := strconv.Itoa(s.HistoryLength)
+		if s.Busy {
+			history = b.printer.Sprintf(lang.MsgSessionBusy)
+		}
+
+		sb.WriteString(fmt.Sprintf(
+			"%d %d %s | %v | %s\n",
+			s.User, s.Chat, s.Model,
+			now.Sub(s.LastAccess).Round(time.Second),
+			history,
+		))
+	}
+
+	b.Send(msg.Chat.ID, b.printer.Sprintf(lang.MsgSessions, len(sessions), sb.String()))
+}
+
+// handleEvict removes a specific session from the session provider cache. The command
+// expects the user ID and the chat ID of the session, optionally followed by the model
+// name (the bot's default model is used if omitted): /evict <user> <chat> [model].
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /evict command.
+func (b *Bot) handleEvict(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 2 || len(args) > 3 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgEvictUsage))
+		return
+	}
+
+	user, errUser := strconv.ParseInt(args[0], 10, 64)
+	chatID, errChat := strconv.ParseInt(args[1], 10, 64)
+	if errUser != nil || errChat != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgEvictUsage))
+		return
+	}
+
+	id := chat.ID{User: user, Chat: chatID, Model: b.model}
+	if len(args) == 3 {
+		id.Model = args[2]
+	}
+
+	evicted, err := b.session.Evict(ctx, id)
+	if err != nil {
+		b.handleError(ctx, msg, "handleEvict Evict", err)
+		return
+	}
+
+	if !evicted {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgSessionNotFound))
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgDone))
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// statementHour is the hour of the first day of a month at which the statements
+// for the previous month are sent, in chat.DefaultLocation.
+const statementHour = 9
+
+// RunMonthlyStatements sends every user who used the bot during the previous month
+// a statement of their usage on the first day of every month until the context is
+// cancelled. The statement contains the number of messages, the tokens consumed and
+// the cost in the bot's currency.
+//
+// ctx: The context controlling the lifecycle of the scheduler.
+func (b *Bot) RunMonthlyStatements(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextStatement(chat.Now().In(chat.DefaultLocation))))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+
+		case <-timer.C:
+		}
+
+		month := chat.Now().In(chat.DefaultLocation).AddDate(0, -1, 0)
+		if err := b.sendMonthlyStatements(ctx, month); err != nil {
+			slog.Error("RunMonthlyStatements sendMonthlyStatements error", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// nextStatement returns the time of the next monthly statements after the given time.
+func nextStatement(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), 1, statementHour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 1, 0)
+	}
+
+	return next
+}
+
+// sendMonthlyStatements sends the statements for the given month to all users who
+// used the bot during that month. Messages are delivered sequentially with the
+// broadcast rate limit.
+//
+// ctx: The context for the storage operations. Cancelling it stops the delivery.
+// month: Any time within the month to report.
+//
+// Returns an error if the statistics could not be loaded.
+func (b *Bot) sendMonthlyStatements(ctx context.Context, month time.Time) error {
+	type statement struct {
+		usage chat.Usage
+		cost  chat.Cost
+	}
+
+	statements := make(map[int64]*statement)
+	err := chat.EachStatistics(ctx, b.storage, func(stats *chat.Statistics) error {
+		usage := stats.MonthlyUsage(month)
+		cost := stats.Month(month)
+		if usage.Messages == 0 && cost == 0 {
+			return nil
+		}
+
+		s, exists := statements[stats.User]
+		if !exists {
+			s = &statement{}
+			statements[stats.User] = s
+		}
+
+		s.usage.Messages += usage.Messages
+		s.usage.Tokens.Input += usage.Tokens.Input
+		s.usage.Tokens.Output += usage.Tokens.Output
+		s.cost += cost
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error loading statistics: %w", err)
+	}
+
+	users := make([]int64, 0, len(statements))
+	for user := range statements {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for _, user := range users {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+		}
+
+		s := statements[user]
+		text := b.printer.Sprintf(
+			lang.MsgMonthlyStatement,
+			month.Format(chat.MonthLayout),
+			s.usage.Messages,
+			s.usage.Tokens.Input, s.usage.Tokens.Output,
+			b.currency, b.amount(s.cost),
+		)
+
+		if err := b.deliver(user, text); err != nil {
+			slog.Error(
+				"sendMonthlyStatements deliver error",
+				slog.Int64("userID", user),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chart"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// chartDays is the number of days shown on the /stats spend chart.
+const chartDays = 30
+
+// formatModelCosts formats the all-time cost breakdown per model for the /stats reply.
+// It returns an empty string if no per-model costs have been recorded.
+//
+// stats: The statistics of the chat session.
+// p: The pricing of the user the costs are shown to.
+func (b *Bot) formatModelCosts(stats *chat.Statistics, p pricing) string {
+	if len(stats.PerModel) == 0 {
+		return ""
+	}
+
+	models := make([]string, 0, len(stats.PerModel))
+	width := 0
+	for model := range stats.PerModel {
+		models = append(models, model)
+		width = max(width, len(model))
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return stats.PerModel[models[i]] > stats.PerModel[models[j]]
+	})
+
+	sb := &strings.Builder{}
+	for _, model := range models {
+		sb.WriteString(fmt.Sprintf(
+			"%-*s: %s%.2f\n",
+			width, model,
+			p.currency, p.amount(stats.PerModel[model]),
+		))
+	}
+
+	return b.printer.Sprintf(lang.MsgStatsPerModel, sb.String())
+}
+
+// sendStatsChart renders the daily spend of the chat session over the last chartDays
+// days as a bar chart and sends it to the chat. Nothing is sent if there was no spend
+// during the period. Rendering and sending errors are logged, since the chart is
+// supplementary to the textual statistics.
+//
+// msg: The message containing the /stats command.
+// stats: The statistics of the chat session.
+// now: The current time; its day is the last day of the chart.
+// p: The pricing of the user the chart is shown to.
+func (b *Bot) sendStatsChart(msg *tgbotapi.Message, stats *chat.Statistics, now time.Time, p pricing) {
+	series := stats.DailySeries(now, chartDays)
+
+	var (
+		labels = make([]string, len(series))
+		values = make([]float64, len(series))
+		spent  bool
+	)
+	for i, cost := range series {
+		labels[i] = now.AddDate(0, 0, i-len(series)+1).Format("01-02")
+		values[i] = p.amount(cost)
+		spent = spent || cost > 0
+	}
+
+	if !spent {
+		return
+	}
+
+	// The bitmap font of the chart covers ASCII only, so the localized title goes to the caption.
+	png, err := chart.Bar(
+		"",
+		labels,
+		values,
+		strings.ReplaceAll(asciiOnly(p.currency), "%", "%%")+"%.2f",
+	)
+	if err != nil {
+		slog.Error(
+			"sendStatsChart Bar error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileBytes{Name: "stats.png", Bytes: png})
+	photo.Caption = b.printer.Sprintf(lang.MsgStatsChartTitle, chartDays)
+	if _, err := b.sender.Send(photo); err != nil {
+		slog.Error(
+			"sendStatsChart Send error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// asciiOnly removes the non-ASCII characters from the string, e.g., currency symbols
+// which cannot be drawn with the chart font.
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return -1
+		}
+		return r
+	}, s)
+}
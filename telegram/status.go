@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// statusCheckTimeout limits the duration of a single status check.
+const statusCheckTimeout = time.Second * 10
+
+// StatusCheck is a self-diagnostic probe reported by the /status command.
+// It checks the health of a single dependency of the bot.
+//
+// ctx: The context for the check, which carries the check timeout.
+//
+// Returns optional details to display next to the check result (e.g., a number
+// of active sessions) and an error if the dependency is unhealthy.
+type StatusCheck func(ctx context.Context) (details string, err error)
+
+// statusCheck is a named StatusCheck.
+type statusCheck struct {
+	name  string
+	check StatusCheck
+}
+
+// AddStatusCheck registers a self-diagnostic probe which is executed and reported
+// by the admin /status command. Checks are reported in the order they were added,
+// after the built-in Telegram API check.
+//
+// name: The name of the checked dependency (e.g., "OpenAI").
+// check: The probe to execute.
+func (b *Bot) AddStatusCheck(name string, check StatusCheck) {
+	b.statusChecks = append(b.statusChecks, statusCheck{name: name, check: check})
+}
+
+// handleStatus runs all status checks concurrently and replies with a single
+// formatted report containing the result and latency of each check along with
+// the bot's uptime.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /status command.
+func (b *Bot) handleStatus(ctx context.Context, msg *tgbotapi.Message) {
+	checks := append([]statusCheck{{name: "Telegram", check: b.checkTelegram}}, b.statusChecks...)
+
+	// Pad the names to align the results.
+	width := 0
+	for _, c := range checks {
+		width = max(width, len(c.name))
+	}
+
+	lines := make([]string, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c statusCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, statusCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := c.check(checkCtx)
+			latency := time.Since(start).Round(time.Millisecond)
+
+			result := b.printer.Sprintf(lang.MsgStatusOK)
+			if err != nil {
+				result = b.printer.Sprintf(lang.MsgStatusFailed, err.Error())
+			}
+
+			line := fmt.Sprintf("%-*s: %s, %v", width, c.name, result, latency)
+			if details != "" {
+				line += ", " + details
+			}
+
+			lines[i] = line
+		}(i, c)
+	}
+	wg.Wait()
+
+	uptime := time.Since(b.started).Round(time.Second)
+	b.Send(msg.Chat.ID, b.printer.Sprintf(lang.MsgStatus, uptime, strings.Join(lines, "\n")))
+}
+
+// checkTelegram checks Telegram Bot API reachability with a lightweight read-only request.
+func (b *Bot) checkTelegram(_ context.Context) (string, error) {
+	if _, err := b.sender.Request(tgbotapi.GetMyCommandsConfig{}); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
@@ -0,0 +1,240 @@
+// Package telegramtest provides the helpers for testing the Telegram bot without
+// connecting to Telegram.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Call is a Bot API method called with MakeRequest.
+type Call struct {
+	Endpoint string          // Endpoint is the name of the Bot API method.
+	Params   tgbotapi.Params // Params holds the parameters of the method.
+}
+
+// Sender is a fake telegram.Sender which records everything sent through it and
+// answers with scripted responses. By default every call succeeds: Send returns a
+// message with a new ID in the chat of the content, Request and MakeRequest return
+// a successful response.
+//
+// Sender is safe for concurrent use.
+type Sender struct {
+	// OnSend, if set, answers the Send calls instead of the default response.
+	OnSend func(c tgbotapi.Chattable) (tgbotapi.Message, error)
+
+	// OnRequest, if set, answers the Request calls instead of the default response.
+	OnRequest func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+
+	// OnMakeRequest, if set, answers the MakeRequest calls instead of the default response.
+	OnMakeRequest func(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+
+	// Files maps the file IDs to the download URLs returned by GetFileDirectURL.
+	Files map[string]string
+
+	mu        sync.Mutex
+	sent      []tgbotapi.Chattable // sent holds the content of the Send calls.
+	requested []tgbotapi.Chattable // requested holds the content of the Request calls.
+	calls     []Call               // calls holds the MakeRequest calls.
+	failures  []error              // failures holds the errors of the next calls.
+	messageID int                  // messageID is the ID of the last sent message.
+}
+
+// NewSender returns a Sender which accepts everything sent through it.
+func NewSender() *Sender {
+	return &Sender{Files: make(map[string]string)}
+}
+
+// Fail makes the next calls of Send, Request or MakeRequest fail with the errors, one
+// error per call, in the order given.
+//
+// errs: The errors to return.
+func (s *Sender) Fail(errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, errs...)
+}
+
+// Send records the content and returns the scripted response.
+//
+// c: The content to send.
+func (s *Sender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.mu.Lock()
+	s.sent = append(s.sent, c)
+	err := s.failure()
+	s.messageID++
+	id := s.messageID
+	s.mu.Unlock()
+
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	if s.OnSend != nil {
+		return s.OnSend(c)
+	}
+
+	return tgbotapi.Message{
+		MessageID: id,
+		Chat:      &tgbotapi.Chat{ID: ChatOf(c)},
+	}, nil
+}
+
+// Request records the content and returns the scripted response.
+//
+// c: The content to send.
+func (s *Sender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	s.mu.Lock()
+	s.requested = append(s.requested, c)
+	err := s.failure()
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.OnRequest != nil {
+		return s.OnRequest(c)
+	}
+
+	return success(), nil
+}
+
+// MakeRequest records the call and returns the scripted response.
+//
+// endpoint: The name of the Bot API method.
+// params: The parameters of the method.
+func (s *Sender) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Endpoint: endpoint, Params: params})
+	err := s.failure()
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.OnMakeRequest != nil {
+		return s.OnMakeRequest(endpoint, params)
+	}
+
+	return success(), nil
+}
+
+// GetFileDirectURL returns the URL of the file from Files.
+//
+// fileID: The identifier of the file.
+func (s *Sender) GetFileDirectURL(fileID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	url, ok := s.Files[fileID]
+	if !ok {
+		return "", &tgbotapi.Error{Code: 400, Message: fmt.Sprintf("Bad Request: file %s not found", fileID)}
+	}
+
+	return url, nil
+}
+
+// Sent returns the content of the Send calls in the order they were made.
+func (s *Sender) Sent() []tgbotapi.Chattable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]tgbotapi.Chattable(nil), s.sent...)
+}
+
+// Requested returns the content of the Request calls in the order they were made.
+func (s *Sender) Requested() []tgbotapi.Chattable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]tgbotapi.Chattable(nil), s.requested...)
+}
+
+// Calls returns the MakeRequest calls in the order they were made.
+func (s *Sender) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// Messages returns the text messages sent with Send, skipping the other content.
+func (s *Sender) Messages() []tgbotapi.MessageConfig {
+	var messages []tgbotapi.MessageConfig
+	for _, c := range s.Sent() {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Texts returns the texts of the sent messages and of the message edits.
+func (s *Sender) Texts() []string {
+	var texts []string
+	for _, c := range s.Sent() {
+		switch c := c.(type) {
+		case tgbotapi.MessageConfig:
+			texts = append(texts, c.Text)
+		case tgbotapi.EditMessageTextConfig:
+			texts = append(texts, c.Text)
+		}
+	}
+
+	return texts
+}
+
+// Reset forgets everything sent so far and the pending failures.
+func (s *Sender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent, s.requested, s.calls, s.failures = nil, nil, nil, nil
+}
+
+// failure takes the error of the next call, if any. The caller must hold the lock.
+func (s *Sender) failure() error {
+	if len(s.failures) == 0 {
+		return nil
+	}
+
+	err := s.failures[0]
+	s.failures = s.failures[1:]
+	return err
+}
+
+// success returns a successful response of the Bot API.
+func success() *tgbotapi.APIResponse {
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}
+}
+
+// ChatOf returns the ID of the chat the content is sent to, or 0 if unknown.
+//
+// c: The content to send.
+func ChatOf(c tgbotapi.Chattable) int64 {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID
+	case tgbotapi.PhotoConfig:
+		return c.ChatID
+	case tgbotapi.DocumentConfig:
+		return c.ChatID
+	case tgbotapi.VoiceConfig:
+		return c.ChatID
+	case tgbotapi.AudioConfig:
+		return c.ChatID
+	case tgbotapi.ChatActionConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID
+	default:
+		return 0
+	}
+}
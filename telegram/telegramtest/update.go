@@ -0,0 +1,42 @@
+package telegramtest
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageID is the ID of the last message created with NewMessage.
+var messageID atomic.Int64
+
+// NewMessage returns a text message of the user in the private chat with the bot.
+// A text starting with "/" is marked as a command.
+//
+// user: The ID of the user, which is also the ID of the private chat.
+// text: The text of the message.
+func NewMessage(user int64, text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: int(messageID.Add(1)),
+		From:      &tgbotapi.User{ID: user, FirstName: "Test"},
+		Chat:      &tgbotapi.Chat{ID: user, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	return msg
+}
+
+// NewUpdate wraps the message in an update.
+//
+// id: The ID of the update.
+// msg: The message of the update.
+func NewUpdate(id int, msg *tgbotapi.Message) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: msg}
+}
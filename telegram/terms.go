@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// callbackAcceptTerms is the callback action accepting the terms of use. Its argument
+// is the version of the accepted terms.
+const callbackAcceptTerms = "terms"
+
+// termsExemptCommands are the commands served before the terms of use are accepted,
+// as they send nothing to the model.
+var termsExemptCommands = map[string]bool{
+	"start":        true,
+	"help":         true,
+	"version":      true,
+	"deletemydata": true,
+}
+
+// SetTerms requires the users to accept the terms of use before the bot sends anything
+// to the model. The acceptance is persisted with its time; when the text of the terms
+// changes, the users are asked to accept them again. Administrators are not asked.
+//
+// terms: The text of the terms of use; empty disables the acceptance.
+func (b *Bot) SetTerms(terms string) {
+	sum := sha256.Sum256([]byte(terms))
+
+	b.terms = terms
+	b.termsVersion = hex.EncodeToString(sum[:8])
+}
+
+// checkTerms asks the user to accept the terms of use if the user has not accepted
+// them yet.
+//
+// ctx: The context for the storage operation.
+// msg: The message being processed.
+//
+// Returns false if the message must not be processed until the terms are accepted.
+func (b *Bot) checkTerms(ctx context.Context, msg *tgbotapi.Message) bool {
+	if b.terms == "" || b.IsUserAdmin(msg.From.ID) || b.acceptedTerms(ctx, msg.From.ID) {
+		return true
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, b.printer.Sprintf(lang.MsgTermsPrompt, b.terms))
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				b.printer.Sprintf(lang.MsgTermsAccept),
+				fmt.Sprintf("%s:%s", callbackAcceptTerms, b.termsVersion),
+			),
+		),
+	)
+	if _, err := b.sender.Send(reply); err != nil {
+		slog.Error(
+			"checkTerms Send error",
+			slog.Int64("chatID", msg.Chat.ID),
+			slog.Int64("userID", msg.From.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return msg.IsCommand() && termsExemptCommands[msg.Command()]
+}
+
+// acceptedTerms reports whether the user has accepted the current terms of use. If the
+// profile of the user cannot be loaded, the terms are considered not accepted.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) acceptedTerms(ctx context.Context, user int64) bool {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"acceptedTerms LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+
+	return !profile.TermsAccepted.IsZero() && profile.TermsVersion == b.termsVersion
+}
+
+// handleAcceptTermsCallback records the acceptance of the terms of use by the user who
+// pressed the Accept button.
+//
+// ctx: The context for controlling the processing lifecycle.
+// cq: The callback query of the Accept button.
+// version: The version of the accepted terms.
+func (b *Bot) handleAcceptTermsCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, version string) {
+	if version != b.termsVersion {
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgTermsChanged))
+		return
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, cq.From.ID)
+	if err == nil {
+		profile.TermsAccepted, profile.TermsVersion = time.Now().UTC(), version
+		err = b.storage.SaveProfile(ctx, profile)
+	}
+	if err != nil {
+		slog.Error(
+			"handleAcceptTermsCallback error",
+			slog.Int64("userID", cq.From.ID),
+			slog.String("error", err.Error()),
+		)
+		b.answerCallback(cq, b.printer.Sprintf(lang.MsgUnexpectedError, b.adminContact, err.Error()))
+		return
+	}
+
+	slog.Info(
+		"handleAcceptTermsCallback accepted",
+		slog.Int64("userID", cq.From.ID),
+		slog.String("version", version),
+	)
+
+	b.editCallbackMessage(cq, b.printer.Sprintf(
+		lang.MsgTermsAccepted,
+		b.terms, profile.TermsAccepted.Format("2006-01-02 15:04:05 MST"),
+	))
+	b.answerCallback(cq, "")
+}
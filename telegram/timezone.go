@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// handleTimezone shows or changes the time zone used for the day and month
+// boundaries of the user's statistics: /timezone [IANA name|reset].
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /timezone command.
+func (b *Bot) handleTimezone(ctx context.Context, msg *tgbotapi.Message) {
+	profile, err := b.storage.LoadProfile(ctx, msg.From.ID)
+	if err != nil {
+		b.handleError(ctx, msg, "handleTimezone LoadProfile", err)
+		return
+	}
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgTimezone, profile.Location().String()))
+		return
+	}
+
+	if strings.EqualFold(args, "reset") {
+		profile.Timezone = ""
+	} else {
+		loc, err := time.LoadLocation(args)
+		if err != nil || args == "Local" {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgTimezoneUsage))
+			return
+		}
+		profile.Timezone = loc.String()
+	}
+
+	if err := b.storage.SaveProfile(ctx, profile); err != nil {
+		b.handleError(ctx, msg, "handleTimezone SaveProfile", err)
+		return
+	}
+
+	// Cached sessions keep the time zone they were created with.
+	b.evictUserSessions(ctx, msg.From.ID)
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgTimezone, profile.Location().String()))
+}
+
+// userLocation returns the time zone of the given user. If the user's profile
+// cannot be loaded, the error is logged and chat.DefaultLocation is returned.
+//
+// ctx: The context for the storage operation.
+// user: The ID of the user.
+func (b *Bot) userLocation(ctx context.Context, user int64) *time.Location {
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		slog.Error(
+			"userLocation LoadProfile error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return chat.DefaultLocation
+	}
+
+	return profile.Location()
+}
+
+// evictUserSessions evicts all cached sessions of the given user, so they are
+// recreated with the user's current profile settings. Errors are logged.
+//
+// ctx: The context for the session provider operations.
+// user: The ID of the user.
+func (b *Bot) evictUserSessions(ctx context.Context, user int64) {
+	sessions, err := b.session.Sessions(ctx)
+	if err != nil {
+		slog.Error(
+			"evictUserSessions Sessions error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, s := range sessions {
+		if s.User != user {
+			continue
+		}
+
+		if _, err := b.session.Evict(ctx, s.ID); err != nil {
+			slog.Error(
+				"evictUserSessions Evict error",
+				slog.Int64("userID", user),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
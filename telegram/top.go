@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// defaultTopUsers is the number of users shown by /top when no limit is given.
+const defaultTopUsers = 10
+
+// handleTop replies with the users who spent the most today and this month:
+// /top [N]. It helps to quickly identify abuse or runaway usage.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /top command.
+func (b *Bot) handleTop(ctx context.Context, msg *tgbotapi.Message) {
+	limit := defaultTopUsers
+	if args := strings.TrimSpace(msg.CommandArguments()); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 || n > maxListedUsers {
+			b.Reply(msg, b.printer.Sprintf(lang.MsgTopUsage, maxListedUsers))
+			return
+		}
+		limit = n
+	}
+
+	rollup, err := b.rollup(ctx)
+	if err != nil {
+		b.handleError(ctx, msg, "handleTop rollup", err)
+		return
+	}
+
+	today := topSpenders(rollup.PerUser, limit, func(t chat.Totals) chat.Cost { return t.Today })
+	month := topSpenders(rollup.PerUser, limit, func(t chat.Totals) chat.Cost { return t.ThisMonth })
+
+	b.Send(msg.Chat.ID, b.printer.Sprintf(
+		lang.MsgTop,
+		limit,
+		b.formatSpenders(today, rollup.PerUser, func(t chat.Totals) chat.Cost { return t.Today }),
+		limit,
+		b.formatSpenders(month, rollup.PerUser, func(t chat.Totals) chat.Cost { return t.ThisMonth }),
+		rollup.Updated.Format("2006-01-02 15:04:05 MST"),
+	))
+}
+
+// topSpenders returns up to limit users with the highest non-zero cost selected by the
+// given function, in descending order of the cost.
+func topSpenders(perUser map[int64]chat.Totals, limit int, cost func(chat.Totals) chat.Cost) []int64 {
+	users := make([]int64, 0, len(perUser))
+	for user, totals := range perUser {
+		if cost(totals) > 0 {
+			users = append(users, user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return cost(perUser[users[i]]) > cost(perUser[users[j]])
+	})
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users
+}
+
+// formatSpenders formats a ranked list of users with their cost selected by the given function.
+func (b *Bot) formatSpenders(users []int64, perUser map[int64]chat.Totals, cost func(chat.Totals) chat.Cost) string {
+	if len(users) == 0 {
+		return "-\n"
+	}
+
+	sb := &strings.Builder{}
+	for i, user := range users {
+		sb.WriteString(fmt.Sprintf(
+			"%2d. %d: %s%.2f\n",
+			i+1, user,
+			b.currency, b.amount(cost(perUser[user])),
+		))
+	}
+
+	return sb.String()
+}
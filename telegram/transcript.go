@@ -0,0 +1,149 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+	"github.com/muzykantov/tgpt/transcript"
+)
+
+// Limits of the imported history files.
+const (
+	maxImportSize       = 5 << 20
+	maxImportedMessages = 1000
+)
+
+// handleExport sends the conversation of the session as a formatted HTML document with
+// highlighted code, which can be viewed in a browser, printed or saved as PDF. With the
+// "json" argument the raw history is sent instead, which can be loaded with /import.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /export command.
+// session: The chat session to export.
+func (b *Bot) handleExport(ctx context.Context, msg *tgbotapi.Message, session chat.Session) {
+	history, err := session.History(ctx)
+	if err != nil {
+		b.handleError(ctx, msg, "handleExport History", err)
+		return
+	}
+
+	if len(history.Log) == 0 {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgExportEmpty))
+		return
+	}
+
+	now := chat.Now().In(b.userLocation(ctx, msg.From.ID))
+
+	if strings.TrimSpace(msg.CommandArguments()) == "json" {
+		buf := &bytes.Buffer{}
+		if err := history.Write(buf); err != nil {
+			b.handleError(ctx, msg, "handleExport Write", err)
+			return
+		}
+
+		b.sendDocument(ctx, msg, fmt.Sprintf("conversation-%s.json", now.Format("2006-01-02")), buf.Bytes())
+		return
+	}
+
+	doc, err := transcript.HTML(history, b.printer.Sprintf(lang.MsgExportTitle, b.name), now)
+	if err != nil {
+		b.handleError(ctx, msg, "handleExport HTML", err)
+		return
+	}
+
+	b.sendDocument(ctx, msg, fmt.Sprintf("conversation-%s.html", now.Format("2006-01-02")), doc)
+}
+
+// sendDocument sends a file in reply to the message.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message to reply to.
+// name: The name of the file.
+// content: The content of the file.
+func (b *Bot) sendDocument(ctx context.Context, msg *tgbotapi.Message, name string, content []byte) {
+	file := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{Name: name, Bytes: content})
+	file.ReplyToMessageID = msg.MessageID
+
+	if _, err := b.sender.Send(file); err != nil {
+		b.handleError(ctx, msg, "sendDocument Send", err)
+	}
+}
+
+// isImport reports whether the message is a history file captioned with /import.
+func isImport(msg *tgbotapi.Message) bool {
+	return msg.Document != nil && strings.HasPrefix(msg.Caption, "/import")
+}
+
+// handleImport loads a history file exported with "/export json" into the session of
+// the chat, replacing its conversation. The file is sent captioned with /import, or
+// /import is sent in reply to the file. The file is validated before the import: it
+// must be a history with at most maxImportedMessages complete exchanges.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message with the /import command.
+func (b *Bot) handleImport(ctx context.Context, msg *tgbotapi.Message) {
+	doc := msg.Document
+	if doc == nil && msg.ReplyToMessage != nil {
+		doc = msg.ReplyToMessage.Document
+	}
+
+	if doc == nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgImportUsage))
+		return
+	}
+
+	if doc.FileSize > maxImportSize {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgImportInvalid))
+		return
+	}
+
+	content, err := b.downloadFile(ctx, doc.FileID, maxImportSize)
+	if err != nil {
+		b.handleError(ctx, msg, "handleImport downloadFile", err)
+		return
+	}
+
+	history := &chat.History{}
+	if err := history.Read(bytes.NewReader(content)); err != nil || !validImport(history) {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgImportInvalid))
+		return
+	}
+
+	session, err := b.session.ProvideSession(ctx, chat.ID{
+		User:  msg.From.ID,
+		Chat:  msg.Chat.ID,
+		Model: b.sessionModel(ctx, msg.From.ID),
+	})
+	if err != nil {
+		b.handleError(ctx, msg, "handleImport ProvideSession", err)
+		return
+	}
+
+	if err := session.Import(ctx, history.Log); err != nil {
+		b.handleError(ctx, msg, "handleImport Import", err)
+		return
+	}
+
+	b.Reply(msg, b.printer.Sprintf(lang.MsgImported, len(history.Log)))
+}
+
+// validImport reports whether the imported history is a non-empty conversation of
+// complete exchanges within the size limit.
+func validImport(history *chat.History) bool {
+	if len(history.Log) == 0 || len(history.Log) > maxImportedMessages {
+		return false
+	}
+
+	for _, msg := range history.Log {
+		if strings.TrimSpace(msg.User) == "" || strings.TrimSpace(msg.Assistant) == "" {
+			return false
+		}
+	}
+
+	return true
+}
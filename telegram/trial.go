@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// TrialQuota defines the free usage granted to users who are not in the allowlist.
+// A zero value of a limit means the limit does not apply; the trial is disabled if
+// both limits are zero.
+type TrialQuota struct {
+	Messages int       // Messages is the number of free messages.
+	Budget   chat.Cost // Budget is the free spending allowance, before the conversion with the bot's rate.
+}
+
+// SetTrialQuota enables the trial mode: users who are not in the allowlist may use
+// the bot until they exhaust the quota, after which they are rejected like any other
+// unknown user.
+//
+// quota: The free usage granted to unknown users.
+func (b *Bot) SetTrialQuota(quota TrialQuota) {
+	b.trial = quota
+}
+
+// isUserPermitted reports whether the user may interact with the bot, either because
+// the user is in the allowlist, was granted access at runtime, or has not exhausted
+// the trial quota.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user to check.
+func (b *Bot) isUserPermitted(ctx context.Context, user int64) bool {
+	if b.IsUserAllowed(user) || b.isPersistedAllowed(ctx, user) {
+		return true
+	}
+
+	if b.trial.Messages <= 0 && b.trial.Budget <= 0 {
+		return false
+	}
+
+	usage, cost, err := b.userUsage(ctx, user)
+	if err != nil {
+		slog.Error(
+			"isUserPermitted userUsage error",
+			slog.Int64("userID", user),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+
+	if b.trial.Messages > 0 && usage.Messages >= b.trial.Messages {
+		return false
+	}
+
+	if b.trial.Budget > 0 && cost >= b.trial.Budget {
+		return false
+	}
+
+	return true
+}
+
+// userUsage returns the all-time usage and cost of all chat sessions of the user,
+// kept in the user's ledger, so deleting the statistics does not renew the trial.
+//
+// ctx: The context for the storage operations.
+// user: The ID of the user.
+//
+// Returns the usage, the cost and an error if the ledger could not be loaded.
+func (b *Bot) userUsage(ctx context.Context, user int64) (chat.Usage, chat.Cost, error) {
+	ledger, err := b.storage.LoadLedger(ctx, user)
+	if err != nil {
+		return chat.Usage{}, 0, fmt.Errorf("error loading ledger: %w", err)
+	}
+
+	return ledger.Usage, ledger.Total, nil
+}
@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// maxListedUsers limits the number of users listed in the per-user breakdown
+// of the /usage report to keep the reply within Telegram's message size limit.
+const maxListedUsers = 30
+
+// RollupProvider defines an interface for obtaining the aggregated statistics
+// of all chat sessions used by the administrative reports.
+type RollupProvider interface {
+	// Rollup returns the aggregated statistics of all chat sessions.
+	//
+	// ctx: The context for the operation, which allows for deadline control and cancellation.
+	//
+	// Returns the rollup and an error if it could not be obtained.
+	Rollup(ctx context.Context) (*chat.Rollup, error)
+}
+
+// SetRollupProvider configures the source of the aggregated statistics used by the
+// administrative reports. If no provider is set, the statistics of all sessions are
+// aggregated from the storage on every report.
+//
+// provider: The RollupProvider implementation to use.
+func (b *Bot) SetRollupProvider(provider RollupProvider) {
+	b.rollups = provider
+}
+
+// rollup returns the aggregated statistics from the configured provider, or
+// aggregates them from the storage if no provider is configured.
+func (b *Bot) rollup(ctx context.Context) (*chat.Rollup, error) {
+	if b.rollups != nil {
+		return b.rollups.Rollup(ctx)
+	}
+
+	return chat.Aggregate(ctx, b.storage, chat.Now().In(chat.DefaultLocation))
+}
+
+// handleUsage replies with the fleet-wide spend for today and this month, followed
+// by the per-user and per-model breakdowns, based on the aggregated statistics.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /usage command.
+func (b *Bot) handleUsage(ctx context.Context, msg *tgbotapi.Message) {
+	rollup, err := b.rollup(ctx)
+	if err != nil {
+		b.handleError(ctx, msg, "handleUsage rollup", err)
+		return
+	}
+
+	users := make([]int64, 0, len(rollup.PerUser))
+	for user := range rollup.PerUser {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return rollup.PerUser[users[i]].ThisMonth > rollup.PerUser[users[j]].ThisMonth
+	})
+
+	models := make([]string, 0, len(rollup.PerModel))
+	for model := range rollup.PerModel {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return rollup.PerModel[models[i]].ThisMonth > rollup.PerModel[models[j]].ThisMonth
+	})
+
+	userLines := &strings.Builder{}
+	for i, user := range users {
+		if i == maxListedUsers {
+			userLines.WriteString(b.printer.Sprintf(lang.MsgSessionsMore, len(users)-maxListedUsers))
+			break
+		}
+		userLines.WriteString(b.formatTotals(fmt.Sprint(user), rollup.PerUser[user]))
+	}
+
+	modelLines := &strings.Builder{}
+	for _, model := range models {
+		modelLines.WriteString(b.formatTotals(model, rollup.PerModel[model]))
+	}
+
+	b.Send(msg.Chat.ID, b.printer.Sprintf(
+		lang.MsgUsage,
+		b.currency, b.amount(rollup.Global.Today),
+		b.currency, b.amount(rollup.Global.ThisMonth),
+		b.currency, b.amount(rollup.Global.Total),
+		len(users),
+		userLines.String(),
+		modelLines.String(),
+		rollup.Updated.Format("2006-01-02 15:04:05 MST"),
+	))
+}
+
+// formatTotals formats a single line of a usage breakdown.
+func (b *Bot) formatTotals(name string, t chat.Totals) string {
+	return fmt.Sprintf(
+		"%s: %s%.2f / %s%.2f / %s%.2f\n",
+		name,
+		b.currency, b.amount(t.Today),
+		b.currency, b.amount(t.ThisMonth),
+		b.currency, b.amount(t.Total),
+	)
+}
@@ -0,0 +1,61 @@
+package telegram
+
+import (
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// AllowUsernames adds users to the allowlist by their Telegram usernames. Since the
+// Bot API cannot look up users by username, every username is resolved to the user
+// ID when the user contacts the bot for the first time, and the ID is cached in the
+// allowlist from then on.
+//
+// usernames: The usernames to allow, with or without the leading "@".
+func (b *Bot) AllowUsernames(usernames ...string) {
+	b.accessMu.Lock()
+	defer b.accessMu.Unlock()
+
+	for _, username := range usernames {
+		b.allowedUsernames[normalizeUsername(username)] = struct{}{}
+	}
+}
+
+// resolveUsername adds the user to the allowlist if the user's username is allowed
+// and the user ID is not cached yet.
+//
+// user: The sender of an update.
+func (b *Bot) resolveUsername(user *tgbotapi.User) {
+	if user == nil || user.UserName == "" {
+		return
+	}
+
+	username := normalizeUsername(user.UserName)
+
+	b.accessMu.RLock()
+	_, allowed := b.allowedUsernames[username]
+	_, cached := b.allowedUsers[user.ID]
+	b.accessMu.RUnlock()
+
+	if !allowed || cached {
+		return
+	}
+
+	b.accessMu.Lock()
+	b.allowedUsers[user.ID] = struct{}{}
+	b.configuredUsers[user.ID] = struct{}{}
+	b.accessMu.Unlock()
+
+	slog.Info(
+		"resolveUsername allowed user",
+		slog.Int64("userID", user.ID),
+		slog.String("username", username),
+	)
+}
+
+// normalizeUsername strips the leading "@" and lowercases the username, since
+// Telegram usernames are case-insensitive.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+	"github.com/muzykantov/tgpt/lang"
+)
+
+// handleUserStats replies with the profile, the statistics of the stored sessions and
+// the cached sessions of a user: /userstats <user>.
+//
+// ctx: The context for controlling the processing lifecycle.
+// msg: The message containing the /userstats command.
+func (b *Bot) handleUserStats(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		b.Reply(msg, b.printer.Sprintf(lang.MsgUserStatsUsage))
+		return
+	}
+
+	profile, err := b.storage.LoadProfile(ctx, user)
+	if err != nil {
+		b.handleError(ctx, msg, "handleUserStats LoadProfile", err)
+		return
+	}
+
+	ids, err := b.storage.List(ctx)
+	if err != nil {
+		b.handleError(ctx, msg, "handleUserStats List", err)
+		return
+	}
+
+	now := chat.Now().In(profile.Location())
+
+	var (
+		stored     int
+		total      chat.Totals
+		tokens     chat.Tokens
+		lastUpdate time.Time
+		lines      = &strings.Builder{}
+	)
+	for _, id := range ids {
+		if id.User != user {
+			continue
+		}
+
+		stats, err := b.storage.LoadStatistics(ctx, id)
+		if err != nil {
+			b.handleError(ctx, msg, "handleUserStats LoadStatistics", err)
+			return
+		}
+
+		var totals chat.Totals
+		totals.Add(stats, now)
+		total.Add(stats, now)
+		tokens.Input += stats.TotalTokens.Input
+		tokens.Output += stats.TotalTokens.Output
+		if stats.LastUpdate.After(lastUpdate) {
+			lastUpdate = stats.LastUpdate
+		}
+
+		stored++
+		if stored <= maxListedSessions {
+			lines.WriteString(b.formatTotals(fmt.Sprintf("%d %s", id.Chat, id.Model), totals))
+		}
+	}
+	if stored > maxListedSessions {
+		lines.WriteString(b.printer.Sprintf(lang.MsgSessionsMore, stored-maxListedSessions))
+	}
+
+	last := "-"
+	if !lastUpdate.IsZero() {
+		last = lastUpdate.In(profile.Location()).Format("2006-01-02 15:04:05 MST")
+	}
+
+	active, activeLines := b.formatUserSessions(ctx, user)
+
+	b.Send(msg.Chat.ID, b.printer.Sprintf(
+		lang.MsgUserStats,
+		user,
+		b.userRole(ctx, user),
+		profile.HasAccess(chat.Now()) || b.IsUserAllowed(user), profile.Banned,
+		profile.Location(),
+		stored, lines.String(),
+		b.currency, b.amount(total.Today),
+		b.currency, b.amount(total.ThisMonth),
+		b.currency, b.amount(total.Total),
+		tokens.Input, tokens.Output,
+		last,
+		active, activeLines,
+	))
+}
+
+// formatUserSessions formats the sessions of the user cached by the session provider.
+//
+// ctx: The context for the session provider operation.
+// user: The ID of the user.
+//
+// Returns the number of the sessions and their lines, "-" if there are none.
+func (b *Bot) formatUserSessions(ctx context.Context, user int64) (int, string) {
+	sessions, err := b.session.Sessions(ctx)
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	now := chat.Now()
+	count, sb := 0, &strings.Builder{}
+	for _, s := range sessions {
+		if s.User != user {
+			continue
+		}
+
+		history := strconv.Itoa(s.HistoryLength)
+		if s.Busy {
+			history = b.printer.Sprintf(lang.MsgSessionBusy)
+		}
+
+		count++
+		sb.WriteString(fmt.Sprintf(
+			"%d %s | %v | %s\n",
+			s.Chat, s.Model, now.Sub(s.LastAccess).Round(time.Second), history,
+		))
+	}
+
+	if count == 0 {
+		return 0, "-"
+	}
+
+	return count, sb.String()
+}
@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// SetVoiceReplies configures whether the replies to voice messages are also sent
+// as synthesized voice messages in addition to the text.
+//
+// enabled: True to send voice replies.
+func (b *Bot) SetVoiceReplies(enabled bool) {
+	b.voiceReplies = enabled
+}
+
+// transcribeVoice downloads the voice message from Telegram and transcribes it
+// with the session, which accounts the cost of the transcription.
+//
+// ctx: The context for the download and the transcription.
+// voice: The voice message to transcribe.
+// session: The chat session of the user.
+//
+// Returns the transcribed text and an error if the voice message could not be
+// downloaded or transcribed.
+func (b *Bot) transcribeVoice(ctx context.Context, voice *tgbotapi.Voice, session chat.Session) (string, error) {
+	file, err := b.openFile(ctx, voice.FileID)
+	if err != nil {
+		return "", fmt.Errorf("error downloading voice file: %w", err)
+	}
+	defer file.Close()
+
+	// Telegram voice messages are OGG files encoded with Opus.
+	duration := time.Duration(voice.Duration) * time.Second
+	return session.Transcribe(ctx, file, "voice.ogg", duration)
+}
+
+// sendVoiceReply synthesizes the reply with the session, which accounts the cost of
+// the synthesis, and sends it as a voice message in reply to the given message.
+//
+// ctx: The context for the synthesis.
+// msg: The message to reply to.
+// session: The chat session of the user.
+// reply: The text of the reply.
+//
+// Returns an error if the speech could not be synthesized or sent.
+func (b *Bot) sendVoiceReply(ctx context.Context, msg *tgbotapi.Message, session chat.Session, reply string) error {
+	speech, err := session.Speak(ctx, reply)
+	if err != nil {
+		return err
+	}
+
+	voice := tgbotapi.NewVoice(msg.Chat.ID, tgbotapi.FileBytes{Name: "reply.ogg", Bytes: speech})
+	voice.ReplyToMessageID = msg.MessageID
+	if _, err := b.sender.Send(voice); err != nil {
+		return fmt.Errorf("error sending voice: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package telegraph publishes pages on Telegraph (telegra.ph), used to share answers
+// which are too long for Telegram messages.
+package telegraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the endpoint of the Telegraph API.
+const DefaultURL = "https://api.telegra.ph"
+
+// requestTimeout limits the duration of a single API request.
+const requestTimeout = time.Second * 30
+
+// Node is an element of the page content: a string for text, or an Element.
+type Node any
+
+// Element is an HTML element of the page content.
+type Element struct {
+	Tag      string `json:"tag"`
+	Children []Node `json:"children,omitempty"`
+}
+
+// Client publishes pages with a Telegraph account.
+type Client struct {
+	// client is the HTTP client used to call the API.
+	client *http.Client
+
+	// url is the endpoint of the Telegraph API.
+	url string
+
+	// token is the access token of the Telegraph account.
+	token string
+
+	// author is the author name displayed on the pages.
+	author string
+}
+
+// NewClient creates a new Client for an existing account.
+//
+// token: The access token of the Telegraph account.
+// author: The author name displayed on the pages.
+//
+// Returns a pointer to the newly created Client.
+func NewClient(token, author string) *Client {
+	return &Client{
+		client: &http.Client{Timeout: requestTimeout},
+		url:    DefaultURL,
+		token:  token,
+		author: author,
+	}
+}
+
+// CreateAccount creates a new Telegraph account and returns a Client for it.
+//
+// ctx: The context for the API request.
+// name: The short name of the account, also used as the author name.
+//
+// Returns a pointer to the Client and an error if the account could not be created.
+func CreateAccount(ctx context.Context, name string) (*Client, error) {
+	c := NewClient("", name)
+
+	var account struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.call(ctx, "createAccount", url.Values{
+		"short_name":  {name},
+		"author_name": {name},
+	}, &account); err != nil {
+		return nil, err
+	}
+
+	c.token = account.AccessToken
+	return c, nil
+}
+
+// Token returns the access token of the account, which may be stored to reuse the account.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// Publish creates a page with the given Markdown text.
+//
+// ctx: The context for the API request.
+// title: The title of the page.
+// markdown: The content of the page; code blocks and paragraphs are preserved.
+//
+// Returns the URL of the page and an error if the page could not be created.
+func (c *Client) Publish(ctx context.Context, title, markdown string) (string, error) {
+	content, err := json.Marshal(Content(markdown))
+	if err != nil {
+		return "", fmt.Errorf("error encoding content: %w", err)
+	}
+
+	var page struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, "createPage", url.Values{
+		"access_token": {c.token},
+		"title":        {title},
+		"author_name":  {c.author},
+		"content":      {string(content)},
+	}, &page); err != nil {
+		return "", err
+	}
+
+	return page.URL, nil
+}
+
+// call calls the API method and decodes its result.
+//
+// ctx: The context for the API request.
+// method: The name of the API method.
+// params: The parameters of the method.
+// result: The value to decode the result into.
+func (c *Client) call(ctx context.Context, method string, params url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		OK     bool            `json:"ok"`
+		Error  string          `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", method, err)
+	}
+
+	if !body.OK {
+		return fmt.Errorf("error calling %s: %s", method, body.Error)
+	}
+
+	if err := json.Unmarshal(body.Result, result); err != nil {
+		return fmt.Errorf("error decoding %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// Content converts Markdown text into the page content. Fenced code blocks become
+// preformatted blocks, "#" headings become headings, and the remaining text is split
+// into paragraphs at blank lines. Inline markup is kept as is.
+//
+// markdown: The text to convert.
+func Content(markdown string) []Node {
+	var (
+		nodes     []Node
+		paragraph []string
+		code      []string
+		inCode    bool
+	)
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			nodes = append(nodes, Element{Tag: "p", Children: []Node{strings.Join(paragraph, "\n")}})
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			if inCode {
+				nodes = append(nodes, Element{Tag: "pre", Children: []Node{strings.Join(code, "\n")}})
+				code = nil
+			} else {
+				flush()
+			}
+			inCode = !inCode
+
+		case inCode:
+			code = append(code, line)
+
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			nodes = append(nodes, Element{Tag: "h4", Children: []Node{strings.TrimSpace(strings.TrimLeft(trimmed, "#"))}})
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+
+	// An unterminated code block is kept as code.
+	if inCode {
+		nodes = append(nodes, Element{Tag: "pre", Children: []Node{strings.Join(code, "\n")}})
+	}
+	flush()
+
+	return nodes
+}
@@ -0,0 +1,21 @@
+package telegraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContent(t *testing.T) {
+	markdown := "# Title\n\nFirst line\nsecond line\n\n```go\nfmt.Println(\"hi\")\n\n```\nLast"
+
+	want := []Node{
+		Element{Tag: "h4", Children: []Node{"Title"}},
+		Element{Tag: "p", Children: []Node{"First line\nsecond line"}},
+		Element{Tag: "pre", Children: []Node{"fmt.Println(\"hi\")\n"}},
+		Element{Tag: "p", Children: []Node{"Last"}},
+	}
+
+	if got := Content(markdown); !reflect.DeepEqual(got, want) {
+		t.Errorf("Content() = %#v, want %#v", got, want)
+	}
+}
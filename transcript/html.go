@@ -0,0 +1,167 @@
+// Package transcript renders chat histories as documents which users can keep,
+// print or share outside Telegram.
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+// page is the template of the HTML transcript. The code blocks are highlighted with
+// highlight.js when the document is opened online and stay readable offline.
+var page = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/styles/github.min.css">
+<script src="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/highlight.min.js"></script>
+<script>window.addEventListener("load", function () { if (window.hljs) { hljs.highlightAll(); } });</script>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 800px; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+h1 { font-size: 1.4em; }
+.meta { color: #777; font-size: 0.9em; }
+.message { border-radius: 8px; padding: 0.5em 1em; margin: 1em 0; }
+.user { background: #e8f1fb; }
+.assistant { background: #f5f5f5; }
+.prompt { background: #fff8e1; }
+.role { font-weight: bold; font-size: 0.85em; color: #555; }
+pre { background: #fff; border: 1px solid #ddd; border-radius: 4px; padding: 0.5em; overflow-x: auto; }
+code { font-family: "SFMono-Regular", Consolas, monospace; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Model}} · {{.Exported}}</p>
+{{if .Prompt}}<div class="message prompt"><div class="role">System</div>{{.Prompt}}</div>
+{{end}}{{range .Messages}}<div class="message user"><div class="role">User</div>{{.User}}</div>
+<div class="message assistant"><div class="role">Assistant</div>{{.Assistant}}</div>
+{{end}}</body>
+</html>
+`))
+
+// message is an exchange rendered as HTML.
+type message struct {
+	User      template.HTML
+	Assistant template.HTML
+}
+
+// HTML renders the history as a standalone HTML document.
+//
+// history: The history to render.
+// title: The title of the document.
+// exported: The time of the export, displayed in the document.
+//
+// Returns the HTML document and an error if it could not be rendered.
+func HTML(history *chat.History, title string, exported time.Time) ([]byte, error) {
+	data := struct {
+		Title    string
+		Model    string
+		Exported string
+		Prompt   template.HTML
+		Messages []message
+	}{
+		Title:    title,
+		Model:    history.Model,
+		Exported: exported.Format("2006-01-02 15:04 MST"),
+		Prompt:   markdown(history.Prompt),
+	}
+
+	for _, msg := range history.Log {
+		data.Messages = append(data.Messages, message{
+			User:      markdown(msg.User),
+			Assistant: markdown(msg.Assistant),
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	if err := page.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("error rendering transcript: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Inline Markdown markup supported in the paragraphs.
+var (
+	inlineCode = regexp.MustCompile("`([^`\n]+)`")
+	bold       = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+)
+
+// markdown converts the basic Markdown used by the models into HTML: fenced code
+// blocks, paragraphs, inline code and bold text. All other text is escaped.
+//
+// text: The Markdown text.
+func markdown(text string) template.HTML {
+	var (
+		sb        strings.Builder
+		paragraph []string
+		code      []string
+		language  string
+		inCode    bool
+	)
+
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+
+		p := html.EscapeString(strings.Join(paragraph, "\n"))
+		p = inlineCode.ReplaceAllString(p, "<code>$1</code>")
+		p = bold.ReplaceAllString(p, "<strong>$1</strong>")
+		p = strings.ReplaceAll(p, "\n", "<br>")
+
+		sb.WriteString("<p>" + p + "</p>\n")
+		paragraph = nil
+	}
+
+	writeCode := func() {
+		class := ""
+		if language != "" {
+			class = ` class="language-` + html.EscapeString(language) + `"`
+		}
+
+		sb.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		code = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			if inCode {
+				writeCode()
+			} else {
+				flush()
+				language = strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			}
+			inCode = !inCode
+
+		case inCode:
+			code = append(code, line)
+
+		case trimmed == "":
+			flush()
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+
+	// An unterminated code block is kept as code.
+	if inCode {
+		writeCode()
+	}
+	flush()
+
+	return template.HTML(sb.String())
+}
@@ -0,0 +1,37 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muzykantov/tgpt/chat"
+)
+
+func TestHTML(t *testing.T) {
+	history := &chat.History{
+		ID: chat.ID{User: 1, Chat: 1, Model: "test-model"},
+		Log: []chat.Message{
+			{
+				User:      "How do I print <b>?",
+				Assistant: "Use **fmt**:\n\n```go\nfmt.Println(\"<b>\")\n```",
+			},
+		},
+	}
+
+	doc, err := HTML(history, "Conversation", time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("HTML() error: %s", err)
+	}
+
+	for _, want := range []string{
+		"<p>How do I print &lt;b&gt;?</p>",
+		"<p>Use <strong>fmt</strong>:</p>",
+		`<pre><code class="language-go">fmt.Println(&#34;&lt;b&gt;&#34;)</code></pre>`,
+		"test-model · 2024-01-02 03:04 UTC",
+	} {
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("HTML() does not contain %q:\n%s", want, doc)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package version exposes the build metadata of the bot: its version, the VCS
+// commit it was built from and the build date.
+//
+// The values can be injected at build time with ldflags, for example:
+//
+//	go build -ldflags "-X github.com/muzykantov/tgpt/version.Version=v1.2.3 \
+//		-X github.com/muzykantov/tgpt/version.Commit=$(git rev-parse HEAD) \
+//		-X github.com/muzykantov/tgpt/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Values that are not injected are resolved from the build information embedded
+// by the Go toolchain (debug.ReadBuildInfo) whenever possible.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata injected with ldflags. Empty values are resolved from the
+// embedded build information.
+var (
+	Version string // Version is the semantic version of the build.
+	Commit  string // Commit is the VCS revision the build was made from.
+	Date    string // Date is the build date in RFC 3339 format.
+)
+
+// unknown is reported for metadata that could not be determined.
+const unknown = "unknown"
+
+// Info contains the resolved build metadata of the running binary.
+type Info struct {
+	Version   string // Version is the semantic version of the build.
+	Commit    string // Commit is the VCS revision the build was made from.
+	Date      string // Date is the build date (or the commit date if unavailable).
+	GoVersion string // GoVersion is the version of the Go toolchain used for the build.
+	Modified  bool   // Modified reports whether the working tree had local changes.
+}
+
+// Get returns the build metadata of the running binary. Values injected with
+// ldflags take precedence over the build information embedded by the toolchain.
+//
+// Returns:
+// Info: The resolved build metadata.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+
+	if info.Date == "" {
+		info.Date = unknown
+	}
+
+	return info
+}
+
+// Revision returns the commit the build was made from, suffixed with "-dirty"
+// if the working tree had local changes.
+func (i Info) Revision() string {
+	if i.Modified {
+		return i.Commit + "-dirty"
+	}
+
+	return i.Commit
+}
+
+// String returns a single-line human-readable representation of the build metadata.
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Revision(), i.Date, i.GoVersion)
+}